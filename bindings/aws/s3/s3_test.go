@@ -15,11 +15,21 @@ package s3
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -188,3 +198,145 @@ func TestDeleteOption(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+// newFakeS3Server returns a server implementing the S3 PutObject and ranged GetObject requests for path-style URLs.
+func newFakeS3Server(t *testing.T) (*httptest.Server, map[string][]byte) {
+	t.Helper()
+
+	var lock sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			objects[r.URL.Path] = data
+			w.Header().Set("ETag", `"etag"`)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+				return
+			}
+			var start, end int
+			_, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			require.NoError(t, err)
+			if start >= len(data) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				fmt.Fprint(w, `<Error><Code>InvalidRange</Code><Message>invalid range</Message></Error>`)
+				return
+			}
+			if end >= len(data) {
+				end = len(data) - 1
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, objects
+}
+
+func TestInvokeStream(t *testing.T) {
+	server, objects := newFakeS3Server(t)
+
+	s := NewAWSS3(logger.NewLogger("test")).(*AWSS3)
+	err := s.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"accessKey":      "key",
+		"secretKey":      "secret",
+		"region":         "us-east-1",
+		"bucket":         "test",
+		"endpoint":       server.URL,
+		"forcePathStyle": "true",
+		"disableSSL":     "true",
+	}}})
+	require.NoError(t, err)
+
+	assert.True(t, bindings.FeatureStreaming.IsPresent(bindings.Features(s)))
+
+	get := func(t *testing.T, md map[string]string) string {
+		t.Helper()
+		res, err := s.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  md,
+		})
+		require.NoError(t, err)
+		defer res.Data.Close()
+		data, err := io.ReadAll(res.Data)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		res, err := s.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.CreateOperation,
+			Data:      strings.NewReader("hello streaming world"),
+			Metadata:  map[string]string{"key": "obj"},
+		})
+		require.NoError(t, err)
+		defer res.Data.Close()
+
+		var created createResponse
+		require.NoError(t, json.NewDecoder(res.Data).Decode(&created))
+		assert.Contains(t, created.Location, "/test/obj")
+		assert.Equal(t, "hello streaming world", string(objects["/test/obj"]))
+	})
+
+	t.Run("create with base64 decoding", func(t *testing.T) {
+		_, err := s.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.CreateOperation,
+			Data:      strings.NewReader("aGVsbG8="),
+			Metadata:  map[string]string{"key": "b64", "decodeBase64": "true"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(objects["/test/b64"]))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		assert.Equal(t, "hello streaming world", get(t, map[string]string{"key": "obj"}))
+	})
+
+	t.Run("get with offset and length", func(t *testing.T) {
+		assert.Equal(t, "streaming", get(t, map[string]string{"key": "obj", "offset": "6", "length": "9"}))
+		assert.Equal(t, "world", get(t, map[string]string{"key": "obj", "offset": "16"}))
+		assert.Equal(t, "", get(t, map[string]string{"key": "obj", "offset": "100"}))
+	})
+
+	t.Run("get empty object", func(t *testing.T) {
+		objects["/test/empty"] = []byte{}
+		assert.Equal(t, "", get(t, map[string]string{"key": "empty"}))
+	})
+
+	t.Run("get missing object", func(t *testing.T) {
+		_, err := s.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{"key": "missing"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("get in multiple ranges", func(t *testing.T) {
+		r := &rangeReader{
+			ctx:       context.Background(),
+			client:    s.s3Client,
+			bucket:    "test",
+			key:       "obj",
+			end:       -1,
+			chunkSize: 4,
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "hello streaming world", string(data))
+	})
+}
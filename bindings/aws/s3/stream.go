@@ -0,0 +1,275 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	metadataOffset = "offset"
+	metadataLength = "length"
+
+	// Size of the ranges requested by streamed get operations.
+	defaultStreamChunkSize = s3manager.DefaultDownloadPartSize
+
+	errCodeInvalidRange = "InvalidRange"
+)
+
+// Features returns the features of the binding.
+func (s *AWSS3) Features() []bindings.Feature {
+	return []bindings.Feature{bindings.FeatureStreaming}
+}
+
+// InvokeStream is called for output bindings invoked with a streamed payload.
+// The create operation uploads the payload with a multipart upload, and the get
+// operation reads the object with sequential ranged requests, starting at the optional
+// `offset` metadata and reading up to `length` bytes.
+// Payloads are not base64-encoded by streamed get operations.
+func (s *AWSS3) InvokeStream(ctx context.Context, req *bindings.InvokeStreamRequest) (*bindings.InvokeStreamResponse, error) {
+	switch req.Operation { //nolint:exhaustive
+	case bindings.CreateOperation:
+		return s.createStream(ctx, req)
+	case bindings.GetOperation:
+		return s.getStream(ctx, req)
+	default:
+		invokeReq, err := req.ToInvokeRequest()
+		if err != nil {
+			return nil, fmt.Errorf("s3 binding error: %w", err)
+		}
+		res, err := s.Invoke(ctx, invokeReq)
+		if err != nil {
+			return nil, err
+		}
+		return bindings.NewInvokeStreamResponse(res), nil
+	}
+}
+
+func (s *AWSS3) createStream(ctx context.Context, req *bindings.InvokeStreamRequest) (*bindings.InvokeStreamResponse, error) {
+	metadata, err := s.metadata.mergeWithRequestMetadata(&bindings.InvokeRequest{Metadata: req.Metadata})
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: error merging metadata: %w", err)
+	}
+
+	key := req.Metadata[metadataKey]
+	if key == "" {
+		var u uuid.UUID
+		u, err = uuid.NewRandom()
+		if err != nil {
+			return nil, fmt.Errorf("s3 binding error: failed to generate UUID: %w", err)
+		}
+		key = u.String()
+		s.logger.Debugf("s3 binding error: key not found. generating key %s", key)
+	}
+
+	r := req.Data
+	if r == nil {
+		r = strings.NewReader("")
+	}
+	if metadata.DecodeBase64 {
+		r = b64.NewDecoder(b64.StdEncoding, r)
+	}
+
+	// The uploader switches to a multipart upload when the payload is larger than a part
+	resultUpload, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: ptr.Of(metadata.Bucket),
+		Key:    ptr.Of(key),
+		Body:   r,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: uploading failed: %w", err)
+	}
+
+	var presignURL string
+	if metadata.PresignTTL != "" {
+		url, presignErr := s.presignObject(metadata.Bucket, key, metadata.PresignTTL)
+		if presignErr != nil {
+			return nil, fmt.Errorf("s3 binding error: %s", presignErr)
+		}
+
+		presignURL = url
+	}
+
+	jsonResponse, err := json.Marshal(createResponse{
+		Location:   resultUpload.Location,
+		VersionID:  resultUpload.VersionID,
+		PresignURL: presignURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: error marshalling create response: %w", err)
+	}
+
+	return bindings.NewInvokeStreamResponse(&bindings.InvokeResponse{
+		Data: jsonResponse,
+	}), nil
+}
+
+func (s *AWSS3) getStream(ctx context.Context, req *bindings.InvokeStreamRequest) (*bindings.InvokeStreamResponse, error) {
+	key := req.Metadata[metadataKey]
+	if key == "" {
+		return nil, fmt.Errorf("s3 binding error: required metadata '%s' missing", metadataKey)
+	}
+
+	r := &rangeReader{
+		ctx:       ctx,
+		client:    s.s3Client,
+		bucket:    s.metadata.Bucket,
+		key:       key,
+		end:       -1,
+		chunkSize: defaultStreamChunkSize,
+	}
+
+	if val := req.Metadata[metadataOffset]; val != "" {
+		offset, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("s3 binding error: invalid value for metadata '%s': %s", metadataOffset, val)
+		}
+		r.pos = offset
+	}
+	if val := req.Metadata[metadataLength]; val != "" {
+		length, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("s3 binding error: invalid value for metadata '%s': %s", metadataLength, val)
+		}
+		r.end = r.pos + length
+	}
+
+	// Request the first range right away, so errors such as missing objects are returned to the caller
+	contentType, err := r.next()
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: error downloading S3 object: %w", err)
+	}
+
+	return &bindings.InvokeStreamResponse{
+		Data:        r,
+		ContentType: contentType,
+	}, nil
+}
+
+// rangeReader reads an object with sequential ranged GetObject requests.
+type rangeReader struct {
+	ctx       context.Context
+	client    *s3.S3
+	bucket    string
+	key       string
+	chunkSize int64
+
+	// Position of the next byte to read, and end of the range to read (exclusive), or -1 until the size of the object is known.
+	pos  int64
+	end  int64
+	body io.ReadCloser
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for {
+		if r.body == nil {
+			if r.end >= 0 && r.pos >= r.end {
+				return 0, io.EOF
+			}
+			_, err := r.next()
+			if err != nil {
+				return 0, err
+			}
+			if r.body == nil {
+				return 0, io.EOF
+			}
+		}
+
+		n, err := r.body.Read(p)
+		r.pos += int64(n)
+		if errors.Is(err, io.EOF) {
+			r.body.Close()
+			r.body = nil
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// next requests the next range of the object.
+func (r *rangeReader) next() (*string, error) {
+	last := r.pos + r.chunkSize - 1
+	if r.end >= 0 && last >= r.end {
+		last = r.end - 1
+	}
+	if last < r.pos {
+		// Nothing to read
+		return nil, nil
+	}
+
+	res, err := r.client.GetObjectWithContext(r.ctx, &s3.GetObjectInput{
+		Bucket: ptr.Of(r.bucket),
+		Key:    ptr.Of(r.key),
+		Range:  ptr.Of("bytes=" + strconv.FormatInt(r.pos, 10) + "-" + strconv.FormatInt(last, 10)),
+	})
+	if err != nil {
+		// Ranges starting at the end of the object are not satisfiable, which includes all ranges of empty objects
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == errCodeInvalidRange {
+			r.end = r.pos
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	size, ok := parseContentRangeSize(res.ContentRange)
+	if ok && (r.end < 0 || r.end > size) {
+		r.end = size
+	}
+	r.body = res.Body
+
+	return res.ContentType, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body != nil {
+		err := r.body.Close()
+		r.body = nil
+		return err
+	}
+	return nil
+}
+
+// parseContentRangeSize returns the size of the object from a Content-Range header such as `bytes 0-99/1000`.
+func parseContentRangeSize(contentRange *string) (int64, bool) {
+	if contentRange == nil {
+		return 0, false
+	}
+	idx := strings.LastIndexByte(*contentRange, '/')
+	if idx < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt((*contentRange)[idx+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
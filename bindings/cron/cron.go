@@ -16,6 +16,10 @@ package cron
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -24,9 +28,23 @@ import (
 	cron "github.com/dapr/kit/cron"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
 
+const (
+	defaultMaxCatchUpRuns = 10
+
+	timeZoneMetadataKey      = "timeZone"
+	readTimeMetadataKey      = "readTimeUTC"
+	scheduledTimeMetadataKey = "scheduledTime"
+	catchUpMetadataKey       = "catchUp"
+)
+
+var _ components.ResolverConsumer = (*Binding)(nil)
+
 // Binding represents Cron input binding.
 type Binding struct {
 	logger   logger.Logger
@@ -34,6 +52,32 @@ type Binding struct {
 	schedule string
 	parser   cron.Parser
 	clk      clock.Clock
+	metadata cronMetadata
+	sched    cron.Schedule
+	location *time.Location
+	resolver components.Resolver
+	store    state.Store
+	running  atomic.Bool
+
+	lastFireLock sync.Mutex
+	lastFire     time.Time
+}
+
+type cronMetadata struct {
+	Schedule string `mapstructure:"schedule"`
+	// TimeZone is the IANA time zone the schedule is evaluated in. Defaults to the local time zone.
+	TimeZone string `mapstructure:"timeZone"`
+	// Jitter is the upper bound of a random delay added before each scheduled run.
+	Jitter time.Duration `mapstructure:"jitter"`
+	// SkipIfRunning skips a run when the previous one has not completed yet.
+	SkipIfRunning bool `mapstructure:"skipIfRunning"`
+	// CatchUp fires the runs missed since the last recorded fire time when the binding starts.
+	// It requires StateStore to be set.
+	CatchUp bool `mapstructure:"catchUp"`
+	// StateStore is the name of the state store the last fire time is saved to.
+	StateStore string `mapstructure:"stateStore"`
+	// MaxCatchUpRuns is the maximum number of missed runs fired on start; only the most recent ones are kept.
+	MaxCatchUpRuns int `mapstructure:"maxCatchUpRuns"`
 }
 
 // NewCron returns a new Cron event input binding.
@@ -51,50 +95,172 @@ func NewCronWithClock(logger logger.Logger, clk clock.Clock) bindings.InputBindi
 	}
 }
 
+// SetComponentResolver sets the resolver of the state store named in the metadata.
+func (b *Binding) SetComponentResolver(resolver components.Resolver) {
+	b.resolver = resolver
+}
+
 // Init initializes the Cron binding
 // Examples from https://godoc.org/github.com/robfig/cron:
 //
 //	"15 * * * * *" - Every 15 sec
 //	"0 30 * * * *" - Every 30 min
-func (b *Binding) Init(metadata bindings.Metadata) error {
-	b.name = metadata.Name
-	s, f := metadata.Properties["schedule"]
-	if !f || s == "" {
+//	"@at 2023-01-02T15:04:05Z" - Once at the given time
+func (b *Binding) Init(meta bindings.Metadata) error {
+	b.name = meta.Name
+	m := cronMetadata{
+		MaxCatchUpRuns: defaultMaxCatchUpRuns,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse metadata")
+	}
+	if m.Schedule == "" {
 		return fmt.Errorf("schedule not set")
 	}
-	_, err := b.parser.Parse(s)
+	if m.Jitter < 0 {
+		return fmt.Errorf("jitter must not be negative")
+	}
+	if m.MaxCatchUpRuns < 1 {
+		return fmt.Errorf("maxCatchUpRuns must be greater than 0")
+	}
+	if m.CatchUp && m.StateStore == "" {
+		return fmt.Errorf("catch-up requires a state store")
+	}
+	if m.StateStore != "" && b.resolver == nil {
+		return fmt.Errorf("state store %s can't be used: the host doesn't provide other components", m.StateStore)
+	}
+
+	b.location = time.Local
+	if m.TimeZone != "" {
+		b.location, err = time.LoadLocation(m.TimeZone)
+		if err != nil {
+			return errors.Wrapf(err, "invalid time zone: %s", m.TimeZone)
+		}
+	}
+
+	b.sched, err = b.parseSchedule(m.Schedule)
 	if err != nil {
-		return errors.Wrapf(err, "invalid schedule format: %s", s)
+		return errors.Wrapf(err, "invalid schedule format: %s", m.Schedule)
 	}
-	b.schedule = s
+	b.schedule = m.Schedule
+	b.metadata = m
 
 	return nil
 }
 
 // Read triggers the Cron scheduler.
 func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
-	c := cron.New(cron.WithParser(b.parser), cron.WithClock(b.clk))
-	id, err := c.AddFunc(b.schedule, func() {
-		b.logger.Debugf("name: %s, schedule fired: %v", b.name, time.Now())
-		handler(ctx, &bindings.ReadResponse{
-			Metadata: map[string]string{
-				"timeZone":    c.Location().String(),
-				"readTimeUTC": time.Now().UTC().String(),
-			},
-		})
-	})
-	if err != nil {
-		return errors.Wrapf(err, "name: %s, error scheduling %s", b.name, b.schedule)
+	if b.metadata.StateStore != "" {
+		store, err := b.resolver.StateStore(b.metadata.StateStore)
+		if err != nil {
+			return errors.Wrapf(err, "name: %s, error getting state store %s", b.name, b.metadata.StateStore)
+		}
+		b.store = store
+	}
+
+	var missed []time.Time
+	if b.metadata.CatchUp {
+		lastFire, err := b.loadLastFire(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "name: %s, error loading last fire time", b.name)
+		}
+		b.lastFire = lastFire
+		missed = b.missedRuns(lastFire, b.clk.Now().In(b.location))
+	}
+
+	// The first timer is created before returning so that no run is missed while the scheduler starts
+	now := b.clk.Now().In(b.location)
+	next := b.sched.Next(now)
+	var timer *clock.Timer
+	if !next.IsZero() {
+		b.logger.Debugf("name: %s, next run: %v", b.name, next.Sub(now))
+		timer = b.clk.Timer(next.Sub(now))
 	}
-	c.Start()
-	b.logger.Debugf("name: %s, next run: %v", b.name, time.Until(c.Entry(id).Next))
 
 	go func() {
-		// Wait for context to be canceled
-		<-ctx.Done()
-		b.logger.Debugf("name: %s, stopping schedule: %s", b.name, b.schedule)
-		c.Stop()
+		for _, t := range missed {
+			if ctx.Err() != nil {
+				break
+			}
+			b.logger.Debugf("name: %s, catching up missed run: %v", b.name, t)
+			b.run(ctx, handler, t, true)
+		}
+
+		for !next.IsZero() {
+			select {
+			case <-ctx.Done():
+				// Wait for context to be canceled
+				timer.Stop()
+				b.logger.Debugf("name: %s, stopping schedule: %s", b.name, b.schedule)
+				return
+			case <-timer.C:
+			}
+
+			b.dispatch(ctx, handler, next)
+
+			now = b.clk.Now().In(b.location)
+			next = b.sched.Next(now)
+			if !next.IsZero() {
+				b.logger.Debugf("name: %s, next run: %v", b.name, next.Sub(now))
+				timer = b.clk.Timer(next.Sub(now))
+			}
+		}
+		b.logger.Debugf("name: %s, no more runs for schedule: %s", b.name, b.schedule)
 	}()
 
 	return nil
 }
+
+// dispatch starts the run for the scheduled time in the background, applying the overlap policy and jitter.
+func (b *Binding) dispatch(ctx context.Context, handler bindings.Handler, scheduled time.Time) {
+	if b.metadata.SkipIfRunning && !b.running.CompareAndSwap(false, true) {
+		b.logger.Infof("name: %s, skipping run scheduled at %v: previous run still in progress", b.name, scheduled)
+		return
+	}
+
+	go func() {
+		if b.metadata.SkipIfRunning {
+			defer b.running.Store(false)
+		}
+
+		if b.metadata.Jitter > 0 {
+			//nolint:gosec
+			delay := time.Duration(rand.Int63n(int64(b.metadata.Jitter)))
+			timer := b.clk.Timer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		b.run(ctx, handler, scheduled, false)
+	}()
+}
+
+// run invokes the handler for the scheduled time and, if it succeeds, records it as the last fire time.
+func (b *Binding) run(ctx context.Context, handler bindings.Handler, scheduled time.Time, catchUp bool) {
+	b.logger.Debugf("name: %s, schedule fired: %v", b.name, b.clk.Now())
+	_, err := handler(ctx, &bindings.ReadResponse{
+		Metadata: map[string]string{
+			timeZoneMetadataKey:      b.location.String(),
+			readTimeMetadataKey:      b.clk.Now().UTC().String(),
+			scheduledTimeMetadataKey: scheduled.Format(time.RFC3339),
+			catchUpMetadataKey:       strconv.FormatBool(catchUp),
+		},
+	})
+	if err != nil {
+		// The last fire time isn't saved, so that the run is caught up if the binding restarts
+		b.logger.Errorf("name: %s, error handling run scheduled at %v: %v", b.name, scheduled, err)
+		return
+	}
+
+	if b.store != nil {
+		err = b.saveLastFire(ctx, scheduled)
+		if err != nil {
+			b.logger.Errorf("name: %s, error saving last fire time: %v", b.name, err)
+		}
+	}
+}
@@ -16,13 +16,21 @@ package cron
 import (
 	"context"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
 	"github.com/dapr/kit/logger"
 )
 
@@ -35,6 +43,30 @@ func getTestMetadata(schedule string) bindings.Metadata {
 	return m
 }
 
+// stateStores resolves the state stores of the map.
+type stateStores map[string]state.Store
+
+func (s stateStores) StateStore(name string) (state.Store, error) {
+	if store, ok := s[name]; ok {
+		return store, nil
+	}
+	return nil, errors.Errorf("state store %s not found", name)
+}
+
+func (s stateStores) SecretStore(name string) (secretstores.SecretStore, error) {
+	return nil, errors.Errorf("secret store %s not found", name)
+}
+
+func (s stateStores) OutputBinding(name string) (bindings.OutputBinding, error) {
+	return nil, errors.Errorf("output binding %s not found", name)
+}
+
+func (s stateStores) PubSub(name string) (pubsub.PubSub, error) {
+	return nil, errors.Errorf("pubsub %s not found", name)
+}
+
+var _ components.Resolver = stateStores{}
+
 func getNewCron() *Binding {
 	clk := clock.New()
 	return getNewCronWithClock(clk)
@@ -76,6 +108,14 @@ func TestCronInitSuccess(t *testing.T) {
 			schedule:      "0 0 */6 ? * *", // quartz cron format
 			errorExpected: false,
 		},
+		{
+			schedule:      "@at 2023-01-02T15:04:05Z", // one-shot schedule
+			errorExpected: false,
+		},
+		{
+			schedule:      "@at tomorrow", // invalid one-shot schedule
+			errorExpected: true,
+		},
 		{
 			schedule:      "INVALID_SCHEDULE", // invalid cron format
 			errorExpected: true,
@@ -145,3 +185,270 @@ func TestCronReadWithContextCancellation(t *testing.T) {
 	assert.Equal(t, expectedCount, observedCount, "Cron did not trigger expected number of times, expected %d, got %d", expectedCount, observedCount)
 	assert.NoErrorf(t, err, "error on read")
 }
+
+func TestCronInitOptions(t *testing.T) {
+	t.Run("invalid time zone", func(t *testing.T) {
+		m := getTestMetadata("@every 1s")
+		m.Properties["timeZone"] = "Mars/Olympus_Mons"
+		assert.Error(t, getNewCron().Init(m))
+	})
+
+	t.Run("negative jitter", func(t *testing.T) {
+		m := getTestMetadata("@every 1s")
+		m.Properties["jitter"] = "-1s"
+		assert.Error(t, getNewCron().Init(m))
+	})
+
+	t.Run("invalid max catch-up runs", func(t *testing.T) {
+		m := getTestMetadata("@every 1s")
+		m.Properties["maxCatchUpRuns"] = "0"
+		assert.Error(t, getNewCron().Init(m))
+	})
+
+	t.Run("catch-up without state store", func(t *testing.T) {
+		m := getTestMetadata("@every 1s")
+		m.Properties["catchUp"] = "true"
+		assert.Error(t, getNewCron().Init(m))
+	})
+
+	t.Run("state store without resolver", func(t *testing.T) {
+		m := getTestMetadata("@every 1s")
+		m.Properties["stateStore"] = "statestore"
+		assert.Error(t, getNewCron().Init(m))
+	})
+
+	t.Run("unknown state store", func(t *testing.T) {
+		c := getNewCronWithClock(clock.NewMock())
+		c.SetComponentResolver(stateStores{})
+		m := getTestMetadata("@every 1s")
+		m.Properties["catchUp"] = "true"
+		m.Properties["stateStore"] = "statestore"
+		require.NoError(t, c.Init(m))
+		err := c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			return nil, nil
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestCronReadMetadata(t *testing.T) {
+	clk := clock.NewMock()
+	c := getNewCronWithClock(clk)
+	m := getTestMetadata("0 0 9 * * *")
+	m.Properties["timeZone"] = "America/New_York"
+	require.NoError(t, c.Init(m))
+
+	events := make(chan map[string]string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.Read(ctx, func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		events <- res.Metadata
+		return nil, nil
+	}))
+
+	// 9:00 in New York is 14:00 UTC on January 1st
+	time.Sleep(10 * time.Millisecond)
+	clk.Add(14 * time.Hour)
+
+	select {
+	case md := <-events:
+		assert.Equal(t, "America/New_York", md["timeZone"])
+		assert.Equal(t, "1970-01-01T09:00:00-05:00", md["scheduledTime"])
+		assert.Equal(t, "false", md["catchUp"])
+		assert.NotEmpty(t, md["readTimeUTC"])
+	case <-time.After(time.Second):
+		t.Fatal("schedule did not fire")
+	}
+}
+
+func TestCronReadSkipIfRunning(t *testing.T) {
+	clk := clock.NewMock()
+	c := getNewCronWithClock(clk)
+	m := getTestMetadata("@every 1s")
+	m.Properties["skipIfRunning"] = "true"
+	require.NoError(t, c.Init(m))
+
+	var count atomic.Int32
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.Read(ctx, func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		count.Add(1)
+		<-release
+		return nil, nil
+	}))
+
+	for i := 0; i < 5; i++ {
+		clk.Add(time.Second)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), count.Load())
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	clk.Add(time.Second)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), count.Load())
+}
+
+func TestCronReadJitter(t *testing.T) {
+	clk := clock.NewMock()
+	c := getNewCronWithClock(clk)
+	m := getTestMetadata("@every 1m")
+	m.Properties["jitter"] = "10s"
+	require.NoError(t, c.Init(m))
+
+	events := make(chan map[string]string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.Read(ctx, func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		events <- res.Metadata
+		return nil, nil
+	}))
+
+	time.Sleep(10 * time.Millisecond)
+	clk.Add(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	clk.Add(10 * time.Second)
+
+	select {
+	case md := <-events:
+		assert.Equal(t, "1970-01-01T00:01:00Z", md["scheduledTime"])
+	case <-time.After(time.Second):
+		t.Fatal("schedule did not fire")
+	}
+}
+
+func TestCronReadOneShot(t *testing.T) {
+	clk := clock.NewMock()
+	c := getNewCronWithClock(clk)
+	m := getTestMetadata("@at 1970-01-01T00:00:03Z")
+	m.Properties["timeZone"] = "UTC"
+	require.NoError(t, c.Init(m))
+
+	var count atomic.Int32
+	require.NoError(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		assert.Equal(t, "1970-01-01T00:00:03Z", res.Metadata["scheduledTime"])
+		count.Add(1)
+		return nil, nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		clk.Add(time.Second)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), count.Load())
+}
+
+func TestCronReadCatchUp(t *testing.T) {
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+
+	clk := clock.NewMock()
+	newBinding := func() *Binding {
+		c := getNewCronWithClock(clk)
+		c.SetComponentResolver(stateStores{"statestore": store})
+		m := getTestMetadata("0 * * * * *")
+		m.Name = "catchup"
+		m.Properties["timeZone"] = "UTC"
+		m.Properties["catchUp"] = "true"
+		m.Properties["stateStore"] = "statestore"
+		m.Properties["maxCatchUpRuns"] = "3"
+		require.NoError(t, c.Init(m))
+		return c
+	}
+
+	// First run records the last fire time
+	events := make(chan map[string]string, 10)
+	handler := func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		events <- res.Metadata
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, newBinding().Read(ctx, handler))
+	time.Sleep(10 * time.Millisecond)
+	clk.Add(time.Minute)
+	md := <-events
+	assert.Equal(t, "1970-01-01T00:01:00Z", md["scheduledTime"])
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	// The process is down for 10 minutes; only the 3 most recent runs are caught up
+	clk.Add(10*time.Minute + 30*time.Second)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, newBinding().Read(ctx, handler))
+
+	for _, expected := range []string{"1970-01-01T00:09:00Z", "1970-01-01T00:10:00Z", "1970-01-01T00:11:00Z"} {
+		select {
+		case md = <-events:
+			assert.Equal(t, expected, md["scheduledTime"])
+			assert.Equal(t, "true", md["catchUp"])
+		case <-time.After(time.Second):
+			t.Fatal("missed run was not caught up")
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	clk.Add(30 * time.Second)
+	select {
+	case md = <-events:
+		assert.Equal(t, "1970-01-01T00:12:00Z", md["scheduledTime"])
+		assert.Equal(t, "false", md["catchUp"])
+	case <-time.After(time.Second):
+		t.Fatal("schedule did not fire")
+	}
+}
+
+func TestCronMissedRuns(t *testing.T) {
+	c := getNewCron()
+	m := getTestMetadata("* * * * * *")
+	m.Properties["timeZone"] = "UTC"
+	m.Properties["maxCatchUpRuns"] = "3"
+	require.NoError(t, c.Init(m))
+
+	lastFire := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := time.Now()
+	missed := c.missedRuns(lastFire, now)
+	assert.Less(t, time.Since(start), time.Second, "the runs of a long outage aren't all scanned")
+	assert.Equal(t, []time.Time{now.Add(-2 * time.Second), now.Add(-time.Second), now}, missed)
+
+	// Fewer runs than maxCatchUpRuns were missed
+	assert.Equal(t, []time.Time{now.Add(-time.Second), now}, c.missedRuns(now.Add(-2*time.Second), now))
+	assert.Empty(t, c.missedRuns(now, now))
+	assert.Empty(t, c.missedRuns(time.Time{}, now))
+}
+
+func TestCronReadCatchUpFailedRun(t *testing.T) {
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+
+	clk := clock.NewMock()
+	c := getNewCronWithClock(clk)
+	c.SetComponentResolver(stateStores{"statestore": store})
+	m := getTestMetadata("0 * * * * *")
+	m.Name = "failed"
+	m.Properties["timeZone"] = "UTC"
+	m.Properties["catchUp"] = "true"
+	m.Properties["stateStore"] = "statestore"
+	require.NoError(t, c.Init(m))
+
+	var calls atomic.Int32
+	handler := func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		calls.Add(1)
+		return nil, errors.New("failed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.Read(ctx, handler))
+	time.Sleep(10 * time.Millisecond)
+	clk.Add(time.Minute)
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The failed run isn't recorded, so it's caught up when the binding restarts
+	time.Sleep(10 * time.Millisecond)
+	res, err := store.Get(context.Background(), &state.GetRequest{Key: c.stateKey()})
+	require.NoError(t, err)
+	assert.Empty(t, res.Data)
+}
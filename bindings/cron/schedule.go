@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	cron "github.com/dapr/kit/cron"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+const atDescriptor = "@at "
+
+// onceSchedule is a schedule that fires a single time.
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next returns the fire time if it is after t, or the zero time once it has passed.
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at.In(t.Location())
+	}
+	return time.Time{}
+}
+
+// parseSchedule parses either an "@at <RFC3339>" one-shot schedule or a cron expression.
+func (b *Binding) parseSchedule(spec string) (cron.Schedule, error) {
+	if strings.HasPrefix(spec, atDescriptor) {
+		at, err := time.Parse(time.RFC3339, strings.TrimSpace(strings.TrimPrefix(spec, atDescriptor)))
+		if err != nil {
+			return nil, err
+		}
+		return onceSchedule{at: at}, nil
+	}
+	return b.parser.Parse(spec)
+}
+
+// missedRuns returns the fire times of the schedule after lastFire and up to now, keeping only the most recent ones.
+// Only the runs in a window before now are scanned, which is widened until it holds enough runs, so that a long
+// outage doesn't iterate over every run missed.
+func (b *Binding) missedRuns(lastFire time.Time, now time.Time) []time.Time {
+	if lastFire.IsZero() {
+		return nil
+	}
+
+	lastFire = lastFire.In(b.location)
+	var missed []time.Time
+	for window := time.Minute; ; window *= 2 {
+		from := now.Add(-window)
+		if !from.After(lastFire) {
+			from = lastFire
+		}
+		missed = b.runsBetween(from, now)
+		if len(missed) == b.metadata.MaxCatchUpRuns || from.Equal(lastFire) {
+			break
+		}
+	}
+	if len(missed) > 0 {
+		b.logger.Infof("name: %s, firing %d missed runs since %v", b.name, len(missed), lastFire)
+	}
+	return missed
+}
+
+// runsBetween returns the most recent fire times of the schedule after from and up to to.
+func (b *Binding) runsBetween(from time.Time, to time.Time) []time.Time {
+	runs := make([]time.Time, 0, b.metadata.MaxCatchUpRuns)
+	for t := b.sched.Next(from); !t.IsZero() && !t.After(to); t = b.sched.Next(t) {
+		if len(runs) == b.metadata.MaxCatchUpRuns {
+			runs = append(runs[:0], runs[1:]...)
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+func (b *Binding) stateKey() string {
+	return "cron||" + b.name + "||lastFire"
+}
+
+func (b *Binding) loadLastFire(ctx context.Context) (time.Time, error) {
+	res, err := b.store.Get(ctx, &state.GetRequest{Key: b.stateKey()})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if res == nil || len(res.Data) == 0 {
+		return time.Time{}, nil
+	}
+
+	// Stores may return the value as a JSON string or as the raw string
+	val := string(res.Data)
+	var s string
+	if json.Unmarshal(res.Data, &s) == nil {
+		val = s
+	}
+	return time.Parse(time.RFC3339Nano, val)
+}
+
+// saveLastFire persists t as the last fire time, unless a later run has already been recorded.
+func (b *Binding) saveLastFire(ctx context.Context, t time.Time) error {
+	b.lastFireLock.Lock()
+	defer b.lastFireLock.Unlock()
+
+	if !t.After(b.lastFire) {
+		return nil
+	}
+	err := b.store.Set(ctx, &state.SetRequest{
+		Key:   b.stateKey(),
+		Value: t.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	b.lastFire = t
+	return nil
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	oauth2auth "github.com/dapr/components-contrib/internal/authentication/oauth2"
+)
+
+const (
+	defaultHMACHeader          = "X-Signature"
+	defaultHMACTimestampHeader = "X-Timestamp"
+	defaultHMACCanonicalString = "{method}\n{path}\n{query}\n{timestamp}\n{body}"
+)
+
+// authProvider adds authentication to the requests sent by the binding.
+// It is invoked for each attempt of a request, after its headers are set.
+type authProvider interface {
+	authenticate(request *http.Request, body []byte) error
+}
+
+// authMetadata contains the metadata of the authentication providers.
+type authMetadata struct {
+	BasicAuthUsername string `mapstructure:"basicAuthUsername"`
+	BasicAuthPassword string `mapstructure:"basicAuthPassword"`
+
+	// The OAuth2 client credentials options are the same as the ones of the OAuth2 client credentials middleware.
+	oauth2auth.ClientCredentialsMetadata `mapstructure:",squash"`
+
+	HMACSecret          string `mapstructure:"hmacSecret"`
+	HMACAlgorithm       string `mapstructure:"hmacAlgorithm"`
+	HMACEncoding        string `mapstructure:"hmacEncoding"`
+	HMACHeader          string `mapstructure:"hmacHeader"`
+	HMACSignaturePrefix string `mapstructure:"hmacSignaturePrefix"`
+	HMACTimestampHeader string `mapstructure:"hmacTimestampHeader"`
+	HMACCanonicalString string `mapstructure:"hmacCanonicalString"`
+}
+
+// newAuthProviders returns the authentication providers configured in the metadata.
+func (h *HTTPSource) newAuthProviders(m authMetadata) ([]authProvider, error) {
+	var providers []authProvider
+
+	if m.BasicAuthUsername != "" {
+		providers = append(providers, basicAuthProvider{
+			username: m.BasicAuthUsername,
+			password: m.BasicAuthPassword,
+		})
+	}
+
+	if m.ClientID != "" {
+		if m.BasicAuthUsername != "" {
+			return nil, errors.New("basic authentication and OAuth2 client credentials cannot be used together")
+		}
+		p, err := h.newOAuth2Provider(m)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	if m.HMACSecret != "" {
+		p, err := newHMACProvider(m)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	return providers, nil
+}
+
+// authenticate applies the authentication providers to a request.
+func (h *HTTPSource) authenticate(request *http.Request, body []byte) error {
+	for _, p := range h.authProviders {
+		err := p.authenticate(request, body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// needsBody returns true if the authentication providers read the body of requests.
+func (h *HTTPSource) needsBody() bool {
+	for _, p := range h.authProviders {
+		if _, ok := p.(*hmacProvider); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type basicAuthProvider struct {
+	username string
+	password string
+}
+
+func (p basicAuthProvider) authenticate(request *http.Request, _ []byte) error {
+	request.SetBasicAuth(p.username, p.password)
+	return nil
+}
+
+// oauth2Provider sets the Authorization header to a token obtained with the OAuth2 client credentials flow.
+// Tokens are cached, and they are refreshed when they expire.
+type oauth2Provider struct {
+	tokenSource oauth2.TokenSource
+}
+
+func (h *HTTPSource) newOAuth2Provider(m authMetadata) (*oauth2Provider, error) {
+	tokenSource, err := m.TokenSource(h.client)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OAuth2 client credentials: %w", err)
+	}
+	return &oauth2Provider{
+		tokenSource: tokenSource,
+	}, nil
+}
+
+func (p *oauth2Provider) authenticate(request *http.Request, _ []byte) error {
+	token, err := p.tokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("error acquiring OAuth2 token: %w", err)
+	}
+	request.Header.Set("Authorization", token.Type()+" "+token.AccessToken)
+	return nil
+}
+
+// hmacProvider signs requests with an HMAC of a canonical string.
+// The canonical string is a template with the placeholders {method}, {path}, {query}, {timestamp}, {body},
+// {bodySha256} (hex-encoded) and {header:<name>}.
+type hmacProvider struct {
+	secret          []byte
+	newHash         func() hash.Hash
+	encoding        string
+	header          string
+	prefix          string
+	timestampHeader string
+	canonicalString string
+	now             func() time.Time
+}
+
+func newHMACProvider(m authMetadata) (*hmacProvider, error) {
+	p := &hmacProvider{
+		secret:          []byte(m.HMACSecret),
+		encoding:        strings.ToLower(m.HMACEncoding),
+		header:          m.HMACHeader,
+		prefix:          m.HMACSignaturePrefix,
+		timestampHeader: m.HMACTimestampHeader,
+		canonicalString: m.HMACCanonicalString,
+		now:             time.Now,
+	}
+
+	switch strings.ToLower(m.HMACAlgorithm) {
+	case "", "sha256":
+		p.newHash = sha256.New
+	case "sha512":
+		p.newHash = sha512.New
+	case "sha1":
+		p.newHash = sha1.New
+	default:
+		return nil, fmt.Errorf("invalid hmacAlgorithm: %s", m.HMACAlgorithm)
+	}
+
+	switch p.encoding {
+	case "":
+		p.encoding = "hex"
+	case "hex", "base64":
+	default:
+		return nil, fmt.Errorf("invalid hmacEncoding: %s", m.HMACEncoding)
+	}
+
+	if p.header == "" {
+		p.header = defaultHMACHeader
+	}
+	if p.timestampHeader == "" {
+		p.timestampHeader = defaultHMACTimestampHeader
+	}
+	if p.canonicalString == "" {
+		p.canonicalString = defaultHMACCanonicalString
+	}
+	// Allow escaped newlines in the metadata
+	p.canonicalString = strings.ReplaceAll(p.canonicalString, `\n`, "\n")
+
+	return p, nil
+}
+
+func (p *hmacProvider) authenticate(request *http.Request, body []byte) error {
+	var timestamp string
+	if strings.Contains(p.canonicalString, "{timestamp}") {
+		timestamp = strconv.FormatInt(p.now().Unix(), 10)
+		request.Header.Set(p.timestampHeader, timestamp)
+	}
+
+	// Placeholders are replaced in a single pass, so their values are never expanded
+	var canonical strings.Builder
+	rest := p.canonicalString
+	for {
+		start := strings.IndexByte(rest, '{')
+		end := -1
+		if start >= 0 {
+			end = strings.IndexByte(rest[start:], '}')
+		}
+		if end < 0 {
+			canonical.WriteString(rest)
+			break
+		}
+
+		canonical.WriteString(rest[:start])
+		placeholder := rest[start+1 : start+end]
+		if val, ok := p.placeholderValue(placeholder, request, body, timestamp); ok {
+			canonical.WriteString(val)
+		} else {
+			canonical.WriteString(rest[start : start+end+1])
+		}
+		rest = rest[start+end+1:]
+	}
+
+	mac := hmac.New(p.newHash, p.secret)
+	mac.Write([]byte(canonical.String()))
+	sum := mac.Sum(nil)
+
+	var signature string
+	if p.encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	} else {
+		signature = hex.EncodeToString(sum)
+	}
+	request.Header.Set(p.header, p.prefix+signature)
+
+	return nil
+}
+
+func (p *hmacProvider) placeholderValue(placeholder string, request *http.Request, body []byte, timestamp string) (string, bool) {
+	switch placeholder {
+	case "method":
+		return request.Method, true
+	case "path":
+		return request.URL.EscapedPath(), true
+	case "query":
+		return request.URL.RawQuery, true
+	case "timestamp":
+		return timestamp, true
+	case "body":
+		return string(body), true
+	case "bodySha256":
+		sum := sha256.Sum256(body)
+		return hex.EncodeToString(sum[:]), true
+	}
+
+	if strings.HasPrefix(placeholder, "header:") {
+		return request.Header.Get(strings.TrimPrefix(placeholder, "header:")), true
+	}
+	return "", false
+}
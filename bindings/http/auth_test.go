@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+func TestBasicAuth(t *testing.T) {
+	handler := NewHTTPHandler()
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	hs, err := InitBinding(s, map[string]string{
+		"basicAuthUsername": "user",
+		"basicAuthPassword": "pass",
+	})
+	require.NoError(t, err)
+
+	_, err = hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+	require.NoError(t, err)
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")), handler.Headers["Authorization"])
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var tokenRequests atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "a b", r.Form.Get("scope"))
+		assert.Equal(t, "myapi", r.Form.Get("audience"))
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "id", user)
+		assert.Equal(t, "secret", pass)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"tok","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	handler := NewHTTPHandler()
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	hs, err := InitBinding(s, map[string]string{
+		"clientID":            "id",
+		"clientSecret":        "secret",
+		"tokenURL":            tokenServer.URL,
+		"scopes":              "a,b",
+		"endpointParamsQuery": "audience=myapi",
+		"authStyle":           "2",
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer tok", handler.Headers["Authorization"])
+	}
+	// The token is cached
+	assert.Equal(t, int32(1), tokenRequests.Load())
+}
+
+func TestHMACSigning(t *testing.T) {
+	sign := func(s string) string {
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	t.Run("default canonical string", func(t *testing.T) {
+		handler := NewHTTPHandler()
+		s := httptest.NewServer(handler)
+		defer s.Close()
+
+		hs, err := InitBinding(s, map[string]string{"hmacSecret": "key"})
+		require.NoError(t, err)
+		hs.(*HTTPSource).authProviders[0].(*hmacProvider).now = func() time.Time {
+			return time.Unix(1700000000, 0)
+		}
+
+		_, err = hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "post",
+			Data:      []byte(`{"a":1}`),
+			Metadata:  map[string]string{"path": "/items", "query": "x=1"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "1700000000", handler.Headers["X-Timestamp"])
+		assert.Equal(t, sign("POST\n/items\nx=1\n1700000000\n{\"a\":1}"), handler.Headers["X-Signature"])
+	})
+
+	t.Run("custom canonical string", func(t *testing.T) {
+		handler := NewHTTPHandler()
+		s := httptest.NewServer(handler)
+		defer s.Close()
+
+		hs, err := InitBinding(s, map[string]string{
+			"hmacSecret":          "key",
+			"hmacHeader":          "X-Hub-Signature-256",
+			"hmacSignaturePrefix": "sha256=",
+			"hmacCanonicalString": `{method} {header:X-Request-Id} {unknown}\n{bodySha256}`,
+		})
+		require.NoError(t, err)
+
+		shs := hs.(bindings.StreamingOutputBinding)
+		res, err := shs.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: "put",
+			Data:      strings.NewReader("body"),
+			Metadata:  map[string]string{"X-Request-Id": "{method}"},
+		})
+		require.NoError(t, err)
+		res.Data.Close()
+
+		bodySum := sha256.Sum256([]byte("body"))
+		assert.Equal(t, "sha256="+sign("PUT {method} {unknown}\n"+hex.EncodeToString(bodySum[:])), handler.Headers["X-Hub-Signature-256"])
+		assert.Empty(t, handler.Headers["X-Timestamp"])
+	})
+}
+
+func TestInvalidAuthMetadata(t *testing.T) {
+	s := httptest.NewServer(NewHTTPHandler())
+	defer s.Close()
+
+	for _, props := range []map[string]string{
+		{"basicAuthUsername": "user", "clientID": "id", "clientSecret": "secret", "scopes": "a", "tokenURL": "http://localhost"},
+		{"clientID": "id"},
+		{"clientID": "id", "clientSecret": "secret", "scopes": "a", "tokenURL": "http://localhost", "authStyle": "3"},
+		{"clientID": "id", "clientSecret": "secret", "scopes": "a", "tokenURL": "http://localhost", "endpointParamsQuery": "a=%zz"},
+		{"hmacSecret": "key", "hmacAlgorithm": "md5"},
+		{"hmacSecret": "key", "hmacEncoding": "base32"},
+	} {
+		_, err := InitBinding(s, props)
+		require.Error(t, err, props)
+	}
+}
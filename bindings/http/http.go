@@ -24,6 +24,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -34,6 +35,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/internal/utils"
+	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -47,6 +49,10 @@ const (
 	TraceMetadataKey     = "traceHeaders"
 	securityToken        = "securityToken"
 	securityTokenHeader  = "securityTokenHeader"
+
+	// Request metadata keys.
+	queryMetadataKey     = "query"
+	multipartMetadataKey = "multipart"
 )
 
 // HTTPSource is a binding for an http url endpoint invocation
@@ -56,6 +62,8 @@ type HTTPSource struct {
 	metadata      httpMetadata
 	client        *http.Client
 	errorIfNot2XX bool
+	retryPolicy   retryPolicy
+	authProviders []authProvider
 	logger        logger.Logger
 }
 
@@ -66,6 +74,8 @@ type httpMetadata struct {
 	MTLSRootCA          string `mapstructure:"mtlsRootCA"`
 	SecurityToken       string `mapstructure:"securityToken"`
 	SecurityTokenHeader string `mapstructure:"securityTokenHeader"`
+	RetryStatusCodes    string `mapstructure:"retryStatusCodes"`
+	RetryMethods        string `mapstructure:"retryMethods"`
 }
 
 // NewHTTP returns a new HTTPSource.
@@ -111,6 +121,20 @@ func (h *HTTPSource) Init(metadata bindings.Metadata) error {
 		h.errorIfNot2XX = true
 	}
 
+	var authMeta authMetadata
+	if err = mdutils.DecodeMetadata(metadata.Properties, &authMeta); err != nil {
+		return err
+	}
+	h.authProviders, err = h.newAuthProviders(authMeta)
+	if err != nil {
+		return err
+	}
+
+	h.retryPolicy, err = parseRetryPolicy(metadata.Properties, h.metadata.RetryStatusCodes, h.metadata.RetryMethods)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -185,90 +209,172 @@ func (h *HTTPSource) Operations() []bindings.OperationKind {
 	}
 }
 
-// Invoke performs an HTTP request to the configured HTTP endpoint.
-func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+// preparedRequest contains the options of a request to the HTTP endpoint.
+type preparedRequest struct {
+	method        string
+	url           string
+	header        http.Header
+	hasBody       bool
+	errorIfNot2XX bool
+}
+
+func (p *preparedRequest) newRequest(ctx context.Context, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, p.method, p.url, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header = p.header.Clone()
+	return request, nil
+}
+
+// prepareRequest returns the method, URL and headers of the request for an operation.
+func (h *HTTPSource) prepareRequest(operation bindings.OperationKind, md map[string]string) (*preparedRequest, error) {
 	u := h.metadata.URL
 
-	errorIfNot2XX := h.errorIfNot2XX // Default to the component config (default is true)
+	p := &preparedRequest{
+		header:        make(http.Header),
+		errorIfNot2XX: h.errorIfNot2XX, // Default to the component config (default is true)
+	}
 
-	if req.Metadata != nil {
-		if path, ok := req.Metadata["path"]; ok {
-			// Simplicity and no "../../.." type exploits.
-			u = fmt.Sprintf("%s/%s", strings.TrimRight(u, "/"), strings.TrimLeft(path, "/"))
-			if strings.Contains(u, "..") {
-				return nil, fmt.Errorf("invalid path: %s", path)
-			}
+	if path, ok := md["path"]; ok {
+		// Simplicity and no "../../.." type exploits.
+		u = fmt.Sprintf("%s/%s", strings.TrimRight(u, "/"), strings.TrimLeft(path, "/"))
+		if strings.Contains(u, "..") {
+			return nil, fmt.Errorf("invalid path: %s", path)
 		}
+	}
 
-		if _, ok := req.Metadata["errorIfNot2XX"]; ok {
-			errorIfNot2XX = utils.IsTruthy(req.Metadata["errorIfNot2XX"])
+	// Query string parameters are added to the ones of the URL
+	if query := md[queryMetadataKey]; query != "" {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
 		}
-	} else {
-		// Prevent things below from failing if req.Metadata is nil.
-		req.Metadata = make(map[string]string)
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		q := parsed.Query()
+		for k, vs := range values {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+		parsed.RawQuery = q.Encode()
+		u = parsed.String()
+	}
+	p.url = u
+
+	if _, ok := md["errorIfNot2XX"]; ok {
+		p.errorIfNot2XX = utils.IsTruthy(md["errorIfNot2XX"])
 	}
 
-	var body io.Reader
-	method := strings.ToUpper(string(req.Operation))
+	p.method = strings.ToUpper(string(operation))
 	// For backward compatibility
-	if method == "CREATE" {
-		method = "POST"
+	if p.method == "CREATE" {
+		p.method = "POST"
 	}
-	switch method {
+	switch p.method {
 	case "PUT", "POST", "PATCH":
-		body = bytes.NewBuffer(req.Data)
+		p.hasBody = true
 	case "GET", "HEAD", "DELETE", "OPTIONS", "TRACE":
 	default:
-		return nil, fmt.Errorf("invalid operation: %s", req.Operation)
-	}
-
-	request, err := http.NewRequestWithContext(ctx, method, u, body)
-	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid operation: %s", operation)
 	}
 
 	// Set default values for Content-Type and Accept headers.
-	if body != nil {
-		if _, ok := req.Metadata["Content-Type"]; !ok {
-			request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if p.hasBody {
+		if _, ok := md["Content-Type"]; !ok {
+			p.header.Set("Content-Type", "application/json; charset=utf-8")
 		}
 	}
-	if _, ok := req.Metadata["Accept"]; !ok {
-		request.Header.Set("Accept", "application/json; charset=utf-8")
+	if _, ok := md["Accept"]; !ok {
+		p.header.Set("Accept", "application/json; charset=utf-8")
 	}
 
 	// Set security token values if set.
 	if h.metadata.SecurityToken != "" && h.metadata.SecurityTokenHeader != "" {
-		request.Header.Set(h.metadata.SecurityTokenHeader, h.metadata.SecurityToken)
+		p.header.Set(h.metadata.SecurityTokenHeader, h.metadata.SecurityToken)
 	}
 
 	// Any metadata keys that start with a capital letter
 	// are treated as request headers
-	for mdKey, mdValue := range req.Metadata {
+	for mdKey, mdValue := range md {
 		keyAsRunes := []rune(mdKey)
 		if len(keyAsRunes) > 0 && unicode.IsUpper(keyAsRunes[0]) {
-			request.Header.Set(mdKey, mdValue)
+			p.header.Set(mdKey, mdValue)
 		}
 	}
 
 	// HTTP binding needs to inject traceparent header for proper tracing stack.
-	if tp, ok := req.Metadata[TraceparentHeaderKey]; ok && tp != "" {
-		if _, ok := request.Header[http.CanonicalHeaderKey(TraceparentHeaderKey)]; ok {
+	if tp, ok := md[TraceparentHeaderKey]; ok && tp != "" {
+		if _, ok := p.header[http.CanonicalHeaderKey(TraceparentHeaderKey)]; ok {
 			h.logger.Warn("Tracing is enabled. A custom Traceparent request header cannot be specified and is ignored.")
 		}
 
-		request.Header.Set(TraceparentHeaderKey, tp)
+		p.header.Set(TraceparentHeaderKey, tp)
 	}
-	if ts, ok := req.Metadata[TracestateHeaderKey]; ok && ts != "" {
-		if _, ok := request.Header[http.CanonicalHeaderKey(TracestateHeaderKey)]; ok {
+	if ts, ok := md[TracestateHeaderKey]; ok && ts != "" {
+		if _, ok := p.header[http.CanonicalHeaderKey(TracestateHeaderKey)]; ok {
 			h.logger.Warn("Tracing is enabled. A custom Tracestate request header cannot be specified and is ignored.")
 		}
 
-		request.Header.Set(TracestateHeaderKey, ts)
+		p.header.Set(TracestateHeaderKey, ts)
+	}
+
+	return p, nil
+}
+
+// responseMetadata returns the status and headers of a response as metadata.
+func responseMetadata(resp *http.Response) map[string]string {
+	metadata := make(map[string]string, len(resp.Header)+2)
+	// Include status code & desc
+	metadata["statusCode"] = strconv.Itoa(resp.StatusCode)
+	metadata["status"] = resp.Status
+
+	// Response headers are mapped from `map[string][]string` to `map[string]string`
+	// where headers with multiple values are delimited with ", ".
+	for key, values := range resp.Header {
+		metadata[key] = strings.Join(values, ", ")
+	}
+
+	return metadata
+}
+
+// Invoke performs an HTTP request to the configured HTTP endpoint.
+func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Metadata == nil {
+		// Prevent things below from failing if req.Metadata is nil.
+		req.Metadata = make(map[string]string)
+	}
+
+	p, err := h.prepareRequest(req.Operation, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	body := req.Data
+	if p.hasBody && utils.IsTruthy(req.Metadata[multipartMetadataKey]) {
+		var contentType string
+		body, contentType, err = newMultipartBody(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		p.header.Set("Content-Type", contentType)
 	}
 
 	// Send the question
-	resp, err := h.client.Do(request)
+	resp, err := h.do(p.method, func() (*http.Request, error) {
+		var r io.Reader
+		if p.hasBody {
+			r = bytes.NewReader(body)
+		}
+		request, err := p.newRequest(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return request, h.authenticate(request, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -281,24 +387,13 @@ func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*
 		return nil, err
 	}
 
-	metadata := make(map[string]string, len(resp.Header)+2)
-	// Include status code & desc
-	metadata["statusCode"] = strconv.Itoa(resp.StatusCode)
-	metadata["status"] = resp.Status
-
-	// Response headers are mapped from `map[string][]string` to `map[string]string`
-	// where headers with multiple values are delimited with ", ".
-	for key, values := range resp.Header {
-		metadata[key] = strings.Join(values, ", ")
-	}
-
 	// Create an error for non-200 status codes unless suppressed.
-	if errorIfNot2XX && resp.StatusCode/100 != 2 {
+	if p.errorIfNot2XX && resp.StatusCode/100 != 2 {
 		err = fmt.Errorf("received status code %d", resp.StatusCode)
 	}
 
 	return &bindings.InvokeResponse{
 		Data:     b,
-		Metadata: metadata,
+		Metadata: responseMetadata(resp),
 	}, err
 }
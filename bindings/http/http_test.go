@@ -14,18 +14,24 @@ limitations under the License.
 package http
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -614,3 +620,159 @@ func verifyNon2XXErrorsSuppressed(t *testing.T, hs bindings.OutputBinding, handl
 		})
 	}
 }
+
+func TestRetries(t *testing.T) {
+	var attempts atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "data", string(b))
+
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, map[string]string{
+		"backOffMaxRetries":      "3",
+		"backOffInitialInterval": "1ms",
+	})
+	require.NoError(t, err)
+
+	t.Run("idempotent requests are retried", func(t *testing.T) {
+		attempts.Store(0)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "put", Data: []byte("data")})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(res.Data))
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("other requests are not retried", func(t *testing.T) {
+		attempts.Store(0)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "post", Data: []byte("data")})
+		require.Error(t, err)
+		assert.Equal(t, "429", res.Metadata["statusCode"])
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("retries are limited", func(t *testing.T) {
+		attempts.Store(-10)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "put", Data: []byte("data")})
+		require.Error(t, err)
+		assert.Equal(t, "429", res.Metadata["statusCode"])
+		assert.Equal(t, int32(-6), attempts.Load())
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter("Sun, 01 Jan 2023 00:00:10 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Sat, 31 Dec 2022 00:00:10 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid", now))
+}
+
+func TestQueryAndMultipart(t *testing.T) {
+	var (
+		query       url.Values
+		contentType string
+		body        []byte
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, nil)
+	require.NoError(t, err)
+
+	t.Run("query", func(t *testing.T) {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{"path": "/items?a=1", "query": "b=2&b=3&c=x%20y"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{"a": {"1"}, "b": {"2", "3"}, "c": {"x y"}}, query)
+
+		_, err = hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{"query": "a=%zz"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "post",
+			Data:      []byte(`[{"name":"field","value":"text"},{"name":"file","fileName":"a.bin","data":"AQID"}]`),
+			Metadata:  map[string]string{"multipart": "true"},
+		})
+		require.NoError(t, err)
+
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/form-data", mediaType)
+
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"text"}, form.Value["field"])
+		require.Len(t, form.File["file"], 1)
+		assert.Equal(t, "a.bin", form.File["file"][0].Filename)
+		assert.Equal(t, "application/octet-stream", form.File["file"][0].Header.Get("Content-Type"))
+		f, err := form.File["file"][0].Open()
+		require.NoError(t, err)
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		assert.Equal(t, []byte{1, 2, 3}, data)
+
+		for _, data := range []string{`{}`, `[]`, `[{"value":"x"}]`} {
+			_, err = hs.Invoke(context.Background(), &bindings.InvokeRequest{
+				Operation: "post",
+				Data:      []byte(data),
+				Metadata:  map[string]string{"multipart": "true"},
+			})
+			require.Error(t, err, data)
+		}
+	})
+}
+
+func TestInvokeStream(t *testing.T) {
+	handler := NewHTTPHandler()
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	hs, err := InitBinding(s, nil)
+	require.NoError(t, err)
+
+	assert.True(t, bindings.FeatureStreaming.IsPresent(bindings.Features(hs)))
+	shs := hs.(bindings.StreamingOutputBinding)
+
+	res, err := shs.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+		Operation: "post",
+		Data:      strings.NewReader("streamed"),
+		Metadata:  map[string]string{"path": "/stream"},
+	})
+	require.NoError(t, err)
+	defer res.Data.Close()
+	b, err := io.ReadAll(res.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "STREAMED", string(b))
+	assert.Equal(t, "200", res.Metadata["statusCode"])
+	assert.Equal(t, "/stream", handler.Path)
+
+	res, err = shs.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+		Operation: "get",
+		Metadata:  map[string]string{"X-Status-Code": "500"},
+	})
+	require.Error(t, err)
+	b, err = io.ReadAll(res.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "GET", string(b))
+	assert.Equal(t, "500", res.Metadata["statusCode"])
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// multipartPart describes a part of a multipart/form-data request body.
+// The content of the part is Value, or Data for binary content, which is base64-encoded in JSON.
+type multipartPart struct {
+	Name        string            `json:"name"`
+	FileName    string            `json:"fileName"`
+	ContentType string            `json:"contentType"`
+	Headers     map[string]string `json:"headers"`
+	Value       string            `json:"value"`
+	Data        []byte            `json:"data"`
+}
+
+// newMultipartBody returns a multipart/form-data body, and its content type, from a JSON array of parts.
+func newMultipartBody(data []byte) ([]byte, string, error) {
+	var parts []multipartPart
+	err := json.Unmarshal(data, &parts)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid multipart request: must be a JSON array of parts: %w", err)
+	}
+	if len(parts) == 0 {
+		return nil, "", errors.New("invalid multipart request: no parts")
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for i, p := range parts {
+		if p.Name == "" {
+			return nil, "", fmt.Errorf("invalid multipart request: part %d has no name", i)
+		}
+
+		header := make(textproto.MIMEHeader, len(p.Headers)+2)
+		for k, v := range p.Headers {
+			header.Set(k, v)
+		}
+		disposition := map[string]string{"name": p.Name}
+		if p.FileName != "" {
+			disposition["filename"] = p.FileName
+		}
+		header.Set("Content-Disposition", mime.FormatMediaType("form-data", disposition))
+		switch {
+		case p.ContentType != "":
+			header.Set("Content-Type", p.ContentType)
+		case p.FileName != "":
+			header.Set("Content-Type", "application/octet-stream")
+		}
+
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		if p.Data != nil {
+			_, err = pw.Write(p.Data)
+		} else {
+			_, err = pw.Write([]byte(p.Value))
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
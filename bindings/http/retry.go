@@ -0,0 +1,169 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/dapr/kit/retry"
+)
+
+const (
+	defaultRetryStatusCodes = "429,500,502,503,504"
+	defaultRetryMethods     = "GET,HEAD,PUT,DELETE,OPTIONS,TRACE"
+
+	// Maximum number of bytes read from the body of responses that are retried, so the connection can be reused.
+	maxDrainedBodySize = 64 << 10
+)
+
+// retryPolicy contains the configuration of retries, which are disabled by default.
+// Requests are retried on network errors and on the configured status codes, for the configured methods only,
+// because retrying requests that are not idempotent can apply them more than once.
+type retryPolicy struct {
+	backOffConfig retry.Config
+	statusCodes   map[int]struct{}
+	methods       map[string]struct{}
+}
+
+func parseRetryPolicy(properties map[string]string, statusCodes string, methods string) (retryPolicy, error) {
+	p := retryPolicy{
+		backOffConfig: retry.DefaultConfigWithNoRetry(),
+		statusCodes:   map[int]struct{}{},
+		methods:       map[string]struct{}{},
+	}
+	p.backOffConfig.Policy = retry.PolicyExponential
+	err := retry.DecodeConfigWithPrefix(&p.backOffConfig, properties, "backOff")
+	if err != nil {
+		return p, fmt.Errorf("retry configuration error: %w", err)
+	}
+
+	if statusCodes == "" {
+		statusCodes = defaultRetryStatusCodes
+	}
+	for _, s := range strings.Split(statusCodes, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || code < 100 || code > 599 {
+			return p, fmt.Errorf("invalid status code in retryStatusCodes: %s", s)
+		}
+		p.statusCodes[code] = struct{}{}
+	}
+
+	if methods == "" {
+		methods = defaultRetryMethods
+	}
+	for _, m := range strings.Split(methods, ",") {
+		p.methods[strings.ToUpper(strings.TrimSpace(m))] = struct{}{}
+	}
+
+	return p, nil
+}
+
+func (p retryPolicy) enabled(method string) bool {
+	if p.backOffConfig.MaxRetries == 0 {
+		return false
+	}
+	_, ok := p.methods[method]
+	return ok
+}
+
+func (p retryPolicy) isRetryableStatus(code int) bool {
+	_, ok := p.statusCodes[code]
+	return ok
+}
+
+// do sends a request, retrying it according to the policy.
+// newRequest is invoked for each attempt, so the request body can be sent again.
+func (h *HTTPSource) do(method string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	request, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	if !h.retryPolicy.enabled(method) {
+		return h.client.Do(request)
+	}
+
+	ctx := request.Context()
+	b := h.retryPolicy.backOffConfig.NewBackOffWithContext(ctx)
+	// The exponential back off is created with the default initial interval: reset it to use the configured one
+	b.Reset()
+	for {
+		resp, err := h.client.Do(request)
+
+		var retryAfter time.Duration
+		switch {
+		case err == nil && !h.retryPolicy.isRetryableStatus(resp.StatusCode):
+			return resp, nil
+		case err == nil:
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		case ctx.Err() != nil:
+			return nil, err
+		}
+
+		delay := b.NextBackOff()
+		if delay == backoff.Stop {
+			return resp, err
+		}
+		if retryAfter > delay {
+			delay = retryAfter
+			if maxInterval := h.retryPolicy.backOffConfig.MaxInterval; maxInterval > 0 && delay > maxInterval {
+				delay = maxInterval
+			}
+		}
+
+		if err != nil {
+			h.logger.Debugf("HTTP request failed, retrying in %v: %v", delay, err)
+		} else {
+			h.logger.Debugf("HTTP request failed with status code %d, retrying in %v", resp.StatusCode, delay)
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBodySize))
+			resp.Body.Close()
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+
+		request, err = newRequest()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(val string, now time.Time) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(val); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
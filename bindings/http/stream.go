@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+// Maximum number of bytes of the body of non-2XX responses returned by InvokeStream with an error.
+const maxErrorBodySize = 1 << 20
+
+// Features returns the features of the binding.
+func (h *HTTPSource) Features() []bindings.Feature {
+	return []bindings.Feature{bindings.FeatureStreaming}
+}
+
+// InvokeStream performs an HTTP request to the configured HTTP endpoint, streaming the request and response bodies.
+// Requests with a streamed body are not retried, because the body cannot be sent again,
+// unless they are signed with HMAC, which requires reading the whole body.
+func (h *HTTPSource) InvokeStream(ctx context.Context, req *bindings.InvokeStreamRequest) (*bindings.InvokeStreamResponse, error) {
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+
+	p, err := h.prepareRequest(req.Operation, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	body := req.Data
+	if !p.hasBody {
+		body = nil
+	}
+
+	var resp *http.Response
+	switch {
+	case body != nil && h.needsBody():
+		// Request signing needs the whole body, so it is read in memory and the request can be retried
+		var b []byte
+		b, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading request data: %w", err)
+		}
+		resp, err = h.do(p.method, func() (*http.Request, error) {
+			request, err := p.newRequest(ctx, bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return request, h.authenticate(request, b)
+		})
+	case body != nil:
+		var request *http.Request
+		request, err = p.newRequest(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		err = h.authenticate(request, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = h.client.Do(request)
+	default:
+		resp, err = h.do(p.method, func() (*http.Request, error) {
+			request, err := p.newRequest(ctx, nil)
+			if err != nil {
+				return nil, err
+			}
+			return request, h.authenticate(request, nil)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an error for non-200 status codes unless suppressed.
+	// The body of the response is read, so the caller does not need to close it.
+	if p.errorIfNot2XX && resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		b, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if err != nil {
+			return nil, err
+		}
+		return bindings.NewInvokeStreamResponse(&bindings.InvokeResponse{
+			Data:     b,
+			Metadata: responseMetadata(resp),
+		}), fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+
+	return &bindings.InvokeStreamResponse{
+		Data:     resp.Body,
+		Metadata: responseMetadata(resp),
+	}, nil
+}
@@ -32,6 +32,7 @@ type Binding struct {
 	kafka           *kafka.Kafka
 	publishTopic    string
 	topics          []string
+	valueSchemaType kafka.SchemaType
 	logger          logger.Logger
 	subscribeCtx    context.Context
 	subscribeCancel context.CancelFunc
@@ -66,6 +67,11 @@ func (b *Binding) Init(metadata bindings.Metadata) error {
 		b.topics = strings.Split(val, ",")
 	}
 
+	b.valueSchemaType, err = kafka.GetValueSchemaType(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -79,7 +85,8 @@ func (b *Binding) Close() (err error) {
 }
 
 func (b *Binding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	err := b.kafka.Publish(ctx, b.publishTopic, req.Data, req.Metadata)
+	// The valueSchemaType of the component applies to the messages published too, unless the request sets it
+	err := b.kafka.Publish(ctx, b.publishTopic, req.Data, kafka.WithValueSchemaType(req.Metadata, b.valueSchemaType))
 	return nil, err
 }
 
@@ -92,6 +99,7 @@ func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
 	handlerConfig := kafka.SubscriptionHandlerConfig{
 		IsBulkSubscribe: false,
 		Handler:         adaptHandler(handler),
+		ValueSchemaType: b.valueSchemaType,
 	}
 	for _, t := range b.topics {
 		b.kafka.AddTopicHandler(t, handlerConfig)
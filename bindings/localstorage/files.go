@@ -0,0 +1,320 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstorage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+// fileInfo is the response of the stat operation, and an entry of the list operation.
+type fileInfo struct {
+	FileName string    `json:"fileName"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	IsDir    bool      `json:"isDir,omitempty"`
+}
+
+// listPayload is the optional request of the list operation.
+// When it is set, the response is a listResponse instead of the list of absolute paths.
+type listPayload struct {
+	Prefix     string `json:"prefix"`
+	MaxResults int    `json:"maxResults"`
+	Marker     string `json:"marker"`
+}
+
+type listResponse struct {
+	Files      []fileInfo `json:"files"`
+	NextMarker string     `json:"nextMarker,omitempty"`
+}
+
+func newFileInfo(relPath string, fi fs.FileInfo) fileInfo {
+	return fileInfo{
+		FileName: relPath,
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime(),
+		IsDir:    fi.IsDir(),
+	}
+}
+
+func (ls *LocalStorage) stat(filename string) (*bindings.InvokeResponse, error) {
+	absPath, relPath, err := getSecureAbsRelPath(ls.metadata.RootPath, filename)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path for file %s: %w", filename, err)
+	}
+
+	fi, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", absPath)
+		}
+		return nil, fmt.Errorf("error getting stats for path %s: %w", absPath, err)
+	}
+
+	b, err := json.Marshal(newFileInfo(relPath, fi))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response as JSON: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+func (ls *LocalStorage) append(filename string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("required metadata '%s' missing", fileNameMetadataKey)
+	}
+
+	data := decodeData(req.Data)
+
+	absPath, relPath, err := getSecureAbsRelPath(ls.metadata.RootPath, filename)
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path for file %s: %w", filename, err)
+	}
+
+	dir := filepath.Dir(absPath)
+	err = os.MkdirAll(dir, 0o777)
+	if err != nil {
+		return nil, fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	f, err := os.OpenFile(absPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", absPath, err)
+	}
+	defer f.Close()
+
+	numBytes, err := f.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("error writing to file %s: %w", absPath, err)
+	}
+
+	ls.logger.Debugf("appended to file: %s. numBytes: %d", absPath, numBytes)
+
+	b, err := json.Marshal(createResponse{
+		FileName: relPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response as JSON: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+// getSourceAndDestination returns the paths of the source and destination of copy and move operations,
+// and creates the parent directory of the destination.
+func (ls *LocalStorage) getSourceAndDestination(filename string, req *bindings.InvokeRequest) (srcPath string, dstPath string, dstRelPath string, err error) {
+	destination := req.Metadata[destinationMetadataKey]
+	if filename == "" {
+		return "", "", "", fmt.Errorf("required metadata '%s' missing", fileNameMetadataKey)
+	}
+	if destination == "" {
+		return "", "", "", fmt.Errorf("required metadata '%s' missing", destinationMetadataKey)
+	}
+
+	srcPath, _, err = getSecureAbsRelPath(ls.metadata.RootPath, filename)
+	if err != nil {
+		return "", "", "", fmt.Errorf("error getting absolute path for file %s: %w", filename, err)
+	}
+	dstPath, dstRelPath, err = getSecureAbsRelPath(ls.metadata.RootPath, destination)
+	if err != nil {
+		return "", "", "", fmt.Errorf("error getting absolute path for file %s: %w", destination, err)
+	}
+	if srcPath == ls.metadata.RootPath || dstPath == ls.metadata.RootPath {
+		return "", "", "", errors.New("the root path cannot be copied or moved")
+	}
+
+	_, err = os.Stat(srcPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", "", fmt.Errorf("file not found: %s", srcPath)
+		}
+		return "", "", "", fmt.Errorf("error getting stats for path %s: %w", srcPath, err)
+	}
+
+	dir := filepath.Dir(dstPath)
+	err = os.MkdirAll(dir, 0o777)
+	if err != nil {
+		return "", "", "", fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	return srcPath, dstPath, dstRelPath, nil
+}
+
+func (ls *LocalStorage) copy(filename string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	srcPath, dstPath, dstRelPath, err := ls.getSourceAndDestination(filename, req)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening path %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error getting stats for path %s: %w", srcPath, err)
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("unable to copy %s as it is a directory", srcPath)
+	}
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: %w", dstPath, err)
+	}
+
+	numBytes, err := io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return nil, fmt.Errorf("error copying file %s to %s: %w", srcPath, dstPath, err)
+	}
+	// Errors writing the data may only be reported when the file is closed
+	err = dst.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error closing file %s: %w", dstPath, err)
+	}
+
+	ls.logger.Debugf("copied file: %s to %s. numBytes: %d", srcPath, dstPath, numBytes)
+
+	b, err := json.Marshal(createResponse{
+		FileName: dstRelPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response as JSON: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+func (ls *LocalStorage) move(filename string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	srcPath, dstPath, dstRelPath, err := ls.getSourceAndDestination(filename, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Rename(srcPath, dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("error moving file %s to %s: %w", srcPath, dstPath, err)
+	}
+
+	ls.logger.Debugf("moved file: %s to %s", srcPath, dstPath)
+
+	b, err := json.Marshal(createResponse{
+		FileName: dstRelPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding response as JSON: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+// listFiles returns the files in a directory, with their paths relative to the root path.
+// Files are sorted by path, and filtered by prefix; the page starts after the marker.
+// The walk starts from the directory of the prefix, and skips the directories that can't contain matching files.
+func (ls *LocalStorage) listFiles(absPath string, payload listPayload) (*listResponse, error) {
+	if payload.MaxResults < 1 {
+		payload.MaxResults = defaultMaxResults
+	}
+
+	start := absPath
+	if prefixDir := filepath.Dir(payload.Prefix); prefixDir != "." {
+		prefixPath, _, err := getSecureAbsRelPath(ls.metadata.RootPath, prefixDir)
+		if err != nil {
+			return nil, fmt.Errorf("error getting absolute path for prefix %s: %w", payload.Prefix, err)
+		}
+		switch {
+		case isWithin(prefixPath, absPath):
+			start = prefixPath
+		case !isWithin(absPath, prefixPath):
+			// The prefix is outside of the directory
+			return &listResponse{Files: []fileInfo{}}, nil
+		}
+	}
+
+	files := []fileInfo{}
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == start && errors.Is(err, fs.ErrNotExist) {
+				// The directory of the prefix doesn't exist
+				return fs.SkipDir
+			}
+			return err
+		}
+
+		relPath, err := filepath.Rel(ls.metadata.RootPath, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirPrefix := relPath + string(filepath.Separator)
+			if path != start && !strings.HasPrefix(dirPrefix, payload.Prefix) && !strings.HasPrefix(payload.Prefix, dirPrefix) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(relPath, payload.Prefix) || (payload.Marker != "" && relPath <= payload.Marker) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, newFileInfo(relPath, fi))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FileName < files[j].FileName
+	})
+
+	res := &listResponse{
+		Files: files,
+	}
+	if len(files) > payload.MaxResults {
+		res.Files = files[:payload.MaxResults]
+		res.NextMarker = res.Files[len(res.Files)-1].FileName
+	}
+
+	return res, nil
+}
+
+// isWithin returns true if the path is the directory or one of its descendants.
+func isWithin(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
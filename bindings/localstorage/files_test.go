@@ -0,0 +1,172 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstorage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestFileOperations(t *testing.T) {
+	ls := NewLocalStorage(logger.NewLogger("test")).(*LocalStorage)
+	err := ls.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{"rootPath": t.TempDir()}}})
+	require.NoError(t, err)
+
+	invoke := func(t *testing.T, op bindings.OperationKind, data string, md map[string]string) *bindings.InvokeResponse {
+		t.Helper()
+		res, err := ls.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: op,
+			Data:      []byte(data),
+			Metadata:  md,
+		})
+		require.NoError(t, err)
+		return res
+	}
+	readFile := func(t *testing.T, name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(ls.metadata.RootPath, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	invoke(t, bindings.CreateOperation, "hello", map[string]string{fileNameMetadataKey: "a.txt"})
+
+	t.Run("append", func(t *testing.T) {
+		invoke(t, appendOperation, " world", map[string]string{fileNameMetadataKey: "a.txt"})
+		assert.Equal(t, "hello world", readFile(t, "a.txt"))
+
+		invoke(t, appendOperation, "new", map[string]string{fileNameMetadataKey: "new/b.txt"})
+		assert.Equal(t, "new", readFile(t, filepath.Join("new", "b.txt")))
+	})
+
+	t.Run("ranged get", func(t *testing.T) {
+		res := invoke(t, bindings.GetOperation, "", map[string]string{fileNameMetadataKey: "a.txt", offsetMetadataKey: "6"})
+		assert.Equal(t, "world", string(res.Data))
+
+		res = invoke(t, bindings.GetOperation, "", map[string]string{fileNameMetadataKey: "a.txt", offsetMetadataKey: "2", lengthMetadataKey: "3"})
+		assert.Equal(t, "llo", string(res.Data))
+
+		_, err := ls.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{fileNameMetadataKey: "a.txt", offsetMetadataKey: "-1"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		res := invoke(t, statOperation, "", map[string]string{fileNameMetadataKey: "a.txt"})
+		var fi fileInfo
+		require.NoError(t, json.Unmarshal(res.Data, &fi))
+		assert.Equal(t, "a.txt", fi.FileName)
+		assert.Equal(t, int64(11), fi.Size)
+		assert.False(t, fi.IsDir)
+		assert.False(t, fi.ModTime.IsZero())
+
+		_, err := ls.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: statOperation,
+			Metadata:  map[string]string{fileNameMetadataKey: "missing"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("copy and move", func(t *testing.T) {
+		res := invoke(t, copyOperation, "", map[string]string{fileNameMetadataKey: "a.txt", destinationMetadataKey: "copies/c.txt"})
+		assert.JSONEq(t, `{"fileName":"`+filepath.Join("copies", "c.txt")+`"}`, string(res.Data))
+		assert.Equal(t, "hello world", readFile(t, "a.txt"))
+		assert.Equal(t, "hello world", readFile(t, filepath.Join("copies", "c.txt")))
+
+		invoke(t, moveOperation, "", map[string]string{fileNameMetadataKey: "copies/c.txt", destinationMetadataKey: "d.txt"})
+		assert.Equal(t, "hello world", readFile(t, "d.txt"))
+		_, err := os.Stat(filepath.Join(ls.metadata.RootPath, "copies", "c.txt"))
+		assert.True(t, os.IsNotExist(err))
+
+		// Paths cannot escape the root path
+		invoke(t, copyOperation, "", map[string]string{fileNameMetadataKey: "../../a.txt", destinationMetadataKey: "../../e.txt"})
+		assert.Equal(t, "hello world", readFile(t, "e.txt"))
+
+		for _, md := range []map[string]string{
+			{fileNameMetadataKey: "a.txt"},
+			{fileNameMetadataKey: "missing", destinationMetadataKey: "f.txt"},
+			{fileNameMetadataKey: "a.txt", destinationMetadataKey: "/"},
+		} {
+			_, err = ls.Invoke(context.Background(), &bindings.InvokeRequest{Operation: moveOperation, Metadata: md})
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		// Without a payload, the list of absolute paths is returned
+		res := invoke(t, bindings.ListOperation, "", map[string]string{})
+		var paths []string
+		require.NoError(t, json.Unmarshal(res.Data, &paths))
+		assert.Contains(t, paths, filepath.Join(ls.metadata.RootPath, "a.txt"))
+
+		list := func(t *testing.T, dir string, payload string) listResponse {
+			t.Helper()
+			res := invoke(t, bindings.ListOperation, payload, map[string]string{fileNameMetadataKey: dir})
+			var lr listResponse
+			require.NoError(t, json.Unmarshal(res.Data, &lr))
+			return lr
+		}
+		names := func(lr listResponse) []string {
+			n := make([]string, len(lr.Files))
+			for i, f := range lr.Files {
+				n[i] = f.FileName
+			}
+			return n
+		}
+
+		lr := list(t, "", `{}`)
+		assert.Equal(t, []string{"a.txt", "d.txt", "e.txt", filepath.Join("new", "b.txt")}, names(lr))
+		assert.Equal(t, int64(11), lr.Files[0].Size)
+		assert.Empty(t, lr.NextMarker)
+
+		lr = list(t, "", `{"maxResults":2}`)
+		assert.Equal(t, []string{"a.txt", "d.txt"}, names(lr))
+		assert.Equal(t, "d.txt", lr.NextMarker)
+		lr = list(t, "", `{"maxResults":2,"marker":"d.txt"}`)
+		assert.Equal(t, []string{"e.txt", filepath.Join("new", "b.txt")}, names(lr))
+		assert.Empty(t, lr.NextMarker)
+
+		lr = list(t, "", `{"prefix":"new"}`)
+		assert.Equal(t, []string{filepath.Join("new", "b.txt")}, names(lr))
+
+		lr = list(t, "new", `{}`)
+		assert.Equal(t, []string{filepath.Join("new", "b.txt")}, names(lr))
+
+		invoke(t, bindings.CreateOperation, "x", map[string]string{fileNameMetadataKey: "newer/deep/f.txt"})
+		invoke(t, bindings.CreateOperation, "x", map[string]string{fileNameMetadataKey: "newer/g.txt"})
+
+		lr = list(t, "", `{"prefix":"new"}`)
+		assert.Equal(t, []string{filepath.Join("new", "b.txt"), filepath.Join("newer", "deep", "f.txt"), filepath.Join("newer", "g.txt")}, names(lr))
+		lr = list(t, "", `{"prefix":"`+filepath.Join("newer", "d")+`"}`)
+		assert.Equal(t, []string{filepath.Join("newer", "deep", "f.txt")}, names(lr))
+		lr = list(t, "newer", `{"prefix":"`+filepath.Join("newer", "deep", "f")+`"}`)
+		assert.Equal(t, []string{filepath.Join("newer", "deep", "f.txt")}, names(lr))
+		lr = list(t, "new", `{"prefix":"`+filepath.Join("newer", "g")+`"}`)
+		assert.Empty(t, lr.Files)
+		lr = list(t, "", `{"prefix":"`+filepath.Join("missing", "a")+`"}`)
+		assert.Empty(t, lr.Files)
+	})
+}
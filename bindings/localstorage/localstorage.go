@@ -14,6 +14,7 @@ limitations under the License.
 package localstorage
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -24,6 +25,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/google/uuid"
@@ -34,7 +36,17 @@ import (
 )
 
 const (
-	fileNameMetadataKey = "fileName"
+	fileNameMetadataKey    = "fileName"
+	destinationMetadataKey = "destination"
+	offsetMetadataKey      = "offset"
+	lengthMetadataKey      = "length"
+
+	statOperation   bindings.OperationKind = "stat"
+	appendOperation bindings.OperationKind = "append"
+	copyOperation   bindings.OperationKind = "copy"
+	moveOperation   bindings.OperationKind = "move"
+
+	defaultMaxResults = 1000
 )
 
 // List of root paths that are disallowed
@@ -52,6 +64,14 @@ type LocalStorage struct {
 // Metadata defines the metadata.
 type Metadata struct {
 	RootPath string `json:"rootPath"`
+
+	// Options of the input binding.
+	// Include and Exclude are lists of glob patterns, matched against the path relative to the root path,
+	// or against the file name for patterns without a path separator.
+	Include          []string      `json:"include"`
+	Exclude          []string      `json:"exclude"`
+	DebounceInterval time.Duration `json:"debounceInterval"`
+	ProcessedPath    string        `json:"processedPath"`
 }
 
 type createResponse struct {
@@ -63,6 +83,11 @@ func NewLocalStorage(logger logger.Logger) bindings.OutputBinding {
 	return &LocalStorage{logger: logger}
 }
 
+// NewLocalStorageInput returns a new LocalStorage instance that watches the files of the root path.
+func NewLocalStorageInput(logger logger.Logger) bindings.InputBinding {
+	return &LocalStorage{logger: logger}
+}
+
 // Init performs metadata parsing.
 func (ls *LocalStorage) Init(metadata bindings.Metadata) error {
 	m, err := ls.parseMetadata(metadata)
@@ -80,7 +105,9 @@ func (ls *LocalStorage) Init(metadata bindings.Metadata) error {
 }
 
 func (ls *LocalStorage) parseMetadata(meta bindings.Metadata) (*Metadata, error) {
-	var m Metadata
+	m := Metadata{
+		DebounceInterval: defaultDebounceInterval,
+	}
 	err := metadata.DecodeMetadata(meta.Properties, &m)
 	if err != nil {
 		return nil, err
@@ -91,6 +118,16 @@ func (ls *LocalStorage) parseMetadata(meta bindings.Metadata) (*Metadata, error)
 		return nil, err
 	}
 
+	for _, pattern := range append(m.Include, m.Exclude...) {
+		_, err = filepath.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
+		}
+	}
+	if m.DebounceInterval < 0 {
+		return nil, errors.New("property debounceInterval must not be negative")
+	}
+
 	return &m, nil
 }
 
@@ -150,20 +187,31 @@ func (ls *LocalStorage) Operations() []bindings.OperationKind {
 		bindings.GetOperation,
 		bindings.ListOperation,
 		bindings.DeleteOperation,
+		statOperation,
+		appendOperation,
+		copyOperation,
+		moveOperation,
 	}
 }
 
-func (ls *LocalStorage) create(filename string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	d, err := strconv.Unquote(string(req.Data))
+// decodeData unquotes and base64-decodes the payload of create and append requests, when possible.
+func decodeData(data []byte) []byte {
+	d, err := strconv.Unquote(string(data))
 	if err == nil {
-		req.Data = []byte(d)
+		data = []byte(d)
 	}
 
-	decoded, err := base64.StdEncoding.DecodeString(string(req.Data))
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
 	if err == nil {
-		req.Data = decoded
+		data = decoded
 	}
 
+	return data
+}
+
+func (ls *LocalStorage) create(filename string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	req.Data = decodeData(req.Data)
+
 	absPath, relPath, err := getSecureAbsRelPath(ls.metadata.RootPath, filename)
 	if err != nil {
 		return nil, fmt.Errorf("error getting absolute path for file %s: %w", filename, err)
@@ -208,12 +256,9 @@ func (ls *LocalStorage) get(filename string, req *bindings.InvokeRequest) (*bind
 		return nil, fmt.Errorf("error getting absolute path for file %s: %w", filename, err)
 	}
 
-	f, err := os.Open(absPath)
+	f, err := openRange(absPath, req.Metadata)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("file not found: %s", absPath)
-		}
-		return nil, fmt.Errorf("error opening path %s: %w", absPath, err)
+		return nil, err
 	}
 	defer f.Close()
 
@@ -260,12 +305,22 @@ func (ls *LocalStorage) list(filename string, req *bindings.InvokeRequest) (*bin
 		return nil, fmt.Errorf("unable to list files as the file specified is not a directory: %s", absPath)
 	}
 
-	files, err := walkPath(absPath)
+	var res any
+	if len(bytes.TrimSpace(req.Data)) == 0 {
+		res, err = walkPath(absPath)
+	} else {
+		var payload listPayload
+		err = json.Unmarshal(req.Data, &payload)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing list request: %w", err)
+		}
+		res, err = ls.listFiles(absPath, payload)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error listing files in the directory %s: %w", absPath, err)
 	}
 
-	b, err := json.Marshal(files)
+	b, err := json.Marshal(res)
 	if err != nil {
 		return nil, fmt.Errorf("error encoding response as JSON: %w", err)
 	}
@@ -275,6 +330,55 @@ func (ls *LocalStorage) list(filename string, req *bindings.InvokeRequest) (*bin
 	}, nil
 }
 
+// openRange opens a file for reading, starting at the optional `offset` metadata and
+// reading up to `length` bytes.
+func openRange(absPath string, md map[string]string) (io.ReadCloser, error) {
+	var (
+		offset int64
+		length int64 = -1
+		err    error
+	)
+	if val := md[offsetMetadataKey]; val != "" {
+		offset, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid value for metadata '%s': %s", offsetMetadataKey, val)
+		}
+	}
+	if val := md[lengthMetadataKey]; val != "" {
+		length, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid value for metadata '%s': %s", lengthMetadataKey, val)
+		}
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", absPath)
+		}
+		return nil, fmt.Errorf("error opening path %s: %w", absPath, err)
+	}
+
+	if offset > 0 {
+		_, err = f.Seek(offset, io.SeekStart)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error seeking file %s: %w", absPath, err)
+		}
+	}
+	if length < 0 {
+		return f, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.LimitReader(f, length),
+		Closer: f,
+	}, nil
+}
+
 func getSecureAbsRelPath(rootPath string, filename string) (absPath string, relPath string, err error) {
 	absPath, err = securejoin.SecureJoin(rootPath, filename)
 	if err != nil {
@@ -301,17 +405,27 @@ func walkPath(root string) ([]string, error) {
 	return files, err
 }
 
-// Invoke is called for output bindings.
-func (ls *LocalStorage) Invoke(_ context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	filename := req.Metadata[fileNameMetadataKey]
-	if filename == "" && req.Operation == bindings.CreateOperation {
+// getFileName returns the file name of the request, generating one for create operations.
+func getFileName(operation bindings.OperationKind, metadata map[string]string) (string, error) {
+	filename := metadata[fileNameMetadataKey]
+	if filename == "" && operation == bindings.CreateOperation {
 		u, err := uuid.NewRandom()
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate UUID: %w", err)
+			return "", fmt.Errorf("failed to generate UUID: %w", err)
 		}
 		filename = u.String()
 	}
 
+	return filename, nil
+}
+
+// Invoke is called for output bindings.
+func (ls *LocalStorage) Invoke(_ context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	filename, err := getFileName(req.Operation, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	switch req.Operation {
 	case bindings.CreateOperation:
 		return ls.create(filename, req)
@@ -321,6 +435,14 @@ func (ls *LocalStorage) Invoke(_ context.Context, req *bindings.InvokeRequest) (
 		return ls.delete(filename, req)
 	case bindings.ListOperation:
 		return ls.list(filename, req)
+	case statOperation:
+		return ls.stat(filename)
+	case appendOperation:
+		return ls.append(filename, req)
+	case copyOperation:
+		return ls.copy(filename, req)
+	case moveOperation:
+		return ls.move(filename, req)
 	default:
 		return nil, fmt.Errorf("unsupported operation %s", req.Operation)
 	}
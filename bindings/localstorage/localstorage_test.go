@@ -14,15 +14,20 @@ limitations under the License.
 package localstorage
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -110,6 +115,60 @@ func TestValidateRootPath(t *testing.T) {
 	}
 }
 
+func TestInvokeStream(t *testing.T) {
+	ls := NewLocalStorage(logger.NewLogger("test")).(*LocalStorage)
+	err := ls.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{"rootPath": t.TempDir()}}})
+	require.NoError(t, err)
+
+	assert.True(t, bindings.FeatureStreaming.IsPresent(bindings.Features(ls)))
+
+	t.Run("create and get", func(t *testing.T) {
+		// The payload is stored as is, without unquoting
+		res, err := ls.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.CreateOperation,
+			Data:      strings.NewReader(`"hello world"`),
+			Metadata:  map[string]string{fileNameMetadataKey: "dir/file.txt"},
+		})
+		require.NoError(t, err)
+		defer res.Data.Close()
+
+		var created createResponse
+		require.NoError(t, json.NewDecoder(res.Data).Decode(&created))
+		assert.Equal(t, filepath.Join("dir", "file.txt"), created.FileName)
+
+		res, err = ls.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{fileNameMetadataKey: "dir/file.txt"},
+		})
+		require.NoError(t, err)
+		defer res.Data.Close()
+
+		data, err := io.ReadAll(res.Data)
+		require.NoError(t, err)
+		assert.Equal(t, `"hello world"`, string(data))
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		_, err := ls.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{fileNameMetadataKey: "../../etc/passwd"},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("buffered operations", func(t *testing.T) {
+		res, err := ls.InvokeStream(context.Background(), &bindings.InvokeStreamRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{fileNameMetadataKey: "dir/file.txt"},
+		})
+		require.NoError(t, err)
+		assert.Nil(t, res)
+
+		_, err = os.Stat(filepath.Join(ls.metadata.RootPath, "dir", "file.txt"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
 func joinWithMustEvalSymlinks(v ...string) string {
 	r, err := filepath.EvalSymlinks(filepath.Join(v...))
 	if err != nil {
@@ -0,0 +1,366 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+const (
+	defaultDebounceInterval = 500 * time.Millisecond
+
+	eventMetadataKey = "event"
+
+	fileEventCreate = "create"
+	fileEventModify = "modify"
+	fileEventDelete = "delete"
+)
+
+// fileEvent is the payload of the events of the input binding.
+type fileEvent struct {
+	FileName string     `json:"fileName"`
+	Event    string     `json:"event"`
+	Size     int64      `json:"size,omitempty"`
+	ModTime  *time.Time `json:"modTime,omitempty"`
+}
+
+type pendingEvent struct {
+	// True if the file was created in the debounce interval
+	created bool
+	timer   *time.Timer
+}
+
+type queuedEvent struct {
+	path    string
+	created bool
+}
+
+// fileWatcher emits the events of the input binding.
+// Changes to a file are debounced: the event is emitted once no change was made to the file for the debounce interval,
+// and it is a create, modify or delete event depending on the state of the file at that time.
+type fileWatcher struct {
+	ctx           context.Context
+	ls            *LocalStorage
+	handler       bindings.Handler
+	watcher       *fsnotify.Watcher
+	processedPath string
+
+	lock    sync.Mutex
+	pending map[string]*pendingEvent
+	// Files moved to the processed path, whose removal must not emit an event
+	moved map[string]struct{}
+	queue chan queuedEvent
+}
+
+// Read watches the files in the root path, and invokes the handler when files are created, modified or deleted.
+// When a processed path is configured, created and modified files are moved to it after the handler succeeds.
+func (ls *LocalStorage) Read(ctx context.Context, handler bindings.Handler) error {
+	w := &fileWatcher{
+		ctx:     ctx,
+		ls:      ls,
+		handler: handler,
+		pending: map[string]*pendingEvent{},
+		moved:   map[string]struct{}{},
+		queue:   make(chan queuedEvent, 100),
+	}
+
+	if ls.metadata.ProcessedPath != "" {
+		absPath, _, err := getSecureAbsRelPath(ls.metadata.RootPath, ls.metadata.ProcessedPath)
+		if err != nil {
+			return fmt.Errorf("error getting absolute path for processed path %s: %w", ls.metadata.ProcessedPath, err)
+		}
+		if absPath == ls.metadata.RootPath {
+			return errors.New("processed path must be a directory inside the root path")
+		}
+		err = os.MkdirAll(absPath, 0o777)
+		if err != nil {
+			return fmt.Errorf("error creating directory %s: %w", absPath, err)
+		}
+		w.processedPath = absPath
+	}
+
+	var err error
+	w.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	err = w.addDir(ls.metadata.RootPath, false)
+	if err != nil {
+		w.watcher.Close()
+		return err
+	}
+
+	go w.watch()
+	go w.process()
+
+	return nil
+}
+
+func (w *fileWatcher) watch() {
+	defer func() {
+		w.watcher.Close()
+
+		w.lock.Lock()
+		for _, p := range w.pending {
+			p.timer.Stop()
+		}
+		w.pending = map[string]*pendingEvent{}
+		w.lock.Unlock()
+	}()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.ls.logger.Errorf("error watching files in %s: %v", w.ls.metadata.RootPath, err)
+		}
+	}
+}
+
+func (w *fileWatcher) handleEvent(event fsnotify.Event) {
+	if w.isProcessedPath(event.Name) || event.Op == fsnotify.Chmod {
+		return
+	}
+
+	if event.Op&fsnotify.Create == fsnotify.Create {
+		fi, err := os.Stat(event.Name)
+		if err == nil && fi.IsDir() {
+			// Files may have been created in the directory before it was watched
+			err = w.addDir(event.Name, true)
+			if err != nil {
+				w.ls.logger.Errorf("error watching directory %s: %v", event.Name, err)
+			}
+			return
+		}
+	}
+
+	relPath, err := filepath.Rel(w.ls.metadata.RootPath, event.Name)
+	if err != nil || !w.ls.metadata.matchesFilters(relPath) {
+		return
+	}
+
+	w.schedule(event.Name, event.Op&fsnotify.Create == fsnotify.Create)
+}
+
+// addDir watches a directory and its subdirectories, and schedules create events for their files if emit is true.
+func (w *fileWatcher) addDir(dir string, emit bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if w.isProcessedPath(path) {
+				return filepath.SkipDir
+			}
+			err = w.watcher.Add(path)
+			if err != nil {
+				return fmt.Errorf("watcher error: %w", err)
+			}
+			return nil
+		}
+
+		if emit {
+			relPath, err := filepath.Rel(w.ls.metadata.RootPath, path)
+			if err == nil && w.ls.metadata.matchesFilters(relPath) {
+				w.schedule(path, true)
+			}
+		}
+		return nil
+	})
+}
+
+func (w *fileWatcher) isProcessedPath(path string) bool {
+	return w.processedPath != "" &&
+		(path == w.processedPath || strings.HasPrefix(path, w.processedPath+string(os.PathSeparator)))
+}
+
+// schedule emits an event for the file after the debounce interval, restarting the interval if one is pending.
+func (w *fileWatcher) schedule(path string, created bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	p, ok := w.pending[path]
+	if ok {
+		p.timer.Reset(w.ls.metadata.DebounceInterval)
+		return
+	}
+
+	p = &pendingEvent{created: created}
+	p.timer = time.AfterFunc(w.ls.metadata.DebounceInterval, func() {
+		w.lock.Lock()
+		p, ok := w.pending[path]
+		if ok {
+			delete(w.pending, path)
+		}
+		w.lock.Unlock()
+
+		if ok {
+			select {
+			case w.queue <- queuedEvent{path: path, created: p.created}:
+			case <-w.ctx.Done():
+			}
+		}
+	})
+	w.pending[path] = p
+}
+
+// process invokes the handler for the queued events, one at a time.
+func (w *fileWatcher) process() {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case e := <-w.queue:
+			w.processEvent(e)
+		}
+	}
+}
+
+func (w *fileWatcher) processEvent(e queuedEvent) {
+	relPath, err := filepath.Rel(w.ls.metadata.RootPath, e.path)
+	if err != nil {
+		return
+	}
+
+	event := fileEvent{
+		FileName: relPath,
+	}
+
+	fi, err := os.Stat(e.path)
+	w.lock.Lock()
+	_, moved := w.moved[e.path]
+	delete(w.moved, e.path)
+	w.lock.Unlock()
+	switch {
+	case err == nil:
+		if fi.IsDir() {
+			return
+		}
+		event.Event = fileEventModify
+		if e.created {
+			event.Event = fileEventCreate
+		}
+		event.Size = fi.Size()
+		modTime := fi.ModTime()
+		event.ModTime = &modTime
+	case os.IsNotExist(err):
+		if moved || e.created {
+			// The file was moved by the binding, or it was created and deleted in the debounce interval
+			return
+		}
+		event.Event = fileEventDelete
+	default:
+		w.ls.logger.Errorf("error getting stats for path %s: %v", e.path, err)
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		w.ls.logger.Errorf("error encoding event as JSON: %v", err)
+		return
+	}
+
+	w.ls.logger.Debugf("file event: %s %s", event.Event, e.path)
+	_, err = w.handler(w.ctx, &bindings.ReadResponse{
+		Data: data,
+		Metadata: map[string]string{
+			fileNameMetadataKey: relPath,
+			eventMetadataKey:    event.Event,
+		},
+	})
+	if err != nil {
+		w.ls.logger.Errorf("error handling %s event for file %s: %v", event.Event, e.path, err)
+		return
+	}
+
+	if w.processedPath != "" && event.Event != fileEventDelete {
+		w.moveToProcessed(e.path, relPath)
+	}
+}
+
+func (w *fileWatcher) moveToProcessed(path string, relPath string) {
+	dst, _, err := getSecureAbsRelPath(w.processedPath, relPath)
+	if err != nil {
+		w.ls.logger.Errorf("error getting absolute path for file %s: %v", relPath, err)
+		return
+	}
+
+	dir := filepath.Dir(dst)
+	err = os.MkdirAll(dir, 0o777)
+	if err != nil {
+		w.ls.logger.Errorf("error creating directory %s: %v", dir, err)
+		return
+	}
+
+	w.lock.Lock()
+	w.moved[path] = struct{}{}
+	w.lock.Unlock()
+
+	err = os.Rename(path, dst)
+	if err != nil {
+		w.lock.Lock()
+		delete(w.moved, path)
+		w.lock.Unlock()
+		w.ls.logger.Errorf("error moving file %s to %s: %v", path, dst, err)
+		return
+	}
+
+	w.ls.logger.Debugf("moved processed file: %s to %s", path, dst)
+}
+
+// matchesFilters returns true if the path, relative to the root path, matches the include and exclude patterns.
+func (m *Metadata) matchesFilters(relPath string) bool {
+	if len(m.Include) > 0 && !matchesAny(m.Include, relPath) {
+		return false
+	}
+	return !matchesAny(m.Exclude, relPath)
+}
+
+func matchesAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.FromSlash(pattern)
+		name := relPath
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			name = filepath.Base(relPath)
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
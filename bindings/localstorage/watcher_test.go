@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func startWatcher(t *testing.T, props map[string]string, handlerErr error) (string, <-chan fileEvent) {
+	t.Helper()
+
+	rootPath := t.TempDir()
+	props["rootPath"] = rootPath
+	props["debounceInterval"] = "50ms"
+
+	ls := NewLocalStorageInput(logger.NewLogger("test")).(*LocalStorage)
+	err := ls.Init(bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := make(chan fileEvent, 10)
+	err = ls.Read(ctx, func(_ context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		var event fileEvent
+		require.NoError(t, json.Unmarshal(res.Data, &event))
+		assert.Equal(t, event.FileName, res.Metadata[fileNameMetadataKey])
+		assert.Equal(t, event.Event, res.Metadata[eventMetadataKey])
+		events <- event
+		return nil, handlerErr
+	})
+	require.NoError(t, err)
+
+	return ls.metadata.RootPath, events
+}
+
+func receiveEvent(t *testing.T, events <-chan fileEvent) fileEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for event")
+		return fileEvent{}
+	}
+}
+
+func assertNoEvent(t *testing.T, events <-chan fileEvent) {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		assert.Failf(t, "unexpected event", "%v", event)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Run("create, modify and delete", func(t *testing.T) {
+		rootPath, events := startWatcher(t, map[string]string{}, nil)
+		path := filepath.Join(rootPath, "a.txt")
+
+		// Writes in the debounce interval are a single event
+		require.NoError(t, os.WriteFile(path, []byte("a"), 0o600))
+		require.NoError(t, os.WriteFile(path, []byte("abc"), 0o600))
+		event := receiveEvent(t, events)
+		assert.Equal(t, "a.txt", event.FileName)
+		assert.Equal(t, fileEventCreate, event.Event)
+		assert.Equal(t, int64(3), event.Size)
+		assert.NotNil(t, event.ModTime)
+		assertNoEvent(t, events)
+
+		require.NoError(t, os.WriteFile(path, []byte("abcd"), 0o600))
+		event = receiveEvent(t, events)
+		assert.Equal(t, fileEventModify, event.Event)
+		assert.Equal(t, int64(4), event.Size)
+
+		require.NoError(t, os.Remove(path))
+		event = receiveEvent(t, events)
+		assert.Equal(t, fileEventDelete, event.Event)
+		assert.Nil(t, event.ModTime)
+	})
+
+	t.Run("subdirectories and filters", func(t *testing.T) {
+		rootPath, events := startWatcher(t, map[string]string{"include": "*.csv", "exclude": "tmp/*"}, nil)
+
+		require.NoError(t, os.WriteFile(filepath.Join(rootPath, "a.txt"), []byte("a"), 0o600))
+		require.NoError(t, os.MkdirAll(filepath.Join(rootPath, "sub", "dir"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(rootPath, "sub", "dir", "b.csv"), []byte("b"), 0o600))
+		event := receiveEvent(t, events)
+		assert.Equal(t, filepath.Join("sub", "dir", "b.csv"), event.FileName)
+		assert.Equal(t, fileEventCreate, event.Event)
+
+		require.NoError(t, os.MkdirAll(filepath.Join(rootPath, "tmp"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(rootPath, "tmp", "c.csv"), []byte("c"), 0o600))
+		assertNoEvent(t, events)
+	})
+
+	t.Run("move to processed path", func(t *testing.T) {
+		rootPath, events := startWatcher(t, map[string]string{"processedPath": "done"}, nil)
+
+		require.NoError(t, os.WriteFile(filepath.Join(rootPath, "a.txt"), []byte("a"), 0o600))
+		event := receiveEvent(t, events)
+		assert.Equal(t, fileEventCreate, event.Event)
+
+		// The removal of the moved file is not an event
+		assertNoEvent(t, events)
+		_, err := os.Stat(filepath.Join(rootPath, "a.txt"))
+		assert.True(t, os.IsNotExist(err))
+		data, err := os.ReadFile(filepath.Join(rootPath, "done", "a.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+	})
+
+	t.Run("files are not moved when the handler fails", func(t *testing.T) {
+		rootPath, events := startWatcher(t, map[string]string{"processedPath": "done"}, errors.New("handler error"))
+
+		require.NoError(t, os.WriteFile(filepath.Join(rootPath, "a.txt"), []byte("a"), 0o600))
+		receiveEvent(t, events)
+		assertNoEvent(t, events)
+		_, err := os.Stat(filepath.Join(rootPath, "a.txt"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestMatchesFilters(t *testing.T) {
+	m := Metadata{
+		Include: []string{"*.csv", "data/*.json"},
+		Exclude: []string{"ignored-*"},
+	}
+
+	assert.True(t, m.matchesFilters("a.csv"))
+	assert.True(t, m.matchesFilters(filepath.Join("sub", "a.csv")))
+	assert.True(t, m.matchesFilters(filepath.Join("data", "a.json")))
+	assert.False(t, m.matchesFilters(filepath.Join("other", "a.json")))
+	assert.False(t, m.matchesFilters("a.txt"))
+	assert.False(t, m.matchesFilters("ignored-a.csv"))
+
+	assert.True(t, (&Metadata{}).matchesFilters("a.txt"))
+}
@@ -30,14 +30,16 @@ import (
 	"github.com/go-sql-driver/mysql"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/sqlbinding"
 	"github.com/dapr/kit/logger"
 )
 
 const (
 	// list of operations.
-	execOperation  bindings.OperationKind = "exec"
-	queryOperation bindings.OperationKind = "query"
-	closeOperation bindings.OperationKind = "close"
+	execOperation        bindings.OperationKind = "exec"
+	queryOperation       bindings.OperationKind = "query"
+	transactionOperation bindings.OperationKind = "transaction"
+	closeOperation       bindings.OperationKind = "close"
 
 	// configurations to connect to Mysql, either a data source name represent by URL.
 	connectionURLKey = "url"
@@ -140,11 +142,20 @@ func (m *Mysql) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 	}
 	m.logger.Debugf("operation: %v", req.Operation)
 
+	if req.Operation == transactionOperation {
+		return m.invokeTransaction(ctx, req)
+	}
+
 	s, ok := req.Metadata[commandSQLKey]
 	if !ok || s == "" {
 		return nil, fmt.Errorf("required metadata not set: %s", commandSQLKey)
 	}
 
+	params, err := sqlbinding.ParseParams(req.Metadata, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
 	startTime := time.Now()
 
 	resp := &bindings.InvokeResponse{
@@ -157,22 +168,22 @@ func (m *Mysql) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 
 	switch req.Operation { //nolint:exhaustive
 	case execOperation:
-		r, err := m.exec(ctx, s)
+		r, err := m.exec(ctx, s, params...)
 		if err != nil {
 			return nil, err
 		}
 		resp.Metadata[respRowsAffectedKey] = strconv.FormatInt(r, 10)
 
 	case queryOperation:
-		d, err := m.query(ctx, s)
+		d, err := m.query(ctx, s, params...)
 		if err != nil {
 			return nil, err
 		}
 		resp.Data = d
 
 	default:
-		return nil, fmt.Errorf("invalid operation type: %s. Expected %s, %s, or %s",
-			req.Operation, execOperation, queryOperation, closeOperation)
+		return nil, fmt.Errorf("invalid operation type: %s. Expected %s, %s, %s, or %s",
+			req.Operation, execOperation, queryOperation, transactionOperation, closeOperation)
 	}
 
 	endTime := time.Now()
@@ -187,6 +198,7 @@ func (m *Mysql) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{
 		execOperation,
 		queryOperation,
+		transactionOperation,
 		closeOperation,
 	}
 }
@@ -200,8 +212,8 @@ func (m *Mysql) Close() error {
 	return nil
 }
 
-func (m *Mysql) query(ctx context.Context, sql string) ([]byte, error) {
-	rows, err := m.db.QueryContext(ctx, sql)
+func (m *Mysql) query(ctx context.Context, sql string, args ...any) ([]byte, error) {
+	rows, err := m.db.QueryContext(ctx, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error executing query: %w", err)
 	}
@@ -219,10 +231,10 @@ func (m *Mysql) query(ctx context.Context, sql string) ([]byte, error) {
 	return result, nil
 }
 
-func (m *Mysql) exec(ctx context.Context, sql string) (int64, error) {
+func (m *Mysql) exec(ctx context.Context, sql string, args ...any) (int64, error) {
 	m.logger.Debugf("exec: %s", sql)
 
-	res, err := m.db.ExecContext(ctx, sql)
+	res, err := m.db.ExecContext(ctx, sql, args...)
 	if err != nil {
 		return 0, fmt.Errorf("error executing query: %w", err)
 	}
@@ -230,6 +242,72 @@ func (m *Mysql) exec(ctx context.Context, sql string) (int64, error) {
 	return res.RowsAffected()
 }
 
+// invokeTransaction runs the statements in the request data in a single transaction,
+// returning the rows affected by each of them.
+func (m *Mysql) invokeTransaction(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	statements, err := sqlbinding.ParseStatements(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	results, err := m.transaction(ctx, statements)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, r := range results {
+		total += r.RowsAffected
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling transaction result: %w", err)
+	}
+
+	endTime := time.Now()
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			respOpKey:           string(req.Operation),
+			respRowsAffectedKey: strconv.FormatInt(total, 10),
+			respStartTimeKey:    startTime.Format(time.RFC3339Nano),
+			respEndTimeKey:      endTime.Format(time.RFC3339Nano),
+			respDurationKey:     endTime.Sub(startTime).String(),
+		},
+	}, nil
+}
+
+func (m *Mysql) transaction(ctx context.Context, statements []sqlbinding.Statement) ([]sqlbinding.StatementResult, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() {
+		// No-op if the transaction was committed
+		_ = tx.Rollback()
+	}()
+
+	results := make([]sqlbinding.StatementResult, len(statements))
+	for i, s := range statements {
+		m.logger.Debugf("transaction exec: %s", s.SQL)
+		res, err := tx.ExecContext(ctx, s.SQL, s.Params...)
+		if err != nil {
+			return nil, fmt.Errorf("error executing statement %d of transaction: %w", i, err)
+		}
+		results[i].RowsAffected, err = res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return results, nil
+}
+
 func propertyToInt(props map[string]string, key string, setter func(int)) error {
 	if v, ok := props[key]; ok {
 		if i, err := strconv.Atoi(v); err == nil {
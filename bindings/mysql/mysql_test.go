@@ -153,6 +153,102 @@ func TestInvoke(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 
+	t.Run("exec operation with params in metadata", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO foo \\(id, v1, ts\\) VALUES \\(\\?, \\?, \\?\\)").
+			WithArgs(int64(1), "test-1", "2021-01-22").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		metadata := map[string]string{
+			commandSQLKey: "INSERT INTO foo (id, v1, ts) VALUES (?, ?, ?)",
+			"params":      `[1, "test-1", "2021-01-22"]`,
+		}
+		req := &bindings.InvokeRequest{
+			Metadata:  metadata,
+			Operation: execOperation,
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Equal(t, "1", resp.Metadata[respRowsAffectedKey])
+	})
+
+	t.Run("query operation with params in data", func(t *testing.T) {
+		col1 := sqlmock.NewColumn("id").OfType("BIGINT", 1)
+		rows := sqlmock.NewRowsWithColumnDefinition(col1).AddRow(1)
+		mock.ExpectQuery("SELECT id FROM foo WHERE id < \\? AND v1 = \\?").
+			WithArgs(int64(2), "'; DROP TABLE foo; --").
+			WillReturnRows(rows)
+
+		req := &bindings.InvokeRequest{
+			Data: []byte(`[2, "'; DROP TABLE foo; --"]`),
+			Metadata: map[string]string{
+				commandSQLKey:    "SELECT id FROM foo WHERE id < ? AND v1 = ?",
+				"paramsFromData": "true",
+			},
+			Operation: queryOperation,
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `[{"id":1}]`, string(resp.Data))
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		req := &bindings.InvokeRequest{
+			Metadata:  map[string]string{commandSQLKey: "SELECT 1", "params": `{"id":1}`},
+			Operation: queryOperation,
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, resp)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("transaction operation succeeds", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO foo").WithArgs(int64(1), "a").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE foo").WithArgs("b").WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		req := &bindings.InvokeRequest{
+			Data: []byte(`[
+				{"sql": "INSERT INTO foo (id, v1) VALUES (?, ?)", "params": [1, "a"]},
+				{"sql": "UPDATE foo SET v1 = ?", "params": ["b"]}
+			]`),
+			Operation: transactionOperation,
+			Metadata:  map[string]string{},
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `[{"rowsAffected":1},{"rowsAffected":3}]`, string(resp.Data))
+		assert.Equal(t, "4", resp.Metadata[respRowsAffectedKey])
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transaction operation rolls back on failure", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO foo").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE foo").WillReturnError(errors.New("update failed"))
+		mock.ExpectRollback()
+
+		req := &bindings.InvokeRequest{
+			Data:      []byte(`[{"sql": "INSERT INTO foo (id) VALUES (1)"}, {"sql": "UPDATE foo SET v1 = 'b'"}]`),
+			Operation: transactionOperation,
+			Metadata:  map[string]string{},
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, resp)
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transaction operation without statements", func(t *testing.T) {
+		req := &bindings.InvokeRequest{
+			Data:      []byte(`[]`),
+			Operation: transactionOperation,
+			Metadata:  map[string]string{},
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, resp)
+		assert.NotNil(t, err)
+	})
+
 	t.Run("close operation", func(t *testing.T) {
 		mock.ExpectClose()
 		req := &bindings.InvokeRequest{
@@ -27,6 +27,15 @@ type OutputBinding interface {
 	Operations() []OperationKind
 }
 
+// StreamingOutputBinding is the interface for output bindings that can stream the payloads
+// of requests and responses, instead of holding them in memory.
+// Bindings implementing it advertise FeatureStreaming.
+type StreamingOutputBinding interface {
+	OutputBinding
+	Features() []Feature
+	InvokeStream(ctx context.Context, req *InvokeStreamRequest) (*InvokeStreamResponse, error)
+}
+
 func PingOutBinding(outputBinding OutputBinding) error {
 	// checks if this output binding has the ping option then executes
 	if outputBindingWithPing, ok := outputBinding.(health.Pinger); ok {
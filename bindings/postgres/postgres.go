@@ -23,14 +23,16 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/sqlbinding"
 	"github.com/dapr/kit/logger"
 )
 
 // List of operations.
 const (
-	execOperation  bindings.OperationKind = "exec"
-	queryOperation bindings.OperationKind = "query"
-	closeOperation bindings.OperationKind = "close"
+	execOperation        bindings.OperationKind = "exec"
+	queryOperation       bindings.OperationKind = "query"
+	transactionOperation bindings.OperationKind = "transaction"
+	closeOperation       bindings.OperationKind = "close"
 
 	connectionURLKey = "url"
 	commandSQLKey    = "sql"
@@ -72,6 +74,7 @@ func (p *Postgres) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{
 		execOperation,
 		queryOperation,
+		transactionOperation,
 		closeOperation,
 	}
 }
@@ -93,11 +96,20 @@ func (p *Postgres) Invoke(ctx context.Context, req *bindings.InvokeRequest) (res
 	}
 	p.logger.Debugf("operation: %v", req.Operation)
 
+	if req.Operation == transactionOperation {
+		return p.invokeTransaction(ctx, req)
+	}
+
 	sql, ok := req.Metadata[commandSQLKey]
 	if !ok || sql == "" {
 		return nil, errors.Errorf("required metadata not set: %s", commandSQLKey)
 	}
 
+	params, err := sqlbinding.ParseParams(req.Metadata, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
 	startTime := time.Now().UTC()
 	resp = &bindings.InvokeResponse{
 		Metadata: map[string]string{
@@ -109,14 +121,14 @@ func (p *Postgres) Invoke(ctx context.Context, req *bindings.InvokeRequest) (res
 
 	switch req.Operation { //nolint:exhaustive
 	case execOperation:
-		r, err := p.exec(ctx, sql)
+		r, err := p.exec(ctx, sql, params...)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error executing %s with %v", sql, err)
 		}
 		resp.Metadata["rows-affected"] = strconv.FormatInt(r, 10) // 0 if error
 
 	case queryOperation:
-		d, err := p.query(ctx, sql)
+		d, err := p.query(ctx, sql, params...)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error executing %s with %v", sql, err)
 		}
@@ -124,8 +136,8 @@ func (p *Postgres) Invoke(ctx context.Context, req *bindings.InvokeRequest) (res
 
 	default:
 		return nil, errors.Errorf(
-			"invalid operation type: %s. Expected %s, %s, or %s",
-			req.Operation, execOperation, queryOperation, closeOperation,
+			"invalid operation type: %s. Expected %s, %s, %s, or %s",
+			req.Operation, execOperation, queryOperation, transactionOperation, closeOperation,
 		)
 	}
 
@@ -146,10 +158,10 @@ func (p *Postgres) Close() error {
 	return nil
 }
 
-func (p *Postgres) query(ctx context.Context, sql string) (result []byte, err error) {
+func (p *Postgres) query(ctx context.Context, sql string, args ...any) (result []byte, err error) {
 	p.logger.Debugf("query: %s", sql)
 
-	rows, err := p.db.Query(ctx, sql)
+	rows, err := p.db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error executing %s", sql)
 	}
@@ -170,10 +182,10 @@ func (p *Postgres) query(ctx context.Context, sql string) (result []byte, err er
 	return
 }
 
-func (p *Postgres) exec(ctx context.Context, sql string) (result int64, err error) {
+func (p *Postgres) exec(ctx context.Context, sql string, args ...any) (result int64, err error) {
 	p.logger.Debugf("exec: %s", sql)
 
-	res, err := p.db.Exec(ctx, sql)
+	res, err := p.db.Exec(ctx, sql, args...)
 	if err != nil {
 		return 0, errors.Wrapf(err, "error executing %s", sql)
 	}
@@ -182,3 +194,66 @@ func (p *Postgres) exec(ctx context.Context, sql string) (result int64, err erro
 
 	return
 }
+
+// invokeTransaction runs the statements in the request data in a single transaction,
+// returning the rows affected by each of them.
+func (p *Postgres) invokeTransaction(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	statements, err := sqlbinding.ParseStatements(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now().UTC()
+	results, err := p.transaction(ctx, statements)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, r := range results {
+		total += r.RowsAffected
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, errors.Wrap(err, "error serializing results")
+	}
+
+	endTime := time.Now().UTC()
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			"operation":     string(req.Operation),
+			"rows-affected": strconv.FormatInt(total, 10),
+			"start-time":    startTime.Format(time.RFC3339Nano),
+			"end-time":      endTime.Format(time.RFC3339Nano),
+			"duration":      endTime.Sub(startTime).String(),
+		},
+	}, nil
+}
+
+func (p *Postgres) transaction(ctx context.Context, statements []sqlbinding.Statement) ([]sqlbinding.StatementResult, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error starting transaction")
+	}
+	defer func() {
+		// No-op if the transaction was committed
+		_ = tx.Rollback(ctx)
+	}()
+
+	results := make([]sqlbinding.StatementResult, len(statements))
+	for i, s := range statements {
+		p.logger.Debugf("transaction exec: %s", s.SQL)
+		res, err := tx.Exec(ctx, s.SQL, s.Params...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error executing statement %d of transaction: %s", i, s.SQL)
+		}
+		results[i].RowsAffected = res.RowsAffected()
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, errors.Wrap(err, "error committing transaction")
+	}
+
+	return results, nil
+}
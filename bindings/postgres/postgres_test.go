@@ -36,6 +36,9 @@ const (
 	testDelete = "DELETE FROM foo"
 	testUpdate = "UPDATE foo SET ts = '%v' WHERE id = %d"
 	testSelect = "SELECT * FROM foo WHERE id < 3"
+
+	testInsertParams = "INSERT INTO foo (id, v1, ts) VALUES ($1, $2, $3)"
+	testSelectParams = "SELECT * FROM foo WHERE id < $1"
 )
 
 func TestOperations(t *testing.T) {
@@ -44,7 +47,7 @@ func TestOperations(t *testing.T) {
 		b := NewPostgres(nil)
 		assert.NotNil(t, b)
 		l := b.Operations()
-		assert.Equal(t, 3, len(l))
+		assert.Equal(t, 4, len(l))
 	})
 }
 
@@ -108,6 +111,56 @@ func TestPostgresIntegration(t *testing.T) {
 		assertResponse(t, res, err)
 	})
 
+	t.Run("Invoke insert with params", func(t *testing.T) {
+		req.Operation = execOperation
+		req.Metadata[commandSQLKey] = testInsertParams
+		req.Metadata["paramsFromData"] = "true"
+		req.Data = []byte(fmt.Sprintf(`[10, "test-'10", %q]`, time.Now().Format(time.RFC3339)))
+		res, err := b.Invoke(ctx, req)
+		assertResponse(t, res, err)
+		assert.Equal(t, "1", res.Metadata["rows-affected"])
+		delete(req.Metadata, "paramsFromData")
+	})
+
+	t.Run("Invoke select with params", func(t *testing.T) {
+		req.Operation = queryOperation
+		req.Metadata[commandSQLKey] = testSelectParams
+		req.Metadata["params"] = `[3]`
+		req.Data = nil
+		res, err := b.Invoke(ctx, req)
+		assertResponse(t, res, err)
+		delete(req.Metadata, "params")
+	})
+
+	t.Run("Invoke transaction", func(t *testing.T) {
+		res, err := b.Invoke(ctx, &bindings.InvokeRequest{
+			Operation: transactionOperation,
+			Metadata:  map[string]string{},
+			Data: []byte(`[
+				{"sql": "INSERT INTO foo (id, v1) VALUES ($1, $2)", "params": [20, "tx"]},
+				{"sql": "UPDATE foo SET v1 = $1 WHERE id >= $2", "params": ["tx", 10]}
+			]`),
+		})
+		assertResponse(t, res, err)
+		assert.JSONEq(t, `[{"rowsAffected":1},{"rowsAffected":2}]`, string(res.Data))
+	})
+
+	t.Run("Invoke failed transaction is rolled back", func(t *testing.T) {
+		_, err := b.Invoke(ctx, &bindings.InvokeRequest{
+			Operation: transactionOperation,
+			Metadata:  map[string]string{},
+			Data:      []byte(`[{"sql": "DELETE FROM foo"}, {"sql": "SELECT * FROM missing"}]`),
+		})
+		assert.Error(t, err)
+
+		res, err := b.Invoke(ctx, &bindings.InvokeRequest{
+			Operation: queryOperation,
+			Metadata:  map[string]string{commandSQLKey: "SELECT id FROM foo"},
+		})
+		assertResponse(t, res, err)
+		assert.NotEqual(t, "[]", string(res.Data))
+	})
+
 	t.Run("Invoke delete", func(t *testing.T) {
 		req.Operation = execOperation
 		req.Metadata[commandSQLKey] = testDelete
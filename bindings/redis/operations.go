@@ -0,0 +1,404 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+const (
+	HSetOperation     bindings.OperationKind = "hset"
+	HGetOperation     bindings.OperationKind = "hget"
+	HGetAllOperation  bindings.OperationKind = "hgetall"
+	LPushOperation    bindings.OperationKind = "lpush"
+	RPopOperation     bindings.OperationKind = "rpop"
+	SAddOperation     bindings.OperationKind = "sadd"
+	SMembersOperation bindings.OperationKind = "smembers"
+	ZAddOperation     bindings.OperationKind = "zadd"
+	ZRangeOperation   bindings.OperationKind = "zrange"
+	IncrByOperation   bindings.OperationKind = "incrby"
+	ExpireOperation   bindings.OperationKind = "expire"
+	EvalOperation     bindings.OperationKind = "eval"
+
+	fieldMetadataKey     = "field"
+	incrementMetadataKey = "increment"
+	ttlMetadataKey       = "ttlInSeconds"
+	startMetadataKey     = "start"
+	stopMetadataKey      = "stop"
+)
+
+// countResponse is returned by operations that add elements, with the number reported by Redis.
+type countResponse struct {
+	Count int64 `json:"count"`
+}
+
+// valueResponse is returned by operations that read a single value; Value is nil when it doesn't exist.
+type valueResponse struct {
+	Value *string `json:"value"`
+}
+
+type valuesResponse struct {
+	Values []string `json:"values"`
+}
+
+type hashResponse struct {
+	Fields map[string]string `json:"fields"`
+}
+
+type intResponse struct {
+	Value int64 `json:"value"`
+}
+
+type expireResponse struct {
+	Updated bool `json:"updated"`
+}
+
+type evalResponse struct {
+	Result interface{} `json:"result"`
+}
+
+type sortedSetMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+type sortedSetResponse struct {
+	Members []sortedSetMember `json:"members"`
+}
+
+// evalRequest is the payload of the eval operation.
+type evalRequest struct {
+	Script string            `json:"script"`
+	Keys   []string          `json:"keys"`
+	Args   []json.RawMessage `json:"args"`
+}
+
+func (r *Redis) hset(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(req.Data, &fields); err != nil || len(fields) == 0 {
+		return nil, errors.New("redis binding: hset requires a non-empty JSON object of fields")
+	}
+
+	// Sort the fields so the command is deterministic
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := []interface{}{"HSET", key}
+	for _, name := range names {
+		args = append(args, name, toRedisValue(fields[name]))
+	}
+
+	res, err := r.client.DoWriteResult(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt64(res)
+	if err != nil {
+		return nil, err
+	}
+	return countResponse{Count: n}, nil
+}
+
+func (r *Redis) hget(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	field := req.Metadata[fieldMetadataKey]
+	if field == "" {
+		return nil, errors.New("redis binding: missing field in request metadata")
+	}
+
+	res, err := r.client.DoRead(ctx, "HGET", key, field)
+	if err != nil {
+		if r.isNil(err) {
+			return valueResponse{}, nil
+		}
+		return nil, err
+	}
+	val := fmt.Sprint(res)
+	return valueResponse{Value: &val}, nil
+}
+
+func (r *Redis) hgetall(ctx context.Context, key string) (interface{}, error) {
+	res, err := r.client.DoRead(ctx, "HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	switch v := res.(type) {
+	case []interface{}:
+		// RESP2 returns a flat list of field/value pairs
+		for i := 0; i+1 < len(v); i += 2 {
+			fields[fmt.Sprint(v[i])] = fmt.Sprint(v[i+1])
+		}
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			fields[fmt.Sprint(k)] = fmt.Sprint(val)
+		}
+	default:
+		return nil, fmt.Errorf("redis binding: unexpected hgetall reply type %T", res)
+	}
+	return hashResponse{Fields: fields}, nil
+}
+
+func (r *Redis) lpush(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	values, err := parseValues(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("redis binding: lpush %w", err)
+	}
+
+	res, err := r.client.DoWriteResult(ctx, append([]interface{}{"LPUSH", key}, values...)...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt64(res)
+	if err != nil {
+		return nil, err
+	}
+	return countResponse{Count: n}, nil
+}
+
+func (r *Redis) rpop(ctx context.Context, key string) (interface{}, error) {
+	res, err := r.client.DoWriteResult(ctx, "RPOP", key)
+	if err != nil {
+		if r.isNil(err) {
+			return valueResponse{}, nil
+		}
+		return nil, err
+	}
+	val := fmt.Sprint(res)
+	return valueResponse{Value: &val}, nil
+}
+
+func (r *Redis) sadd(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	values, err := parseValues(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("redis binding: sadd %w", err)
+	}
+
+	res, err := r.client.DoWriteResult(ctx, append([]interface{}{"SADD", key}, values...)...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt64(res)
+	if err != nil {
+		return nil, err
+	}
+	return countResponse{Count: n}, nil
+}
+
+func (r *Redis) smembers(ctx context.Context, key string) (interface{}, error) {
+	res, err := r.client.DoRead(ctx, "SMEMBERS", key)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []string{}
+	switch v := res.(type) {
+	case []interface{}:
+		for _, member := range v {
+			values = append(values, fmt.Sprint(member))
+		}
+	case map[interface{}]struct{}:
+		// RESP3 returns sets as a set type
+		for member := range v {
+			values = append(values, fmt.Sprint(member))
+		}
+	default:
+		return nil, fmt.Errorf("redis binding: unexpected smembers reply type %T", res)
+	}
+	sort.Strings(values)
+	return valuesResponse{Values: values}, nil
+}
+
+func (r *Redis) zadd(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	var members []sortedSetMember
+	if err := json.Unmarshal(req.Data, &members); err != nil || len(members) == 0 {
+		return nil, errors.New("redis binding: zadd requires a non-empty JSON array of members with scores")
+	}
+
+	args := []interface{}{"ZADD", key}
+	for _, m := range members {
+		args = append(args, m.Score, m.Member)
+	}
+	res, err := r.client.DoWriteResult(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt64(res)
+	if err != nil {
+		return nil, err
+	}
+	return countResponse{Count: n}, nil
+}
+
+func (r *Redis) zrange(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	start, err := int64Metadata(req.Metadata, startMetadataKey, 0)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := int64Metadata(req.Metadata, stopMetadataKey, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.DoRead(ctx, "ZRANGE", key, start, stop, "WITHSCORES")
+	if err != nil {
+		return nil, err
+	}
+	items, ok := res.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis binding: unexpected zrange reply type %T", res)
+	}
+
+	members := []sortedSetMember{}
+	appendMember := func(member, score interface{}) error {
+		s, err := strconv.ParseFloat(fmt.Sprint(score), 64)
+		if err != nil {
+			return fmt.Errorf("redis binding: invalid score %v: %w", score, err)
+		}
+		members = append(members, sortedSetMember{Member: fmt.Sprint(member), Score: s})
+		return nil
+	}
+	for i := 0; i < len(items); i++ {
+		// RESP3 returns [member, score] pairs, RESP2 a flat list
+		if pair, ok := items[i].([]interface{}); ok && len(pair) == 2 {
+			err = appendMember(pair[0], pair[1])
+		} else if i+1 < len(items) {
+			err = appendMember(items[i], items[i+1])
+			i++
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sortedSetResponse{Members: members}, nil
+}
+
+func (r *Redis) incrby(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	increment, err := int64Metadata(req.Metadata, incrementMetadataKey, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.DoWriteResult(ctx, "INCRBY", key, increment)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt64(res)
+	if err != nil {
+		return nil, err
+	}
+	return intResponse{Value: n}, nil
+}
+
+func (r *Redis) expire(ctx context.Context, key string, req *bindings.InvokeRequest) (interface{}, error) {
+	if req.Metadata[ttlMetadataKey] == "" {
+		return nil, errors.New("redis binding: missing ttlInSeconds in request metadata")
+	}
+	ttl, err := int64Metadata(req.Metadata, ttlMetadataKey, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.DoWriteResult(ctx, "EXPIRE", key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt64(res)
+	if err != nil {
+		return nil, err
+	}
+	return expireResponse{Updated: n == 1}, nil
+}
+
+func (r *Redis) eval(ctx context.Context, req *bindings.InvokeRequest) (interface{}, error) {
+	var payload evalRequest
+	if err := json.Unmarshal(req.Data, &payload); err != nil {
+		return nil, fmt.Errorf("redis binding: invalid eval request: %w", err)
+	}
+	if payload.Script == "" {
+		return nil, errors.New("redis binding: eval requires a script")
+	}
+
+	args := []interface{}{"EVAL", payload.Script, len(payload.Keys)}
+	for _, k := range payload.Keys {
+		args = append(args, k)
+	}
+	for _, a := range payload.Args {
+		args = append(args, toRedisValue(a))
+	}
+
+	res, err := r.client.DoWriteResult(ctx, args...)
+	if err != nil {
+		if r.isNil(err) {
+			return evalResponse{}, nil
+		}
+		return nil, err
+	}
+	return evalResponse{Result: res}, nil
+}
+
+func (r *Redis) isNil(err error) bool {
+	return err != nil && err.Error() == r.client.GetNilValueError().Error()
+}
+
+// parseValues parses a non-empty JSON array into Redis command arguments.
+func parseValues(data []byte) ([]interface{}, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw) == 0 {
+		return nil, errors.New("requires a non-empty JSON array of values")
+	}
+	values := make([]interface{}, len(raw))
+	for i, v := range raw {
+		values[i] = toRedisValue(v)
+	}
+	return values, nil
+}
+
+// toRedisValue stores JSON strings as their plain value and any other JSON value as its JSON text.
+func toRedisValue(v json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s
+	}
+	return string(v)
+}
+
+func toInt64(res interface{}) (int64, error) {
+	n, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis binding: unexpected reply type %T", res)
+	}
+	return n, nil
+}
+
+func int64Metadata(md map[string]string, key string, defaultValue int64) (int64, error) {
+	val, ok := md[key]
+	if !ok || val == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("redis binding: invalid %s in request metadata: %w", key, err)
+	}
+	return n, nil
+}
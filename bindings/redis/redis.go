@@ -15,6 +15,7 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -68,10 +69,27 @@ func (r *Redis) Operations() []bindings.OperationKind {
 		bindings.CreateOperation,
 		bindings.DeleteOperation,
 		bindings.GetOperation,
+		HSetOperation,
+		HGetOperation,
+		HGetAllOperation,
+		LPushOperation,
+		RPopOperation,
+		SAddOperation,
+		SMembersOperation,
+		ZAddOperation,
+		ZRangeOperation,
+		IncrByOperation,
+		ExpireOperation,
+		EvalOperation,
 	}
 }
 
 func (r *Redis) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Operation == EvalOperation {
+		// Keys of a script are passed in the request data
+		return r.respond(r.eval(ctx, req))
+	}
+
 	if key, ok := req.Metadata["key"]; ok && key != "" {
 		switch req.Operation {
 		case bindings.DeleteOperation:
@@ -92,6 +110,28 @@ func (r *Redis) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 			if err != nil {
 				return nil, err
 			}
+		case HSetOperation:
+			return r.respond(r.hset(ctx, key, req))
+		case HGetOperation:
+			return r.respond(r.hget(ctx, key, req))
+		case HGetAllOperation:
+			return r.respond(r.hgetall(ctx, key))
+		case LPushOperation:
+			return r.respond(r.lpush(ctx, key, req))
+		case RPopOperation:
+			return r.respond(r.rpop(ctx, key))
+		case SAddOperation:
+			return r.respond(r.sadd(ctx, key, req))
+		case SMembersOperation:
+			return r.respond(r.smembers(ctx, key))
+		case ZAddOperation:
+			return r.respond(r.zadd(ctx, key, req))
+		case ZRangeOperation:
+			return r.respond(r.zrange(ctx, key, req))
+		case IncrByOperation:
+			return r.respond(r.incrby(ctx, key, req))
+		case ExpireOperation:
+			return r.respond(r.expire(ctx, key, req))
 		default:
 			return nil, fmt.Errorf("invalid operation type: %s", req.Operation)
 		}
@@ -100,6 +140,22 @@ func (r *Redis) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 	return nil, errors.New("redis binding: missing key in request metadata")
 }
 
+// respond serializes the typed result of an operation as the JSON response.
+func (r *Redis) respond(res interface{}, err error) (*bindings.InvokeResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("redis binding: error serializing response: %w", err)
+	}
+	contentType := "application/json"
+	return &bindings.InvokeResponse{
+		Data:        data,
+		ContentType: &contentType,
+	}, nil
+}
+
 func (r *Redis) Close() error {
 	r.cancel()
 
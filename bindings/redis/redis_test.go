@@ -16,10 +16,12 @@ package redis
 import (
 	"context"
 	"testing"
+	"time"
 
 	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	internalredis "github.com/dapr/components-contrib/internal/component/redis"
@@ -120,3 +122,76 @@ func setupMiniredis() (*miniredis.Miniredis, internalredis.RedisClient) {
 
 	return s, internalredis.ClientFromV8Client(redis.NewClient(opts))
 }
+
+func TestInvokeDataStructureOperations(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	bind := &Redis{
+		client: c,
+		logger: logger.NewLogger("test"),
+	}
+	bind.ctx, bind.cancel = context.WithCancel(context.Background())
+
+	invoke := func(t *testing.T, op bindings.OperationKind, md map[string]string, data string) string {
+		t.Helper()
+		res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Data:      []byte(data),
+			Metadata:  md,
+			Operation: op,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, "application/json", *res.ContentType)
+		return string(res.Data)
+	}
+
+	t.Run("hash", func(t *testing.T) {
+		md := map[string]string{"key": "hash"}
+		assert.JSONEq(t, `{"count":2}`, invoke(t, HSetOperation, md, `{"a":"1","b":{"nested":true}}`))
+		assert.JSONEq(t, `{"value":"1"}`, invoke(t, HGetOperation, map[string]string{"key": "hash", "field": "a"}, ""))
+		assert.JSONEq(t, `{"value":null}`, invoke(t, HGetOperation, map[string]string{"key": "hash", "field": "missing"}, ""))
+		assert.JSONEq(t, `{"fields":{"a":"1","b":"{\"nested\":true}"}}`, invoke(t, HGetAllOperation, md, ""))
+
+		_, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Metadata:  md,
+			Operation: HGetOperation,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		md := map[string]string{"key": "list"}
+		assert.JSONEq(t, `{"count":3}`, invoke(t, LPushOperation, md, `["a","b",3]`))
+		assert.JSONEq(t, `{"value":"a"}`, invoke(t, RPopOperation, md, ""))
+		assert.JSONEq(t, `{"value":"b"}`, invoke(t, RPopOperation, md, ""))
+		assert.JSONEq(t, `{"value":"3"}`, invoke(t, RPopOperation, md, ""))
+		assert.JSONEq(t, `{"value":null}`, invoke(t, RPopOperation, md, ""))
+	})
+
+	t.Run("set", func(t *testing.T) {
+		md := map[string]string{"key": "set"}
+		assert.JSONEq(t, `{"count":2}`, invoke(t, SAddOperation, md, `["x","y","x"]`))
+		assert.JSONEq(t, `{"values":["x","y"]}`, invoke(t, SMembersOperation, md, ""))
+	})
+
+	t.Run("sorted set", func(t *testing.T) {
+		md := map[string]string{"key": "zset"}
+		assert.JSONEq(t, `{"count":3}`, invoke(t, ZAddOperation, md, `[{"member":"b","score":2},{"member":"a","score":1},{"member":"c","score":3.5}]`))
+		assert.JSONEq(t, `{"members":[{"member":"a","score":1},{"member":"b","score":2},{"member":"c","score":3.5}]}`, invoke(t, ZRangeOperation, md, ""))
+		assert.JSONEq(t, `{"members":[{"member":"b","score":2}]}`, invoke(t, ZRangeOperation, map[string]string{"key": "zset", "start": "1", "stop": "1"}, ""))
+	})
+
+	t.Run("incrby and expire", func(t *testing.T) {
+		assert.JSONEq(t, `{"value":1}`, invoke(t, IncrByOperation, map[string]string{"key": "counter"}, ""))
+		assert.JSONEq(t, `{"value":11}`, invoke(t, IncrByOperation, map[string]string{"key": "counter", "increment": "10"}, ""))
+		assert.JSONEq(t, `{"updated":true}`, invoke(t, ExpireOperation, map[string]string{"key": "counter", "ttlInSeconds": "60"}, ""))
+		assert.JSONEq(t, `{"updated":false}`, invoke(t, ExpireOperation, map[string]string{"key": "missing", "ttlInSeconds": "60"}, ""))
+		assert.Equal(t, 60*time.Second, s.TTL("counter"))
+	})
+
+	t.Run("eval", func(t *testing.T) {
+		res := invoke(t, EvalOperation, nil, `{"script":"redis.call('SET', KEYS[1], ARGV[1]); return {KEYS[1], redis.call('GET', KEYS[1])}","keys":["scripted"],"args":["value"]}`)
+		assert.JSONEq(t, `{"result":["scripted","value"]}`, res)
+	})
+}
@@ -15,6 +15,7 @@ package bindings
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 )
 
@@ -25,6 +26,31 @@ type InvokeRequest struct {
 	Operation OperationKind     `json:"operation"`
 }
 
+// InvokeStreamRequest is the request of a streaming invocation of an output binding.
+// Data is read until EOF, and can be nil for operations that do not have a payload.
+type InvokeStreamRequest struct {
+	Data      io.Reader
+	Metadata  map[string]string
+	Operation OperationKind
+}
+
+// ToInvokeRequest reads the payload of the request to memory, for operations of
+// streaming bindings that do not stream their request.
+func (r *InvokeStreamRequest) ToInvokeRequest() (*InvokeRequest, error) {
+	req := &InvokeRequest{
+		Metadata:  r.Metadata,
+		Operation: r.Operation,
+	}
+	if r.Data != nil {
+		data, err := io.ReadAll(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error reading request data: %w", err)
+		}
+		req.Data = data
+	}
+	return req, nil
+}
+
 // OperationKind defines an output binding operation.
 type OperationKind string
 
@@ -14,6 +14,9 @@ limitations under the License.
 package bindings
 
 import (
+	"bytes"
+	"io"
+
 	"github.com/dapr/components-contrib/state"
 )
 
@@ -39,3 +42,28 @@ type InvokeResponse struct {
 	Metadata    map[string]string `json:"metadata"`
 	ContentType *string           `json:"contentType,omitempty"`
 }
+
+// InvokeStreamResponse is the response of a streaming invocation of an output binding.
+// The caller must close Data when it is not nil.
+type InvokeStreamResponse struct {
+	Data        io.ReadCloser
+	Metadata    map[string]string
+	ContentType *string
+}
+
+// NewInvokeStreamResponse returns an InvokeStreamResponse with the payload of a buffered InvokeResponse,
+// for operations of streaming bindings that do not stream their response.
+func NewInvokeStreamResponse(res *InvokeResponse) *InvokeStreamResponse {
+	if res == nil {
+		return nil
+	}
+
+	streamRes := &InvokeStreamResponse{
+		Metadata:    res.Metadata,
+		ContentType: res.ContentType,
+	}
+	if res.Data != nil {
+		streamRes.Data = io.NopCloser(bytes.NewReader(res.Data))
+	}
+	return streamRes
+}
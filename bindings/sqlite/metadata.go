@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+)
+
+const (
+	defaultTimeout     = 20 * time.Second
+	defaultBusyTimeout = 2 * time.Second
+
+	errMissingConnectionString = "missing connection string"
+)
+
+type sqliteMetadata struct {
+	ConnectionString string        `mapstructure:"connectionString"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+	BusyTimeout      time.Duration `mapstructure:"busyTimeout"`
+	DisableWAL       bool          `mapstructure:"disableWAL"` // Disable WAL journaling. You should not use WAL if the database is stored on a network filesystem (or data corruption may happen). This is ignored if the database is in-memory.
+	ReadOnly         bool          `mapstructure:"readOnly"`   // Open the database in read-only mode: the exec operation is disabled and statements that write are rejected.
+}
+
+func (m *sqliteMetadata) InitWithMetadata(meta bindings.Metadata) error {
+	m.reset()
+
+	err := metadata.DecodeMetadata(meta.Properties, m)
+	if err != nil {
+		return err
+	}
+
+	if m.ConnectionString == "" {
+		return errors.New(errMissingConnectionString)
+	}
+	if m.Timeout <= 0 {
+		return errors.New("invalid value for 'timeout': must be greater than 0")
+	}
+
+	// Truncate values to milliseconds. Values <= 0 do not set any timeout
+	m.BusyTimeout = m.BusyTimeout.Truncate(time.Millisecond)
+
+	return nil
+}
+
+func (m *sqliteMetadata) reset() {
+	m.ConnectionString = ""
+	m.Timeout = defaultTimeout
+	m.BusyTimeout = defaultBusyTimeout
+	m.DisableWAL = false
+	m.ReadOnly = false
+}
+
+// GetConnectionString returns the connection string for the driver, with the pragmas
+// for the busy timeout, journaling mode and read-only mode.
+func (m *sqliteMetadata) GetConnectionString() (string, error) {
+	lc := strings.ToLower(m.ConnectionString)
+	isMemoryDB := strings.HasPrefix(lc, ":memory:") || strings.HasPrefix(lc, "file::memory:")
+
+	connString := m.ConnectionString
+	var qs url.Values
+	if idx := strings.IndexRune(connString, '?'); idx > 0 {
+		qs, _ = url.ParseQuery(connString[(idx + 1):])
+		connString = connString[:idx]
+	}
+	if qs == nil {
+		qs = make(url.Values, 2)
+	}
+
+	for _, p := range qs["_pragma"] {
+		p = strings.ToLower(p)
+		if strings.HasPrefix(p, "busy_timeout") {
+			return "", errors.New("found forbidden option '_pragma=busy_timeout' in the connection string; please use the 'busyTimeout' metadata property instead")
+		} else if strings.HasPrefix(p, "journal_mode") {
+			return "", errors.New("found forbidden option '_pragma=journal_mode' in the connection string; please use the 'disableWAL' metadata property instead")
+		}
+	}
+
+	if isMemoryDB {
+		qs.Set("cache", "shared")
+	}
+	if len(qs["mode"]) > 0 && qs["mode"][0] == "ro" || len(qs["immutable"]) > 0 && qs["immutable"][0] == "1" {
+		m.ReadOnly = true
+	}
+	if m.ReadOnly {
+		if !isMemoryDB {
+			qs.Set("mode", "ro")
+		}
+		// query_only also applies to in-memory databases, which ignore mode=ro
+		qs.Add("_pragma", "query_only(1)")
+	}
+
+	if m.BusyTimeout > 0 {
+		qs.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", m.BusyTimeout.Milliseconds()))
+	}
+	switch {
+	case isMemoryDB:
+		qs.Add("_pragma", "journal_mode(MEMORY)")
+	case m.ReadOnly:
+		// Changing the journaling mode requires writing to the database
+	case m.DisableWAL:
+		qs.Add("_pragma", "journal_mode(DELETE)")
+	default:
+		qs.Add("_pragma", "journal_mode(WAL)")
+	}
+
+	connString += "?" + qs.Encode()
+	if !strings.HasPrefix(lc, "file:") {
+		connString = "file:" + connString
+	}
+
+	return connString, nil
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Blank import for the underlying SQLite Driver.
+	_ "modernc.org/sqlite"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/sqlbinding"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// list of operations.
+	execOperation  bindings.OperationKind = "exec"
+	queryOperation bindings.OperationKind = "query"
+	closeOperation bindings.OperationKind = "close"
+
+	// keys from request's metadata.
+	commandSQLKey = "sql"
+
+	// keys from response's metadata.
+	respOpKey           = "operation"
+	respSQLKey          = "sql"
+	respStartTimeKey    = "start-time"
+	respRowsAffectedKey = "rows-affected"
+	respEndTimeKey      = "end-time"
+	respDurationKey     = "duration"
+)
+
+// SQLite represents the SQLite output binding.
+type SQLite struct {
+	metadata sqliteMetadata
+	db       *sql.DB
+	logger   logger.Logger
+}
+
+// NewSQLite returns a new SQLite output binding.
+func NewSQLite(logger logger.Logger) bindings.OutputBinding {
+	return &SQLite{logger: logger}
+}
+
+// Init initializes the SQLite binding.
+func (s *SQLite) Init(metadata bindings.Metadata) error {
+	err := s.metadata.InitWithMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	connString, err := s.metadata.GetConnectionString()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", connString)
+	if err != nil {
+		return fmt.Errorf("failed to create connection: %w", err)
+	}
+	s.db = db
+
+	err = s.Ping()
+	if err != nil {
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+
+	return nil
+}
+
+// Ping checks that the database is reachable.
+func (s *SQLite) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.metadata.Timeout)
+	defer cancel()
+	return s.db.PingContext(ctx)
+}
+
+// Operations returns list of operations supported by SQLite binding.
+func (s *SQLite) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		execOperation,
+		queryOperation,
+		closeOperation,
+	}
+}
+
+// Invoke handles all invoke operations.
+func (s *SQLite) Invoke(parentCtx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req == nil {
+		return nil, errors.New("invoke request required")
+	}
+
+	if req.Operation == closeOperation {
+		return nil, s.db.Close()
+	}
+
+	if req.Metadata == nil {
+		return nil, errors.New("metadata required")
+	}
+	s.logger.Debugf("operation: %v", req.Operation)
+
+	stmt, ok := req.Metadata[commandSQLKey]
+	if !ok || stmt == "" {
+		return nil, fmt.Errorf("required metadata not set: %s", commandSQLKey)
+	}
+
+	params, err := sqlbinding.ParseParams(req.Metadata, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	resp := &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			respOpKey:        string(req.Operation),
+			respSQLKey:       stmt,
+			respStartTimeKey: startTime.Format(time.RFC3339Nano),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.metadata.Timeout)
+	defer cancel()
+
+	switch req.Operation { //nolint:exhaustive
+	case execOperation:
+		if s.metadata.ReadOnly {
+			return nil, errors.New("exec operation is not allowed: the database is opened in read-only mode")
+		}
+		r, err := s.exec(ctx, stmt, params...)
+		if err != nil {
+			return nil, err
+		}
+		resp.Metadata[respRowsAffectedKey] = strconv.FormatInt(r, 10)
+
+	case queryOperation:
+		d, err := s.query(ctx, stmt, params...)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data = d
+
+	default:
+		return nil, fmt.Errorf("invalid operation type: %s. Expected %s, %s, or %s",
+			req.Operation, execOperation, queryOperation, closeOperation)
+	}
+
+	endTime := time.Now()
+	resp.Metadata[respEndTimeKey] = endTime.Format(time.RFC3339Nano)
+	resp.Metadata[respDurationKey] = endTime.Sub(startTime).String()
+
+	return resp, nil
+}
+
+// Close will close the DB.
+func (s *SQLite) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+
+	return nil
+}
+
+func (s *SQLite) exec(ctx context.Context, stmt string, args ...any) (int64, error) {
+	s.logger.Debugf("exec: %s", stmt)
+
+	res, err := s.db.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error executing statement: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+func (s *SQLite) query(ctx context.Context, stmt string, args ...any) ([]byte, error) {
+	s.logger.Debugf("query: %s", stmt)
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := jsonify(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling query result: %w", err)
+	}
+
+	return result, nil
+}
+
+// jsonify returns the rows as a JSON array of objects keyed by column name.
+func jsonify(rows *sql.Rows) ([]byte, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []map[string]any{}
+	values := make([]any, len(columnTypes))
+	ptrs := make([]any, len(columnTypes))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		err = rows.Scan(ptrs...)
+		if err != nil {
+			return nil, err
+		}
+
+		r := make(map[string]any, len(columnTypes))
+		for i, ct := range columnTypes {
+			r[ct.Name()] = convert(ct.DatabaseTypeName(), values[i])
+		}
+		ret = append(ret, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(ret)
+}
+
+// convert maps a value read from SQLite to its JSON representation, based on the
+// declared type of the column.
+// SQLite columns are dynamically typed, so the declared type is only a hint: for example,
+// integers in BOOLEAN columns become booleans, and text in JSON columns is embedded as JSON.
+// BLOBs are encoded as base64 strings and date/times as RFC 3339 strings.
+func convert(declType string, value any) any {
+	declType = strings.ToUpper(declType)
+
+	switch v := value.(type) {
+	case int64:
+		if declType == "BOOLEAN" || declType == "BOOL" {
+			return v != 0
+		}
+		return v
+	case []byte:
+		if isTextType(declType) {
+			return convertText(declType, string(v))
+		}
+		return v
+	case string:
+		return convertText(declType, v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+func convertText(declType string, v string) any {
+	if declType == "JSON" && json.Valid([]byte(v)) {
+		return json.RawMessage(v)
+	}
+	return v
+}
+
+// isTextType implements the SQLite type affinity rules for TEXT columns.
+func isTextType(declType string) bool {
+	return declType == "JSON" ||
+		strings.Contains(declType, "CHAR") ||
+		strings.Contains(declType, "CLOB") ||
+		strings.Contains(declType, "TEXT")
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const testTableDDL = `CREATE TABLE foo (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	price REAL,
+	active BOOLEAN,
+	attrs JSON,
+	raw BLOB,
+	created DATETIME
+)`
+
+func newTestBinding(t *testing.T, props map[string]string) *SQLite {
+	t.Helper()
+
+	b := NewSQLite(logger.NewLogger("test")).(*SQLite)
+	err := b.Init(bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		b.Close()
+	})
+	return b
+}
+
+func invoke(t *testing.T, b *SQLite, op bindings.OperationKind, data string, md map[string]string) *bindings.InvokeResponse {
+	t.Helper()
+
+	res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: op,
+		Data:      []byte(data),
+		Metadata:  md,
+	})
+	require.NoError(t, err)
+	return res
+}
+
+func TestOperations(t *testing.T) {
+	b := NewSQLite(nil)
+	l := b.Operations()
+	assert.Equal(t, 3, len(l))
+	assert.Contains(t, l, execOperation)
+	assert.Contains(t, l, queryOperation)
+	assert.Contains(t, l, closeOperation)
+}
+
+func TestInvoke(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	b := newTestBinding(t, map[string]string{"connectionString": dbPath})
+
+	t.Run("exec", func(t *testing.T) {
+		res := invoke(t, b, execOperation, "", map[string]string{commandSQLKey: testTableDDL})
+		assert.Equal(t, "0", res.Metadata[respRowsAffectedKey])
+		assert.NotEmpty(t, res.Metadata[respStartTimeKey])
+		assert.NotEmpty(t, res.Metadata[respDurationKey])
+
+		res = invoke(t, b, execOperation, `[1, "book", 9.5, true, "{\"color\":\"red\"}", null, "2023-01-31T10:00:00Z"]`, map[string]string{
+			commandSQLKey: "INSERT INTO foo (id, name, price, active, attrs, raw, created) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		})
+		assert.Equal(t, "1", res.Metadata[respRowsAffectedKey])
+
+		res = invoke(t, b, execOperation, "", map[string]string{
+			commandSQLKey: "INSERT INTO foo (id, name, active, raw) VALUES (?, ?, ?, x'010203')",
+			"params":      `[2, "'; DROP TABLE foo; --", false]`,
+		})
+		assert.Equal(t, "1", res.Metadata[respRowsAffectedKey])
+	})
+
+	t.Run("query with typed columns", func(t *testing.T) {
+		res := invoke(t, b, queryOperation, "", map[string]string{commandSQLKey: "SELECT * FROM foo ORDER BY id"})
+		assert.JSONEq(t, `[
+			{"id":1,"name":"book","price":9.5,"active":true,"attrs":{"color":"red"},"raw":null,"created":"2023-01-31T10:00:00Z"},
+			{"id":2,"name":"'; DROP TABLE foo; --","price":null,"active":false,"attrs":null,"raw":"AQID","created":null}
+		]`, string(res.Data))
+	})
+
+	t.Run("query with params", func(t *testing.T) {
+		res := invoke(t, b, queryOperation, `[1]`, map[string]string{commandSQLKey: "SELECT name FROM foo WHERE id = ?"})
+		assert.JSONEq(t, `[{"name":"book"}]`, string(res.Data))
+
+		res = invoke(t, b, queryOperation, `[100]`, map[string]string{commandSQLKey: "SELECT name FROM foo WHERE id = ?"})
+		assert.JSONEq(t, `[]`, string(res.Data))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{Operation: queryOperation, Metadata: map[string]string{}})
+		assert.Error(t, err)
+
+		_, err = b.Invoke(context.Background(), &bindings.InvokeRequest{Operation: execOperation, Metadata: map[string]string{commandSQLKey: "INSERT INTO missing VALUES (1)"}})
+		assert.Error(t, err)
+
+		_, err = b.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "unsupported", Metadata: map[string]string{commandSQLKey: "SELECT 1"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("read-only mode", func(t *testing.T) {
+		ro := newTestBinding(t, map[string]string{"connectionString": dbPath, "readOnly": "true"})
+
+		res := invoke(t, ro, queryOperation, "", map[string]string{commandSQLKey: "SELECT COUNT(*) AS n FROM foo"})
+		assert.JSONEq(t, `[{"n":2}]`, string(res.Data))
+
+		_, err := ro.Invoke(context.Background(), &bindings.InvokeRequest{Operation: execOperation, Metadata: map[string]string{commandSQLKey: "DELETE FROM foo"}})
+		assert.Error(t, err)
+
+		_, err = ro.Invoke(context.Background(), &bindings.InvokeRequest{Operation: queryOperation, Metadata: map[string]string{commandSQLKey: "DELETE FROM foo RETURNING id"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{Operation: closeOperation})
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	t.Run("missing connection string", func(t *testing.T) {
+		m := sqliteMetadata{}
+		err := m.InitWithMetadata(bindings.Metadata{})
+		assert.Error(t, err)
+	})
+
+	t.Run("connection string", func(t *testing.T) {
+		m := sqliteMetadata{}
+		err := m.InitWithMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"connectionString": "data.db",
+			"busyTimeout":      "500ms",
+			"timeout":          "5s",
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Second, m.Timeout)
+
+		connString, err := m.GetConnectionString()
+		require.NoError(t, err)
+		assert.Equal(t, "file:data.db?_pragma=busy_timeout%28500%29&_pragma=journal_mode%28WAL%29", connString)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		m := sqliteMetadata{}
+		err := m.InitWithMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"connectionString": "file:data.db",
+			"readOnly":         "true",
+		}}})
+		require.NoError(t, err)
+
+		connString, err := m.GetConnectionString()
+		require.NoError(t, err)
+		assert.Equal(t, "file:data.db?_pragma=query_only%281%29&_pragma=busy_timeout%282000%29&mode=ro", connString)
+	})
+
+	t.Run("forbidden pragma", func(t *testing.T) {
+		m := sqliteMetadata{}
+		err := m.InitWithMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"connectionString": "data.db?_pragma=journal_mode(WAL)",
+		}}})
+		require.NoError(t, err)
+
+		_, err = m.GetConnectionString()
+		assert.Error(t, err)
+	})
+}
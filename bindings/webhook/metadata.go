@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+)
+
+const (
+	defaultAddress            = ":8080"
+	defaultPath               = "/"
+	defaultMaxBodySize        = 4 << 20
+	defaultSignatureTolerance = 5 * time.Minute
+
+	signatureStyleGitHub = "github"
+	signatureStyleStripe = "stripe"
+)
+
+type webhookMetadata struct {
+	Address            string        `mapstructure:"address"`
+	Path               string        `mapstructure:"path"`
+	Methods            []string      `mapstructure:"methods"`
+	MaxBodySize        int64         `mapstructure:"maxBodySize"`
+	TLSCertificate     string        `mapstructure:"tlsCertificate"`
+	TLSKey             string        `mapstructure:"tlsKey"`
+	SignatureStyle     string        `mapstructure:"signatureStyle"`
+	SignatureSecret    string        `mapstructure:"signatureSecret"`
+	SignatureTolerance time.Duration `mapstructure:"signatureTolerance"`
+}
+
+func (m *webhookMetadata) InitWithMetadata(meta bindings.Metadata) error {
+	m.reset()
+
+	err := metadata.DecodeMetadata(meta.Properties, m)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(m.Path, "/") {
+		m.Path = "/" + m.Path
+	}
+	for i, method := range m.Methods {
+		m.Methods[i] = strings.ToUpper(strings.TrimSpace(method))
+	}
+	if len(m.Methods) == 0 {
+		m.Methods = []string{http.MethodPost}
+	}
+	if m.MaxBodySize <= 0 {
+		return errors.New("invalid value for 'maxBodySize': must be greater than 0")
+	}
+
+	if (m.TLSCertificate == "") != (m.TLSKey == "") {
+		return errors.New("both 'tlsCertificate' and 'tlsKey' must be set to enable TLS")
+	}
+
+	m.SignatureStyle = strings.ToLower(m.SignatureStyle)
+	switch m.SignatureStyle {
+	case "":
+	case signatureStyleGitHub, signatureStyleStripe:
+		if m.SignatureSecret == "" {
+			return fmt.Errorf("'signatureSecret' is required with the %s signature style", m.SignatureStyle)
+		}
+	default:
+		return fmt.Errorf("invalid value for 'signatureStyle': %s", m.SignatureStyle)
+	}
+
+	return nil
+}
+
+func (m *webhookMetadata) reset() {
+	m.Address = defaultAddress
+	m.Path = defaultPath
+	m.Methods = nil
+	m.MaxBodySize = defaultMaxBodySize
+	m.TLSCertificate = ""
+	m.TLSKey = ""
+	m.SignatureStyle = ""
+	m.SignatureSecret = ""
+	m.SignatureTolerance = defaultSignatureTolerance
+}
+
+// getPemBytes returns the PEM encoded bytes of a metadata property, which is either PEM or the path of a PEM file.
+func getPemBytes(name, val string) ([]byte, error) {
+	if block, _ := pem.Decode([]byte(val)); block != nil {
+		return []byte(val), nil
+	}
+
+	pemBytes, err := os.ReadFile(val)
+	if err != nil {
+		return nil, fmt.Errorf("provided %q value is neither a valid file path or nor a valid pem encoded string: %w", name, err)
+	}
+	return pemBytes, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	gitHubSignatureHeader = "X-Hub-Signature-256"
+	gitHubSignaturePrefix = "sha256="
+	stripeSignatureHeader = "Stripe-Signature"
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+// verifySignature verifies the signature of a request, according to the configured signature style.
+func (w *Webhook) verifySignature(header http.Header, body []byte) error {
+	switch w.metadata.SignatureStyle {
+	case signatureStyleGitHub:
+		return verifyGitHubSignature(header.Get(gitHubSignatureHeader), body, []byte(w.metadata.SignatureSecret))
+	case signatureStyleStripe:
+		return verifyStripeSignature(header.Get(stripeSignatureHeader), body, []byte(w.metadata.SignatureSecret), w.metadata.SignatureTolerance, w.clock.Now())
+	default:
+		return nil
+	}
+}
+
+// verifyGitHubSignature verifies a signature in the format of the X-Hub-Signature-256 header of GitHub webhooks:
+// `sha256=` followed by the hex-encoded HMAC-SHA256 of the body.
+func verifyGitHubSignature(signature string, body []byte, secret []byte) error {
+	if !strings.HasPrefix(signature, gitHubSignaturePrefix) {
+		return errInvalidSignature
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, gitHubSignaturePrefix))
+	if err != nil {
+		return errInvalidSignature
+	}
+
+	if !hmac.Equal(expected, computeHMAC(secret, body)) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// verifyStripeSignature verifies a signature in the format of the Stripe-Signature header of Stripe webhooks:
+// `t=<timestamp>,v1=<signature>`, where the signature is the hex-encoded HMAC-SHA256 of `<timestamp>.<body>`.
+// The header can contain multiple v1 signatures, when secrets are rolled, and the timestamp must be within the tolerance.
+func verifyStripeSignature(header string, body []byte, secret []byte, tolerance time.Duration, now time.Time) error {
+	var (
+		timestamp  string
+		signatures [][]byte
+	)
+	for _, item := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			sig, err := hex.DecodeString(v)
+			if err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errInvalidSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errInvalidSignature
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(ts, 0))
+		if age > tolerance || age < -tolerance {
+			return errors.New("signature timestamp is outside of the tolerance")
+		}
+	}
+
+	payload := make([]byte, 0, len(timestamp)+1+len(body))
+	payload = append(payload, timestamp...)
+	payload = append(payload, '.')
+	payload = append(payload, body...)
+	expected := computeHMAC(secret, payload)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}
+
+func computeHMAC(secret []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Keys of the request properties in the metadata of events, in addition to the request headers.
+	methodMetadataKey     = "method"
+	pathMetadataKey       = "path"
+	queryMetadataKey      = "query"
+	remoteAddrMetadataKey = "remoteAddr"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+// Webhook is an input binding that receives HTTP requests.
+type Webhook struct {
+	metadata webhookMetadata
+	logger   logger.Logger
+	clock    clock.Clock
+
+	lock     sync.Mutex
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewWebhook returns a new Webhook input binding.
+func NewWebhook(logger logger.Logger) bindings.InputBinding {
+	return &Webhook{
+		logger: logger,
+		clock:  clock.New(),
+	}
+}
+
+// Init performs metadata parsing.
+func (w *Webhook) Init(metadata bindings.Metadata) error {
+	return w.metadata.InitWithMetadata(metadata)
+}
+
+// Read starts the HTTP server, which invokes the handler for each request it receives.
+// The data returned by the handler is the body of the response.
+func (w *Webhook) Read(ctx context.Context, handler bindings.Handler) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.server != nil {
+		return errors.New("webhook binding is already reading")
+	}
+
+	var tlsConfig *tls.Config
+	if w.metadata.TLSCertificate != "" {
+		certBytes, err := getPemBytes("tlsCertificate", w.metadata.TLSCertificate)
+		if err != nil {
+			return err
+		}
+		keyBytes, err := getPemBytes("tlsKey", w.metadata.TLSKey)
+		if err != nil {
+			return err
+		}
+		cert, err := tls.X509KeyPair(certBytes, keyBytes)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	listener, err := net.Listen("tcp", w.metadata.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", w.metadata.Address, err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(w.metadata.Path, w.handler(handler))
+	w.listener = listener
+	w.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	server := w.server
+	go func() {
+		w.logger.Debugf("Listening for webhook requests on %s%s", listener.Addr(), w.metadata.Path)
+		srvErr := server.Serve(listener)
+		if srvErr != nil && !errors.Is(srvErr, http.ErrServerClosed) {
+			w.logger.Errorf("Error serving webhook requests: %v", srvErr)
+		}
+	}()
+
+	// Close the server when context is canceled
+	go func() {
+		<-ctx.Done()
+		w.Close()
+	}()
+
+	return nil
+}
+
+// Close stops the HTTP server.
+func (w *Webhook) Close() error {
+	w.lock.Lock()
+	server := w.server
+	w.server = nil
+	w.lock.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+func (w *Webhook) handler(handler bindings.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !w.isAllowedMethod(r.Method) {
+			rw.Header().Set("Allow", strings.Join(w.metadata.Methods, ", "))
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, w.metadata.MaxBodySize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(rw, "error reading request body", http.StatusBadRequest)
+			return
+		}
+
+		err = w.verifySignature(r.Header, body)
+		if err != nil {
+			w.logger.Warnf("Rejected webhook request from %s: %v", r.RemoteAddr, err)
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		res, err := handler(r.Context(), &bindings.ReadResponse{
+			Data:     body,
+			Metadata: requestMetadata(r),
+		})
+		if err != nil {
+			w.logger.Errorf("Error handling webhook request: %v", err)
+			http.Error(rw, "error handling request", http.StatusInternalServerError)
+			return
+		}
+
+		if len(res) > 0 {
+			if json.Valid(res) {
+				rw.Header().Set("Content-Type", "application/json")
+			} else {
+				rw.Header().Set("Content-Type", http.DetectContentType(res))
+			}
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write(res)
+	})
+}
+
+func (w *Webhook) isAllowedMethod(method string) bool {
+	for _, m := range w.metadata.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// requestMetadata returns the headers of a request, delimiting multiple values with ", ",
+// and the method, path, query string and remote address of the request.
+func requestMetadata(r *http.Request) map[string]string {
+	md := make(map[string]string, len(r.Header)+4)
+	for key, values := range r.Header {
+		md[key] = strings.Join(values, ", ")
+	}
+	md[methodMetadataKey] = r.Method
+	md[pathMetadataKey] = r.URL.Path
+	md[queryMetadataKey] = r.URL.RawQuery
+	md[remoteAddrMetadataKey] = r.RemoteAddr
+	return md
+}
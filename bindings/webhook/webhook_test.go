@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func startWebhook(t *testing.T, props map[string]string, handler bindings.Handler) (*Webhook, string) {
+	t.Helper()
+
+	props["address"] = "127.0.0.1:0"
+	w := NewWebhook(logger.NewLogger("test")).(*Webhook)
+	err := w.Init(bindings.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	err = w.Read(ctx, handler)
+	require.NoError(t, err)
+
+	scheme := "http"
+	if props["tlsCertificate"] != "" {
+		scheme = "https"
+	}
+	return w, scheme + "://" + w.listener.Addr().String()
+}
+
+func send(t *testing.T, client *http.Client, method, url, body string, header http.Header) (int, string, http.Header) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(b), res.Header
+}
+
+func TestRead(t *testing.T) {
+	var received *bindings.ReadResponse
+	_, url := startWebhook(t, map[string]string{"path": "/hooks/", "methods": "post,put", "maxBodySize": "10"},
+		func(_ context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			received = res
+			if string(res.Data) == "fail" {
+				return nil, errors.New("handler error")
+			}
+			return []byte(`{"ok":true}`), nil
+		})
+
+	t.Run("request", func(t *testing.T) {
+		status, body, header := send(t, http.DefaultClient, http.MethodPost, url+"/hooks/a?x=1", "data", http.Header{
+			"X-Event": {"push"},
+			"X-Multi": {"a", "b"},
+		})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `{"ok":true}`, body)
+		assert.Equal(t, "application/json", header.Get("Content-Type"))
+
+		require.NotNil(t, received)
+		assert.Equal(t, "data", string(received.Data))
+		assert.Equal(t, "push", received.Metadata["X-Event"])
+		assert.Equal(t, "a, b", received.Metadata["X-Multi"])
+		assert.Equal(t, "POST", received.Metadata["method"])
+		assert.Equal(t, "/hooks/a", received.Metadata["path"])
+		assert.Equal(t, "x=1", received.Metadata["query"])
+		assert.NotEmpty(t, received.Metadata["remoteAddr"])
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		status, _, _ := send(t, http.DefaultClient, http.MethodPut, url+"/hooks/", "fail", nil)
+		assert.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("rejected requests", func(t *testing.T) {
+		received = nil
+
+		status, _, header := send(t, http.DefaultClient, http.MethodGet, url+"/hooks/", "", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, status)
+		assert.Equal(t, "POST, PUT", header.Get("Allow"))
+
+		status, _, _ = send(t, http.DefaultClient, http.MethodPost, url+"/other", "data", nil)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _, _ = send(t, http.DefaultClient, http.MethodPost, url+"/hooks/", "more than 10 bytes", nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+		assert.Nil(t, received)
+	})
+}
+
+func TestSignatures(t *testing.T) {
+	handler := func(context.Context, *bindings.ReadResponse) ([]byte, error) {
+		return []byte("ok"), nil
+	}
+
+	t.Run("github", func(t *testing.T) {
+		_, url := startWebhook(t, map[string]string{"signatureStyle": "github", "signatureSecret": "secret"}, handler)
+
+		sig := "sha256=" + hex.EncodeToString(computeHMAC([]byte("secret"), []byte("payload")))
+		status, body, _ := send(t, http.DefaultClient, http.MethodPost, url, "payload", http.Header{"X-Hub-Signature-256": {sig}})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "ok", body)
+
+		for _, sig := range []string{"", sig[7:], "sha256=zz", "sha256=" + hex.EncodeToString(computeHMAC([]byte("other"), []byte("payload")))} {
+			status, _, _ = send(t, http.DefaultClient, http.MethodPost, url, "payload", http.Header{"X-Hub-Signature-256": {sig}})
+			assert.Equal(t, http.StatusUnauthorized, status, sig)
+		}
+	})
+
+	t.Run("stripe", func(t *testing.T) {
+		w, url := startWebhook(t, map[string]string{"signatureStyle": "stripe", "signatureSecret": "secret", "signatureTolerance": "1m"}, handler)
+		mockClock := clock.NewMock()
+		mockClock.Set(time.Unix(1700000000, 0))
+		w.clock = mockClock
+
+		stripeHeader := func(ts int64, secret string) string {
+			t := strconv.FormatInt(ts, 10)
+			return "t=" + t + ",v1=" + hex.EncodeToString(computeHMAC([]byte(secret), []byte(t+".payload")))
+		}
+
+		status, _, _ := send(t, http.DefaultClient, http.MethodPost, url, "payload", http.Header{"Stripe-Signature": {stripeHeader(1700000030, "secret")}})
+		assert.Equal(t, http.StatusOK, status)
+
+		// Any of the v1 signatures can match
+		status, _, _ = send(t, http.DefaultClient, http.MethodPost, url, "payload", http.Header{"Stripe-Signature": {stripeHeader(1700000000, "old") + ",v1=" + hex.EncodeToString(computeHMAC([]byte("secret"), []byte("1700000000.payload")))}})
+		assert.Equal(t, http.StatusOK, status)
+
+		for _, h := range []string{"", stripeHeader(1700000000, "other"), stripeHeader(1699999000, "secret"), "t=1700000000", "v1=00"} {
+			status, _, _ = send(t, http.DefaultClient, http.MethodPost, url, "payload", http.Header{"Stripe-Signature": {h}})
+			assert.Equal(t, http.StatusUnauthorized, status, h)
+		}
+	})
+}
+
+func TestTLS(t *testing.T) {
+	// Generate a self-signed certificate for 127.0.0.1
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	_, url := startWebhook(t, map[string]string{
+		"tlsCertificate": string(certPEM),
+		"tlsKey":         string(keyPEM),
+	}, func(context.Context, *bindings.ReadResponse) ([]byte, error) {
+		return []byte("secure"), nil
+	})
+
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				MinVersion: tls.VersionTLS12,
+				RootCAs:    pool,
+			},
+		},
+	}
+
+	status, body, _ := send(t, client, http.MethodPost, url, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "secure", body)
+}
+
+func TestMetadata(t *testing.T) {
+	m := webhookMetadata{}
+	err := m.InitWithMetadata(bindings.Metadata{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultAddress, m.Address)
+	assert.Equal(t, "/", m.Path)
+	assert.Equal(t, []string{"POST"}, m.Methods)
+
+	for _, props := range []map[string]string{
+		{"maxBodySize": "0"},
+		{"tlsCertificate": "cert"},
+		{"signatureStyle": "github"},
+		{"signatureStyle": "other", "signatureSecret": "secret"},
+	} {
+		err = m.InitWithMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+		require.Error(t, err, props)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package components lets components use the other components loaded by the host.
+package components
+
+import (
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+)
+
+// Resolver looks up the other components loaded by the host, by the name of the component.
+// The components returned are initialized and owned by the host: they must not be closed by the caller.
+// An error is returned if there's no component of that type with the name.
+type Resolver interface {
+	StateStore(name string) (state.Store, error)
+	SecretStore(name string) (secretstores.SecretStore, error)
+	OutputBinding(name string) (bindings.OutputBinding, error)
+	PubSub(name string) (pubsub.PubSub, error)
+}
+
+// ResolverConsumer is implemented by the components that use other components, which are referenced by name in
+// their metadata. It's used by middlewares, bindings and pubsubs.
+//
+// The host must call SetComponentResolver right after creating the component, before GetHandler for middlewares
+// and before Init for bindings and pubsubs. The names are resolved in GetHandler for middlewares, in Read for input
+// bindings and when the first message is published or received for pubsubs, so the components they reference must
+// be initialized by then.
+type ResolverConsumer interface {
+	SetComponentResolver(resolver Resolver)
+}
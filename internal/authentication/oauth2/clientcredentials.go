@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oauth2 contains the configuration of the OAuth2 client credentials flow shared by the components
+// that obtain tokens with it.
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ClientCredentialsMetadata contains the metadata properties of the OAuth2 client credentials flow.
+// It's decoded with metadata.DecodeMetadata, embedded in the metadata of the component.
+type ClientCredentialsMetadata struct {
+	ClientID            string `json:"clientID"`
+	ClientSecret        string `json:"clientSecret"`
+	Scopes              string `json:"scopes"`
+	TokenURL            string `json:"tokenURL"`
+	EndpointParamsQuery string `json:"endpointParamsQuery,omitempty"`
+	AuthStyle           int    `json:"authStyle"`
+}
+
+// Validate returns an error describing every missing or invalid property, if any.
+func (m ClientCredentialsMetadata) Validate() error {
+	var errs []string
+
+	for _, p := range []struct {
+		name  string
+		value string
+	}{
+		{"clientID", m.ClientID},
+		{"clientSecret", m.ClientSecret},
+		{"scopes", m.Scopes},
+		{"tokenURL", m.TokenURL},
+	} {
+		if p.value == "" {
+			errs = append(errs, fmt.Sprintf("Parameter '%s' needs to be set.", p.name))
+		}
+	}
+
+	if m.AuthStyle < 0 || m.AuthStyle > 2 {
+		errs = append(errs, fmt.Sprintf("Parameter 'authStyle' can only have the values 0,1,2. Received: '%d'.", m.AuthStyle))
+	}
+	if _, err := url.ParseQuery(m.EndpointParamsQuery); err != nil {
+		errs = append(errs, fmt.Sprintf("Parameter 'endpointParamsQuery' is not a valid query string: %v.", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, " "))
+	}
+	return nil
+}
+
+// Config returns the configuration of the client credentials flow.
+func (m ClientCredentialsMetadata) Config() (*clientcredentials.Config, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The query was validated
+	endpointParams, _ := url.ParseQuery(m.EndpointParamsQuery)
+
+	return &clientcredentials.Config{
+		ClientID:       m.ClientID,
+		ClientSecret:   m.ClientSecret,
+		Scopes:         strings.Split(m.Scopes, ","),
+		TokenURL:       m.TokenURL,
+		EndpointParams: endpointParams,
+		AuthStyle:      oauth2.AuthStyle(m.AuthStyle),
+	}, nil
+}
+
+// TokenSource returns a source of tokens obtained with the client credentials flow. Tokens are cached until they
+// expire. If client isn't nil, it's used to request the tokens.
+func (m ClientCredentialsMetadata) TokenSource(client *http.Client) (oauth2.TokenSource, error) {
+	conf, err := m.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	return conf.TokenSource(ctx), nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth2
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+)
+
+func TestClientCredentialsConfig(t *testing.T) {
+	tests := map[string]struct {
+		properties map[string]string
+		err        string
+	}{
+		"valid": {
+			properties: map[string]string{
+				"clientID":            "id",
+				"clientSecret":        "secret",
+				"scopes":              "a,b",
+				"tokenURL":            "https://localhost/token",
+				"endpointParamsQuery": "audience=myapi",
+				"authStyle":           "2",
+			},
+		},
+		"missing properties": {
+			properties: map[string]string{"clientID": "id"},
+			err:        "Parameter 'clientSecret' needs to be set. Parameter 'scopes' needs to be set. Parameter 'tokenURL' needs to be set.",
+		},
+		"invalid auth style": {
+			properties: map[string]string{"clientID": "id", "clientSecret": "secret", "scopes": "a", "tokenURL": "https://localhost/token", "authStyle": "3"},
+			err:        "Parameter 'authStyle' can only have the values 0,1,2. Received: '3'.",
+		},
+		"invalid endpoint params": {
+			properties: map[string]string{"clientID": "id", "clientSecret": "secret", "scopes": "a", "tokenURL": "https://localhost/token", "endpointParamsQuery": "a=%zz"},
+			err:        `Parameter 'endpointParamsQuery' is not a valid query string: invalid URL escape "%zz".`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var m ClientCredentialsMetadata
+			require.NoError(t, mdutils.DecodeMetadata(tc.properties, &m))
+
+			conf, err := m.Config()
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "id", conf.ClientID)
+			assert.Equal(t, "secret", conf.ClientSecret)
+			assert.Equal(t, []string{"a", "b"}, conf.Scopes)
+			assert.Equal(t, "https://localhost/token", conf.TokenURL)
+			assert.Equal(t, url.Values{"audience": {"myapi"}}, conf.EndpointParams)
+			assert.Equal(t, oauth2.AuthStyleInHeader, conf.AuthStyle)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	cloudEventsMode = "cloudEventsMode"
+
+	cloudEventsModeStructured = "structured"
+	cloudEventsModeBinary     = "binary"
+	cloudEventsModeProtobuf   = "protobuf"
+	cloudEventsModeAvro       = "avro"
+)
+
+// isReservedMetadata returns true for the metadata properties that configure
+// the component and must not be forwarded as message headers.
+func isReservedMetadata(name string) bool {
+	return name == key || name == valueSchemaType || name == cloudEventsMode
+}
+
+// encodeCloudEvent converts a structured JSON CloudEvent to the mode requested with
+// the `cloudEventsMode` metadata property of a publish request.
+// In binary mode, the attributes are returned as `ce_` headers and the data as the payload.
+// In protobuf and avro mode, the event is re-encoded in the corresponding CloudEvents format.
+func encodeCloudEvent(data []byte, metadata map[string]string) ([]byte, []sarama.RecordHeader, error) {
+	mode := strings.ToLower(metadata[cloudEventsMode])
+	switch mode {
+	case "", cloudEventsModeStructured:
+		return data, nil, nil
+
+	case cloudEventsModeBinary:
+		payload, ceHeaders, err := pubsub.BinaryCloudEventFromStructured(data, pubsub.CloudEventsHeaderPrefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("kafka error: cannot convert message to a binary CloudEvent: %w", err)
+		}
+		headers := make([]sarama.RecordHeader, 0, len(ceHeaders))
+		for name, value := range ceHeaders {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(name), Value: []byte(value)})
+		}
+		return payload, headers, nil
+
+	case cloudEventsModeProtobuf, cloudEventsModeAvro:
+		contentType := pubsub.CloudEventProtobufContentType
+		if mode == cloudEventsModeAvro {
+			contentType = pubsub.CloudEventAvroContentType
+		}
+		payload, err := pubsub.CloudEventFormatFromStructured(data, contentType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("kafka error: cannot convert message to %s: %w", contentType, err)
+		}
+		return payload, []sarama.RecordHeader{{Key: []byte(pubsub.ContentTypeHeader), Value: []byte(contentType)}}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", cloudEventsMode, metadata[cloudEventsMode])
+	}
+}
+
+// decodeCloudEvent converts a message carrying a binary-mode CloudEvent, or a CloudEvent
+// in the protobuf or Avro format, to a structured JSON CloudEvent.
+// Other messages are returned unchanged.
+func decodeCloudEvent(data []byte, headers map[string]string) ([]byte, error) {
+	if pubsub.IsBinaryCloudEvent(headers, pubsub.CloudEventsHeaderPrefix) {
+		res, err := pubsub.StructuredCloudEventFromBinary(data, headers, pubsub.CloudEventsHeaderPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: cannot convert binary CloudEvent: %w", err)
+		}
+		return res, nil
+	}
+
+	contentType := headers[pubsub.ContentTypeHeader]
+	if contenttype.IsCloudEventContentType(contentType) || !pubsub.IsCloudEventFormatContentType(contentType) {
+		return data, nil
+	}
+	res, err := pubsub.StructuredCloudEventFromFormat(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("kafka error: cannot parse %s message: %w", contentType, err)
+	}
+	return res, nil
+}
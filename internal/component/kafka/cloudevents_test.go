@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStructuredCloudEvent = `{"specversion":"1.0","id":"a","source":"/orders","type":"order.created","datacontenttype":"application/json","data":{"id":7}}`
+
+func TestEncodeCloudEvent(t *testing.T) {
+	t.Run("structured mode by default", func(t *testing.T) {
+		data, headers, err := encodeCloudEvent([]byte(testStructuredCloudEvent), map[string]string{})
+		require.NoError(t, err)
+		assert.Equal(t, testStructuredCloudEvent, string(data))
+		assert.Empty(t, headers)
+	})
+
+	t.Run("binary mode", func(t *testing.T) {
+		data, headers, err := encodeCloudEvent([]byte(testStructuredCloudEvent), map[string]string{cloudEventsMode: "binary"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":7}`, string(data))
+
+		metadata := map[string]string{}
+		for _, h := range headers {
+			metadata[string(h.Key)] = string(h.Value)
+		}
+		assert.Equal(t, "a", metadata["ce_id"])
+		assert.Equal(t, "order.created", metadata["ce_type"])
+		assert.Equal(t, "application/json", metadata["content-type"])
+
+		res, err := decodeCloudEvent(data, metadata)
+		require.NoError(t, err)
+		assert.JSONEq(t, testStructuredCloudEvent, string(res))
+	})
+
+	for _, mode := range []string{"protobuf", "avro"} {
+		t.Run(mode+" mode", func(t *testing.T) {
+			data, headers, err := encodeCloudEvent([]byte(testStructuredCloudEvent), map[string]string{cloudEventsMode: mode})
+			require.NoError(t, err)
+			require.Len(t, headers, 1)
+			assert.Equal(t, "application/cloudevents+"+mode, string(headers[0].Value))
+
+			res, err := decodeCloudEvent(data, map[string]string{string(headers[0].Key): string(headers[0].Value)})
+			require.NoError(t, err)
+			assert.JSONEq(t, testStructuredCloudEvent, string(res))
+		})
+	}
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, _, err := encodeCloudEvent([]byte(testStructuredCloudEvent), map[string]string{cloudEventsMode: "xml"})
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeCloudEvent(t *testing.T) {
+	t.Run("other messages are unchanged", func(t *testing.T) {
+		res, err := decodeCloudEvent([]byte("hello"), map[string]string{"content-type": "text/plain"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res))
+
+		res, err = decodeCloudEvent([]byte(testStructuredCloudEvent), map[string]string{"content-type": "application/cloudevents+json"})
+		require.NoError(t, err)
+		assert.Equal(t, testStructuredCloudEvent, string(res))
+	})
+
+	t.Run("invalid protobuf message", func(t *testing.T) {
+		_, err := decodeCloudEvent([]byte{0x0a, 0x05, 'a'}, map[string]string{"content-type": "application/cloudevents+protobuf"})
+		require.Error(t, err)
+	})
+}
@@ -132,24 +132,50 @@ func (consumer *consumer) doBulkCallback(session sarama.ConsumerGroupSession,
 	messages []*sarama.ConsumerMessage, handler BulkEventHandler, topic string,
 ) error {
 	consumer.k.logger.Debugf("Processing Kafka bulk message: %s", topic)
-	messageValues := make([]KafkaBulkMessageEntry, (len(messages)))
+	handlerConfig, err := consumer.k.GetTopicHandlerConfig(topic)
+	if err != nil {
+		return err
+	}
+	messageValues := make([]KafkaBulkMessageEntry, 0, len(messages))
+	entryMessages := make([]*sarama.ConsumerMessage, 0, len(messages))
 
 	for i, message := range messages {
-		if message != nil {
-			metadata := make(map[string]string, len(message.Headers))
-			if message.Headers != nil {
-				for _, t := range message.Headers {
-					metadata[string(t.Key)] = string(t.Value)
-				}
-			}
-			childMessage := KafkaBulkMessageEntry{
-				EntryId:  strconv.Itoa(i),
-				Event:    message.Value,
-				Metadata: metadata,
+		if message == nil {
+			continue
+		}
+		value, err := consumer.k.deserializeValue(session.Context(), message.Value, handlerConfig.ValueSchemaType)
+		if errors.Is(err, errUndecodableValue) {
+			// The message can never be delivered: it's skipped so that the rest of the batch is
+			consumer.k.logger.Errorf("Skipping Kafka message %s/%d/%d that can't be deserialized: %v", message.Topic, message.Partition, message.Offset, err)
+			continue
+		} else if err != nil {
+			return err
+		}
+		metadata := make(map[string]string, len(message.Headers))
+		if message.Headers != nil {
+			for _, t := range message.Headers {
+				metadata[string(t.Key)] = string(t.Value)
 			}
-			messageValues[i] = childMessage
 		}
+		value, err = decodeCloudEvent(value, metadata)
+		if err != nil {
+			return err
+		}
+		childMessage := KafkaBulkMessageEntry{
+			EntryId:  strconv.Itoa(i),
+			Event:    value,
+			Metadata: metadata,
+		}
+		messageValues = append(messageValues, childMessage)
+		entryMessages = append(entryMessages, message)
 	}
+	if len(messageValues) == 0 {
+		for _, message := range messages {
+			session.MarkMessage(message, "")
+		}
+		return nil
+	}
+
 	event := KafkaBulkMessage{
 		Topic:   topic,
 		Entries: messageValues,
@@ -165,7 +191,7 @@ func (consumer *consumer) doBulkCallback(session sarama.ConsumerGroupSession,
 			if resp.Error != nil {
 				break
 			}
-			session.MarkMessage(messages[i], "")
+			session.MarkMessage(entryMessages[i], "")
 		}
 	} else {
 		for _, message := range messages {
@@ -184,9 +210,13 @@ func (consumer *consumer) doCallback(session sarama.ConsumerGroupSession, messag
 	if !handlerConfig.IsBulkSubscribe && handlerConfig.Handler == nil {
 		return errors.New("invalid handler config for subscribe call")
 	}
+	data, err := consumer.k.deserializeValue(session.Context(), message.Value, handlerConfig.ValueSchemaType)
+	if err != nil {
+		return err
+	}
 	event := NewEvent{
 		Topic: message.Topic,
-		Data:  message.Value,
+		Data:  data,
 	}
 	// This is true only when headers are set (Kafka > 0.11)
 	if len(message.Headers) > 0 {
@@ -194,6 +224,10 @@ func (consumer *consumer) doCallback(session sarama.ConsumerGroupSession, messag
 		for _, header := range message.Headers {
 			event.Metadata[string(header.Key)] = string(header.Value)
 		}
+		event.Data, err = decodeCloudEvent(event.Data, event.Metadata)
+		if err != nil {
+			return err
+		}
 	}
 	err = handlerConfig.Handler(session.Context(), &event)
 	if err == nil {
@@ -206,7 +240,13 @@ func (consumer *consumer) Cleanup(sarama.ConsumerGroupSession) error {
 	return nil
 }
 
-func (consumer *consumer) Setup(sarama.ConsumerGroupSession) error {
+func (consumer *consumer) Setup(session sarama.ConsumerGroupSession) error {
+	// The session is retried when the start positions can't be applied, rather than consuming from the
+	// committed offsets
+	if err := consumer.k.applyStartPositions(session); err != nil {
+		return fmt.Errorf("kafka error: cannot apply start positions: %w", err)
+	}
+
 	consumer.once.Do(func() {
 		close(consumer.ready)
 	})
@@ -219,6 +259,14 @@ func (k *Kafka) AddTopicHandler(topic string, handlerConfig SubscriptionHandlerC
 	k.subscribeLock.Lock()
 	k.subscribeTopics[topic] = handlerConfig
 	k.subscribeLock.Unlock()
+
+	k.startPositionsLock.Lock()
+	if handlerConfig.StartPosition != nil {
+		k.startPositions[topic] = handlerConfig.StartPosition
+	} else {
+		delete(k.startPositions, topic)
+	}
+	k.startPositionsLock.Unlock()
 }
 
 // RemoveTopicHandler removes a topic handler
@@ -262,6 +310,13 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 	// Close resources and reset synchronization primitives
 	k.closeSubscriptionResources()
 
+	k.subscribeCtx = ctx
+	return k.subscribe(ctx)
+}
+
+// subscribe starts the consumer group for the subscribed topics.
+// It must be called with subscribeLock held, after closing the previous subscription resources.
+func (k *Kafka) subscribe(ctx context.Context) error {
 	topics := k.subscribeTopics.TopicList()
 	if len(topics) == 0 {
 		// Nothing to subscribe to
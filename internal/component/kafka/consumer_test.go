@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// fakeSession is a consumer group session recording the offsets of the messages marked.
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func TestBulkCallbackUndecodableMessages(t *testing.T) {
+	registry := newRegistryStandIn(t)
+	k := getKafka()
+	k.schemaRegistry = newSchemaRegistryClient(registry.server.URL+"/", "key", "secret", time.Minute)
+	value, err := k.serializeValue(context.Background(), "orders", []byte(`{"id":7,"item":"book","quantity":2}`), Avro)
+	require.NoError(t, err)
+	unknownSchema := append([]byte{}, value...)
+	binary.BigEndian.PutUint32(unknownSchema[1:schemaRegistryHeaderSize], 99)
+
+	var entries []KafkaBulkMessageEntry
+	handler := func(ctx context.Context, msg *KafkaBulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+		entries = msg.Entries
+		return nil, nil
+	}
+	k.subscribeTopics = make(TopicHandlerConfig)
+	k.AddTopicHandler("orders", SubscriptionHandlerConfig{IsBulkSubscribe: true, BulkHandler: handler, ValueSchemaType: Avro})
+	c := &consumer{k: k}
+
+	t.Run("undecodable messages are skipped", func(t *testing.T) {
+		entries = nil
+		session := &fakeSession{}
+		err := c.doBulkCallback(session, []*sarama.ConsumerMessage{
+			{Topic: "orders", Offset: 1, Value: value},
+			{Topic: "orders", Offset: 2, Value: []byte(`{"id":8}`)},
+			{Topic: "orders", Offset: 3, Value: value},
+		}, handler, "orders")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "0", entries[0].EntryId)
+		assert.Equal(t, "2", entries[1].EntryId)
+		assert.JSONEq(t, `{"id":7,"item":"book","quantity":2,"note":null}`, string(entries[1].Event))
+		assert.Equal(t, []int64{1, 2, 3}, session.marked)
+	})
+
+	t.Run("only undecodable messages", func(t *testing.T) {
+		entries = nil
+		session := &fakeSession{}
+		err := c.doBulkCallback(session, []*sarama.ConsumerMessage{
+			{Topic: "orders", Offset: 4, Value: []byte("raw")},
+		}, handler, "orders")
+		require.NoError(t, err)
+		assert.Nil(t, entries)
+		assert.Equal(t, []int64{4}, session.marked)
+	})
+
+	t.Run("registry errors fail the batch", func(t *testing.T) {
+		entries = nil
+		session := &fakeSession{}
+		err := c.doBulkCallback(session, []*sarama.ConsumerMessage{
+			{Topic: "orders", Offset: 5, Value: value},
+			{Topic: "orders", Offset: 6, Value: unknownSchema},
+		}, handler, "orders")
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, errUndecodableValue))
+		assert.Nil(t, entries)
+		assert.Empty(t, session.marked)
+	})
+
+	t.Run("failed entries", func(t *testing.T) {
+		session := &fakeSession{}
+		failing := func(ctx context.Context, msg *KafkaBulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+			return []pubsub.BulkSubscribeResponseEntry{
+				{EntryId: "1"},
+				{EntryId: "2", Error: errors.New("failed")},
+			}, errors.New("failed")
+		}
+		err := c.doBulkCallback(session, []*sarama.ConsumerMessage{
+			{Topic: "orders", Offset: 7, Value: []byte("raw")},
+			{Topic: "orders", Offset: 8, Value: value},
+			{Topic: "orders", Offset: 9, Value: value},
+		}, failing, "orders")
+		require.Error(t, err)
+		assert.Equal(t, []int64{8}, session.marked)
+	})
+}
@@ -41,6 +41,11 @@ type Kafka struct {
 	config          *sarama.Config
 	subscribeTopics TopicHandlerConfig
 	subscribeLock   sync.Mutex
+	subscribeCtx    context.Context
+	schemaRegistry  *schemaRegistryClient
+
+	startPositions     map[string]*StartPosition
+	startPositionsLock sync.Mutex
 
 	backOffConfig retry.Config
 
@@ -56,6 +61,7 @@ func NewKafka(logger logger.Logger) *Kafka {
 		logger:          logger,
 		subscribeTopics: make(TopicHandlerConfig),
 		subscribeLock:   sync.Mutex{},
+		startPositions:  make(map[string]*StartPosition),
 	}
 }
 
@@ -109,6 +115,10 @@ func (k *Kafka) Init(metadata map[string]string) error {
 		}
 	}
 
+	if meta.SchemaRegistryURL != "" {
+		k.schemaRegistry = newSchemaRegistryClient(meta.SchemaRegistryURL, meta.SchemaRegistryAPIKey, meta.SchemaRegistryAPISecret, meta.SchemaCacheTTL)
+	}
+
 	k.config = config
 	sarama.Logger = SaramaLogBridge{daprLogger: k.logger}
 
@@ -156,6 +166,8 @@ type SubscriptionHandlerConfig struct {
 	SubscribeConfig pubsub.BulkSubscribeConfig
 	BulkHandler     BulkEventHandler
 	Handler         EventHandler
+	ValueSchemaType SchemaType
+	StartPosition   *StartPosition
 }
 
 // NewEvent is an event arriving from a message bus instance.
@@ -36,6 +36,11 @@ const (
 	oidcAuthType         = "oidc"
 	mtlsAuthType         = "mtls"
 	noAuthType           = "none"
+
+	schemaRegistryURL       = "schemaRegistryURL"
+	schemaRegistryAPIKey    = "schemaRegistryAPIKey"
+	schemaRegistryAPISecret = "schemaRegistryAPISecret"
+	schemaCacheTTL          = "schemaLatestVersionCacheTTL"
 )
 
 type kafkaMetadata struct {
@@ -60,6 +65,11 @@ type kafkaMetadata struct {
 	ConsumeRetryEnabled  bool
 	ConsumeRetryInterval time.Duration
 	Version              sarama.KafkaVersion
+
+	SchemaRegistryURL       string
+	SchemaRegistryAPIKey    string
+	SchemaRegistryAPISecret string
+	SchemaCacheTTL          time.Duration
 }
 
 // upgradeMetadata updates metadata properties based on deprecated usage.
@@ -102,6 +112,7 @@ func (k *Kafka) upgradeMetadata(metadata map[string]string) (map[string]string,
 func (k *Kafka) getKafkaMetadata(metadata map[string]string) (*kafkaMetadata, error) {
 	meta := kafkaMetadata{
 		ConsumeRetryInterval: 100 * time.Millisecond,
+		SchemaCacheTTL:       5 * time.Minute,
 	}
 	// use the runtimeConfig.ID as the consumer group so that each dapr runtime creates its own consumergroup
 	if val, ok := metadata["consumerID"]; ok && val != "" {
@@ -279,5 +290,19 @@ func (k *Kafka) getKafkaMetadata(metadata map[string]string) (*kafkaMetadata, er
 		meta.Version = sarama.V2_0_0_0 //nolint:nosnakecase
 	}
 
+	if val, ok := metadata[schemaRegistryURL]; ok && val != "" {
+		meta.SchemaRegistryURL = val
+		meta.SchemaRegistryAPIKey = metadata[schemaRegistryAPIKey]
+		meta.SchemaRegistryAPISecret = metadata[schemaRegistryAPISecret]
+	}
+
+	if val, ok := metadata[schemaCacheTTL]; ok && val != "" {
+		durationVal, err := time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %w", schemaCacheTTL, err)
+		}
+		meta.SchemaCacheTTL = durationVal
+	}
+
 	return &meta, nil
 }
@@ -319,3 +319,36 @@ func TestTls(t *testing.T) {
 		require.Equal(t, "kafka error: invalid ca certificate", err.Error())
 	})
 }
+
+func TestSchemaRegistry(t *testing.T) {
+	k := getKafka()
+
+	t.Run("schema registry settings", func(t *testing.T) {
+		m := getBaseMetadata()
+		m[schemaRegistryURL] = "http://localhost:8081"
+		m[schemaRegistryAPIKey] = "key"
+		m[schemaRegistryAPISecret] = "secret"
+		m[schemaCacheTTL] = "30s"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost:8081", meta.SchemaRegistryURL)
+		require.Equal(t, "key", meta.SchemaRegistryAPIKey)
+		require.Equal(t, "secret", meta.SchemaRegistryAPISecret)
+		require.Equal(t, 30*time.Second, meta.SchemaCacheTTL)
+	})
+
+	t.Run("default cache TTL", func(t *testing.T) {
+		meta, err := k.getKafkaMetadata(getBaseMetadata())
+		require.NoError(t, err)
+		require.Equal(t, "", meta.SchemaRegistryURL)
+		require.Equal(t, 5*time.Minute, meta.SchemaCacheTTL)
+	})
+
+	t.Run("invalid cache TTL", func(t *testing.T) {
+		m := getBaseMetadata()
+		m[schemaCacheTTL] = "soon"
+		meta, err := k.getKafkaMetadata(m)
+		require.Error(t, err)
+		require.Nil(t, meta)
+	})
+}
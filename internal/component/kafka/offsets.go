@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+const (
+	startOffsets   = "startOffsets"
+	startTimestamp = "startTimestamp"
+)
+
+// StartPosition is the position a consumer group reads a topic from.
+// Offsets are keyed by partition and take precedence over Timestamp;
+// partitions with neither keep their committed offset.
+type StartPosition struct {
+	Offsets   map[int32]int64
+	Timestamp time.Time
+}
+
+// GetStartPosition returns the start position requested in the metadata of a subscription,
+// or nil if none is set.
+// startOffsets is a list of partition:offset pairs, e.g. "0:120,1:98", and startTimestamp is
+// an RFC3339 time.
+func GetStartPosition(metadata map[string]string) (*StartPosition, error) {
+	var pos StartPosition
+
+	if val, ok := metadata[startOffsets]; ok && val != "" {
+		pos.Offsets = make(map[int32]int64)
+		for _, pair := range strings.Split(val, ",") {
+			partition, offset, found := strings.Cut(strings.TrimSpace(pair), ":")
+			if !found {
+				return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", startOffsets, pair)
+			}
+			p, err := strconv.ParseInt(partition, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("kafka error: invalid partition in '%s' attribute: %w", startOffsets, err)
+			}
+			o, err := strconv.ParseInt(offset, 10, 64)
+			if err != nil || o < 0 {
+				return nil, fmt.Errorf("kafka error: invalid offset in '%s' attribute: %s", startOffsets, offset)
+			}
+			pos.Offsets[int32(p)] = o
+		}
+	}
+
+	if val, ok := metadata[startTimestamp]; ok && val != "" {
+		ts, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %w", startTimestamp, err)
+		}
+		pos.Timestamp = ts
+	}
+
+	if len(pos.Offsets) == 0 && pos.Timestamp.IsZero() {
+		return nil, nil
+	}
+	return &pos, nil
+}
+
+// resolveOffsets returns the offset to start from for each of the partitions
+// the position applies to, looking up timestamps in the cluster.
+func resolveOffsets(client sarama.Client, topic string, partitions []int32, pos StartPosition) (map[int32]int64, error) {
+	offsets := make(map[int32]int64, len(partitions))
+	for _, partition := range partitions {
+		if offset, ok := pos.Offsets[partition]; ok {
+			offsets[partition] = offset
+			continue
+		}
+		if pos.Timestamp.IsZero() {
+			continue
+		}
+
+		offset, err := client.GetOffset(topic, partition, pos.Timestamp.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: cannot look up offset of %s/%d at %v: %w", topic, partition, pos.Timestamp, err)
+		}
+		// No message was produced after the timestamp, start from the end of the partition.
+		if offset == sarama.OffsetNewest {
+			offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("kafka error: cannot look up newest offset of %s/%d: %w", topic, partition, err)
+			}
+		}
+		offsets[partition] = offset
+	}
+	return offsets, nil
+}
+
+// applyStartPositions moves the session to the start positions requested by the subscriptions.
+// Each start position is applied only once, in the first session that claims its topic,
+// so rebalances do not rewind the consumer again.
+func (k *Kafka) applyStartPositions(session sarama.ConsumerGroupSession) error {
+	k.startPositionsLock.Lock()
+	defer k.startPositionsLock.Unlock()
+
+	var client sarama.Client
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+
+	for topic, partitions := range session.Claims() {
+		pos, ok := k.startPositions[topic]
+		if !ok {
+			continue
+		}
+
+		if client == nil {
+			var err error
+			client, err = sarama.NewClient(k.brokers, k.config)
+			if err != nil {
+				return err
+			}
+		}
+		offsets, err := resolveOffsets(client, topic, partitions, *pos)
+		if err != nil {
+			return err
+		}
+
+		for partition, offset := range offsets {
+			k.logger.Infof("Moving consumer group %s to offset %d of %s/%d", k.consumerGroup, offset, topic, partition)
+			// ResetOffset only moves backwards and MarkOffset only moves forwards.
+			session.ResetOffset(topic, partition, offset, "")
+			session.MarkOffset(topic, partition, offset, "")
+		}
+		delete(k.startPositions, topic)
+	}
+	return nil
+}
+
+// ResetOffsets commits new offsets for the consumer group on a topic, so it
+// replays or skips messages.
+// Kafka only accepts the commit when the group has no active members: this
+// instance leaves the group for the duration of the reset and rejoins it
+// afterwards, but other instances sharing the consumer group must be stopped.
+func (k *Kafka) ResetOffsets(topic string, pos StartPosition) error {
+	if k.consumerGroup == "" {
+		return errors.New("kafka: consumerGroup must be set to reset offsets")
+	}
+
+	k.subscribeLock.Lock()
+	defer k.subscribeLock.Unlock()
+
+	k.closeSubscriptionResources()
+
+	err := k.commitOffsets(topic, pos)
+
+	// Rejoin the consumer group even if the reset failed.
+	if k.subscribeCtx != nil && k.subscribeCtx.Err() == nil {
+		if subErr := k.subscribe(k.subscribeCtx); subErr != nil {
+			k.logger.Errorf("Error re-subscribing after resetting offsets: %v", subErr)
+		}
+	}
+
+	return err
+}
+
+func (k *Kafka) commitOffsets(topic string, pos StartPosition) error {
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("kafka error: cannot list partitions of topic %s: %w", topic, err)
+	}
+	offsets, err := resolveOffsets(client, topic, partitions, pos)
+	if err != nil {
+		return err
+	}
+	if len(offsets) == 0 {
+		return errors.New("kafka error: no offsets to reset")
+	}
+
+	coordinator, err := client.Coordinator(k.consumerGroup)
+	if err != nil {
+		return fmt.Errorf("kafka error: cannot find coordinator of consumer group %s: %w", k.consumerGroup, err)
+	}
+
+	req := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           k.consumerGroup,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+		RetentionTime:           -1,
+	}
+	for partition, offset := range offsets {
+		req.AddBlock(topic, partition, offset, -1, 0, "")
+	}
+
+	res, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return fmt.Errorf("kafka error: cannot commit offsets of consumer group %s: %w", k.consumerGroup, err)
+	}
+	for _, partitionErrs := range res.Errors {
+		for partition, kerr := range partitionErrs {
+			if kerr != sarama.ErrNoError {
+				return fmt.Errorf("kafka error: cannot commit offset of %s/%d for consumer group %s, make sure the group has no active members: %w", topic, partition, k.consumerGroup, kerr)
+			}
+		}
+	}
+
+	k.logger.Infof("Reset offsets of consumer group %s on topic %s: %v", k.consumerGroup, topic, offsets)
+	return nil
+}
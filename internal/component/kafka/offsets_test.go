@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStartPosition(t *testing.T) {
+	t.Run("no start position", func(t *testing.T) {
+		pos, err := GetStartPosition(map[string]string{})
+		require.NoError(t, err)
+		require.Nil(t, pos)
+	})
+
+	t.Run("offsets per partition", func(t *testing.T) {
+		pos, err := GetStartPosition(map[string]string{startOffsets: "0:120, 1:98"})
+		require.NoError(t, err)
+		require.Equal(t, map[int32]int64{0: 120, 1: 98}, pos.Offsets)
+		require.True(t, pos.Timestamp.IsZero())
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		pos, err := GetStartPosition(map[string]string{startTimestamp: "2023-01-31T10:00:00Z"})
+		require.NoError(t, err)
+		require.Nil(t, pos.Offsets)
+		require.Equal(t, time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC), pos.Timestamp)
+	})
+
+	t.Run("invalid offsets", func(t *testing.T) {
+		for _, val := range []string{"120", "a:120", "0:b", "0:-1"} {
+			pos, err := GetStartPosition(map[string]string{startOffsets: val})
+			require.Error(t, err, val)
+			require.Nil(t, pos)
+		}
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		pos, err := GetStartPosition(map[string]string{startTimestamp: "yesterday"})
+		require.Error(t, err)
+		require.Nil(t, pos)
+	})
+}
+
+func TestAddTopicHandlerStartPosition(t *testing.T) {
+	k := NewKafka(getKafka().logger)
+	pos := &StartPosition{Offsets: map[int32]int64{0: 10}}
+
+	k.AddTopicHandler("a", SubscriptionHandlerConfig{StartPosition: pos})
+	require.Equal(t, pos, k.startPositions["a"])
+
+	k.AddTopicHandler("a", SubscriptionHandlerConfig{})
+	require.NotContains(t, k.startPositions, "a")
+}
+
+func TestResetOffsets(t *testing.T) {
+	ts := time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	newKafka := func(t *testing.T, commit *sarama.MockOffsetCommitResponse) (*Kafka, *sarama.MockBroker) {
+		broker := sarama.NewMockBroker(t, 1)
+		t.Cleanup(broker.Close)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(t).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("orders", 0, broker.BrokerID()).
+				SetLeader("orders", 1, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(t).
+				SetOffset("orders", 1, ts.UnixMilli(), 57),
+			"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+				SetCoordinator(sarama.CoordinatorGroup, "group", broker),
+			"OffsetCommitRequest": commit,
+		})
+
+		k := NewKafka(getKafka().logger)
+		k.brokers = []string{broker.Addr()}
+		k.consumerGroup = "group"
+		k.config = sarama.NewConfig()
+		k.config.Version = sarama.V1_0_0_0
+		return k, broker
+	}
+
+	t.Run("offsets take precedence over timestamp", func(t *testing.T) {
+		k, broker := newKafka(t, sarama.NewMockOffsetCommitResponse(t))
+		err := k.ResetOffsets("orders", StartPosition{Offsets: map[int32]int64{0: 120}, Timestamp: ts})
+		require.NoError(t, err)
+
+		var commit *sarama.OffsetCommitRequest
+		for _, rr := range broker.History() {
+			if req, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+				commit = req
+			}
+		}
+		require.NotNil(t, commit)
+		require.Equal(t, "group", commit.ConsumerGroup)
+		offset, _, err := commit.Offset("orders", 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(120), offset)
+		offset, _, err = commit.Offset("orders", 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(57), offset)
+	})
+
+	t.Run("group has active members", func(t *testing.T) {
+		k, _ := newKafka(t, sarama.NewMockOffsetCommitResponse(t).
+			SetError("group", "orders", 0, sarama.ErrUnknownMemberId))
+		err := k.ResetOffsets("orders", StartPosition{Offsets: map[int32]int64{0: 120}})
+		require.ErrorIs(t, err, sarama.ErrUnknownMemberId)
+	})
+
+	t.Run("no offsets to reset", func(t *testing.T) {
+		k, _ := newKafka(t, sarama.NewMockOffsetCommitResponse(t))
+		err := k.ResetOffsets("orders", StartPosition{Offsets: map[int32]int64{5: 120}})
+		require.Error(t, err)
+	})
+
+	t.Run("consumer group required", func(t *testing.T) {
+		k, _ := newKafka(t, sarama.NewMockOffsetCommitResponse(t))
+		k.consumerGroup = ""
+		err := k.ResetOffsets("orders", StartPosition{Offsets: map[int32]int64{0: 120}})
+		require.Error(t, err)
+	})
+}
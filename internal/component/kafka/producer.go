@@ -41,22 +41,36 @@ func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int
 }
 
 // Publish message to Kafka cluster.
-func (k *Kafka) Publish(_ context.Context, topic string, data []byte, metadata map[string]string) error {
+func (k *Kafka) Publish(ctx context.Context, topic string, data []byte, metadata map[string]string) error {
 	if k.producer == nil {
 		return errors.New("component is closed")
 	}
 	// k.logger.Debugf("Publishing topic %v with data: %v", topic, string(data))
 	k.logger.Debugf("Publishing on topic %v", topic)
 
+	schemaType, err := GetValueSchemaType(metadata)
+	if err != nil {
+		return err
+	}
+	data, ceHeaders, err := encodeCloudEvent(data, metadata)
+	if err != nil {
+		return err
+	}
+	data, err = k.serializeValue(ctx, topic, data, schemaType)
+	if err != nil {
+		return err
+	}
+
 	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.ByteEncoder(data),
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(data),
+		Headers: ceHeaders,
 	}
 
 	for name, value := range metadata {
 		if name == key {
 			msg.Key = sarama.StringEncoder(value)
-		} else {
+		} else if !isReservedMetadata(name) {
 			if msg.Headers == nil {
 				msg.Headers = make([]sarama.RecordHeader, 0, len(metadata))
 			}
@@ -78,18 +92,32 @@ func (k *Kafka) Publish(_ context.Context, topic string, data []byte, metadata m
 	return nil
 }
 
-func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.BulkMessageEntry, metadata map[string]string) (pubsub.BulkPublishResponse, error) {
+func (k *Kafka) BulkPublish(ctx context.Context, topic string, entries []pubsub.BulkMessageEntry, metadata map[string]string) (pubsub.BulkPublishResponse, error) {
 	if k.producer == nil {
 		err := errors.New("component is closed")
 		return pubsub.NewBulkPublishResponse(entries, err), err
 	}
 	k.logger.Debugf("Bulk Publishing on topic %v", topic)
 
+	schemaType, err := GetValueSchemaType(metadata)
+	if err != nil {
+		return pubsub.NewBulkPublishResponse(entries, err), err
+	}
+
 	msgs := []*sarama.ProducerMessage{}
 	for _, entry := range entries {
+		value, ceHeaders, err := encodeCloudEvent(entry.Event, metadata)
+		if err != nil {
+			return pubsub.NewBulkPublishResponse(entries, err), err
+		}
+		value, err = k.serializeValue(ctx, topic, value, schemaType)
+		if err != nil {
+			return pubsub.NewBulkPublishResponse(entries, err), err
+		}
 		msg := &sarama.ProducerMessage{
-			Topic: topic,
-			Value: sarama.ByteEncoder(entry.Event),
+			Topic:   topic,
+			Value:   sarama.ByteEncoder(value),
+			Headers: ceHeaders,
 		}
 		// From Sarama documentation
 		// This field is used to hold arbitrary data you wish to include so it
@@ -104,7 +132,7 @@ func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.Bu
 		for name, value := range metadata {
 			if name == key {
 				msg.Key = sarama.StringEncoder(value)
-			} else {
+			} else if !isReservedMetadata(name) {
 				if msg.Headers == nil {
 					msg.Headers = make([]sarama.RecordHeader, 0, len(metadata))
 				}
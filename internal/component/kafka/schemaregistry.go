@@ -0,0 +1,348 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// SchemaType is the serialization format of a message value.
+type SchemaType int
+
+const (
+	// None means the value is published and consumed as raw bytes.
+	None SchemaType = iota
+	// Avro means the value is serialized with an Avro schema from the schema registry.
+	Avro
+)
+
+const (
+	valueSchemaType = "valueSchemaType"
+
+	// Confluent wire format: a magic byte followed by a 4-byte big-endian schema ID.
+	schemaRegistryMagicByte  = 0x0
+	schemaRegistryHeaderSize = 5
+
+	schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+)
+
+// parseSchemaType parses the value of the valueSchemaType metadata property.
+func parseSchemaType(value string) (SchemaType, error) {
+	switch strings.ToLower(value) {
+	case "", "none":
+		return None, nil
+	case "avro":
+		return Avro, nil
+	default:
+		return None, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", valueSchemaType, value)
+	}
+}
+
+// String returns the value of the valueSchemaType metadata property for the schema type.
+func (t SchemaType) String() string {
+	if t == Avro {
+		return "Avro"
+	}
+	return "None"
+}
+
+// GetValueSchemaType returns the schema type requested in the metadata of a
+// publish or subscribe request.
+func GetValueSchemaType(metadata map[string]string) (SchemaType, error) {
+	return parseSchemaType(metadata[valueSchemaType])
+}
+
+// WithValueSchemaType returns the metadata of a publish request with the valueSchemaType set to the
+// given default, unless the request sets it. The metadata of the request is not modified.
+func WithValueSchemaType(metadata map[string]string, schemaType SchemaType) map[string]string {
+	if _, ok := metadata[valueSchemaType]; ok || schemaType == None {
+		return metadata
+	}
+	res := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		res[k] = v
+	}
+	res[valueSchemaType] = schemaType.String()
+	return res
+}
+
+type registeredSchema struct {
+	id      int
+	schema  avro.Schema
+	expires time.Time
+}
+
+// schemaRegistryClient fetches and caches schemas from a Confluent-compatible schema registry.
+type schemaRegistryClient struct {
+	url       string
+	apiKey    string
+	apiSecret string
+	cacheTTL  time.Duration
+	client    *http.Client
+
+	lock      sync.RWMutex
+	byID      map[int]avro.Schema
+	bySubject map[string]registeredSchema
+}
+
+func newSchemaRegistryClient(registryURL, apiKey, apiSecret string, cacheTTL time.Duration) *schemaRegistryClient {
+	return &schemaRegistryClient{
+		url:       strings.TrimSuffix(registryURL, "/"),
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		cacheTTL:  cacheTTL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		byID:      make(map[int]avro.Schema),
+		bySubject: make(map[string]registeredSchema),
+	}
+}
+
+type schemaRegistryResponse struct {
+	ID     int    `json:"id"`
+	Schema string `json:"schema"`
+}
+
+func (c *schemaRegistryClient) get(ctx context.Context, path string) (*schemaRegistryResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if c.apiKey != "" {
+		req.SetBasicAuth(c.apiKey, c.apiSecret)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kafka error: schema registry request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kafka error: schema registry returned status %d for %s", res.StatusCode, path)
+	}
+
+	var body schemaRegistryResponse
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("kafka error: invalid schema registry response: %w", err)
+	}
+
+	return &body, nil
+}
+
+// latestSchema returns the latest schema registered for the subject, using the cache while it is fresh.
+func (c *schemaRegistryClient) latestSchema(ctx context.Context, subject string) (int, avro.Schema, error) {
+	c.lock.RLock()
+	cached, ok := c.bySubject[subject]
+	c.lock.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.id, cached.schema, nil
+	}
+
+	res, err := c.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions/latest")
+	if err != nil {
+		return 0, nil, err
+	}
+	schema, err := avro.Parse(res.Schema)
+	if err != nil {
+		return 0, nil, fmt.Errorf("kafka error: cannot parse schema for subject %s: %w", subject, err)
+	}
+
+	c.lock.Lock()
+	c.byID[res.ID] = schema
+	c.bySubject[subject] = registeredSchema{
+		id:      res.ID,
+		schema:  schema,
+		expires: time.Now().Add(c.cacheTTL),
+	}
+	c.lock.Unlock()
+
+	return res.ID, schema, nil
+}
+
+// schemaByID returns the schema with the given ID.
+// Schemas are immutable once registered, so they are cached forever.
+func (c *schemaRegistryClient) schemaByID(ctx context.Context, id int) (avro.Schema, error) {
+	c.lock.RLock()
+	schema, ok := c.byID[id]
+	c.lock.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	res, err := c.get(ctx, fmt.Sprintf("/schemas/ids/%d", id))
+	if err != nil {
+		return nil, err
+	}
+	schema, err = avro.Parse(res.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("kafka error: cannot parse schema with id %d: %w", id, err)
+	}
+
+	c.lock.Lock()
+	c.byID[id] = schema
+	c.lock.Unlock()
+
+	return schema, nil
+}
+
+// subjectForTopic returns the registry subject of the values of a topic, following the TopicNameStrategy.
+func subjectForTopic(topic string) string {
+	return topic + "-value"
+}
+
+// serializeValue converts a JSON value to Avro in the schema registry wire format.
+func (k *Kafka) serializeValue(ctx context.Context, topic string, data []byte, schemaType SchemaType) ([]byte, error) {
+	if schemaType == None {
+		return data, nil
+	}
+	if k.schemaRegistry == nil {
+		return nil, errors.New("kafka error: schemaRegistryURL must be set to publish with a value schema")
+	}
+
+	id, schema, err := k.schemaRegistry.latestSchema(ctx, subjectForTopic(topic))
+	if err != nil {
+		return nil, err
+	}
+
+	var native any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Numbers are kept as json.Number, so that longs above 2^53 don't lose precision
+	dec.UseNumber()
+	if err = dec.Decode(&native); err != nil {
+		return nil, fmt.Errorf("kafka error: value is not valid JSON: %w", err)
+	}
+	payload, err := avro.Marshal(schema, coerceJSONToAvro(schema, native))
+	if err != nil {
+		return nil, fmt.Errorf("kafka error: cannot serialize value with schema %d: %w", id, err)
+	}
+
+	res := make([]byte, schemaRegistryHeaderSize, schemaRegistryHeaderSize+len(payload))
+	res[0] = schemaRegistryMagicByte
+	binary.BigEndian.PutUint32(res[1:schemaRegistryHeaderSize], uint32(id))
+	return append(res, payload...), nil
+}
+
+// errUndecodableValue is wrapped by the errors of the values that can't be deserialized, which retrying doesn't fix.
+var errUndecodableValue = errors.New("kafka error: cannot deserialize value")
+
+// deserializeValue converts a value in the schema registry wire format back to JSON.
+func (k *Kafka) deserializeValue(ctx context.Context, data []byte, schemaType SchemaType) ([]byte, error) {
+	if schemaType == None {
+		return data, nil
+	}
+	if k.schemaRegistry == nil {
+		return nil, errors.New("kafka error: schemaRegistryURL must be set to subscribe with a value schema")
+	}
+	if len(data) < schemaRegistryHeaderSize || data[0] != schemaRegistryMagicByte {
+		return nil, fmt.Errorf("%w: not in the schema registry wire format", errUndecodableValue)
+	}
+
+	id := int(binary.BigEndian.Uint32(data[1:schemaRegistryHeaderSize]))
+	schema, err := k.schemaRegistry.schemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var native any
+	if err = avro.Unmarshal(schema, data[schemaRegistryHeaderSize:], &native); err != nil {
+		return nil, fmt.Errorf("%w with schema %d: %v", errUndecodableValue, id, err)
+	}
+	return json.Marshal(native)
+}
+
+// coerceJSONToAvro converts the generic types produced by encoding/json into
+// the Go types expected by the Avro encoder for the given schema.
+// JSON numbers are decoded as json.Number, which the encoder rejects for numeric fields.
+func coerceJSONToAvro(schema avro.Schema, value any) any {
+	switch s := schema.(type) {
+	case *avro.RecordSchema:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		for _, field := range s.Fields() {
+			if v, ok := obj[field.Name()]; ok {
+				obj[field.Name()] = coerceJSONToAvro(field.Type(), v)
+			}
+		}
+		return obj
+	case *avro.ArraySchema:
+		arr, ok := value.([]any)
+		if !ok {
+			return value
+		}
+		for i, v := range arr {
+			arr[i] = coerceJSONToAvro(s.Items(), v)
+		}
+		return arr
+	case *avro.MapSchema:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return value
+		}
+		for k, v := range obj {
+			obj[k] = coerceJSONToAvro(s.Values(), v)
+		}
+		return obj
+	case *avro.UnionSchema:
+		if value == nil {
+			return nil
+		}
+		// Nullable unions accept the bare value of the non-null type.
+		if s.Nullable() {
+			_, typ := s.Indices()
+			return coerceJSONToAvro(s.Types()[typ], value)
+		}
+	case *avro.PrimitiveSchema:
+		n, isNumber := value.(json.Number)
+		switch s.Type() {
+		case avro.Int:
+			if i, err := n.Int64(); isNumber && err == nil {
+				return int(i)
+			}
+		case avro.Long:
+			if i, err := n.Int64(); isNumber && err == nil {
+				return i
+			}
+		case avro.Float:
+			if f, err := n.Float64(); isNumber && err == nil {
+				return float32(f)
+			}
+		case avro.Bytes:
+			if str, ok := value.(string); ok {
+				return []byte(str)
+			}
+		}
+	}
+	// Doubles, and numbers the encoder will reject for the field, are passed as float64
+	if n, ok := value.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
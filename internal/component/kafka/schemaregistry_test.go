@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAvroSchema = `{"type":"record","name":"order","fields":[` +
+	`{"name":"id","type":"long"},` +
+	`{"name":"item","type":"string"},` +
+	`{"name":"quantity","type":"int"},` +
+	`{"name":"note","type":["null","string"],"default":null}]}`
+
+type registryStandIn struct {
+	server   *httptest.Server
+	requests atomic.Int32
+}
+
+func newRegistryStandIn(t *testing.T) *registryStandIn {
+	r := &registryStandIn{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subjects/orders-value/versions/latest", func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"subject": "orders-value", "version": 1, "id": 42, "schema": testAvroSchema})
+	})
+	mux.HandleFunc("/schemas/ids/42", func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"schema": testAvroSchema})
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func TestParseSchemaType(t *testing.T) {
+	for value, expected := range map[string]SchemaType{"": None, "None": None, "avro": Avro, "Avro": Avro} {
+		schemaType, err := parseSchemaType(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, schemaType)
+	}
+
+	_, err := parseSchemaType("xml")
+	require.Error(t, err)
+}
+
+func TestWithValueSchemaType(t *testing.T) {
+	t.Run("default is added", func(t *testing.T) {
+		metadata := map[string]string{"key": "k"}
+		res := WithValueSchemaType(metadata, Avro)
+		assert.Equal(t, map[string]string{"key": "k", valueSchemaType: "Avro"}, res)
+		assert.Len(t, metadata, 1)
+
+		schemaType, err := GetValueSchemaType(res)
+		require.NoError(t, err)
+		assert.Equal(t, Avro, schemaType)
+	})
+
+	t.Run("request overrides the default", func(t *testing.T) {
+		res := WithValueSchemaType(map[string]string{valueSchemaType: "None"}, Avro)
+		assert.Equal(t, map[string]string{valueSchemaType: "None"}, res)
+	})
+
+	t.Run("no default", func(t *testing.T) {
+		assert.Nil(t, WithValueSchemaType(nil, None))
+	})
+}
+
+func TestSchemaRegistrySerialization(t *testing.T) {
+	registry := newRegistryStandIn(t)
+	k := getKafka()
+	k.schemaRegistry = newSchemaRegistryClient(registry.server.URL+"/", "key", "secret", time.Minute)
+
+	t.Run("round trip in wire format", func(t *testing.T) {
+		value, err := k.serializeValue(context.Background(), "orders", []byte(`{"id":7,"item":"book","quantity":2}`), Avro)
+		require.NoError(t, err)
+		require.Greater(t, len(value), schemaRegistryHeaderSize)
+		assert.Equal(t, []byte{0, 0, 0, 0, 42}, value[:schemaRegistryHeaderSize])
+
+		data, err := k.deserializeValue(context.Background(), value, Avro)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":7,"item":"book","quantity":2,"note":null}`, string(data))
+	})
+
+	t.Run("schemas are cached", func(t *testing.T) {
+		before := registry.requests.Load()
+		_, err := k.serializeValue(context.Background(), "orders", []byte(`{"id":8,"item":"pen","quantity":1}`), Avro)
+		require.NoError(t, err)
+		assert.Equal(t, before, registry.requests.Load())
+	})
+
+	t.Run("no schema passes value through", func(t *testing.T) {
+		value, err := k.serializeValue(context.Background(), "orders", []byte("raw"), None)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("raw"), value)
+	})
+
+	t.Run("value not matching schema", func(t *testing.T) {
+		_, err := k.serializeValue(context.Background(), "orders", []byte(`{"id":"x"}`), Avro)
+		require.Error(t, err)
+	})
+
+	t.Run("value not in wire format", func(t *testing.T) {
+		_, err := k.deserializeValue(context.Background(), []byte(`{"id":7}`), Avro)
+		require.Error(t, err)
+	})
+
+	t.Run("unknown subject", func(t *testing.T) {
+		_, err := k.serializeValue(context.Background(), "invoices", []byte(`{}`), Avro)
+		require.Error(t, err)
+	})
+
+	t.Run("registry not configured", func(t *testing.T) {
+		_, err := getKafka().serializeValue(context.Background(), "orders", []byte(`{}`), Avro)
+		require.Error(t, err)
+	})
+}
+
+func TestCoerceJSONToAvro(t *testing.T) {
+	schema := avro.MustParse(`{"type":"record","name":"reading","fields":[` +
+		`{"name":"sequence","type":["null","long"],"default":null},` +
+		`{"name":"count","type":["int","null"],"default":0},` +
+		`{"name":"ratio","type":"float"},` +
+		`{"name":"value","type":"double"}]}`)
+
+	type reading struct {
+		Sequence *int64  `avro:"sequence"`
+		Count    *int    `avro:"count"`
+		Ratio    float32 `avro:"ratio"`
+		Value    float64 `avro:"value"`
+	}
+	sequence, count := int64(9007199254740993), 3
+
+	for name, tc := range map[string]struct {
+		value    string
+		expected reading
+	}{
+		"nullable numbers": {
+			value:    `{"sequence":9007199254740993,"count":3,"ratio":0.5,"value":1.25}`,
+			expected: reading{Sequence: &sequence, Count: &count, Ratio: 0.5, Value: 1.25},
+		},
+		"null values": {
+			value:    `{"sequence":null,"count":null,"ratio":1,"value":2}`,
+			expected: reading{Ratio: 1, Value: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec := json.NewDecoder(strings.NewReader(tc.value))
+			dec.UseNumber()
+			var native any
+			require.NoError(t, dec.Decode(&native))
+
+			payload, err := avro.Marshal(schema, coerceJSONToAvro(schema, native))
+			require.NoError(t, err)
+
+			var decoded reading
+			require.NoError(t, avro.Unmarshal(schema, payload, &decoded))
+			assert.Equal(t, tc.expected, decoded)
+		})
+	}
+}
@@ -46,6 +46,12 @@ type RedisXPendingExt struct {
 	RetryCount int64
 }
 
+type RedisXInfoConsumer struct {
+	Name    string
+	Pending int64
+	Idle    time.Duration
+}
+
 type RedisPipeliner interface {
 	Exec(ctx context.Context) error
 	Do(ctx context.Context, args ...interface{})
@@ -59,6 +65,7 @@ type RedisClient interface {
 	Context() context.Context
 	DoRead(ctx context.Context, args ...interface{}) (interface{}, error)
 	DoWrite(ctx context.Context, args ...interface{}) error
+	DoWriteResult(ctx context.Context, args ...interface{}) (interface{}, error)
 	Del(ctx context.Context, keys ...string) error
 	Get(ctx context.Context, key string) (string, error)
 	Close() error
@@ -71,6 +78,9 @@ type RedisClient interface {
 	XReadGroupResult(ctx context.Context, group string, consumer string, streams []string, count int64, block time.Duration) ([]RedisXStream, error)
 	XPendingExtResult(ctx context.Context, stream string, group string, start string, end string, count int64) ([]RedisXPendingExt, error)
 	XClaimResult(ctx context.Context, stream string, group string, consumer string, minIdleTime time.Duration, messageIDs []string) ([]RedisXMessage, error)
+	XTrimMinIDApprox(ctx context.Context, stream string, minID string) (int64, error)
+	XInfoConsumersResult(ctx context.Context, stream string, group string) ([]RedisXInfoConsumer, error)
+	XGroupDelConsumer(ctx context.Context, stream string, group string, consumer string) (int64, error)
 	TxPipeline() RedisPipeliner
 	TTLResult(ctx context.Context, key string) (time.Duration, error)
 }
@@ -59,6 +59,15 @@ func (c v9Client) DoWrite(ctx context.Context, args ...interface{}) error {
 	return c.client.Do(ctx, args...).Err()
 }
 
+func (c v9Client) DoWriteResult(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if c.writeTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.writeTimeout))
+		defer cancel()
+		return c.client.Do(timeoutCtx, args...).Result()
+	}
+	return c.client.Do(ctx, args...).Result()
+}
+
 func (c v9Client) DoRead(ctx context.Context, args ...interface{}) (interface{}, error) {
 	if c.readTimeout > 0 {
 		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.readTimeout))
@@ -269,6 +278,56 @@ func (c v9Client) XClaimResult(ctx context.Context, stream string, group string,
 	return redisXMessages, nil
 }
 
+func (c v9Client) XTrimMinIDApprox(ctx context.Context, stream string, minID string) (int64, error) {
+	var writeCtx context.Context
+	if c.writeTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.writeTimeout))
+		defer cancel()
+		writeCtx = timeoutCtx
+	} else {
+		writeCtx = ctx
+	}
+	return c.client.XTrimMinIDApprox(writeCtx, stream, minID, 0).Result()
+}
+
+func (c v9Client) XInfoConsumersResult(ctx context.Context, stream string, group string) ([]RedisXInfoConsumer, error) {
+	var readCtx context.Context
+	if c.readTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.readTimeout))
+		defer cancel()
+		readCtx = timeoutCtx
+	} else {
+		readCtx = ctx
+	}
+	res, err := c.client.XInfoConsumers(readCtx, stream, group).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// convert res to []RedisXInfoConsumer
+	consumers := make([]RedisXInfoConsumer, len(res))
+	for i, consumer := range res {
+		consumers[i] = RedisXInfoConsumer{
+			Name:    consumer.Name,
+			Pending: consumer.Pending,
+			Idle:    consumer.Idle,
+		}
+	}
+	return consumers, nil
+}
+
+func (c v9Client) XGroupDelConsumer(ctx context.Context, stream string, group string, consumer string) (int64, error) {
+	var writeCtx context.Context
+	if c.writeTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.writeTimeout))
+		defer cancel()
+		writeCtx = timeoutCtx
+	} else {
+		writeCtx = ctx
+	}
+	return c.client.XGroupDelConsumer(writeCtx, stream, group, consumer).Result()
+}
+
 func (c v9Client) TxPipeline() RedisPipeliner {
 	return v9Pipeliner{
 		pipeliner:    c.client.TxPipeline(),
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sqlbinding contains the request parsing shared by the SQL database bindings.
+package sqlbinding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dapr/components-contrib/internal/utils"
+)
+
+const (
+	// ParamsKey is the request metadata key carrying the positional parameters
+	// of the statement, as a JSON array.
+	ParamsKey = "params"
+	// ParamsFromDataKey is the request metadata key that, when true, makes the request data
+	// carry the positional parameters of the statement, as a JSON array.
+	ParamsFromDataKey = "paramsFromData"
+)
+
+// Statement is a SQL statement with its positional parameters.
+type Statement struct {
+	SQL    string
+	Params []any
+}
+
+// StatementResult is the result of a statement executed in a transaction.
+type StatementResult struct {
+	RowsAffected int64 `json:"rowsAffected"`
+}
+
+// ParseParams returns the positional parameters of a request.
+// They are read from the `params` metadata key, or from the request data
+// when the `paramsFromData` metadata key is true. Otherwise the request data is ignored.
+func ParseParams(metadata map[string]string, data []byte) ([]any, error) {
+	if params, ok := metadata[ParamsKey]; ok && params != "" {
+		return parseParamsArray([]byte(params))
+	}
+
+	if utils.IsTruthy(metadata[ParamsFromDataKey]) {
+		return parseParamsArray(data)
+	}
+
+	return nil, nil
+}
+
+// ParseStatements parses the statements of a transaction, sent in the request data as
+// a JSON array of objects with a `sql` string and an optional `params` array.
+func ParseStatements(data []byte) ([]Statement, error) {
+	var raw []struct {
+		SQL    string          `json:"sql"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid transaction statements: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("transaction requires at least one statement")
+	}
+
+	statements := make([]Statement, len(raw))
+	for i, r := range raw {
+		if r.SQL == "" {
+			return nil, fmt.Errorf("statement %d of transaction has no sql", i)
+		}
+		statements[i].SQL = r.SQL
+		if len(r.Params) > 0 && string(r.Params) != "null" {
+			params, err := parseParamsArray(r.Params)
+			if err != nil {
+				return nil, fmt.Errorf("statement %d of transaction: %w", i, err)
+			}
+			statements[i].Params = params
+		}
+	}
+
+	return statements, nil
+}
+
+func parseParamsArray(data []byte) ([]any, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid %s: must be a JSON array: %w", ParamsKey, err)
+	}
+
+	params := make([]any, len(raw))
+	for i, p := range raw {
+		v, err := decodeParam(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %d: %w", i, err)
+		}
+		params[i] = v
+	}
+
+	return params, nil
+}
+
+// decodeParam converts a JSON value to a value that database drivers accept.
+// Integers become int64, other numbers float64, and objects and arrays are
+// passed as their JSON text, for JSON columns.
+func decodeParam(raw json.RawMessage) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		return val.Float64()
+	case map[string]any, []any:
+		return string(raw), nil
+	default:
+		return val, nil
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlbinding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParams(t *testing.T) {
+	t.Run("from metadata", func(t *testing.T) {
+		params, err := ParseParams(map[string]string{ParamsKey: `[1, 2.5, "a", true, null, {"k":"v"}, [1,2]]`}, []byte(`[3]`))
+		require.NoError(t, err)
+		assert.Equal(t, []any{int64(1), 2.5, "a", true, nil, `{"k":"v"}`, `[1,2]`}, params)
+	})
+
+	t.Run("from data", func(t *testing.T) {
+		params, err := ParseParams(map[string]string{ParamsFromDataKey: "true"}, []byte(` ["x", 3]`))
+		require.NoError(t, err)
+		assert.Equal(t, []any{"x", int64(3)}, params)
+	})
+
+	t.Run("data is ignored without the flag", func(t *testing.T) {
+		params, err := ParseParams(map[string]string{}, []byte(`["x", 3]`))
+		require.NoError(t, err)
+		assert.Nil(t, params)
+	})
+
+	t.Run("data that is not an array", func(t *testing.T) {
+		_, err := ParseParams(map[string]string{ParamsFromDataKey: "true"}, []byte(`{"id":1}`))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		_, err := ParseParams(map[string]string{ParamsKey: `{"id":1}`}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestParseStatements(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		statements, err := ParseStatements([]byte(`[{"sql":"INSERT INTO t VALUES (?)","params":[1]},{"sql":"DELETE FROM t"}]`))
+		require.NoError(t, err)
+		assert.Equal(t, []Statement{
+			{SQL: "INSERT INTO t VALUES (?)", Params: []any{int64(1)}},
+			{SQL: "DELETE FROM t"},
+		}, statements)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, data := range []string{``, `[]`, `[{"params":[1]}]`, `[{"sql":"x","params":{}}]`} {
+			_, err := ParseStatements([]byte(data))
+			require.Error(t, err, data)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type verifiedClaimsKey struct{}
+
+// verifiedClaims holds the claims of the token verified by an authentication middleware. The holder is shared by
+// the copies of the request, so the middlewares running before the authentication can read the claims once the
+// next handler returns.
+type verifiedClaims struct {
+	lock   sync.Mutex
+	claims map[string]any
+}
+
+// WithVerifiedClaimsHolder returns a request whose context can receive the claims of a verified token, so they
+// can be read with VerifiedClaims once the next handler returns.
+func WithVerifiedClaimsHolder(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(verifiedClaimsKey{}).(*verifiedClaims); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), verifiedClaimsKey{}, &verifiedClaims{}))
+}
+
+// SetVerifiedClaims records the claims of the token an authentication middleware verified, and returns the
+// request to pass to the next handler.
+func SetVerifiedClaims(r *http.Request, claims map[string]any) *http.Request {
+	r = WithVerifiedClaimsHolder(r)
+	holder := r.Context().Value(verifiedClaimsKey{}).(*verifiedClaims)
+	holder.lock.Lock()
+	holder.claims = claims
+	holder.lock.Unlock()
+	return r
+}
+
+// VerifiedClaims returns the claims of the token verified by an authentication middleware, if any.
+func VerifiedClaims(r *http.Request) (map[string]any, bool) {
+	holder, ok := r.Context().Value(verifiedClaimsKey{}).(*verifiedClaims)
+	if !ok {
+		return nil, false
+	}
+	holder.lock.Lock()
+	defer holder.lock.Unlock()
+	return holder.claims, holder.claims != nil
+}
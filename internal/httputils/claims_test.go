@@ -0,0 +1,56 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type otherKey struct{}
+
+func TestVerifiedClaims(t *testing.T) {
+	claims := map[string]any{"sub": "alice"}
+
+	tests := map[string]struct {
+		req      func() *http.Request
+		expected map[string]any
+	}{
+		"no claims": {
+			req: func() *http.Request {
+				return WithVerifiedClaimsHolder(httptest.NewRequest(http.MethodGet, "/", nil))
+			},
+		},
+		"claims set on the request": {
+			req: func() *http.Request {
+				return SetVerifiedClaims(httptest.NewRequest(http.MethodGet, "/", nil), claims)
+			},
+			expected: claims,
+		},
+		"claims set on a request derived from the holder": {
+			req: func() *http.Request {
+				r := WithVerifiedClaimsHolder(httptest.NewRequest(http.MethodGet, "/", nil))
+				SetVerifiedClaims(r.WithContext(context.WithValue(r.Context(), otherKey{}, "v")), claims)
+				return r
+			},
+			expected: claims,
+		},
+		"claims set on a request without holder": {
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				SetVerifiedClaims(r, claims)
+				return r
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, ok := VerifiedClaims(test.req())
+			assert.Equal(t, test.expected != nil, ok)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package httputils
+
+import (
+	"path"
+	"strings"
+)
+
+// HasPathPrefix returns true if the request path is the prefix or one of its sub-paths, comparing whole segments
+// of the cleaned paths: "/api" matches "/api" and "/api/items", but not "/apiadmin", and "/public/../admin" doesn't
+// match "/public". An empty prefix matches every path.
+func HasPathPrefix(requestPath string, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	requestPath = path.Clean("/" + requestPath)
+	prefix = path.Clean("/" + prefix)
+	return prefix == "/" || requestPath == prefix || strings.HasPrefix(requestPath, prefix+"/")
+}
@@ -0,0 +1,30 @@
+package httputils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path     string
+		prefix   string
+		expected bool
+	}{
+		{"/api", "/api", true},
+		{"/api/items", "/api", true},
+		{"/api/items", "/api/", true},
+		{"/apiadmin", "/api", false},
+		{"/public/../admin", "/public", false},
+		{"/public/./docs", "/public/docs", true},
+		{"//api//items", "/api/items", true},
+		{"/anything", "/", true},
+		{"/anything", "", true},
+		{"/", "/api", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, HasPathPrefix(tt.path, tt.prefix), "path %q, prefix %q", tt.path, tt.prefix)
+	}
+}
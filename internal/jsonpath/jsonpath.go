@@ -0,0 +1,411 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonpath implements the subset of JSONPath used by the middlewares to select the fields of
+// decoded JSON documents.
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Segment is an element of a path: a field name, an array index or the wildcard, optionally preceded by
+// the ".." recursive descent.
+type Segment struct {
+	Field     string
+	Index     int
+	Wildcard  bool
+	Recursive bool
+}
+
+// IsIndex returns true if the segment is an array index.
+func (s Segment) IsIndex() bool {
+	return s.Field == "" && !s.Wildcard
+}
+
+// Path is a parsed JSONPath expression. The empty path is the whole document.
+type Path []Segment
+
+// IsRecursive returns true if the path has a recursive descent.
+func (p Path) IsRecursive() bool {
+	for _, s := range p {
+		if s.Recursive {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a JSONPath expression such as "$.items[0].name", "$.cards[*].number", "$['api-key']",
+// "$.headers.*" or "$..token". The leading "$" is optional; "$" alone is the whole document.
+// A wildcard selects every element of an array or every value of an object.
+func Parse(expr string) (Path, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+	p := Path{}
+	recursive := false
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			if recursive {
+				return nil, fmt.Errorf("invalid path %q", expr)
+			}
+			recursive = true
+			rest = rest[2:]
+			if rest == "" || rest[0] == '.' {
+				return nil, fmt.Errorf("invalid path %q: empty field name", expr)
+			}
+			if rest[0] != '[' {
+				rest = "." + rest
+			}
+			continue
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			switch name := rest[:end]; name {
+			case "":
+				return nil, fmt.Errorf("invalid path %q: empty field name", expr)
+			case "*":
+				p = append(p, Segment{Wildcard: true, Recursive: recursive})
+			default:
+				p = append(p, Segment{Field: name, Recursive: recursive})
+			}
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				p = append(p, Segment{Wildcard: true, Recursive: recursive})
+			case len(inner) > 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				p = append(p, Segment{Field: inner[1 : len(inner)-1], Recursive: recursive})
+			default:
+				idx, err := strconv.Atoi(inner)
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("invalid path %q: invalid index %q", expr, inner)
+				}
+				p = append(p, Segment{Index: idx, Recursive: recursive})
+			}
+		default:
+			if len(p) > 0 || recursive {
+				return nil, fmt.Errorf("invalid path %q", expr)
+			}
+			// The first field name doesn't need a leading "."
+			rest = "." + rest
+			continue
+		}
+		recursive = false
+	}
+	return p, nil
+}
+
+// Lookup returns the value at the path. A wildcard collects the values of every element of the array, or
+// of every value of the object in the order of the keys. Recursive descents are not supported.
+func Lookup(node any, p Path) (any, bool) {
+	if len(p) == 0 {
+		return node, true
+	}
+	s := p[0]
+	switch {
+	case s.Recursive:
+		return nil, false
+	case s.Wildcard:
+		var items []any
+		switch v := node.(type) {
+		case []any:
+			items = v
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				items = append(items, v[k])
+			}
+		default:
+			return nil, false
+		}
+		res := make([]any, 0, len(items))
+		for _, item := range items {
+			if v, ok := Lookup(item, p[1:]); ok {
+				res = append(res, v)
+			}
+		}
+		return res, true
+	case s.IsIndex():
+		arr, ok := node.([]any)
+		if !ok || s.Index >= len(arr) {
+			return nil, false
+		}
+		return Lookup(arr[s.Index], p[1:])
+	default:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		child, ok := obj[s.Field]
+		if !ok {
+			return nil, false
+		}
+		return Lookup(child, p[1:])
+	}
+}
+
+// Set sets the value at the path, creating the missing objects, and returns the updated node. A wildcard
+// sets a copy of the value in every element. Recursive descents are not supported.
+// The node is left unchanged when an error is returned, except for the elements already updated by a wildcard.
+func Set(node any, p Path, value any) (any, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	s := p[0]
+	switch {
+	case s.Recursive:
+		return nil, errors.New("a recursive descent can't be set")
+	case s.Wildcard:
+		switch v := node.(type) {
+		case []any:
+			for i := range v {
+				child, err := Set(v[i], p[1:], Clone(value))
+				if err != nil {
+					return nil, err
+				}
+				v[i] = child
+			}
+			return v, nil
+		case map[string]any:
+			for k := range v {
+				child, err := Set(v[k], p[1:], Clone(value))
+				if err != nil {
+					return nil, err
+				}
+				v[k] = child
+			}
+			return v, nil
+		default:
+			return nil, errors.New("not an array or an object")
+		}
+	case s.IsIndex():
+		arr, ok := node.([]any)
+		if !ok || s.Index >= len(arr) {
+			return nil, fmt.Errorf("index %d out of range", s.Index)
+		}
+		child, err := Set(arr[s.Index], p[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		arr[s.Index] = child
+		return arr, nil
+	default:
+		obj, ok := node.(map[string]any)
+		if node == nil {
+			obj = map[string]any{}
+		} else if !ok {
+			return nil, fmt.Errorf("cannot set field %q of a non-object", s.Field)
+		}
+		child, err := Set(obj[s.Field], p[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		obj[s.Field] = child
+		return obj, nil
+	}
+}
+
+// Update replaces every node matched by the path with the result of fn, and returns the updated node.
+// Nothing is done for the paths that don't exist. The values of an object are visited in the order of the
+// keys, and with a recursive descent the descendants of a node are updated before the node itself.
+func Update(node any, p Path, fn func(node any) (any, error)) (any, error) {
+	if len(p) == 0 {
+		return fn(node)
+	}
+	s := p[0]
+	if s.Recursive {
+		var err error
+		switch v := node.(type) {
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				v[k], err = Update(v[k], p, fn)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case []any:
+			for i := range v {
+				v[i], err = Update(v[i], p, fn)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		s.Recursive = false
+		return Update(node, append(Path{s}, p[1:]...), fn)
+	}
+
+	switch {
+	case s.Wildcard:
+		switch v := node.(type) {
+		case []any:
+			for i := range v {
+				child, err := Update(v[i], p[1:], fn)
+				if err != nil {
+					return nil, err
+				}
+				v[i] = child
+			}
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				child, err := Update(v[k], p[1:], fn)
+				if err != nil {
+					return nil, err
+				}
+				v[k] = child
+			}
+		}
+		return node, nil
+	case s.IsIndex():
+		arr, ok := node.([]any)
+		if !ok || s.Index >= len(arr) {
+			return node, nil
+		}
+		child, err := Update(arr[s.Index], p[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		arr[s.Index] = child
+		return arr, nil
+	default:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return node, nil
+		}
+		child, ok := obj[s.Field]
+		if !ok {
+			return node, nil
+		}
+		child, err := Update(child, p[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		obj[s.Field] = child
+		return obj, nil
+	}
+}
+
+// Delete removes the fields and the array elements at the path, if present.
+func Delete(node any, p Path) {
+	if len(p) == 0 {
+		return
+	}
+	s := p[0]
+	if s.Recursive {
+		switch v := node.(type) {
+		case map[string]any:
+			for _, child := range v {
+				Delete(child, p)
+			}
+		case []any:
+			for _, child := range v {
+				Delete(child, p)
+			}
+		}
+		s.Recursive = false
+		Delete(node, append(Path{s}, p[1:]...))
+		return
+	}
+
+	switch v := node.(type) {
+	case map[string]any:
+		switch {
+		case s.Wildcard && len(p) == 1:
+			for k := range v {
+				delete(v, k)
+			}
+		case s.Wildcard:
+			for _, child := range v {
+				Delete(child, p[1:])
+			}
+		case s.IsIndex():
+		case len(p) == 1:
+			delete(v, s.Field)
+		default:
+			Delete(v[s.Field], p[1:])
+		}
+	case []any:
+		// Array elements are only deleted from the parent, so that the indexes of the others don't change
+		switch {
+		case len(p) == 1:
+		case s.Wildcard:
+			for _, child := range v {
+				Delete(child, p[1:])
+			}
+		case s.IsIndex() && s.Index < len(v):
+			Delete(v[s.Index], p[1:])
+		}
+	}
+}
+
+// SplitScope splits two paths in their common prefix ending with the last wildcard they share, and the
+// paths relative to it. An error is returned if either relative path has a wildcard, because there's no
+// element to relate it to.
+func SplitScope(a, b Path) (scope, relA, relB Path, err error) {
+	n := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i].Wildcard {
+			n = i + 1
+		}
+	}
+	scope, relA, relB = a[:n], a[n:], b[n:]
+	for _, s := range append(append(Path{}, relA...), relB...) {
+		if s.Wildcard {
+			return nil, nil, nil, errors.New("wildcards must apply to the same array in both paths")
+		}
+	}
+	return scope, relA, relB, nil
+}
+
+// Clone returns a deep copy of a decoded JSON value, so a value copied to another path isn't changed by
+// later operations on the original.
+func Clone(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(v))
+		for k, item := range v {
+			res[k] = Clone(item)
+		}
+		return res
+	case []any:
+		res := make([]any, len(v))
+		for i, item := range v {
+			res[i] = Clone(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
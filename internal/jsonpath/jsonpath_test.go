@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for expr, expected := range map[string]Path{
+		"$":                             {},
+		"":                              {},
+		"$.user.password":               {{Field: "user"}, {Field: "password"}},
+		"user.password":                 {{Field: "user"}, {Field: "password"}},
+		"$.cards[*].number":             {{Field: "cards"}, {Wildcard: true}, {Field: "number"}},
+		"$.items[2]['odd.key'][*].name": {{Field: "items"}, {Index: 2}, {Field: "odd.key"}, {Wildcard: true}, {Field: "name"}},
+		"$['api-key']":                  {{Field: "api-key"}},
+		"$..token":                      {{Field: "token", Recursive: true}},
+		"$.a..b[0]":                     {{Field: "a"}, {Field: "b", Recursive: true}, {Index: 0}},
+		"$..[*]":                        {{Wildcard: true, Recursive: true}},
+		"$.headers.*":                   {{Field: "headers"}, {Wildcard: true}},
+	} {
+		p, err := Parse(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, expected, p, expr)
+	}
+
+	for _, expr := range []string{"$.", "$.a.", "$..", "$...a", "$.a[", "$.a[-1]", "$.a[x]", "$['']", "a[0]b"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestLookupSetDelete(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{"a":{"b":[{"c":1,"d":2},{"c":3}]}}`), &doc))
+	path := func(expr string) Path {
+		p, err := Parse(expr)
+		require.NoError(t, err)
+		return p
+	}
+
+	v, ok := Lookup(doc, path("a.b[1].c"))
+	assert.True(t, ok)
+	assert.Equal(t, float64(3), v)
+	v, ok = Lookup(doc, path("a.b[*].c"))
+	assert.True(t, ok)
+	assert.Equal(t, []any{float64(1), float64(3)}, v)
+	v, ok = Lookup(doc, path("a.b[0].*"))
+	assert.True(t, ok)
+	assert.Equal(t, []any{float64(1), float64(2)}, v)
+	_, ok = Lookup(doc, path("a.b[5]"))
+	assert.False(t, ok)
+	_, ok = Lookup(doc, path("$..c"))
+	assert.False(t, ok)
+
+	doc, err := Set(doc, path("a.b[*].e"), map[string]any{"x": true})
+	require.NoError(t, err)
+	doc, err = Set(doc, path("new.nested"), "v")
+	require.NoError(t, err)
+	_, err = Set(doc, path("new.nested.deeper"), "v")
+	assert.Error(t, err)
+	_, err = Set(doc, path("a.b[9]"), "v")
+	assert.Error(t, err)
+	_, err = Set(doc, path("$..c"), "v")
+	assert.Error(t, err)
+
+	Delete(doc, path("a.b[*].d"))
+	Delete(doc, path("missing.field"))
+	Delete(doc, path("$..x"))
+
+	b, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":{"b":[{"c":1,"e":{}},{"c":3,"e":{}}]},"new":{"nested":"v"}}`, string(b))
+}
+
+func TestUpdate(t *testing.T) {
+	doc := func() any {
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"user": {"name": "alice", "password": "hunter2", "session": {"token": "a"}},
+			"cards": [{"number": "4111", "exp": "01/30"}, {"number": "5500"}],
+			"tokens": ["b", "c"],
+			"token": "d"
+		}`), &v))
+		return v
+	}
+	redacted := func(exprs ...string) any {
+		v := doc()
+		for _, expr := range exprs {
+			p, err := Parse(expr)
+			require.NoError(t, err, expr)
+			v, err = Update(v, p, func(any) (any, error) { return "x", nil })
+			require.NoError(t, err, expr)
+		}
+		return v
+	}
+
+	v := redacted("$.user.password", "$.cards[*].number", "$.tokens[1]", "$.missing.field", "$.cards[5].exp")
+	assert.Equal(t, map[string]any{
+		"user":   map[string]any{"name": "alice", "password": "x", "session": map[string]any{"token": "a"}},
+		"cards":  []any{map[string]any{"number": "x", "exp": "01/30"}, map[string]any{"number": "x"}},
+		"tokens": []any{"b", "x"},
+		"token":  "d",
+	}, v)
+
+	v = redacted("$..token", "$.user.*")
+	assert.Equal(t, map[string]any{
+		"user":   map[string]any{"name": "x", "password": "x", "session": "x"},
+		"cards":  []any{map[string]any{"number": "4111", "exp": "01/30"}, map[string]any{"number": "5500"}},
+		"tokens": []any{"b", "c"},
+		"token":  "x",
+	}, v)
+
+	v = redacted("$..number")
+	assert.Equal(t, []any{map[string]any{"number": "x", "exp": "01/30"}, map[string]any{"number": "x"}}, v.(map[string]any)["cards"])
+
+	_, err := Update(doc(), Path{{Field: "token"}}, func(any) (any, error) { return nil, assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestSplitScope(t *testing.T) {
+	path := func(expr string) Path {
+		p, err := Parse(expr)
+		require.NoError(t, err)
+		return p
+	}
+
+	scope, a, b, err := SplitScope(path("items[*].id"), path("items[*].meta.id"))
+	require.NoError(t, err)
+	assert.Equal(t, path("items[*]"), scope)
+	assert.Equal(t, path("id"), a)
+	assert.Equal(t, path("meta.id"), b)
+
+	scope, a, b, err = SplitScope(path("a.b"), path("c"))
+	require.NoError(t, err)
+	assert.Empty(t, scope)
+	assert.Equal(t, path("a.b"), a)
+	assert.Equal(t, path("c"), b)
+
+	_, _, _, err = SplitScope(path("items[*].id"), path("other[*].id"))
+	assert.Error(t, err)
+}
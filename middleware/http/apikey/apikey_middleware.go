@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+var _ components.ResolverConsumer = (*Middleware)(nil)
+
+// NewMiddleware returns a new API key authentication middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: logger,
+		clock:  clock.New(),
+	}
+}
+
+// Middleware authenticates requests with API keys.
+//
+// API keys have the format "<id>.<secret>". The key is read from the store by ID, and the SHA-256 hash of the
+// secret is compared with the hash in the store, so the store never holds the keys themselves.
+type Middleware struct {
+	logger   logger.Logger
+	clock    clock.Clock
+	resolver components.Resolver
+}
+
+// SetComponentResolver sets the resolver of the state or secret store named in the metadata.
+func (m *Middleware) SetComponentResolver(resolver components.Resolver) {
+	m.resolver = resolver
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.resolver == nil {
+		return nil, errors.New("the API keys can't be read: the host doesn't provide other components")
+	}
+	var keys keyStore
+	if meta.StateStore != "" {
+		store, err := m.resolver.StateStore(meta.StateStore)
+		if err != nil {
+			return nil, fmt.Errorf("error getting state store %s: %w", meta.StateStore, err)
+		}
+		keys = &stateKeyStore{store: store, prefix: meta.KeyPrefix}
+	} else {
+		store, err := m.resolver.SecretStore(meta.SecretStore)
+		if err != nil {
+			return nil, fmt.Errorf("error getting secret store %s: %w", meta.SecretStore, err)
+		}
+		keys = &secretKeyStore{store: store, prefix: meta.KeyPrefix}
+	}
+	keys = newCachedKeyStore(keys, meta.CacheTTL, meta.MissCacheTTL, m.clock)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if meta.SubjectHeader != "" {
+				// Never trust the subject sent by the client
+				r.Header.Del(meta.SubjectHeader)
+			}
+
+			apiKey := meta.apiKeyFrom(r)
+			id, secret, ok := strings.Cut(apiKey, ".")
+			if !ok || id == "" || secret == "" {
+				unauthorized(w)
+				return
+			}
+
+			k, err := keys.getKey(r.Context(), id)
+			if errors.Is(err, errKeyNotFound) {
+				m.logger.Debugf("API key %s not found: %v", id, err)
+				unauthorized(w)
+				return
+			} else if err != nil {
+				m.logger.Warnf("Failed to read API key %s: %v", id, err)
+				httputils.RespondWithError(w, http.StatusInternalServerError)
+				return
+			}
+			if !k.matches(secret) || k.expired(m.clock.Now()) {
+				m.logger.Debugf("API key %s is invalid or expired", id)
+				unauthorized(w)
+				return
+			}
+
+			if !k.allowsRoute(r.URL.Path) {
+				httputils.RespondWithError(w, http.StatusForbidden)
+				return
+			}
+			if rule := meta.ruleFor(r.URL.Path); rule != nil && !k.hasScopes(rule.Scopes) {
+				httputils.RespondWithError(w, http.StatusForbidden)
+				return
+			}
+
+			if meta.SubjectHeader != "" {
+				subject := k.Name
+				if subject == "" {
+					subject = id
+				}
+				r.Header.Set(meta.SubjectHeader, subject)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "ApiKey")
+	httputils.RespondWithError(w, http.StatusUnauthorized)
+}
@@ -0,0 +1,480 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+var expiresAt = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func hashOf(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// fakeSecretStore is a secret store holding the secrets in memory and counting the reads. All the reads fail
+// with err when it's set.
+type fakeSecretStore struct {
+	secrets map[string]map[string]string
+	err     error
+	reads   atomic.Int32
+}
+
+func (s *fakeSecretStore) Init(metadata secretstores.Metadata) error {
+	return nil
+}
+
+func (s *fakeSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	s.reads.Add(1)
+	if s.err != nil {
+		return secretstores.GetSecretResponse{}, s.err
+	}
+	data, ok := s.secrets[req.Name]
+	if !ok {
+		return secretstores.GetSecretResponse{}, fmt.Errorf("%w: %s", secretstores.ErrSecretNotFound, req.Name)
+	}
+	return secretstores.GetSecretResponse{Data: data}, nil
+}
+
+func (s *fakeSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	return secretstores.BulkGetSecretResponse{}, errors.New("not implemented")
+}
+
+func (s *fakeSecretStore) Features() []secretstores.Feature {
+	return nil
+}
+
+func (s *fakeSecretStore) GetComponentMetadata() map[string]string {
+	return nil
+}
+
+// fakeComponents resolves the state and secret stores of the maps.
+type fakeComponents struct {
+	stateStores  map[string]state.Store
+	secretStores map[string]secretstores.SecretStore
+}
+
+func (c *fakeComponents) StateStore(name string) (state.Store, error) {
+	if store, ok := c.stateStores[name]; ok {
+		return store, nil
+	}
+	return nil, errors.New("state store " + name + " not found")
+}
+
+func (c *fakeComponents) SecretStore(name string) (secretstores.SecretStore, error) {
+	if store, ok := c.secretStores[name]; ok {
+		return store, nil
+	}
+	return nil, errors.New("secret store " + name + " not found")
+}
+
+func (c *fakeComponents) OutputBinding(name string) (bindings.OutputBinding, error) {
+	return nil, errors.New("output binding " + name + " not found")
+}
+
+func (c *fakeComponents) PubSub(name string) (pubsub.PubSub, error) {
+	return nil, errors.New("pubsub " + name + " not found")
+}
+
+// mockedRequestHandler acts like an upstream service returns success status code 200, with the subject header,
+// the API key header and the query string it got in the response headers.
+func mockedRequestHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Seen-Subject", r.Header.Get("X-Client"))
+	w.Header().Set("X-Seen-Key", r.Header.Get("X-API-Key"))
+	w.Header().Set("X-Seen-Query", r.URL.RawQuery)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("from mock"))
+}
+
+func newComponents(t *testing.T) *fakeComponents {
+	t.Helper()
+
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("apikey.test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+	for id, k := range map[string]keyRecord{
+		"billing": {Name: "billing-service", Hash: hashOf("s3cret"), Scopes: []string{"orders.read"}, Routes: []string{"/v1.0/invoke/orders"}},
+		"admin":   {Hash: "sha256:" + hashOf("adm1n"), Scopes: []string{"orders.read", "orders.write"}},
+		"old":     {Hash: hashOf("0ld"), ExpiresAt: &expiresAt},
+	} {
+		b, err := json.Marshal(k)
+		require.NoError(t, err)
+		require.NoError(t, store.Set(context.Background(), &state.SetRequest{Key: "apikey-" + id, Value: b}))
+	}
+
+	return &fakeComponents{
+		stateStores: map[string]state.Store{"keys": store},
+		secretStores: map[string]secretstores.SecretStore{
+			"secrets": &fakeSecretStore{secrets: map[string]map[string]string{
+				"key-fields": {"hash": hashOf("one"), "name": "client-one", "scopes": "a, b", "expiresAt": "2030-01-01T00:00:00Z"},
+				"key-json":   {"key-json": `{"hash":"` + hashOf("two") + `","routes":["/v1.0/state"]}`},
+			}},
+			"unavailable": &fakeSecretStore{err: errors.New("connection refused")},
+		},
+	}
+}
+
+func withKey(path, key string) func() *http.Request {
+	return func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:3500"+path, nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		r.Header.Set("X-Client", "spoofed")
+		return r
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	stateStoreMetadata := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"stateStore":     "keys",
+			"queryParameter": "api_key",
+			"subjectHeader":  "X-Client",
+			"accessRules":    `[{"pathPrefix": "/v1.0/invoke/orders/method/write", "scopes": ["orders.write"]}]`,
+		},
+	}}
+	secretStoreMetadata := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"secretStore":   "secrets",
+			"keyPrefix":     "key-",
+			"subjectHeader": "X-Client",
+			"accessRules":   `[{"pathPrefix": "/v1.0/invoke", "scopes": ["b"]}]`,
+		},
+	}}
+
+	tests := map[string]struct {
+		meta               middleware.Metadata
+		noResolver         bool
+		advance            time.Duration
+		req                func() *http.Request
+		status             int
+		headers            *[][]string
+		shouldHandlerError bool
+	}{
+		"key in header": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders/method/list", "billing.s3cret"),
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"X-Seen-Subject", "billing-service"},
+				// The key isn't forwarded to the app
+				{"X-Seen-Key", ""},
+			},
+		},
+		"key in query": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders/method/list?api_key=admin.adm1n&page=2", ""),
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"X-Seen-Subject", "admin"},
+				{"X-Seen-Query", "page=2"},
+			},
+		},
+		"route not allowed": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", "billing.s3cret"),
+			status: http.StatusForbidden,
+		},
+		"missing scope": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders/method/write", "billing.s3cret"),
+			status: http.StatusForbidden,
+		},
+		"scope": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders/method/write", "admin.adm1n"),
+			status: http.StatusOK,
+		},
+		"missing key": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", ""),
+			status: http.StatusUnauthorized,
+			headers: &[][]string{
+				{"WWW-Authenticate", "ApiKey"},
+			},
+		},
+		"key without secret": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", "billing"),
+			status: http.StatusUnauthorized,
+		},
+		"key with empty secret": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", "billing."),
+			status: http.StatusUnauthorized,
+		},
+		"key with empty ID": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", ".s3cret"),
+			status: http.StatusUnauthorized,
+		},
+		"wrong secret": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders/method/list", "billing.wrong"),
+			status: http.StatusUnauthorized,
+		},
+		"unknown key": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", "unknown.s3cret"),
+			status: http.StatusUnauthorized,
+		},
+		"key before expiry": {
+			meta:    stateStoreMetadata,
+			advance: -time.Second,
+			req:     withKey("/v1.0/state/a", "old.0ld"),
+			status:  http.StatusOK,
+		},
+		"expired key": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/state/a", "old.0ld"),
+			status: http.StatusUnauthorized,
+		},
+		"secret with fields": {
+			meta:    secretStoreMetadata,
+			advance: -time.Second,
+			req:     withKey("/v1.0/invoke/a", "fields.one"),
+			status:  http.StatusOK,
+			headers: &[][]string{
+				{"X-Seen-Subject", "client-one"},
+			},
+		},
+		"secret with JSON": {
+			meta:   secretStoreMetadata,
+			req:    withKey("/v1.0/state/a", "json.two"),
+			status: http.StatusOK,
+		},
+		"secret with JSON missing scope": {
+			meta:   secretStoreMetadata,
+			req:    withKey("/v1.0/invoke/a", "json.two"),
+			status: http.StatusForbidden,
+		},
+		"missing secret": {
+			meta:   secretStoreMetadata,
+			req:    withKey("/v1.0/state/a", "missing.x"),
+			status: http.StatusUnauthorized,
+		},
+		"secret store unavailable for an unknown key": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"secretStore": "unavailable"},
+			}},
+			req:    withKey("/v1.0/state/a", "json.two"),
+			status: http.StatusUnauthorized,
+		},
+		"key route prefixes match whole segments": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders-admin/method/list", "billing.s3cret"),
+			status: http.StatusForbidden,
+		},
+		"access rule prefixes match whole segments": {
+			meta:   stateStoreMetadata,
+			req:    withKey("/v1.0/invoke/orders/method/writer", "billing.s3cret"),
+			status: http.StatusOK,
+		},
+		"no store": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{}}},
+			shouldHandlerError: true,
+		},
+		"both stores": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"stateStore": "keys", "secretStore": "secrets"},
+			}},
+			shouldHandlerError: true,
+		},
+		"unknown store": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"stateStore": "unknown"},
+			}},
+			shouldHandlerError: true,
+		},
+		"no resolver": {
+			meta:               stateStoreMetadata,
+			noResolver:         true,
+			shouldHandlerError: true,
+		},
+		"no header and no query parameter": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"stateStore": "keys", "header": "", "queryParameter": ""},
+			}},
+			shouldHandlerError: true,
+		},
+		"negative cacheTTL": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"stateStore": "keys", "cacheTTL": "-1s"},
+			}},
+			shouldHandlerError: true,
+		},
+		"negative missCacheTTL": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"stateStore": "keys", "missCacheTTL": "-1s"},
+			}},
+			shouldHandlerError: true,
+		},
+		"invalid accessRules": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"stateStore": "keys", "accessRules": "not json"},
+			}},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("apikey.test")
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewMock()
+			clk.Set(expiresAt.Add(test.advance))
+			apiKeyMiddleware := NewMiddleware(log).(*Middleware)
+			apiKeyMiddleware.clock = clk
+			if !test.noResolver {
+				apiKeyMiddleware.SetComponentResolver(newComponents(t))
+			}
+
+			handler, err := apiKeyMiddleware.GetHandler(test.meta)
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler(http.HandlerFunc(mockedRequestHandler)).ServeHTTP(w, test.req())
+
+			assert.Equal(t, test.status, w.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, "from mock", w.Body.String())
+			}
+
+			if test.headers != nil {
+				for _, header := range *test.headers {
+					assert.Equal(t, header[1], w.Header().Get(header[0]), header[0])
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyCache(t *testing.T) {
+	c := newComponents(t)
+	secrets := c.secretStores["secrets"].(*fakeSecretStore)
+	clk := clock.NewMock()
+	apiKeyMiddleware := NewMiddleware(logger.NewLogger("apikey.test")).(*Middleware)
+	apiKeyMiddleware.clock = clk
+	apiKeyMiddleware.SetComponentResolver(c)
+	handler, err := apiKeyMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"secretStore": "secrets",
+			"keyPrefix":   "key-",
+			"cacheTTL":    "1m",
+		},
+	}})
+	require.NoError(t, err)
+	h := handler(http.HandlerFunc(mockedRequestHandler))
+	status := func(key string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, withKey("/v1.0/state/a", key)())
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, status("fields.one"))
+	assert.Equal(t, http.StatusOK, status("fields.one"))
+	assert.Equal(t, int32(1), secrets.reads.Load())
+
+	// Cached keys are still checked against the secret
+	assert.Equal(t, http.StatusUnauthorized, status("fields.wrong"))
+	assert.Equal(t, int32(1), secrets.reads.Load())
+
+	// Missing keys are cached for missCacheTTL
+	assert.Equal(t, http.StatusUnauthorized, status("missing.x"))
+	assert.Equal(t, http.StatusUnauthorized, status("missing.x"))
+	assert.Equal(t, int32(2), secrets.reads.Load())
+	clk.Add(5 * time.Second)
+	assert.Equal(t, http.StatusUnauthorized, status("missing.x"))
+	assert.Equal(t, int32(3), secrets.reads.Load())
+
+	// A key added to the store works once its miss expires
+	assert.Equal(t, http.StatusUnauthorized, status("new.three"))
+	secrets.secrets["key-new"] = map[string]string{"hash": hashOf("three")}
+	assert.Equal(t, http.StatusUnauthorized, status("new.three"))
+	clk.Add(5 * time.Second)
+	assert.Equal(t, http.StatusOK, status("new.three"))
+
+	// Failures of the store are errors for the keys that were found before, and unauthorized for the others
+	clk.Add(time.Minute)
+	secrets.err = errors.New("connection refused")
+	assert.Equal(t, http.StatusInternalServerError, status("fields.one"))
+	assert.Equal(t, http.StatusUnauthorized, status("other.x"))
+	secrets.err = nil
+
+	// Revoked keys stop working once the cache expires
+	assert.Equal(t, http.StatusOK, status("fields.one"))
+	delete(secrets.secrets, "key-fields")
+	assert.Equal(t, http.StatusOK, status("fields.one"))
+	clk.Add(time.Minute)
+	assert.Equal(t, http.StatusUnauthorized, status("fields.one"))
+}
+
+func TestAPIKeyWithoutCache(t *testing.T) {
+	c := newComponents(t)
+	secrets := c.secretStores["secrets"].(*fakeSecretStore)
+	apiKeyMiddleware := NewMiddleware(logger.NewLogger("apikey.test")).(*Middleware)
+	apiKeyMiddleware.SetComponentResolver(c)
+	handler, err := apiKeyMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"secretStore":  "secrets",
+			"keyPrefix":    "key-",
+			"cacheTTL":     "0",
+			"missCacheTTL": "0",
+		},
+	}})
+	require.NoError(t, err)
+	h := handler(http.HandlerFunc(mockedRequestHandler))
+	status := func(key string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, withKey("/v1.0/state/a", key)())
+		return w.Code
+	}
+
+	for _, key := range []string{"fields.one", "fields.one", "missing.x", "missing.x"} {
+		status(key)
+	}
+	assert.Equal(t, int32(4), secrets.reads.Load())
+
+	// Keys found before are still known when they aren't cached
+	secrets.err = errors.New("connection refused")
+	assert.Equal(t, http.StatusInternalServerError, status("fields.one"))
+	assert.Equal(t, http.StatusUnauthorized, status("missing.x"))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+)
+
+const (
+	defaultHeader    = "X-API-Key"
+	defaultKeyPrefix = "apikey-"
+	defaultCacheTTL  = time.Minute
+	defaultMissTTL   = 5 * time.Second
+)
+
+type apiKeyMiddlewareMetadata struct {
+	// StateStore is the name of the state store the API keys are read from.
+	StateStore string `json:"stateStore"`
+	// SecretStore is the name of the secret store the API keys are read from. Only one of StateStore and
+	// SecretStore can be set.
+	SecretStore string `json:"secretStore"`
+	// Header is the request header that holds the API key.
+	Header string `json:"header"`
+	// QueryParameter is the query parameter that holds the API key when the header is missing. Disabled when empty.
+	QueryParameter string `json:"queryParameter"`
+	// KeyPrefix is prepended to the key ID to get the name of the key in the state or secret store.
+	KeyPrefix string `json:"keyPrefix"`
+	// CacheTTL is how long the keys read from the store are cached. Set to 0 to disable caching.
+	CacheTTL time.Duration `json:"cacheTTL"`
+	// MissCacheTTL is how long the IDs of the keys missing from the store are cached. Set to 0 to disable caching.
+	MissCacheTTL time.Duration `json:"missCacheTTL"`
+	// AccessRules is a JSON array of the scopes required per path prefix.
+	AccessRules string `json:"accessRules"`
+	// SubjectHeader is the request header set to the name of the key, for the app. Disabled when empty.
+	SubjectHeader string `json:"subjectHeader"`
+
+	accessRules []accessRule
+}
+
+// accessRule lists the scopes required for paths starting with PathPrefix.
+type accessRule struct {
+	PathPrefix string   `json:"pathPrefix"`
+	Scopes     []string `json:"scopes"`
+}
+
+func getNativeMetadata(metadata middleware.Metadata) (*apiKeyMiddlewareMetadata, error) {
+	middlewareMetadata := apiKeyMiddlewareMetadata{
+		Header:       defaultHeader,
+		KeyPrefix:    defaultKeyPrefix,
+		CacheTTL:     defaultCacheTTL,
+		MissCacheTTL: defaultMissTTL,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if (middlewareMetadata.StateStore == "") == (middlewareMetadata.SecretStore == "") {
+		return nil, errors.New("one of stateStore or secretStore is required")
+	}
+	if middlewareMetadata.Header == "" && middlewareMetadata.QueryParameter == "" {
+		return nil, errors.New("one of header or queryParameter is required")
+	}
+	if middlewareMetadata.CacheTTL < 0 {
+		return nil, errors.New("cacheTTL must not be negative")
+	}
+	if middlewareMetadata.MissCacheTTL < 0 {
+		return nil, errors.New("missCacheTTL must not be negative")
+	}
+	if middlewareMetadata.AccessRules != "" {
+		err = json.Unmarshal([]byte(middlewareMetadata.AccessRules), &middlewareMetadata.accessRules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid accessRules: %w", err)
+		}
+	}
+
+	return &middlewareMetadata, nil
+}
+
+// ruleFor returns the access rule with the longest prefix matching the path, or nil.
+func (md *apiKeyMiddlewareMetadata) ruleFor(path string) *accessRule {
+	var match *accessRule
+	for i := range md.accessRules {
+		rule := &md.accessRules[i]
+		if httputils.HasPathPrefix(path, rule.PathPrefix) && (match == nil || len(rule.PathPrefix) > len(match.PathPrefix)) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// apiKeyFrom returns the API key of the request, and removes it so it isn't forwarded to the app.
+func (md *apiKeyMiddlewareMetadata) apiKeyFrom(r *http.Request) string {
+	if md.Header != "" {
+		if key := r.Header.Get(md.Header); key != "" {
+			r.Header.Del(md.Header)
+			return key
+		}
+	}
+	if md.QueryParameter != "" {
+		query := r.URL.Query()
+		if key := query.Get(md.QueryParameter); key != "" {
+			query.Del(md.QueryParameter)
+			r.URL.RawQuery = query.Encode()
+			r.RequestURI = r.URL.RequestURI()
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+)
+
+const (
+	hashPrefix = "sha256:"
+
+	// maxCachedMisses bounds the number of missing key IDs cached, as clients choose the IDs they send.
+	maxCachedMisses = 10000
+)
+
+var errKeyNotFound = errors.New("API key not found")
+
+// keyRecord is an API key as stored in the state or secret store. Only the SHA-256 hash of the secret part
+// of the key is stored.
+type keyRecord struct {
+	// Name identifies the client the key was issued to.
+	Name string `json:"name"`
+	// Hash is the hex-encoded SHA-256 hash of the secret, optionally prefixed with "sha256:".
+	Hash string `json:"hash"`
+	// Scopes are the scopes granted to the key.
+	Scopes []string `json:"scopes"`
+	// Routes are the path prefixes the key can access; all paths when empty.
+	Routes []string `json:"routes"`
+	// ExpiresAt is when the key stops being valid; it never expires when empty.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// matches compares the hash of the secret with the stored hash in constant time.
+func (k *keyRecord) matches(secret string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(k.Hash), hashPrefix))
+	if err != nil || len(expected) != sha256.Size {
+		return false
+	}
+	actual := sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(actual[:], expected) == 1
+}
+
+func (k *keyRecord) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// allowsRoute returns true if the key can access the path.
+func (k *keyRecord) allowsRoute(path string) bool {
+	if len(k.Routes) == 0 {
+		return true
+	}
+	for _, prefix := range k.Routes {
+		if httputils.HasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *keyRecord) hasScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		found := false
+		for _, granted := range k.Scopes {
+			if granted == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// keyStore reads the API keys by ID.
+type keyStore interface {
+	getKey(ctx context.Context, id string) (*keyRecord, error)
+}
+
+// stateKeyStore reads the keys stored as JSON in a state store.
+type stateKeyStore struct {
+	store  state.Store
+	prefix string
+}
+
+func (s *stateKeyStore) getKey(ctx context.Context, id string) (*keyRecord, error) {
+	res, err := s.store.Get(ctx, &state.GetRequest{Key: s.prefix + id})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || len(res.Data) == 0 {
+		return nil, errKeyNotFound
+	}
+	var k keyRecord
+	err = json.Unmarshal(res.Data, &k)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key record: %w", err)
+	}
+	return &k, nil
+}
+
+// secretKeyStore reads the keys from a secret store. A secret either has the "hash", "name", "scopes",
+// "routes" and "expiresAt" fields, with comma-separated scopes and routes, or holds the key as JSON.
+type secretKeyStore struct {
+	store  secretstores.SecretStore
+	prefix string
+}
+
+func (s *secretKeyStore) getKey(ctx context.Context, id string) (*keyRecord, error) {
+	res, err := s.store.GetSecret(ctx, secretstores.GetSecretRequest{Name: s.prefix + id})
+	if errors.Is(err, secretstores.ErrSecretNotFound) {
+		return nil, fmt.Errorf("%w: %v", errKeyNotFound, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if hash, ok := res.Data["hash"]; ok {
+		k := &keyRecord{
+			Name:   res.Data["name"],
+			Hash:   hash,
+			Scopes: splitList(res.Data["scopes"]),
+			Routes: splitList(res.Data["routes"]),
+		}
+		if v := res.Data["expiresAt"]; v != "" {
+			expiresAt, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expiresAt for API key %s: %w", id, err)
+			}
+			k.ExpiresAt = &expiresAt
+		}
+		return k, nil
+	}
+
+	if len(res.Data) != 1 {
+		return nil, errKeyNotFound
+	}
+	for _, v := range res.Data {
+		if v == "" {
+			// Stores such as the environment variables return empty values for missing secrets
+			return nil, errKeyNotFound
+		}
+		var k keyRecord
+		err = json.Unmarshal([]byte(v), &k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API key record: %w", err)
+		}
+		return &k, nil
+	}
+	return nil, errKeyNotFound
+}
+
+// cachedKeyStore caches the keys found in the underlying store for ttl, and the IDs of the missing keys for
+// missTTL; either is disabled when 0. The errors of the store are reported as missing keys for the IDs that were
+// never found, so that requests with made-up keys are rejected as unauthorized.
+type cachedKeyStore struct {
+	keyStore
+	ttl     time.Duration
+	missTTL time.Duration
+	clock   clock.Clock
+
+	lock    sync.RWMutex
+	entries map[string]cachedKey
+	misses  map[string]time.Time
+	known   map[string]struct{}
+}
+
+type cachedKey struct {
+	key     *keyRecord
+	expires time.Time
+}
+
+func newCachedKeyStore(store keyStore, ttl, missTTL time.Duration, clk clock.Clock) *cachedKeyStore {
+	return &cachedKeyStore{
+		keyStore: store,
+		ttl:      ttl,
+		missTTL:  missTTL,
+		clock:    clk,
+		entries:  map[string]cachedKey{},
+		misses:   map[string]time.Time{},
+		known:    map[string]struct{}{},
+	}
+}
+
+func (c *cachedKeyStore) getKey(ctx context.Context, id string) (*keyRecord, error) {
+	now := c.clock.Now()
+	c.lock.RLock()
+	entry, ok := c.entries[id]
+	missExpires, missed := c.misses[id]
+	_, known := c.known[id]
+	c.lock.RUnlock()
+	if ok && now.Before(entry.expires) {
+		return entry.key, nil
+	}
+	if missed && now.Before(missExpires) {
+		return nil, errKeyNotFound
+	}
+
+	k, err := c.keyStore.getKey(ctx, id)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch {
+	case errors.Is(err, errKeyNotFound):
+		delete(c.entries, id)
+		delete(c.known, id)
+		c.addMiss(id, now)
+		return nil, err
+	case err != nil && !known:
+		c.addMiss(id, now)
+		return nil, fmt.Errorf("%w: %v", errKeyNotFound, err)
+	case err != nil:
+		delete(c.entries, id)
+		return nil, err
+	}
+	delete(c.misses, id)
+	c.known[id] = struct{}{}
+	if c.ttl > 0 {
+		c.entries[id] = cachedKey{key: k, expires: now.Add(c.ttl)}
+	}
+	return k, nil
+}
+
+// addMiss caches a missing key ID. Expired IDs are evicted when the cache is full; if none is, the ID isn't cached.
+func (c *cachedKeyStore) addMiss(id string, now time.Time) {
+	if c.missTTL <= 0 {
+		return
+	}
+	if len(c.misses) >= maxCachedMisses {
+		for missID, expires := range c.misses {
+			if !now.Before(expires) {
+				delete(c.misses, missID)
+			}
+		}
+		if len(c.misses) >= maxCachedMisses {
+			return
+		}
+	}
+	c.misses[id] = now.Add(c.missTTL)
+}
+
+func splitList(v string) []string {
+	var res []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
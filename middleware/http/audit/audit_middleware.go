@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// NewMiddleware returns a new audit logging middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: logger,
+		clock:  clock.New(),
+	}
+}
+
+var _ components.ResolverConsumer = (*Middleware)(nil)
+
+// Middleware records who called what and when. The records are sent to an output binding or published to a
+// pubsub topic in the background.
+type Middleware struct {
+	logger   logger.Logger
+	clock    clock.Clock
+	resolver components.Resolver
+
+	lock sync.Mutex
+	logs []*auditLog
+}
+
+// SetComponentResolver sets the resolver of the output binding or of the pubsub named in the metadata.
+func (m *Middleware) SetComponentResolver(resolver components.Resolver) {
+	m.resolver = resolver
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var s sink
+	switch {
+	case m.resolver == nil:
+		return nil, errors.New("the records can't be sent: the host doesn't provide other components")
+	case meta.OutputBinding != "":
+		binding, err := m.resolver.OutputBinding(meta.OutputBinding)
+		if err != nil {
+			return nil, fmt.Errorf("error getting output binding %s: %w", meta.OutputBinding, err)
+		}
+		s = &bindingSink{binding: binding, operation: bindings.OperationKind(meta.BindingOperation)}
+	default:
+		ps, err := m.resolver.PubSub(meta.PubsubName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting pubsub %s: %w", meta.PubsubName, err)
+		}
+		s = &pubsubSink{pubsub: ps, pubsubName: meta.PubsubName, topic: meta.Topic}
+	}
+	records := newAuditLog(s, meta.BufferSize, meta.SendTimeout, m.logger)
+	m.lock.Lock()
+	m.logs = append(m.logs, records)
+	m.lock.Unlock()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if meta.skips(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := m.clock.Now()
+			record := &Record{
+				ID:         uuid.NewString(),
+				Timestamp:  start.UTC(),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				RemoteAddr: r.RemoteAddr,
+				Caller:     meta.callerFrom(r),
+			}
+			if len(meta.IdentityClaims) > 0 {
+				// The authentication middleware may run after this one
+				r = httputils.WithVerifiedClaimsHolder(r)
+			}
+
+			if meta.LogRequestBody {
+				body, truncated, err := captureRequestBody(r, meta.MaxBodySize)
+				if err != nil {
+					m.logger.Debugf("Failed to read the request body: %v", err)
+					httputils.RespondWithError(w, http.StatusBadRequest)
+					return
+				}
+				record.RequestBody = meta.decodeBody(body, truncated)
+				record.RequestBodyTruncated = truncated
+			}
+
+			rw := &responseRecorder{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+				capture:        meta.LogResponseBody,
+				maxSize:        meta.MaxBodySize,
+			}
+			next.ServeHTTP(rw, r)
+
+			record.Caller = meta.withVerifiedClaims(record.Caller, r)
+			record.Status = rw.status
+			record.LatencyMs = float64(m.clock.Since(start).Microseconds()) / 1000
+			if meta.LogResponseBody {
+				record.ResponseBody = meta.decodeBody(rw.body.Bytes(), rw.truncated)
+				record.ResponseBodyTruncated = rw.truncated
+			}
+			records.log(record)
+		})
+	}, nil
+}
+
+// Close stops sending the records of the handlers, once the records already queued are sent.
+func (m *Middleware) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, l := range m.logs {
+		l.close()
+	}
+	m.logs = nil
+	return nil
+}
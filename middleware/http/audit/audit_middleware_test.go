@@ -0,0 +1,581 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/pubsub"
+	inmemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+// fakeBinding is an output binding sending the invocations to a channel. Invocations block until gate is closed,
+// and fail while fail is set.
+type fakeBinding struct {
+	invocations chan *bindings.InvokeRequest
+	calls       atomic.Int32
+	gate        chan struct{}
+	fail        atomic.Bool
+}
+
+func newFakeBinding() *fakeBinding {
+	return &fakeBinding{invocations: make(chan *bindings.InvokeRequest, 100)}
+}
+
+func (b *fakeBinding) Init(metadata bindings.Metadata) error {
+	return nil
+}
+
+func (b *fakeBinding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	b.calls.Add(1)
+	if b.gate != nil {
+		<-b.gate
+	}
+	if b.fail.Load() {
+		return nil, errors.New("unavailable")
+	}
+	b.invocations <- req
+	return nil, nil
+}
+
+func (b *fakeBinding) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation}
+}
+
+func (b *fakeBinding) next(t *testing.T) (*bindings.InvokeRequest, *Record) {
+	t.Helper()
+	select {
+	case req := <-b.invocations:
+		var record Record
+		require.NoError(t, json.Unmarshal(req.Data, &record))
+		return req, &record
+	case <-time.After(5 * time.Second):
+		t.Fatal("no record sent")
+		return nil, nil
+	}
+}
+
+// fakeComponents resolves the output bindings and the pubsubs of the maps.
+type fakeComponents struct {
+	bindings map[string]bindings.OutputBinding
+	pubsubs  map[string]pubsub.PubSub
+}
+
+func (c fakeComponents) StateStore(name string) (state.Store, error) {
+	return nil, errors.New("state store " + name + " not found")
+}
+
+func (c fakeComponents) SecretStore(name string) (secretstores.SecretStore, error) {
+	return nil, errors.New("secret store " + name + " not found")
+}
+
+func (c fakeComponents) OutputBinding(name string) (bindings.OutputBinding, error) {
+	if binding, ok := c.bindings[name]; ok {
+		return binding, nil
+	}
+	return nil, errors.New("output binding " + name + " not found")
+}
+
+func (c fakeComponents) PubSub(name string) (pubsub.PubSub, error) {
+	if ps, ok := c.pubsubs[name]; ok {
+		return ps, nil
+	}
+	return nil, errors.New("pubsub " + name + " not found")
+}
+
+// mockedApp acts like an upstream service taking 25ms: it echoes the body of the requests to /echo, answers
+// /signup with a session token, verifies the caller of /login like an authentication middleware, and answers
+// other requests with 204.
+func mockedApp(clk *clock.Mock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		clk.Add(25 * time.Millisecond)
+		switch r.URL.Path {
+		case "/echo":
+			w.Write(body)
+		case "/signup":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"42","session":{"token":"t0k3n"}}`))
+		case "/login":
+			httputils.SetVerifiedClaims(r, map[string]any{"sub": "bob", "tenant": "fabrikam", "role": "admin"})
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	token, err := jwt.NewBuilder().Subject("alice").Claim("tenant", "contoso").Build()
+	require.NoError(t, err)
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	require.NoError(t, err)
+
+	signupBody := `{"user":"alice","password":"hunter2","cards":[{"number":"4111"},{"number":"5500"}]}`
+	redactMeta := middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"outputBinding":   "audit",
+		"identityHeaders": "X-Client, dapr-caller-app-id",
+		"identityClaims":  "sub,tenant",
+		"logRequestBody":  "true",
+		"logResponseBody": "true",
+		"redactFields":    "$.password, $..token, $.cards[*].number",
+		"skipPaths":       "/healthz",
+	}}}
+	bodiesMeta := middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"outputBinding":   "audit",
+		"logRequestBody":  "true",
+		"logResponseBody": "true",
+		"maxBodySize":     "8",
+	}}}
+
+	tests := map[string]struct {
+		meta               middleware.Metadata
+		req                func() *http.Request
+		status             int
+		body               string
+		record             *Record
+		operation          bindings.OperationKind
+		noResolver         bool
+		shouldHandlerError bool
+	}{
+		"identity and redacted bodies": {
+			meta: redactMeta,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/signup?ref=mail", strings.NewReader(signupBody))
+				r.Header.Set("Authorization", "Bearer "+string(signed))
+				r.Header.Set("dapr-caller-app-id", "frontend")
+				return httputils.SetVerifiedClaims(r, map[string]any{"sub": "alice", "tenant": "contoso"})
+			},
+			status: http.StatusCreated,
+			body:   `{"id":"42","session":{"token":"t0k3n"}}`,
+			record: &Record{
+				Method:     http.MethodPost,
+				Path:       "/signup",
+				Status:     http.StatusCreated,
+				LatencyMs:  25,
+				RemoteAddr: "192.0.2.1:1234",
+				Caller: &Caller{
+					Subject:  "frontend",
+					Headers:  map[string]string{"dapr-caller-app-id": "frontend"},
+					Claims:   map[string]any{"sub": "alice", "tenant": "contoso"},
+					Verified: true,
+				},
+				RequestBody: map[string]any{
+					"user":     "alice",
+					"password": redactedValue,
+					"cards":    []any{map[string]any{"number": redactedValue}, map[string]any{"number": redactedValue}},
+				},
+				ResponseBody: map[string]any{"id": "42", "session": map[string]any{"token": redactedValue}},
+			},
+			operation: bindings.CreateOperation,
+		},
+		"claims verified after the audit middleware": {
+			meta: redactMeta,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/login", nil)
+			},
+			status: http.StatusNoContent,
+			record: &Record{
+				Method:     http.MethodPost,
+				Path:       "/login",
+				Status:     http.StatusNoContent,
+				LatencyMs:  25,
+				RemoteAddr: "192.0.2.1:1234",
+				Caller: &Caller{
+					Subject:  "bob",
+					Claims:   map[string]any{"sub": "bob", "tenant": "fabrikam"},
+					Verified: true,
+				},
+			},
+		},
+		"unverified token": {
+			meta: redactMeta,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/v1.0/state/b", nil)
+				r.Header.Set("Authorization", "Bearer "+string(signed))
+				r.Header.Set("X-Client", "batch")
+				return r
+			},
+			status: http.StatusNoContent,
+			record: &Record{
+				Method:     http.MethodGet,
+				Path:       "/v1.0/state/b",
+				Status:     http.StatusNoContent,
+				LatencyMs:  25,
+				RemoteAddr: "192.0.2.1:1234",
+				Caller:     &Caller{Subject: "batch", Headers: map[string]string{"X-Client": "batch"}},
+			},
+		},
+		"body that can't be redacted": {
+			meta: redactMeta,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo", strings.NewReader("password=hunter2"))
+				r.Header.Set("X-Client", "batch")
+				return r
+			},
+			status: http.StatusOK,
+			body:   "password=hunter2",
+			record: &Record{
+				Method:       http.MethodPost,
+				Path:         "/echo",
+				Status:       http.StatusOK,
+				LatencyMs:    25,
+				RemoteAddr:   "192.0.2.1:1234",
+				Caller:       &Caller{Subject: "batch", Headers: map[string]string{"X-Client": "batch"}},
+				RequestBody:  redactedValue,
+				ResponseBody: redactedValue,
+			},
+		},
+		"body larger than maxBodySize with redacted fields": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding":   "audit",
+				"logRequestBody":  "true",
+				"logResponseBody": "true",
+				"maxBodySize":     "16",
+				"redactFields":    "$.password",
+			}}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo", strings.NewReader(`{"password":"hunter2","user":"alice"}`))
+			},
+			status: http.StatusOK,
+			body:   `{"password":"hunter2","user":"alice"}`,
+			record: &Record{
+				Method:                http.MethodPost,
+				Path:                  "/echo",
+				Status:                http.StatusOK,
+				LatencyMs:             25,
+				RemoteAddr:            "192.0.2.1:1234",
+				RequestBody:           redactedValue,
+				RequestBodyTruncated:  true,
+				ResponseBody:          redactedValue,
+				ResponseBodyTruncated: true,
+			},
+		},
+		"skipped path": {
+			meta: redactMeta,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "http://localhost:3500/healthz", nil)
+			},
+			status: http.StatusNoContent,
+		},
+		"no caller and no body": {
+			meta: redactMeta,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "http://localhost:3500/v1.0/state/b", nil)
+			},
+			status: http.StatusNoContent,
+			record: &Record{
+				Method:     http.MethodGet,
+				Path:       "/v1.0/state/b",
+				Status:     http.StatusNoContent,
+				LatencyMs:  25,
+				RemoteAddr: "192.0.2.1:1234",
+			},
+		},
+		"plain bodies": {
+			meta: bodiesMeta,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo", strings.NewReader("plain"))
+			},
+			status: http.StatusOK,
+			body:   "plain",
+			record: &Record{
+				Method:       http.MethodPost,
+				Path:         "/echo",
+				Status:       http.StatusOK,
+				LatencyMs:    25,
+				RemoteAddr:   "192.0.2.1:1234",
+				RequestBody:  "plain",
+				ResponseBody: "plain",
+			},
+		},
+		"truncated bodies": {
+			meta: bodiesMeta,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo", strings.NewReader(`{"long":"document"}`))
+			},
+			status: http.StatusOK,
+			body:   `{"long":"document"}`,
+			record: &Record{
+				Method:                http.MethodPost,
+				Path:                  "/echo",
+				Status:                http.StatusOK,
+				LatencyMs:             25,
+				RemoteAddr:            "192.0.2.1:1234",
+				RequestBody:           `{"long":`,
+				RequestBodyTruncated:  true,
+				ResponseBody:          `{"long":`,
+				ResponseBodyTruncated: true,
+			},
+		},
+		"binding operation": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding":    "audit",
+				"bindingOperation": "append",
+			}}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodDelete, "http://localhost:3500/v1.0/state/a", nil)
+			},
+			status: http.StatusNoContent,
+			record: &Record{
+				Method:     http.MethodDelete,
+				Path:       "/v1.0/state/a",
+				Status:     http.StatusNoContent,
+				LatencyMs:  25,
+				RemoteAddr: "192.0.2.1:1234",
+			},
+			operation: "append",
+		},
+		"pubsub": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"pubsubName": "pubsub",
+				"topic":      "audit",
+			}}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodDelete, "http://localhost:3500/v1.0/state/a", nil)
+			},
+			status: http.StatusNoContent,
+			record: &Record{
+				Method:     http.MethodDelete,
+				Path:       "/v1.0/state/a",
+				Status:     http.StatusNoContent,
+				LatencyMs:  25,
+				RemoteAddr: "192.0.2.1:1234",
+			},
+		},
+		"no sink": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{}}},
+			shouldHandlerError: true,
+		},
+		"both sinks": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+				"pubsubName":    "pubsub",
+				"topic":         "audit",
+			}}},
+			shouldHandlerError: true,
+		},
+		"pubsub without topic": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"pubsubName": "pubsub",
+			}}},
+			shouldHandlerError: true,
+		},
+		"unknown output binding": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "other",
+			}}},
+			shouldHandlerError: true,
+		},
+		"unknown pubsub": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"pubsubName": "other",
+				"topic":      "audit",
+			}}},
+			shouldHandlerError: true,
+		},
+		"no resolver": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+			}}},
+			noResolver:         true,
+			shouldHandlerError: true,
+		},
+		"invalid maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+				"maxBodySize":   "0",
+			}}},
+			shouldHandlerError: true,
+		},
+		"invalid bufferSize": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+				"bufferSize":    "-1",
+			}}},
+			shouldHandlerError: true,
+		},
+		"invalid sendTimeout": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+				"sendTimeout":   "0",
+			}}},
+			shouldHandlerError: true,
+		},
+		"redacting the whole document": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+				"redactFields":  "$",
+			}}},
+			shouldHandlerError: true,
+		},
+		"invalid redactFields": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"outputBinding": "audit",
+				"redactFields":  "$.a[x]",
+			}}},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("audit.test")
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewMock()
+			clk.Set(start)
+			binding := newFakeBinding()
+			ps := inmemory.New(log)
+			require.NoError(t, ps.Init(pubsub.Metadata{}))
+			messages := make(chan *pubsub.NewMessage, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, ps.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "audit"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+				messages <- msg
+				return nil
+			}))
+
+			auditMiddleware := NewMiddleware(log).(*Middleware)
+			auditMiddleware.clock = clk
+			if !test.noResolver {
+				auditMiddleware.SetComponentResolver(fakeComponents{
+					bindings: map[string]bindings.OutputBinding{"audit": binding},
+					pubsubs:  map[string]pubsub.PubSub{"pubsub": ps},
+				})
+			}
+
+			handler, err := auditMiddleware.GetHandler(test.meta)
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer auditMiddleware.Close()
+
+			w := httptest.NewRecorder()
+			handler(mockedApp(clk)).ServeHTTP(w, test.req())
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, test.body, w.Body.String())
+
+			// Close sends the records queued
+			require.NoError(t, auditMiddleware.Close())
+			var record *Record
+			select {
+			case req := <-binding.invocations:
+				if test.operation != "" {
+					assert.Equal(t, test.operation, req.Operation)
+				}
+				require.NoError(t, json.Unmarshal(req.Data, &record))
+			case msg := <-messages:
+				require.NoError(t, json.Unmarshal(msg.Data, &record))
+			case <-time.After(time.Second):
+			}
+			if test.record == nil {
+				assert.Nil(t, record)
+				return
+			}
+			require.NotNil(t, record, "no record sent")
+			assert.NotEmpty(t, record.ID)
+			assert.WithinDuration(t, start, record.Timestamp, 0)
+			record.ID = ""
+			record.Timestamp = time.Time{}
+			assert.Equal(t, test.record, record)
+		})
+	}
+}
+
+func TestDroppedRecords(t *testing.T) {
+	meta := middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"outputBinding": "audit",
+		"bufferSize":    "1",
+	}}}
+	serve := func(h http.Handler, path string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:3500"+path, nil))
+	}
+
+	t.Run("buffer full", func(t *testing.T) {
+		binding := newFakeBinding()
+		binding.gate = make(chan struct{})
+		auditMiddleware := NewMiddleware(logger.NewLogger("audit.test")).(*Middleware)
+		auditMiddleware.SetComponentResolver(fakeComponents{bindings: map[string]bindings.OutputBinding{"audit": binding}})
+		defer auditMiddleware.Close()
+		handler, err := auditMiddleware.GetHandler(meta)
+		require.NoError(t, err)
+		h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		// The first record is blocked in the binding, the second one waits in the buffer
+		serve(h, "/1")
+		require.Eventually(t, func() bool { return binding.calls.Load() == 1 }, time.Second, time.Millisecond)
+		serve(h, "/2")
+		serve(h, "/3")
+		serve(h, "/4")
+		close(binding.gate)
+
+		for _, path := range []string{"/1", "/2"} {
+			_, record := binding.next(t)
+			assert.Equal(t, path, record.Path)
+			assert.Zero(t, record.Dropped)
+		}
+		serve(h, "/5")
+		_, record := binding.next(t)
+		assert.Equal(t, "/5", record.Path)
+		assert.Equal(t, uint64(2), record.Dropped)
+	})
+
+	t.Run("send failures", func(t *testing.T) {
+		binding := newFakeBinding()
+		binding.fail.Store(true)
+		auditMiddleware := NewMiddleware(logger.NewLogger("audit.test")).(*Middleware)
+		auditMiddleware.SetComponentResolver(fakeComponents{bindings: map[string]bindings.OutputBinding{"audit": binding}})
+		defer auditMiddleware.Close()
+		handler, err := auditMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"outputBinding": "audit",
+		}}})
+		require.NoError(t, err)
+		h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		serve(h, "/1")
+		serve(h, "/2")
+		require.Eventually(t, func() bool { return binding.calls.Load() == 2 }, time.Second, time.Millisecond)
+		binding.fail.Store(false)
+		serve(h, "/3")
+		_, record := binding.next(t)
+		assert.Equal(t, "/3", record.Path)
+		assert.Equal(t, uint64(2), record.Dropped)
+	})
+
+}
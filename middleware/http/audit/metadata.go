@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/jsonpath"
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+)
+
+const (
+	defaultIdentityClaims   = "sub"
+	defaultBindingOperation = "create"
+	defaultMaxBodySize      = 64 << 10
+	defaultBufferSize       = 1000
+	defaultSendTimeout      = 5 * time.Second
+)
+
+type auditMiddlewareMetadata struct {
+	// IdentityHeaders are the request headers identifying the caller, in order of precedence.
+	IdentityHeaders []string `json:"identityHeaders"`
+	// IdentityClaims are the claims of the bearer token identifying the caller, in order of precedence.
+	// They're only read from the token verified by an authentication middleware, such as bearer.
+	IdentityClaims []string `json:"identityClaims"`
+	// LogRequestBody adds the request body to the records.
+	LogRequestBody bool `json:"logRequestBody"`
+	// LogResponseBody adds the response body to the records.
+	LogResponseBody bool `json:"logResponseBody"`
+	// MaxBodySize is the size of the largest body recorded; larger bodies are truncated.
+	MaxBodySize int64 `json:"maxBodySize"`
+	// RedactFields are the JSONPath expressions of the body fields replaced with "[REDACTED]".
+	RedactFields []string `json:"redactFields"`
+	// SkipPaths are the path prefixes of the requests that aren't audited, such as health checks.
+	SkipPaths []string `json:"skipPaths"`
+	// OutputBinding is the name of the output binding component the records are sent to.
+	OutputBinding string `json:"outputBinding"`
+	// BindingOperation is the operation invoked on the output binding.
+	BindingOperation string `json:"bindingOperation"`
+	// PubsubName is the name of the pubsub component the records are published to.
+	PubsubName string `json:"pubsubName"`
+	// Topic is the topic the records are published to.
+	Topic string `json:"topic"`
+	// BufferSize is how many records can wait to be sent. Records are dropped and counted when the buffer is full.
+	BufferSize int `json:"bufferSize"`
+	// SendTimeout is how long sending a record can take.
+	SendTimeout time.Duration `json:"sendTimeout"`
+
+	redactPaths []jsonpath.Path
+}
+
+func getNativeMetadata(metadata middleware.Metadata) (*auditMiddlewareMetadata, error) {
+	middlewareMetadata := auditMiddlewareMetadata{
+		IdentityClaims:   []string{defaultIdentityClaims},
+		BindingOperation: defaultBindingOperation,
+		MaxBodySize:      defaultMaxBodySize,
+		BufferSize:       defaultBufferSize,
+		SendTimeout:      defaultSendTimeout,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.MaxBodySize <= 0 {
+		return nil, errors.New("maxBodySize must be greater than 0")
+	}
+	if middlewareMetadata.BufferSize <= 0 {
+		return nil, errors.New("bufferSize must be greater than 0")
+	}
+	if middlewareMetadata.SendTimeout <= 0 {
+		return nil, errors.New("sendTimeout must be greater than 0")
+	}
+	switch {
+	case middlewareMetadata.OutputBinding != "" && middlewareMetadata.PubsubName != "":
+		return nil, errors.New("only one of outputBinding or pubsubName can be set")
+	case middlewareMetadata.OutputBinding == "" && middlewareMetadata.PubsubName == "":
+		return nil, errors.New("one of outputBinding or pubsubName is required")
+	case middlewareMetadata.PubsubName != "" && middlewareMetadata.Topic == "":
+		return nil, errors.New("topic is required to publish the records")
+	}
+	middlewareMetadata.IdentityHeaders = trimList(middlewareMetadata.IdentityHeaders)
+	middlewareMetadata.IdentityClaims = trimList(middlewareMetadata.IdentityClaims)
+	middlewareMetadata.SkipPaths = trimList(middlewareMetadata.SkipPaths)
+
+	for _, field := range trimList(middlewareMetadata.RedactFields) {
+		path, err := jsonpath.Parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redactFields: %w", err)
+		}
+		if len(path) == 0 {
+			return nil, fmt.Errorf("invalid redactFields: %q selects the whole document", field)
+		}
+		middlewareMetadata.redactPaths = append(middlewareMetadata.redactPaths, path)
+	}
+
+	return &middlewareMetadata, nil
+}
+
+// skips returns true if the requests to the path aren't audited.
+func (md *auditMiddlewareMetadata) skips(path string) bool {
+	for _, prefix := range md.SkipPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func trimList(items []string) []string {
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/internal/jsonpath"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Record is the audit record of a request.
+type Record struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latencyMs"`
+	// RemoteAddr is the network address of the client.
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	// Caller is the identity of the caller, read from the configured headers and verified claims.
+	Caller *Caller `json:"caller,omitempty"`
+	// RequestBody and ResponseBody are the decoded JSON bodies, or the raw bodies as strings.
+	RequestBody           any  `json:"requestBody,omitempty"`
+	RequestBodyTruncated  bool `json:"requestBodyTruncated,omitempty"`
+	ResponseBody          any  `json:"responseBody,omitempty"`
+	ResponseBodyTruncated bool `json:"responseBodyTruncated,omitempty"`
+	// Dropped is how many records were dropped, because the buffer was full, since the previous record.
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// Caller identifies who made the request.
+type Caller struct {
+	// Subject is the first identity found, in the order of the headers and then of the claims.
+	Subject string            `json:"subject,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Claims  map[string]any    `json:"claims,omitempty"`
+	// Verified is true when the claims are those of a token verified by an authentication middleware. The caller
+	// is unverified when it's only identified by the headers, which can be set by the client.
+	Verified bool `json:"verified"`
+}
+
+// callerFrom returns the identity of the caller read from the headers, or nil if none of them is set.
+func (md *auditMiddlewareMetadata) callerFrom(r *http.Request) *Caller {
+	var c *Caller
+	for _, name := range md.IdentityHeaders {
+		if v := r.Header.Get(name); v != "" {
+			if c == nil {
+				c = &Caller{Subject: v, Headers: map[string]string{}}
+			}
+			c.Headers[name] = v
+		}
+	}
+	return c
+}
+
+// withVerifiedClaims adds the claims of the token verified by an authentication middleware, such as bearer, to
+// the caller. Claims are never read from tokens that weren't verified, so the caller is left unverified without
+// an authentication middleware.
+func (md *auditMiddlewareMetadata) withVerifiedClaims(c *Caller, r *http.Request) *Caller {
+	claims, ok := httputils.VerifiedClaims(r)
+	if !ok {
+		return c
+	}
+	for _, name := range md.IdentityClaims {
+		v, ok := claims[name]
+		if !ok {
+			continue
+		}
+		if c == nil {
+			c = &Caller{}
+		}
+		if c.Claims == nil {
+			c.Claims = map[string]any{}
+		}
+		c.Claims[name] = v
+		if s, ok := v.(string); ok && c.Subject == "" {
+			c.Subject = s
+		}
+		c.Verified = true
+	}
+	return c
+}
+
+// decodeBody returns the body to record: the decoded JSON document with the fields redacted, or the raw
+// body as a string. Bodies that can't be redacted are replaced with redactedValue, so that sensitive data
+// is never recorded.
+func (md *auditMiddlewareMetadata) decodeBody(body []byte, truncated bool) any {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if !truncated {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err == nil {
+			for _, path := range md.redactPaths {
+				doc, _ = jsonpath.Update(doc, path, func(any) (any, error) {
+					return redactedValue, nil
+				})
+			}
+			return doc
+		}
+	}
+	if len(md.redactPaths) > 0 {
+		return redactedValue
+	}
+	return string(body)
+}
+
+// captureRequestBody reads up to maxSize bytes of the request body, and restores the body so that the app
+// reads all of it.
+func captureRequestBody(r *http.Request, maxSize int64) (body []byte, truncated bool, err error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false, nil
+	}
+	body, err = io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	r.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if int64(len(body)) > maxSize {
+		return body[:maxSize], true, nil
+	}
+	return body, false, nil
+}
+
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder records the status code and up to maxSize bytes of the body of a response, while
+// passing it through to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	capture     bool
+	maxSize     int64
+	body        bytes.Buffer
+	truncated   bool
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.capture && !w.truncated {
+		if room := w.maxSize - int64(w.body.Len()); int64(len(b)) > room {
+			w.body.Write(b[:room])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+const contentTypeJSON = "application/json"
+
+// sink sends the serialized audit records.
+type sink interface {
+	send(ctx context.Context, data []byte) error
+}
+
+// bindingSink invokes an output binding with each record.
+type bindingSink struct {
+	binding   bindings.OutputBinding
+	operation bindings.OperationKind
+}
+
+func (s *bindingSink) send(ctx context.Context, data []byte) error {
+	_, err := s.binding.Invoke(ctx, &bindings.InvokeRequest{
+		Data:      data,
+		Operation: s.operation,
+		Metadata:  map[string]string{"contentType": contentTypeJSON},
+	})
+	return err
+}
+
+// pubsubSink publishes each record to a topic.
+type pubsubSink struct {
+	pubsub     pubsub.PubSub
+	pubsubName string
+	topic      string
+}
+
+func (s *pubsubSink) send(ctx context.Context, data []byte) error {
+	contentType := contentTypeJSON
+	return s.pubsub.Publish(ctx, &pubsub.PublishRequest{
+		Data:        data,
+		PubsubName:  s.pubsubName,
+		Topic:       s.topic,
+		ContentType: &contentType,
+	})
+}
+
+// auditLog sends the records in the background, so requests aren't slowed down by the sink. When the buffer is
+// full or a record can't be sent, the record is dropped and counted: the count is added to the next record
+// queued, so gaps in the audit trail can be detected.
+type auditLog struct {
+	sink    sink
+	timeout time.Duration
+	logger  logger.Logger
+	records chan *Record
+	dropped atomic.Uint64
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newAuditLog(s sink, bufferSize int, timeout time.Duration, logger logger.Logger) *auditLog {
+	l := &auditLog{
+		sink:    s,
+		timeout: timeout,
+		logger:  logger,
+		records: make(chan *Record, bufferSize),
+	}
+	var ctx context.Context
+	ctx, l.cancel = context.WithCancel(context.Background())
+	l.wg.Add(1)
+	go l.run(ctx)
+	return l
+}
+
+// close stops sending the records. The records already queued are sent first, within the send timeout.
+func (l *auditLog) close() {
+	l.cancel()
+	l.wg.Wait()
+}
+
+// log queues the record without blocking.
+func (l *auditLog) log(record *Record) {
+	record.Dropped = l.dropped.Swap(0)
+	select {
+	case l.records <- record:
+		if record.Dropped > 0 {
+			l.logger.Warnf("Audit log buffer was full, dropped %d records", record.Dropped)
+		}
+	default:
+		// Count this record too, as the previous drops weren't reported
+		l.dropped.Add(record.Dropped + 1)
+	}
+}
+
+func (l *auditLog) run(ctx context.Context) {
+	defer l.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			l.drain()
+			return
+		case record := <-l.records:
+			l.send(context.Background(), record)
+		}
+	}
+}
+
+// drain sends the records left in the buffer, until the send timeout expires.
+func (l *auditLog) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	for {
+		select {
+		case record := <-l.records:
+			if ctx.Err() != nil {
+				l.dropped.Add(record.Dropped + 1)
+				continue
+			}
+			l.send(ctx, record)
+		default:
+			if dropped := l.dropped.Load(); dropped > 0 {
+				l.logger.Warnf("Audit log closed, dropped %d records", dropped)
+			}
+			return
+		}
+	}
+}
+
+func (l *auditLog) send(ctx context.Context, record *Record) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		l.logger.Errorf("Failed to serialize audit record %s: %v", record.ID, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	err = l.sink.send(ctx, data)
+	cancel()
+	if err != nil {
+		l.logger.Errorf("Failed to send audit record %s: %v", record.ID, err)
+		// The record is lost, and so is the count of drops it carried
+		l.dropped.Add(record.Dropped + 1)
+	}
+}
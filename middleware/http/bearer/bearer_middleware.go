@@ -17,27 +17,33 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"sync"
 
-	oidc "github.com/coreos/go-oidc"
+	"github.com/benbjohnson/clock"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 
 	"github.com/dapr/components-contrib/internal/httputils"
-	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
 	"github.com/dapr/kit/logger"
 )
 
-type bearerMiddlewareMetadata struct {
-	IssuerURL string `json:"issuerURL"`
-	ClientID  string `json:"clientID"`
-}
-
 // NewBearerMiddleware returns a new oAuth2 middleware.
-func NewBearerMiddleware(_ logger.Logger) middleware.Middleware {
-	return &Middleware{}
+func NewBearerMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: logger,
+		clock:  clock.New(),
+	}
 }
 
 // Middleware is an oAuth2 authentication middleware.
-type Middleware struct{}
+type Middleware struct {
+	logger logger.Logger
+	clock  clock.Clock
+
+	lock sync.Mutex
+	// Cancel the refresh of the JWKS fetched by the handlers
+	cancels []context.CancelFunc
+}
 
 const (
 	bearerPrefix       = "bearer "
@@ -51,39 +57,82 @@ func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Ha
 		return nil, err
 	}
 
-	provider, err := oidc.NewProvider(context.Background(), meta.IssuerURL)
+	ctx, cancel := context.WithCancel(context.Background())
+	keys, err := meta.keyOption(ctx)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	m.lock.Lock()
+	m.cancels = append(m.cancels, cancel)
+	m.lock.Unlock()
 
-	verifier := provider.Verifier(&oidc.Config{
-		ClientID: meta.ClientID,
-	})
+	parseOpts := []jwt.ParseOption{
+		keys,
+		jwt.WithValidate(true),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithAcceptableSkew(meta.ClockSkew),
+		jwt.WithClock(jwt.ClockFunc(m.clock.Now)),
+	}
+	if meta.IssuerURL != "" {
+		parseOpts = append(parseOpts, jwt.WithIssuer(meta.IssuerURL))
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Never trust the forwarded claim headers sent by the client
+			for _, header := range meta.forwardClaims {
+				r.Header.Del(header)
+			}
+
 			authHeader := r.Header.Get("authorization")
 			if !strings.HasPrefix(strings.ToLower(authHeader), bearerPrefix) {
 				httputils.RespondWithError(w, http.StatusUnauthorized)
 				return
 			}
 			rawToken := authHeader[bearerPrefixLength:]
-			_, err := verifier.Verify(r.Context(), rawToken)
+			token, err := jwt.ParseString(rawToken, parseOpts...)
+			if err != nil {
+				m.logger.Debugf("bearer token rejected: %v", err)
+				httputils.RespondWithError(w, http.StatusUnauthorized)
+				return
+			}
+			if !meta.hasAudience(token) {
+				m.logger.Debugf("bearer token rejected: audience %v not accepted", token.Audience())
+				httputils.RespondWithError(w, http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := token.AsMap(r.Context())
 			if err != nil {
 				httputils.RespondWithError(w, http.StatusUnauthorized)
 				return
 			}
+			if rule := meta.ruleFor(r.URL.Path); rule != nil && !rule.allows(claims, meta.RolesClaim) {
+				httputils.RespondWithError(w, http.StatusForbidden)
+				return
+			}
+
+			for claim, header := range meta.forwardClaims {
+				if v, ok := claimString(claims[claim]); ok {
+					r.Header.Set(header, v)
+				}
+			}
 
-			next.ServeHTTP(w, r)
+			// The claims are available to the other middlewares, such as audit
+			next.ServeHTTP(w, httputils.SetVerifiedClaims(r, claims))
 		})
 	}, nil
 }
 
-func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*bearerMiddlewareMetadata, error) {
-	var middlewareMetadata bearerMiddlewareMetadata
-	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
-	if err != nil {
-		return nil, err
+// Close stops refreshing the JWKS fetched by the handlers.
+func (m *Middleware) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, cancel := range m.cancels {
+		cancel()
 	}
-	return &middlewareMetadata, nil
+	m.cancels = nil
+	return nil
 }
@@ -0,0 +1,326 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bearer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var testNow = time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+// mockedRequestHandler returns the forwarded claims in the response headers.
+func mockedRequestHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Got-Sub", r.Header.Get("X-User"))
+	w.Header().Set("X-Got-Groups", r.Header.Get("X-Groups"))
+	w.WriteHeader(http.StatusOK)
+}
+
+func newToken(t *testing.T, claims map[string]interface{}) jwt.Token {
+	t.Helper()
+
+	token := jwt.New()
+	require.NoError(t, token.Set(jwt.IssuerKey, "https://issuer.example.com"))
+	require.NoError(t, token.Set(jwt.SubjectKey, "alice"))
+	require.NoError(t, token.Set(jwt.AudienceKey, []string{"my-app"}))
+	require.NoError(t, token.Set(jwt.ExpirationKey, testNow.Add(time.Hour)))
+	for k, v := range claims {
+		require.NoError(t, token.Set(k, v))
+	}
+	return token
+}
+
+func hmacToken(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+
+	signed, err := jwt.Sign(newToken(t, claims), jwt.WithKey(jwa.HS256, []byte(testSecret)))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestBearerMiddleware(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(privKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, "key1"))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.RS256))
+	pubKey, err := key.PublicKey()
+	require.NoError(t, err)
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(pubKey))
+	jwks, err := json.Marshal(set)
+	require.NoError(t, err)
+	rsaToken, err := jwt.Sign(newToken(t, nil), jwt.WithKey(jwa.RS256, key))
+	require.NoError(t, err)
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwks)
+	}))
+	defer jwksServer.Close()
+
+	noExp := newToken(t, nil)
+	require.NoError(t, noExp.Remove(jwt.ExpirationKey))
+	noExpToken, err := jwt.Sign(noExp, jwt.WithKey(jwa.HS256, []byte(testSecret)))
+	require.NoError(t, err)
+
+	hmacProps := map[string]string{
+		"hmacSecret": testSecret,
+		"issuerURL":  "https://issuer.example.com",
+		"audiences":  "other-app,my-app",
+		"clockSkew":  "1m",
+	}
+	accessRulesProps := map[string]string{
+		"hmacSecret":  testSecret,
+		"clientID":    "my-app",
+		"accessRules": `[{"pathPrefix":"/v1.0/invoke","scopes":["invoke"]},{"pathPrefix":"/v1.0/invoke/admin","scopes":["invoke"],"roles":["admin","ops"]}]`,
+	}
+	forwardClaimsProps := map[string]string{
+		"hmacSecret":    testSecret,
+		"clientID":      "my-app",
+		"forwardClaims": "sub=x-user, groups=X-Groups",
+	}
+	reader := hmacToken(t, map[string]interface{}{"scope": "read"})
+	invoker := hmacToken(t, map[string]interface{}{"scope": "read invoke"})
+	admin := hmacToken(t, map[string]interface{}{"scp": []string{"invoke"}, "roles": []string{"ops"}})
+
+	tests := map[string]struct {
+		properties         map[string]string
+		path               string
+		token              string
+		headers            map[string]string
+		status             int
+		forwarded          map[string]string
+		shouldHandlerError bool
+	}{
+		"hmac": {
+			properties: hmacProps,
+			token:      hmacToken(t, nil),
+			status:     http.StatusOK,
+		},
+		"no token": {
+			properties: hmacProps,
+			status:     http.StatusUnauthorized,
+		},
+		"invalid signature": {
+			properties: hmacProps,
+			token:      hmacToken(t, nil) + "x",
+			status:     http.StatusUnauthorized,
+		},
+		"audience not accepted": {
+			properties: hmacProps,
+			token:      hmacToken(t, map[string]interface{}{jwt.AudienceKey: []string{"unknown"}}),
+			status:     http.StatusUnauthorized,
+		},
+		"issuer not accepted": {
+			properties: hmacProps,
+			token:      hmacToken(t, map[string]interface{}{jwt.IssuerKey: "https://evil.example.com"}),
+			status:     http.StatusUnauthorized,
+		},
+		"expired within clock skew": {
+			properties: hmacProps,
+			token:      hmacToken(t, map[string]interface{}{jwt.ExpirationKey: testNow.Add(-30 * time.Second)}),
+			status:     http.StatusOK,
+		},
+		"expired": {
+			properties: hmacProps,
+			token:      hmacToken(t, map[string]interface{}{jwt.ExpirationKey: testNow.Add(-2 * time.Minute)}),
+			status:     http.StatusUnauthorized,
+		},
+		"not before within clock skew": {
+			properties: hmacProps,
+			token:      hmacToken(t, map[string]interface{}{jwt.NotBeforeKey: testNow.Add(30 * time.Second)}),
+			status:     http.StatusOK,
+		},
+		"no expiration": {
+			properties: hmacProps,
+			token:      string(noExpToken),
+			status:     http.StatusUnauthorized,
+		},
+		"inline jwks": {
+			properties: map[string]string{"jwks": string(jwks), "clientID": "my-app"},
+			token:      string(rsaToken),
+			status:     http.StatusOK,
+		},
+		"inline jwks with hmac token": {
+			properties: map[string]string{"jwks": string(jwks), "clientID": "my-app"},
+			token:      hmacToken(t, nil),
+			status:     http.StatusUnauthorized,
+		},
+		"jwks url": {
+			properties: map[string]string{"jwksURL": jwksServer.URL, "clientID": "my-app"},
+			token:      string(rsaToken),
+			status:     http.StatusOK,
+		},
+		"jwks url with hmac token": {
+			properties: map[string]string{"jwksURL": jwksServer.URL, "clientID": "my-app"},
+			token:      hmacToken(t, nil),
+			status:     http.StatusUnauthorized,
+		},
+		"path without rule": {
+			properties: accessRulesProps,
+			path:       "/v1.0/state",
+			token:      reader,
+			status:     http.StatusOK,
+		},
+		"missing scope": {
+			properties: accessRulesProps,
+			path:       "/v1.0/invoke/app",
+			token:      reader,
+			status:     http.StatusForbidden,
+		},
+		"scope": {
+			properties: accessRulesProps,
+			path:       "/v1.0/invoke/app",
+			token:      invoker,
+			status:     http.StatusOK,
+		},
+		"missing role": {
+			properties: accessRulesProps,
+			path:       "/v1.0/invoke/admin",
+			token:      invoker,
+			status:     http.StatusForbidden,
+		},
+		"role": {
+			properties: accessRulesProps,
+			path:       "/v1.0/invoke/admin",
+			token:      admin,
+			status:     http.StatusOK,
+		},
+		"rule prefix matches whole segments": {
+			properties: accessRulesProps,
+			path:       "/v1.0/invoke/administration",
+			token:      invoker,
+			status:     http.StatusOK,
+		},
+		"rule prefix matches the cleaned path": {
+			properties: accessRulesProps,
+			path:       "/v1.0/invoke/app/../admin",
+			token:      invoker,
+			status:     http.StatusForbidden,
+		},
+		"forward claims": {
+			properties: forwardClaimsProps,
+			token:      hmacToken(t, map[string]interface{}{"groups": []string{"a", "b"}}),
+			status:     http.StatusOK,
+			forwarded:  map[string]string{"X-Got-Sub": "alice", "X-Got-Groups": "a,b"},
+		},
+		"forwarded claim headers sent by the client are replaced": {
+			properties: forwardClaimsProps,
+			token:      hmacToken(t, nil),
+			headers:    map[string]string{"X-User": "mallory", "X-Groups": "admin"},
+			status:     http.StatusOK,
+			forwarded:  map[string]string{"X-Got-Sub": "alice", "X-Got-Groups": ""},
+		},
+		"no keys": {
+			properties:         map[string]string{"clientID": "my-app"},
+			shouldHandlerError: true,
+		},
+		"no audience": {
+			properties:         map[string]string{"hmacSecret": testSecret},
+			shouldHandlerError: true,
+		},
+		"invalid hmac algorithm": {
+			properties:         map[string]string{"hmacSecret": testSecret, "clientID": "my-app", "hmacAlgorithm": "RS256"},
+			shouldHandlerError: true,
+		},
+		"negative clock skew": {
+			properties:         map[string]string{"hmacSecret": testSecret, "clientID": "my-app", "clockSkew": "-1s"},
+			shouldHandlerError: true,
+		},
+		"invalid forward claims": {
+			properties:         map[string]string{"hmacSecret": testSecret, "clientID": "my-app", "forwardClaims": "sub"},
+			shouldHandlerError: true,
+		},
+		"invalid access rules": {
+			properties:         map[string]string{"hmacSecret": testSecret, "clientID": "my-app", "accessRules": "{"},
+			shouldHandlerError: true,
+		},
+		"invalid jwks": {
+			properties:         map[string]string{"jwks": `{"keys":[{"kty":"unknown"}]}`, "clientID": "my-app"},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("bearer.test")
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewMock()
+			clk.Set(testNow)
+			m := NewBearerMiddleware(log).(*Middleware)
+			m.clock = clk
+			defer m.Close()
+
+			handler, err := m.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: test.properties}})
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			path := test.path
+			if path == "" {
+				path = "/"
+			}
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:3500"+path, nil)
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			handler(http.HandlerFunc(mockedRequestHandler)).ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code)
+			for k, v := range test.forwarded {
+				assert.Equal(t, v, w.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	m := NewBearerMiddleware(logger.NewLogger("bearer.test")).(*Middleware)
+	_, err := m.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"hmacSecret": testSecret,
+		"clientID":   "my-app",
+	}}})
+	require.NoError(t, err)
+	require.Len(t, m.cancels, 1)
+
+	require.NoError(t, m.Close())
+	assert.Empty(t, m.cancels)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bearer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	oidc "github.com/coreos/go-oidc"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+)
+
+const defaultRolesClaim = "roles"
+
+type bearerMiddlewareMetadata struct {
+	// IssuerURL is the expected issuer; unless static keys are configured, the keys are discovered from it.
+	IssuerURL string `json:"issuerURL"`
+	// ClientID is accepted as an audience.
+	ClientID string `json:"clientID"`
+	// Audiences is a list of accepted audiences; tokens must have at least one of them or the clientID.
+	// At least one of them or the clientID is required.
+	Audiences []string `json:"audiences"`
+	// ClockSkew is the tolerance when validating the exp, nbf and iat claims.
+	ClockSkew time.Duration `json:"clockSkew"`
+	// JWKSURL is the URL of a JWKS used instead of the one discovered from the issuer.
+	JWKSURL string `json:"jwksURL"`
+	// JWKS is a static JWKS, as JSON or the path of a file.
+	JWKS string `json:"jwks"`
+	// HMACSecret verifies tokens signed with a shared secret.
+	HMACSecret string `json:"hmacSecret"`
+	// HMACAlgorithm is HS256 (default), HS384 or HS512.
+	HMACAlgorithm string `json:"hmacAlgorithm"`
+	// AccessRules is a JSON array of scopes and roles required per path prefix.
+	AccessRules string `json:"accessRules"`
+	// RolesClaim is the claim holding the roles of the caller.
+	RolesClaim string `json:"rolesClaim"`
+	// ForwardClaims is a comma-separated list of "claim=Header" pairs forwarded to the app.
+	ForwardClaims string `json:"forwardClaims"`
+
+	accessRules   []accessRule
+	forwardClaims map[string]string
+}
+
+// accessRule lists the scopes and roles required for PathPrefix and its sub-paths.
+// All the scopes and at least one of the roles are required.
+type accessRule struct {
+	PathPrefix string   `json:"pathPrefix"`
+	Scopes     []string `json:"scopes"`
+	Roles      []string `json:"roles"`
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*bearerMiddlewareMetadata, error) {
+	middlewareMetadata := bearerMiddlewareMetadata{
+		HMACAlgorithm: jwa.HS256.String(),
+		RolesClaim:    defaultRolesClaim,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.IssuerURL == "" && middlewareMetadata.JWKSURL == "" &&
+		middlewareMetadata.JWKS == "" && middlewareMetadata.HMACSecret == "" {
+		return nil, errors.New("one of issuerURL, jwksURL, jwks or hmacSecret is required")
+	}
+	if middlewareMetadata.ClockSkew < 0 {
+		return nil, errors.New("clockSkew must not be negative")
+	}
+	if middlewareMetadata.ClientID != "" {
+		middlewareMetadata.Audiences = append(middlewareMetadata.Audiences, middlewareMetadata.ClientID)
+	}
+	if len(middlewareMetadata.Audiences) == 0 {
+		return nil, errors.New("one of clientID or audiences is required")
+	}
+
+	if middlewareMetadata.AccessRules != "" {
+		err = json.Unmarshal([]byte(middlewareMetadata.AccessRules), &middlewareMetadata.accessRules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid accessRules: %w", err)
+		}
+	}
+
+	middlewareMetadata.forwardClaims = map[string]string{}
+	for _, pair := range strings.Split(middlewareMetadata.ForwardClaims, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		claim, header, ok := strings.Cut(pair, "=")
+		claim = strings.TrimSpace(claim)
+		header = strings.TrimSpace(header)
+		if !ok || claim == "" || header == "" {
+			return nil, fmt.Errorf("invalid forwardClaims entry %q: expected claim=Header", pair)
+		}
+		middlewareMetadata.forwardClaims[claim] = http.CanonicalHeaderKey(header)
+	}
+
+	return &middlewareMetadata, nil
+}
+
+// keyOption returns the option verifying the token signature with the configured keys.
+// The keys fetched from a URL are refreshed until the context is canceled.
+func (md *bearerMiddlewareMetadata) keyOption(ctx context.Context) (jwt.ParseOption, error) {
+	switch {
+	case md.HMACSecret != "":
+		var alg jwa.SignatureAlgorithm
+		err := alg.Accept(md.HMACAlgorithm)
+		if err != nil || !strings.HasPrefix(alg.String(), "HS") {
+			return nil, fmt.Errorf("invalid hmacAlgorithm %q", md.HMACAlgorithm)
+		}
+		return jwt.WithKey(alg, []byte(md.HMACSecret)), nil
+
+	case md.JWKS != "":
+		data := []byte(md.JWKS)
+		if !strings.HasPrefix(strings.TrimSpace(md.JWKS), "{") {
+			var err error
+			data, err = os.ReadFile(md.JWKS)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read jwks file: %w", err)
+			}
+		}
+		set, err := jwk.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks: %w", err)
+		}
+		return jwt.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)), nil
+	}
+
+	jwksURL := md.JWKSURL
+	if jwksURL == "" {
+		provider, err := oidc.NewProvider(ctx, md.IssuerURL)
+		if err != nil {
+			return nil, err
+		}
+		var discovery struct {
+			JWKSURL string `json:"jwks_uri"`
+		}
+		err = provider.Claims(&discovery)
+		if err != nil || discovery.JWKSURL == "" {
+			return nil, fmt.Errorf("failed to discover the jwks_uri of issuer %s", md.IssuerURL)
+		}
+		jwksURL = discovery.JWKSURL
+	}
+
+	cache := jwk.NewCache(ctx)
+	err := cache.Register(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cache.Refresh(ctx, jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks from %s: %w", jwksURL, err)
+	}
+	return jwt.WithKeySet(jwk.NewCachedSet(cache, jwksURL), jws.WithInferAlgorithmFromKey(true)), nil
+}
+
+func (md *bearerMiddlewareMetadata) hasAudience(token jwt.Token) bool {
+	for _, aud := range token.Audience() {
+		for _, accepted := range md.Audiences {
+			if aud == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ruleFor returns the access rule with the longest path prefix matching the path.
+func (md *bearerMiddlewareMetadata) ruleFor(path string) *accessRule {
+	var match *accessRule
+	for i := range md.accessRules {
+		rule := &md.accessRules[i]
+		if httputils.HasPathPrefix(path, rule.PathPrefix) && (match == nil || len(rule.PathPrefix) > len(match.PathPrefix)) {
+			match = rule
+		}
+	}
+	return match
+}
+
+func (r *accessRule) allows(claims map[string]interface{}, rolesClaim string) bool {
+	if len(r.Scopes) > 0 {
+		// OAuth2 uses a space-separated "scope" claim, some providers a "scp" list
+		granted := claimStrings(claims["scope"])
+		granted = append(granted, claimStrings(claims["scp"])...)
+		for _, scope := range r.Scopes {
+			if !contains(granted, scope) {
+				return false
+			}
+		}
+	}
+	if len(r.Roles) > 0 {
+		roles := claimStrings(claims[rolesClaim])
+		for _, role := range r.Roles {
+			if contains(roles, role) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func claimStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []string:
+		return val
+	case []interface{}:
+		res := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// claimString formats a claim as a header value; lists are joined with commas and objects serialized as JSON.
+func claimString(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return val, true
+	case bool:
+		return strconv.FormatBool(val), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case time.Time:
+		return strconv.FormatInt(val.Unix(), 10), true
+	case []string:
+		return strings.Join(val, ","), true
+	case []interface{}:
+		if s := claimStrings(val); len(s) == len(val) {
+			return strings.Join(s, ","), true
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
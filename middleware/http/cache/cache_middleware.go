@@ -0,0 +1,420 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	headerCacheStatus = "X-Cache"
+	cacheStatusHit    = "HIT"
+	cacheStatusMiss   = "MISS"
+)
+
+// NewMiddleware returns a new response cache middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: logger,
+		clock:  clock.New(),
+	}
+}
+
+var _ components.ResolverConsumer = (*Middleware)(nil)
+
+// Middleware is a response cache middleware.
+type Middleware struct {
+	logger   logger.Logger
+	clock    clock.Clock
+	resolver components.Resolver
+
+	lock sync.Mutex
+	// In-memory state stores created by the handlers, which are closed with the middleware
+	stores []state.Store
+}
+
+// SetComponentResolver sets the resolver of the state store named in the metadata.
+func (m *Middleware) SetComponentResolver(resolver components.Resolver) {
+	m.resolver = resolver
+}
+
+// cachedResponse is a response stored in the state store.
+type cachedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	ETag       string      `json:"etag"`
+	StoredAt   time.Time   `json:"storedAt"`
+	Expires    time.Time   `json:"expires"`
+}
+
+// varyIndex is stored under the URL key and lists the request headers the cached responses vary by.
+type varyIndex struct {
+	Vary []string `json:"vary"`
+}
+
+type responseCache struct {
+	meta   *cacheMiddlewareMetadata
+	store  state.Store
+	clock  clock.Clock
+	logger logger.Logger
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var store state.Store
+	switch {
+	case meta.StateStore == "":
+		store = inmemory.NewInMemoryStateStore(m.logger)
+		err = store.Init(state.Metadata{})
+		if err != nil {
+			return nil, err
+		}
+		m.lock.Lock()
+		m.stores = append(m.stores, store)
+		m.lock.Unlock()
+	case m.resolver == nil:
+		return nil, errors.New("stateStore can't be used: the host doesn't provide other components")
+	default:
+		store, err = m.resolver.StateStore(meta.StateStore)
+		if err != nil {
+			return nil, fmt.Errorf("error getting state store %s: %w", meta.StateStore, err)
+		}
+	}
+
+	c := &responseCache{
+		meta:   meta,
+		store:  store,
+		clock:  m.clock,
+		logger: m.logger,
+	}
+	return c.handler, nil
+}
+
+// Close closes the in-memory state stores created by the handlers. The state stores resolved by name are owned
+// by the host, and are left open.
+func (m *Middleware) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var err error
+	for _, store := range m.stores {
+		if closer, ok := store.(io.Closer); ok {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}
+	m.stores = nil
+	return err
+}
+
+func (c *responseCache) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		baseKey := c.meta.KeyPrefix + "||" + r.Host + "||" + r.URL.RequestURI()
+
+		purge := c.purgeAuthorized(r)
+		if purge {
+			c.purge(ctx, baseKey)
+		}
+		// The token is not forwarded to the app
+		r.Header.Del(c.meta.PurgeHeader)
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// Successful unsafe requests invalidate the cached response for the URL
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			if sw.status >= 200 && sw.status < 400 {
+				c.purge(ctx, baseKey)
+			}
+			return
+		}
+
+		reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+		ttl := c.meta.ttlFor(r.URL.Path)
+		if _, ok := reqCC["no-store"]; ok || ttl == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, noCache := reqCC["no-cache"]
+		if !noCache && reqCC["max-age"] != "0" && !purge {
+			if entry := c.lookup(ctx, r, baseKey); entry != nil {
+				c.serveCached(w, r, entry)
+				return
+			}
+		}
+
+		w.Header().Set(headerCacheStatus, cacheStatusMiss)
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, maxSize: c.meta.MaxBodySize}
+		next.ServeHTTP(rec, r)
+		if rec.passthrough {
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			if entryTTL, ok := cacheableTTL(r, rec, ttl); ok {
+				if w.Header().Get("ETag") == "" {
+					w.Header().Set("ETag", computeETag(rec.buf.Bytes()))
+				}
+				c.save(ctx, r, baseKey, rec, entryTTL)
+			}
+		}
+
+		if etag := w.Header().Get("ETag"); rec.status == http.StatusOK && etag != "" && etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.buf.Bytes())
+	})
+}
+
+// lookup returns the fresh cached response for the request, if any.
+func (c *responseCache) lookup(ctx context.Context, r *http.Request, baseKey string) *cachedResponse {
+	var index varyIndex
+	if !c.get(ctx, baseKey, &index) {
+		return nil
+	}
+	var entry cachedResponse
+	if !c.get(ctx, variantKey(baseKey, index.Vary, r), &entry) {
+		return nil
+	}
+	if !c.clock.Now().Before(entry.Expires) {
+		return nil
+	}
+	return &entry
+}
+
+func (c *responseCache) serveCached(w http.ResponseWriter, r *http.Request, entry *cachedResponse) {
+	h := w.Header()
+	if etagMatches(r.Header.Get("If-None-Match"), entry.ETag) {
+		for _, k := range []string{"ETag", "Cache-Control", "Vary", "Expires", "Content-Location"} {
+			if v := entry.Header.Values(k); len(v) > 0 {
+				h[http.CanonicalHeaderKey(k)] = v
+			}
+		}
+		h.Set(headerCacheStatus, cacheStatusHit)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	for k, v := range entry.Header {
+		h[k] = v
+	}
+	h.Set(headerCacheStatus, cacheStatusHit)
+	h.Set("Age", strconv.FormatInt(int64(c.clock.Since(entry.StoredAt).Seconds()), 10))
+	h.Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	w.WriteHeader(entry.StatusCode)
+	if r.Method != http.MethodHead {
+		w.Write(entry.Body)
+	}
+}
+
+func (c *responseCache) save(ctx context.Context, r *http.Request, baseKey string, rec *recorder, ttl time.Duration) {
+	header := rec.Header().Clone()
+	for _, k := range []string{headerCacheStatus, "Set-Cookie", "Connection", "Keep-Alive", "Transfer-Encoding", "Content-Length"} {
+		header.Del(k)
+	}
+
+	now := c.clock.Now()
+	vary := varyHeaders(rec.Header())
+	entry := cachedResponse{
+		StatusCode: rec.status,
+		Header:     header,
+		Body:       rec.buf.Bytes(),
+		ETag:       rec.Header().Get("ETag"),
+		StoredAt:   now,
+		Expires:    now.Add(ttl),
+	}
+
+	err := c.set(ctx, variantKey(baseKey, vary, r), entry, ttl)
+	if err == nil {
+		err = c.set(ctx, baseKey, varyIndex{Vary: vary}, ttl)
+	}
+	if err != nil {
+		c.logger.Warnf("failed to cache response for %s: %v", r.URL.RequestURI(), err)
+	}
+}
+
+// purgeAuthorized returns true if the request asks to purge the cached response with the purge token.
+func (c *responseCache) purgeAuthorized(r *http.Request) bool {
+	token := r.Header.Get(c.meta.PurgeHeader)
+	if c.meta.PurgeToken == "" || token == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.meta.PurgeToken)) != 1 {
+		c.logger.Debugf("ignoring purge of %s with an invalid token", r.URL.RequestURI())
+		return false
+	}
+	return true
+}
+
+// purge evicts the cached responses for a URL. Removing the index makes all the variants unreachable.
+func (c *responseCache) purge(ctx context.Context, baseKey string) {
+	err := c.store.Delete(ctx, &state.DeleteRequest{Key: baseKey})
+	if err != nil {
+		c.logger.Warnf("failed to purge cached response %s: %v", baseKey, err)
+	}
+}
+
+func (c *responseCache) get(ctx context.Context, key string, v interface{}) bool {
+	res, err := c.store.Get(ctx, &state.GetRequest{Key: key})
+	if err != nil {
+		c.logger.Warnf("failed to read cached response %s: %v", key, err)
+		return false
+	}
+	if res == nil || len(res.Data) == 0 {
+		return false
+	}
+	return json.Unmarshal(res.Data, v) == nil
+}
+
+func (c *responseCache) set(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, &state.SetRequest{
+		Key:   key,
+		Value: data,
+		Metadata: map[string]string{
+			"ttlInSeconds": strconv.FormatInt(int64(ttl.Seconds()+1), 10),
+		},
+	})
+}
+
+// cacheableTTL returns how long the response can be cached, honoring its Cache-Control header.
+func cacheableTTL(r *http.Request, rec *recorder, routeTTL time.Duration) (time.Duration, bool) {
+	if rec.status != http.StatusOK || rec.Header().Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	for _, v := range varyHeaders(rec.Header()) {
+		if v == "*" {
+			return 0, false
+		}
+	}
+
+	cc := parseCacheControl(rec.Header().Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[directive]; ok {
+			return 0, false
+		}
+	}
+	_, public := cc["public"]
+	sMaxAge, hasSMaxAge := cc["s-maxage"]
+	if r.Header.Get("Authorization") != "" && !public && !hasSMaxAge {
+		// Authenticated responses are only shared when explicitly allowed
+		return 0, false
+	}
+	if r.Header.Get("Cookie") != "" && !public {
+		// Responses to requests with cookies may be personalized
+		return 0, false
+	}
+
+	ttl := routeTTL
+	maxAge, hasMaxAge := cc["max-age"]
+	if hasSMaxAge {
+		maxAge, hasMaxAge = sMaxAge, true
+	}
+	if hasMaxAge {
+		secs, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0, false
+		}
+		ttl = time.Duration(secs) * time.Second
+	}
+	return ttl, ttl > 0
+}
+
+// parseCacheControl returns the directives of a Cache-Control header, with their values.
+func parseCacheControl(header string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return directives
+}
+
+func varyHeaders(h http.Header) []string {
+	var vary []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return vary
+}
+
+// variantKey returns the key of the response for the values of the request headers it varies by.
+func variantKey(baseKey string, vary []string, r *http.Request) string {
+	h := sha256.New()
+	for _, name := range vary {
+		h.Write([]byte(name + ":" + strings.Join(r.Header.Values(name), ",") + "\n"))
+	}
+	return baseKey + "||" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison of If-None-Match.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
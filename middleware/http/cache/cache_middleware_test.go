@@ -0,0 +1,429 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+// mockedApp acts like an upstream service: GET requests return the number of calls and the Accept-Language
+// header, other requests return 204.
+type mockedApp struct {
+	calls   int
+	headers map[string]string
+	body    string
+	purge   string
+}
+
+func (a *mockedApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.calls++
+	a.purge = r.Header.Get("X-Cache-Purge")
+	for k, v := range a.headers {
+		w.Header().Set(k, v)
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	body := a.body
+	if body == "" {
+		body = "response " + strconv.Itoa(a.calls) + " lang=" + r.Header.Get("Accept-Language")
+	}
+	w.Write([]byte(body))
+}
+
+// stateStores resolves the state stores of the map.
+type stateStores map[string]state.Store
+
+func (s stateStores) StateStore(name string) (state.Store, error) {
+	if store, ok := s[name]; ok {
+		return store, nil
+	}
+	return nil, errors.New("state store " + name + " not found")
+}
+
+func (s stateStores) SecretStore(name string) (secretstores.SecretStore, error) {
+	return nil, errors.New("secret store " + name + " not found")
+}
+
+func (s stateStores) OutputBinding(name string) (bindings.OutputBinding, error) {
+	return nil, errors.New("output binding " + name + " not found")
+}
+
+func (s stateStores) PubSub(name string) (pubsub.PubSub, error) {
+	return nil, errors.New("pubsub " + name + " not found")
+}
+
+// cacheRequest is a request sent to the middleware after advancing the clock, with the response expected.
+type cacheRequest struct {
+	advance     time.Duration
+	method      string
+	host        string
+	path        string
+	headers     map[string]string
+	status      int
+	body        *string
+	cacheStatus string
+	age         string
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestCache(t *testing.T) {
+	tests := map[string]struct {
+		meta               middleware.Metadata
+		app                *mockedApp
+		requests           []cacheRequest
+		calls              int
+		shouldHandlerError bool
+	}{
+		"hit and expiry": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"defaultTTL": "10s",
+			}}},
+			requests: []cacheRequest{
+				{path: "/v1.0/state/a", body: str("response 1 lang="), cacheStatus: "MISS"},
+				{advance: 5 * time.Second, path: "/v1.0/state/a", body: str("response 1 lang="), cacheStatus: "HIT", age: "5"},
+				{method: http.MethodHead, path: "/v1.0/state/a", body: str(""), cacheStatus: "HIT"},
+				// The query is part of the key
+				{path: "/v1.0/state/a?x=1", cacheStatus: "MISS"},
+				{advance: 5 * time.Second, path: "/v1.0/state/a", body: str("response 3 lang="), cacheStatus: "MISS"},
+			},
+			calls: 3,
+		},
+		"per-route TTL": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"defaultTTL": "10s",
+				"routes":     `[{"pathPrefix":"/v1.0/invoke/app","ttl":"1m"},{"pathPrefix":"/v1.0/invoke/app/method/live","ttl":"0s"}]`,
+			}}},
+			requests: []cacheRequest{
+				{path: "/v1.0/invoke/app/method/list", cacheStatus: "MISS"},
+				{advance: 30 * time.Second, path: "/v1.0/invoke/app/method/list", cacheStatus: "HIT"},
+				{path: "/v1.0/invoke/app/method/live"},
+				{path: "/v1.0/invoke/app/method/live"},
+			},
+			calls: 3,
+		},
+		"conditional request": {
+			app: &mockedApp{headers: map[string]string{"ETag": `"v1"`}},
+			requests: []cacheRequest{
+				{path: "/items", cacheStatus: "MISS"},
+				{path: "/items", headers: map[string]string{"If-None-Match": `"other", W/"v1"`}, status: http.StatusNotModified, body: str(""), cacheStatus: "HIT"},
+				{path: "/items", headers: map[string]string{"If-None-Match": `"other"`}, cacheStatus: "HIT"},
+			},
+			calls: 1,
+		},
+		"conditional request on miss": {
+			app: &mockedApp{headers: map[string]string{"ETag": `W/"v1"`}},
+			requests: []cacheRequest{
+				{path: "/items", headers: map[string]string{"If-None-Match": `"v1"`}, status: http.StatusNotModified, cacheStatus: "MISS"},
+			},
+			calls: 1,
+		},
+		"request no-cache and no-store": {
+			requests: []cacheRequest{
+				{path: "/items", body: str("response 1 lang=")},
+				{path: "/items", headers: map[string]string{"Cache-Control": "no-cache"}, body: str("response 2 lang="), cacheStatus: "MISS"},
+				{path: "/items", body: str("response 2 lang="), cacheStatus: "HIT"},
+				{path: "/items", headers: map[string]string{"Cache-Control": "no-store"}, body: str("response 3 lang=")},
+			},
+			calls: 3,
+		},
+		"response no-store": {
+			app: &mockedApp{headers: map[string]string{"Cache-Control": "no-store"}},
+			requests: []cacheRequest{
+				{path: "/items"},
+				{path: "/items", cacheStatus: "MISS"},
+			},
+			calls: 2,
+		},
+		"response private": {
+			app: &mockedApp{headers: map[string]string{"Cache-Control": "private, max-age=60"}},
+			requests: []cacheRequest{
+				{path: "/items"},
+				{path: "/items", cacheStatus: "MISS"},
+			},
+			calls: 2,
+		},
+		"response public max-age": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"defaultTTL": "10s",
+			}}},
+			app: &mockedApp{headers: map[string]string{"Cache-Control": "public, max-age=120"}},
+			requests: []cacheRequest{
+				{path: "/items", headers: map[string]string{"Authorization": "Bearer x"}},
+				{advance: time.Minute, path: "/items", cacheStatus: "HIT"},
+			},
+			calls: 1,
+		},
+		"authorized request": {
+			requests: []cacheRequest{
+				{path: "/items", headers: map[string]string{"Authorization": "Bearer x"}},
+				{path: "/items", headers: map[string]string{"Authorization": "Bearer x"}, cacheStatus: "MISS"},
+			},
+			calls: 2,
+		},
+		"request with cookie": {
+			requests: []cacheRequest{
+				{path: "/items", headers: map[string]string{"Cookie": "session=x"}},
+				{path: "/items", cacheStatus: "MISS"},
+				{path: "/items", cacheStatus: "HIT"},
+			},
+			calls: 2,
+		},
+		"request with cookie and response public": {
+			app: &mockedApp{headers: map[string]string{"Cache-Control": "public"}},
+			requests: []cacheRequest{
+				{path: "/items", headers: map[string]string{"Cookie": "session=x"}},
+				{path: "/items", cacheStatus: "HIT"},
+			},
+			calls: 1,
+		},
+		"host is part of the key": {
+			requests: []cacheRequest{
+				{host: "a.example.com", path: "/items", body: str("response 1 lang=")},
+				{host: "b.example.com", path: "/items", body: str("response 2 lang="), cacheStatus: "MISS"},
+				{host: "a.example.com", path: "/items", body: str("response 1 lang="), cacheStatus: "HIT"},
+			},
+			calls: 2,
+		},
+		"route prefixes match whole segments": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"routes": `[{"pathPrefix":"/api","ttl":"0s"}]`,
+			}}},
+			requests: []cacheRequest{
+				{path: "/api/items", body: str("response 1 lang=")},
+				{path: "/api/items", body: str("response 2 lang=")},
+				{path: "/apiadmin", cacheStatus: "MISS"},
+				{path: "/apiadmin", cacheStatus: "HIT"},
+			},
+			calls: 3,
+		},
+		"vary": {
+			app: &mockedApp{headers: map[string]string{"Vary": "Accept-Language"}},
+			requests: []cacheRequest{
+				{path: "/items", headers: map[string]string{"Accept-Language": "en"}, body: str("response 1 lang=en")},
+				{path: "/items", headers: map[string]string{"Accept-Language": "fr"}, body: str("response 2 lang=fr")},
+				{path: "/items", headers: map[string]string{"Accept-Language": "en"}, body: str("response 1 lang=en"), cacheStatus: "HIT"},
+				{path: "/items", headers: map[string]string{"Accept-Language": "fr"}, body: str("response 2 lang=fr"), cacheStatus: "HIT"},
+			},
+			calls: 2,
+		},
+		"unsafe request invalidates the URL": {
+			requests: []cacheRequest{
+				{path: "/items"},
+				{method: http.MethodPut, path: "/items", status: http.StatusNoContent},
+				{path: "/items", body: str("response 3 lang="), cacheStatus: "MISS"},
+			},
+			calls: 3,
+		},
+		"body larger than maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"maxBodySize": "50",
+			}}},
+			app: &mockedApp{body: strings.Repeat("x", 100)},
+			requests: []cacheRequest{
+				{path: "/items", body: str(strings.Repeat("x", 100))},
+				{path: "/items", body: str(strings.Repeat("x", 100)), cacheStatus: "MISS"},
+			},
+			calls: 2,
+		},
+		"purge with token": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"purgeToken": "s3cr3t",
+			}}},
+			requests: []cacheRequest{
+				{path: "/items"},
+				{path: "/items", headers: map[string]string{"X-Cache-Purge": "s3cr3t"}, body: str("response 2 lang="), cacheStatus: "MISS"},
+				{path: "/items", body: str("response 2 lang="), cacheStatus: "HIT"},
+			},
+			calls: 2,
+		},
+		"purge with invalid token": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"purgeToken": "s3cr3t",
+			}}},
+			requests: []cacheRequest{
+				{path: "/items"},
+				{path: "/items", headers: map[string]string{"X-Cache-Purge": "guess"}, body: str("response 1 lang="), cacheStatus: "HIT"},
+			},
+			calls: 1,
+		},
+		"purge disabled by default": {
+			requests: []cacheRequest{
+				{path: "/items"},
+				{path: "/items", headers: map[string]string{"X-Cache-Purge": "true"}, body: str("response 1 lang="), cacheStatus: "HIT"},
+			},
+			calls: 1,
+		},
+		"negative defaultTTL": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"defaultTTL": "-1s",
+			}}},
+			shouldHandlerError: true,
+		},
+		"invalid maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"maxBodySize": "0",
+			}}},
+			shouldHandlerError: true,
+		},
+		"invalid route TTL": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"routes": `[{"pathPrefix":"/a","ttl":"forever"}]`,
+			}}},
+			shouldHandlerError: true,
+		},
+		"unknown state store": {
+			meta: middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"stateStore": "unknown",
+			}}},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("cache.test")
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewMock()
+			cacheMiddleware := NewMiddleware(log).(*Middleware)
+			cacheMiddleware.clock = clk
+			cacheMiddleware.SetComponentResolver(stateStores{})
+			defer cacheMiddleware.Close()
+
+			handler, err := cacheMiddleware.GetHandler(test.meta)
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			app := test.app
+			if app == nil {
+				app = &mockedApp{}
+			}
+			h := handler(app)
+
+			for i, req := range test.requests {
+				clk.Add(req.advance)
+				method := req.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				host := req.host
+				if host == "" {
+					host = "localhost:3500"
+				}
+				r := httptest.NewRequest(method, "http://"+host+req.path, nil)
+				for k, v := range req.headers {
+					r.Header.Set(k, v)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				status := req.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				assert.Equal(t, status, w.Code, "request %d", i)
+				if req.body != nil {
+					assert.Equal(t, *req.body, w.Body.String(), "request %d", i)
+				}
+				if req.cacheStatus != "" {
+					assert.Equal(t, req.cacheStatus, w.Header().Get("X-Cache"), "request %d", i)
+				}
+				if req.age != "" {
+					assert.Equal(t, req.age, w.Header().Get("Age"), "request %d", i)
+				}
+			}
+			assert.Equal(t, test.calls, app.calls)
+			assert.Empty(t, app.purge, "the purge token is not forwarded to the app")
+		})
+	}
+}
+
+func TestCacheStateStore(t *testing.T) {
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("cache.test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+	meta := middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"stateStore": "statestore",
+	}}}
+	app := &mockedApp{}
+
+	// Replicas share the cache
+	for _, expected := range []string{"MISS", "HIT"} {
+		cacheMiddleware := NewMiddleware(logger.NewLogger("cache.test")).(*Middleware)
+		cacheMiddleware.SetComponentResolver(stateStores{"statestore": store})
+		handler, err := cacheMiddleware.GetHandler(meta)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/items", nil)
+		w := httptest.NewRecorder()
+		handler(app).ServeHTTP(w, r)
+		assert.Equal(t, expected, w.Header().Get("X-Cache"))
+	}
+	assert.Equal(t, 1, app.calls)
+
+	t.Run("no resolver", func(t *testing.T) {
+		_, err := NewMiddleware(logger.NewLogger("cache.test")).GetHandler(meta)
+		require.Error(t, err)
+	})
+}
+
+func TestClose(t *testing.T) {
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("cache.test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+	require.NoError(t, store.Set(context.Background(), &state.SetRequest{Key: "a", Value: "b"}))
+
+	cacheMiddleware := NewMiddleware(logger.NewLogger("cache.test")).(*Middleware)
+	cacheMiddleware.SetComponentResolver(stateStores{"statestore": store})
+	_, err := cacheMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"stateStore": "statestore",
+	}}})
+	require.NoError(t, err)
+	_, err = cacheMiddleware.GetHandler(middleware.Metadata{})
+	require.NoError(t, err)
+	require.Len(t, cacheMiddleware.stores, 1)
+
+	require.NoError(t, cacheMiddleware.Close())
+	assert.Empty(t, cacheMiddleware.stores)
+
+	// The state store resolved by name is left open
+	res, err := store.Get(context.Background(), &state.GetRequest{Key: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, `"b"`, string(res.Data))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+)
+
+const (
+	defaultTTL         = time.Minute
+	defaultMaxBodySize = 1 << 20
+	defaultKeyPrefix   = "dapr-cache"
+	defaultPurgeHeader = "X-Cache-Purge"
+)
+
+type cacheMiddlewareMetadata struct {
+	// DefaultTTL is how long responses are cached when neither the route nor the response set a TTL.
+	DefaultTTL time.Duration `json:"defaultTTL"`
+	// Routes is a JSON array of per-route TTLs; a TTL of 0 disables caching for the route.
+	Routes string `json:"routes"`
+	// MaxBodySize is the size of the largest response body that is cached.
+	MaxBodySize int64 `json:"maxBodySize"`
+	// KeyPrefix is prepended to the keys stored in the state store.
+	KeyPrefix string `json:"keyPrefix"`
+	// PurgeHeader is the request header that, when set to PurgeToken, evicts the cached response for the URL.
+	PurgeHeader string `json:"purgeHeader"`
+	// PurgeToken is the secret value of PurgeHeader that authorizes purges. Purging is disabled when it's not set.
+	PurgeToken string `json:"purgeToken"`
+	// StateStore is the name of the state store the responses are cached in. Responses are cached in memory
+	// when it's not set.
+	StateStore string `json:"stateStore"`
+
+	routes []routeTTL
+}
+
+// routeTTL is the TTL of the responses for PathPrefix and its sub-paths.
+type routeTTL struct {
+	PathPrefix string `json:"pathPrefix"`
+	TTL        string `json:"ttl"`
+
+	ttl time.Duration
+}
+
+func getNativeMetadata(metadata middleware.Metadata) (*cacheMiddlewareMetadata, error) {
+	middlewareMetadata := cacheMiddlewareMetadata{
+		DefaultTTL:  defaultTTL,
+		MaxBodySize: defaultMaxBodySize,
+		KeyPrefix:   defaultKeyPrefix,
+		PurgeHeader: defaultPurgeHeader,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.DefaultTTL < 0 {
+		return nil, errors.New("defaultTTL must not be negative")
+	}
+	if middlewareMetadata.MaxBodySize <= 0 {
+		return nil, errors.New("maxBodySize must be greater than 0")
+	}
+
+	if middlewareMetadata.Routes != "" {
+		err = json.Unmarshal([]byte(middlewareMetadata.Routes), &middlewareMetadata.routes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routes: %w", err)
+		}
+	}
+	for i := range middlewareMetadata.routes {
+		r := &middlewareMetadata.routes[i]
+		r.ttl, err = time.ParseDuration(r.TTL)
+		if err != nil || r.ttl < 0 {
+			return nil, fmt.Errorf("invalid ttl %q for route %s", r.TTL, r.PathPrefix)
+		}
+	}
+
+	return &middlewareMetadata, nil
+}
+
+// ttlFor returns the TTL of the route with the longest prefix matching the path.
+func (md *cacheMiddlewareMetadata) ttlFor(path string) time.Duration {
+	ttl := md.DefaultTTL
+	matched := -1
+	for _, r := range md.routes {
+		if httputils.HasPathPrefix(path, r.PathPrefix) && len(r.PathPrefix) > matched {
+			ttl = r.ttl
+			matched = len(r.PathPrefix)
+		}
+	}
+	return ttl
+}
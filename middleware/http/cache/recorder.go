@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// recorder buffers a 200 response so it can be cached. Other responses, responses larger than maxSize and
+// flushed responses are passed through to the client.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	passthrough bool
+	maxSize     int64
+	buf         bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	if status != http.StatusOK {
+		r.startPassthrough()
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.passthrough && int64(r.buf.Len()+len(b)) > r.maxSize {
+		r.startPassthrough()
+	}
+	if r.passthrough {
+		return r.ResponseWriter.Write(b)
+	}
+	return r.buf.Write(b)
+}
+
+func (r *recorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.startPassthrough()
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// startPassthrough sends the status and the buffered body, and stops buffering.
+func (r *recorder) startPassthrough() {
+	if r.passthrough {
+		return
+	}
+	r.passthrough = true
+	r.ResponseWriter.WriteHeader(r.status)
+	if r.buf.Len() > 0 {
+		r.ResponseWriter.Write(r.buf.Bytes())
+		r.buf.Reset()
+	}
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
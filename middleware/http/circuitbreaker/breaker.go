@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/dapr/kit/logger"
+)
+
+type state int
+
+const (
+	stateClosed state = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s state) String() string {
+	switch s {
+	case stateClosed:
+		return "closed"
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker is a circuit breaker. While closed, it counts the failures and opens when the trip condition is met.
+// Once open, it rejects the requests until openTimeout has elapsed; it then becomes half-open and lets up to
+// halfOpenRequests probe requests through. The breaker closes if all the probes succeed, and opens again on the
+// first failure.
+type breaker struct {
+	cfg    *breakerConfig
+	clock  clock.Clock
+	logger logger.Logger
+
+	lock     sync.Mutex
+	state    state
+	openedAt time.Time
+	// generation is incremented on every state change, so the results of the requests allowed before the
+	// change are ignored.
+	generation uint64
+
+	// Closed state
+	consecutiveFailures int
+	windowStart         time.Time
+	requests            int
+	failures            int
+
+	// Half-open state
+	probes         int
+	probeSuccesses int
+}
+
+func newBreaker(cfg *breakerConfig, clk clock.Clock, logger logger.Logger) *breaker {
+	return &breaker{
+		cfg:         cfg,
+		clock:       clk,
+		logger:      logger,
+		windowStart: clk.Now(),
+	}
+}
+
+// allow returns true and the current generation if the request can be sent. If it can't, it returns the time
+// left before the breaker lets probes through.
+func (b *breaker) allow() (bool, uint64, time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == stateOpen {
+		elapsed := b.clock.Since(b.openedAt)
+		if elapsed < b.cfg.OpenTimeout {
+			return false, b.generation, b.cfg.OpenTimeout - elapsed
+		}
+		b.setState(stateHalfOpen)
+	}
+
+	if b.state == stateHalfOpen {
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return false, b.generation, 0
+		}
+		b.probes++
+	}
+	return true, b.generation, 0
+}
+
+// record records the result of a request allowed in the generation.
+func (b *breaker) record(generation uint64, success bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case stateHalfOpen:
+		if !success {
+			b.setState(stateOpen)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.cfg.HalfOpenRequests {
+			b.setState(stateClosed)
+		}
+
+	case stateClosed:
+		if b.cfg.Trip == tripConsecutiveFailures {
+			if success {
+				b.consecutiveFailures = 0
+				return
+			}
+			b.consecutiveFailures++
+			if b.consecutiveFailures >= b.cfg.ConsecutiveFailures {
+				b.setState(stateOpen)
+			}
+			return
+		}
+
+		now := b.clock.Now()
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+		b.requests++
+		if !success {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.ErrorRatio {
+			b.setState(stateOpen)
+		}
+	}
+}
+
+// currentState returns the state of the breaker.
+func (b *breaker) currentState() state {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// setState changes the state and resets the counters. It must be called with the lock held.
+func (b *breaker) setState(s state) {
+	from := b.state
+	b.state = s
+	b.generation++
+	b.consecutiveFailures = 0
+	b.requests = 0
+	b.failures = 0
+	b.probes = 0
+	b.probeSuccesses = 0
+
+	now := b.clock.Now()
+	b.windowStart = now
+	if s == stateOpen {
+		b.openedAt = now
+		b.logger.Warnf("Circuit breaker for route %q changed from %s to %s; requests are rejected for %v", b.cfg.PathPrefix, from, s, b.cfg.OpenTimeout)
+		return
+	}
+	b.logger.Infof("Circuit breaker for route %q changed from %s to %s", b.cfg.PathPrefix, from, s)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/kit/logger"
+)
+
+func testBreaker(cfg breakerConfig) (*breaker, *clock.Mock) {
+	clk := clock.NewMock()
+	if cfg.OpenTimeout == 0 {
+		cfg.OpenTimeout = 10 * time.Second
+	}
+	if cfg.HalfOpenRequests == 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return newBreaker(&cfg, clk, logger.NewLogger("circuitbreaker.test")), clk
+}
+
+// send records the result of a request if the breaker allows it, and returns whether it was allowed.
+func send(b *breaker, success bool) bool {
+	allowed, generation, _ := b.allow()
+	if allowed {
+		b.record(generation, success)
+	}
+	return allowed
+}
+
+func TestConsecutiveFailures(t *testing.T) {
+	b, clk := testBreaker(breakerConfig{Trip: tripConsecutiveFailures, ConsecutiveFailures: 3, HalfOpenRequests: 2})
+
+	send(b, false)
+	send(b, false)
+	send(b, true)
+	send(b, false)
+	send(b, false)
+	assert.Equal(t, stateClosed, b.currentState())
+	send(b, false)
+	assert.Equal(t, stateOpen, b.currentState())
+
+	allowed, _, wait := b.allow()
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, wait)
+
+	clk.Add(9 * time.Second)
+	allowed, _, wait = b.allow()
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+
+	// Half-open: only 2 probes are let through
+	clk.Add(time.Second)
+	allowed1, gen1, _ := b.allow()
+	allowed2, gen2, _ := b.allow()
+	allowed3, _, _ := b.allow()
+	assert.True(t, allowed1)
+	assert.True(t, allowed2)
+	assert.False(t, allowed3)
+	assert.Equal(t, stateHalfOpen, b.currentState())
+
+	b.record(gen1, true)
+	assert.Equal(t, stateHalfOpen, b.currentState())
+	b.record(gen2, true)
+	assert.Equal(t, stateClosed, b.currentState())
+}
+
+func TestHalfOpenFailure(t *testing.T) {
+	b, clk := testBreaker(breakerConfig{Trip: tripConsecutiveFailures, ConsecutiveFailures: 1})
+
+	allowedBefore, staleGeneration, _ := b.allow()
+	assert.True(t, allowedBefore)
+	send(b, false)
+	assert.Equal(t, stateOpen, b.currentState())
+
+	clk.Add(10 * time.Second)
+	assert.True(t, send(b, false))
+	assert.Equal(t, stateOpen, b.currentState())
+	assert.False(t, send(b, true))
+
+	// Results of requests allowed before a state change are ignored
+	clk.Add(10 * time.Second)
+	assert.True(t, send(b, true))
+	assert.Equal(t, stateClosed, b.currentState())
+	b.record(staleGeneration, false)
+	assert.Equal(t, stateClosed, b.currentState())
+}
+
+func TestErrorRatio(t *testing.T) {
+	b, clk := testBreaker(breakerConfig{Trip: tripErrorRatio, ErrorRatio: 0.5, MinRequests: 4, Window: 10 * time.Second})
+
+	send(b, false)
+	send(b, false)
+	send(b, false)
+	assert.Equal(t, stateClosed, b.currentState(), "below minRequests")
+
+	// The window resets the counters
+	clk.Add(10 * time.Second)
+	send(b, false)
+	send(b, true)
+	send(b, true)
+	send(b, true)
+	assert.Equal(t, stateClosed, b.currentState())
+	send(b, false)
+	assert.Equal(t, stateClosed, b.currentState())
+	send(b, false)
+	assert.Equal(t, stateOpen, b.currentState())
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// NewMiddleware returns a new circuit breaker middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: logger,
+		clock:  clock.New(),
+	}
+}
+
+// Middleware protects the app with per-route circuit breakers, and retries the failed idempotent requests.
+type Middleware struct {
+	logger logger.Logger
+	clock  clock.Clock
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultBreaker := newBreaker(&meta.breakerConfig, m.clock, m.logger)
+	routeBreakers := make([]*breaker, len(meta.routes))
+	for i, cfg := range meta.routes {
+		routeBreakers[i] = newBreaker(cfg, m.clock, m.logger)
+	}
+	breakerFor := func(path string) *breaker {
+		b := defaultBreaker
+		for _, rb := range routeBreakers {
+			if httputils.HasPathPrefix(path, rb.cfg.PathPrefix) && len(rb.cfg.PathPrefix) > len(b.cfg.PathPrefix) {
+				b = rb
+			}
+		}
+		return b
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b := breakerFor(r.URL.Path)
+
+			if meta.retry.MaxRetries != 0 && isIdempotent(r) {
+				body, ok, err := bufferBody(r, meta.RetryMaxBodySize)
+				if err != nil {
+					m.logger.Debugf("failed to read request body: %v", err)
+					httputils.RespondWithError(w, http.StatusBadRequest)
+					return
+				}
+				if ok {
+					m.serveWithRetries(w, r, next, b, body, meta)
+					return
+				}
+			}
+
+			allowed, generation, wait := b.allow()
+			if !allowed {
+				sendFallback(w, b.cfg, wait)
+				return
+			}
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			serveAttempt(next, sw, r, b, generation, func() int { return sw.status })
+		})
+	}, nil
+}
+
+// serveAttempt sends the request to next and records the result in the breaker. It returns true if the attempt
+// failed. A panic of next is recorded as a failure before it's propagated, so a half-open breaker isn't left
+// waiting for the result of its probe.
+func serveAttempt(next http.Handler, w http.ResponseWriter, r *http.Request, b *breaker, generation uint64, status func() int) (failed bool) {
+	failed = true
+	defer func() {
+		b.record(generation, !failed)
+	}()
+	next.ServeHTTP(w, r)
+	failed = b.cfg.isFailure(status())
+	return failed
+}
+
+// serveWithRetries sends the request until it succeeds, the retries are exhausted or the breaker opens.
+// Responses are buffered so that failed attempts can be discarded, unless they're larger than
+// retryMaxResponseSize or flushed by the app: those are sent as they're written and the attempt is the last.
+func (m *Middleware) serveWithRetries(w http.ResponseWriter, r *http.Request, next http.Handler, b *breaker, body []byte, meta *circuitBreakerMiddlewareMetadata) {
+	bo := meta.retry.NewBackOffWithContext(r.Context())
+	var last *bufferedResponse
+	for attempt := 1; ; attempt++ {
+		allowed, generation, wait := b.allow()
+		if !allowed {
+			if last != nil {
+				last.send(w)
+				return
+			}
+			sendFallback(w, b.cfg, wait)
+			return
+		}
+
+		res := newBufferedResponse(w, meta.RetryMaxResponseSize)
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		failed := serveAttempt(next, res, r, b, generation, func() int { return res.status })
+		if res.passthrough {
+			return
+		}
+		if !failed {
+			res.send(w)
+			return
+		}
+
+		delay := bo.NextBackOff()
+		if delay == backoff.Stop {
+			res.send(w)
+			return
+		}
+		m.logger.Debugf("Attempt %d of %s %s failed with status %d, retrying in %v", attempt, r.Method, r.URL.Path, res.status, delay)
+		if delay > 0 {
+			timer := m.clock.Timer(delay)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				res.send(w)
+				return
+			}
+		}
+		last = res
+	}
+}
+
+// isIdempotent returns true for the methods that are idempotent per RFC 9110, and for requests with an
+// Idempotency-Key header.
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return r.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+// bufferBody reads the request body so it can be sent again. It returns false if the body is larger than max,
+// in which case the request isn't retried.
+func bufferBody(r *http.Request, max int64) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if r.ContentLength > max {
+		return nil, false, nil
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(buf)) > max {
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buf), r.Body), Closer: r.Body}
+		return nil, false, nil
+	}
+	r.Body.Close()
+	return buf, true, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// sendFallback sends the fallback response of the breaker.
+func sendFallback(w http.ResponseWriter, cfg *breakerConfig, wait time.Duration) {
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+	if cfg.FallbackBody == "" {
+		httputils.RespondWithError(w, cfg.FallbackStatus)
+		return
+	}
+	contentType := cfg.FallbackContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(cfg.FallbackStatus)
+	w.Write([]byte(cfg.FallbackBody))
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// bufferedResponse holds the response of an attempt. When the body grows larger than maxSize or the app flushes
+// the response, the response is passed through to w instead.
+type bufferedResponse struct {
+	w           http.ResponseWriter
+	maxSize     int64
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	passthrough bool
+}
+
+// newBufferedResponse returns a response whose headers start as a copy of the headers already set on w by the
+// previous handlers.
+func newBufferedResponse(w http.ResponseWriter, maxSize int64) *bufferedResponse {
+	return &bufferedResponse{
+		w:       w,
+		maxSize: maxSize,
+		header:  w.Header().Clone(),
+		status:  http.StatusOK,
+	}
+}
+
+func (res *bufferedResponse) Header() http.Header {
+	if res.passthrough {
+		return res.w.Header()
+	}
+	return res.header
+}
+
+func (res *bufferedResponse) WriteHeader(status int) {
+	if res.wroteHeader {
+		return
+	}
+	res.wroteHeader = true
+	res.status = status
+}
+
+func (res *bufferedResponse) Write(b []byte) (int, error) {
+	res.wroteHeader = true
+	if !res.passthrough && int64(res.body.Len()+len(b)) > res.maxSize {
+		res.send(res.w)
+	}
+	if res.passthrough {
+		return res.w.Write(b)
+	}
+	return res.body.Write(b)
+}
+
+func (res *bufferedResponse) Flush() {
+	if !res.passthrough {
+		res.wroteHeader = true
+		res.send(res.w)
+	}
+	if f, ok := res.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// send writes the response to w. The rest of the response is then passed through.
+func (res *bufferedResponse) send(w http.ResponseWriter) {
+	h := w.Header()
+	for k := range h {
+		if _, ok := res.header[k]; !ok {
+			delete(h, k)
+		}
+	}
+	for k, v := range res.header {
+		h[k] = v
+	}
+	w.WriteHeader(res.status)
+	w.Write(res.body.Bytes())
+	res.body = bytes.Buffer{}
+	res.passthrough = true
+}
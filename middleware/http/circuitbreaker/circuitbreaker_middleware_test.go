@@ -0,0 +1,354 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// flakyApp fails the first failures requests with a 503, then succeeds echoing the request body.
+// Requests to /large get a body of 100 bytes, requests to /stream get a flushed body and requests to /panic
+// panic instead.
+type flakyApp struct {
+	failures int32
+	calls    atomic.Int32
+}
+
+func (a *flakyApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if a.calls.Add(1) <= a.failures {
+		w.Header().Set("X-Failed", "true")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		switch r.URL.Path {
+		case "/panic":
+			panic("app failure")
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/stream":
+			w.Write([]byte("event"))
+			w.(http.Flusher).Flush()
+		default:
+			w.Write([]byte("unavailable"))
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// breakerRequest is a request sent to the middleware after advancing the clock, with the response expected.
+type breakerRequest struct {
+	advance    time.Duration
+	method     string
+	path       string
+	body       string
+	headers    map[string]string
+	status     int
+	resBody    *string
+	resHeaders map[string]string
+	panics     bool
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	retries := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"consecutiveFailures":  "10",
+			"retryMaxRetries":      "2",
+			"retryDuration":        "0",
+			"retryMaxResponseSize": "50",
+		},
+	}}
+
+	tests := map[string]struct {
+		meta               middleware.Metadata
+		failures           int32
+		requests           []breakerRequest
+		calls              int32
+		shouldHandlerError bool
+	}{
+		"fallback": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"consecutiveFailures": "2",
+					"openTimeout":         "30s",
+					"routes": `[{
+						"pathPrefix": "/v1.0/invoke/orders",
+						"consecutiveFailures": 1,
+						"openTimeout": "5s",
+						"fallbackStatus": 200,
+						"fallbackBody": "{\"orders\":[]}",
+						"fallbackContentType": "application/json"
+					}]`,
+				},
+			}},
+			failures: 4,
+			requests: []breakerRequest{
+				{path: "/v1.0/invoke/orders/method/list", status: http.StatusServiceUnavailable},
+				{path: "/v1.0/invoke/orders/method/list", status: http.StatusOK, resBody: str(`{"orders":[]}`), resHeaders: map[string]string{
+					"Content-Type": "application/json",
+					"Retry-After":  "5",
+				}},
+				// The default breaker is separate
+				{path: "/v1.0/state/a", status: http.StatusServiceUnavailable},
+				{path: "/v1.0/state/a", status: http.StatusServiceUnavailable},
+				{path: "/v1.0/state/a", status: http.StatusServiceUnavailable, resHeaders: map[string]string{"Retry-After": "30"}},
+				// The half-open probe reopens the breaker
+				{advance: 5 * time.Second, path: "/v1.0/invoke/orders/method/list", status: http.StatusServiceUnavailable},
+				// The half-open probe closes the breaker
+				{advance: 5 * time.Second, path: "/v1.0/invoke/orders/method/list", status: http.StatusOK, resHeaders: map[string]string{"Retry-After": ""}},
+				{path: "/v1.0/invoke/orders/method/list", status: http.StatusOK, resBody: str("")},
+			},
+			calls: 6,
+		},
+		"panicking requests": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"consecutiveFailures": "1",
+					"openTimeout":         "5s",
+				},
+			}},
+			failures: 2,
+			requests: []breakerRequest{
+				{path: "/panic", panics: true},
+				{path: "/panic", status: http.StatusServiceUnavailable, resHeaders: map[string]string{"Retry-After": "5"}},
+				// The panicking half-open probe reopens the breaker
+				{advance: 5 * time.Second, path: "/panic", panics: true},
+				{path: "/panic", status: http.StatusServiceUnavailable, resHeaders: map[string]string{"Retry-After": "5"}},
+				{advance: 5 * time.Second, path: "/panic", status: http.StatusOK},
+			},
+			calls: 3,
+		},
+		"panicking retried request": {
+			meta:     retries,
+			failures: 1,
+			requests: []breakerRequest{
+				{path: "/panic", panics: true},
+			},
+			calls: 1,
+		},
+		"route prefixes match whole segments": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"consecutiveFailures": "5",
+					"routes":              `[{"pathPrefix": "/v1.0/invoke/orders", "consecutiveFailures": 1}]`,
+				},
+			}},
+			failures: 2,
+			requests: []breakerRequest{
+				{path: "/v1.0/invoke/ordersv2/method/list", status: http.StatusServiceUnavailable},
+				// The default breaker is still closed
+				{path: "/v1.0/invoke/ordersv2/method/list", status: http.StatusServiceUnavailable, resBody: str("unavailable")},
+				{path: "/v1.0/invoke/ordersv2/method/list", status: http.StatusOK},
+			},
+			calls: 3,
+		},
+		"retries succeed": {
+			meta:     retries,
+			failures: 2,
+			requests: []breakerRequest{
+				{method: http.MethodPut, path: "/v1.0/state/a", body: "payload", status: http.StatusOK, resBody: str("payload"), resHeaders: map[string]string{
+					// Headers of failed attempts are discarded
+					"X-Failed": "",
+				}},
+			},
+			calls: 3,
+		},
+		"retries exhausted": {
+			meta:     retries,
+			failures: 5,
+			requests: []breakerRequest{
+				{path: "/v1.0/state/a", status: http.StatusServiceUnavailable, resBody: str("unavailable")},
+			},
+			calls: 3,
+		},
+		"non-idempotent request": {
+			meta:     retries,
+			failures: 1,
+			requests: []breakerRequest{
+				{method: http.MethodPost, path: "/v1.0/state/a", body: "payload", status: http.StatusServiceUnavailable},
+			},
+			calls: 1,
+		},
+		"request with idempotency key": {
+			meta:     retries,
+			failures: 1,
+			requests: []breakerRequest{
+				{method: http.MethodPost, path: "/v1.0/state/a", body: "payload", headers: map[string]string{"Idempotency-Key": "abc"}, status: http.StatusOK, resBody: str("payload")},
+			},
+			calls: 2,
+		},
+		"response larger than retryMaxResponseSize": {
+			meta:     retries,
+			failures: 1,
+			requests: []breakerRequest{
+				{path: "/large", status: http.StatusServiceUnavailable, resBody: str(strings.Repeat("x", 100)), resHeaders: map[string]string{"X-Failed": "true"}},
+			},
+			calls: 1,
+		},
+		"flushed response": {
+			meta:     retries,
+			failures: 1,
+			requests: []breakerRequest{
+				{path: "/stream", status: http.StatusServiceUnavailable, resBody: str("event")},
+			},
+			calls: 1,
+		},
+		"breaker stops retries": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"consecutiveFailures": "2",
+					"retryMaxRetries":     "5",
+					"retryDuration":       "0",
+				},
+			}},
+			failures: 5,
+			requests: []breakerRequest{
+				{path: "/v1.0/state/a", status: http.StatusServiceUnavailable, resBody: str("unavailable")},
+			},
+			calls: 2,
+		},
+		"invalid trip": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"trip": "sometimes"}}},
+			shouldHandlerError: true,
+		},
+		"invalid errorRatio": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"trip": "errorRatio", "errorRatio": "1.5"}}},
+			shouldHandlerError: true,
+		},
+		"invalid consecutiveFailures": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"consecutiveFailures": "0"}}},
+			shouldHandlerError: true,
+		},
+		"invalid failureStatusCodes": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"failureStatusCodes": "599-500"}}},
+			shouldHandlerError: true,
+		},
+		"invalid fallbackStatus": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"fallbackStatus": "42"}}},
+			shouldHandlerError: true,
+		},
+		"invalid retryMaxResponseSize": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"retryMaxResponseSize": "0"}}},
+			shouldHandlerError: true,
+		},
+		"invalid routes": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"routes": "not json"}}},
+			shouldHandlerError: true,
+		},
+		"route without pathPrefix": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"routes": `[{"consecutiveFailures": 1}]`}}},
+			shouldHandlerError: true,
+		},
+		"invalid route": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{"routes": `[{"pathPrefix": "/a", "openTimeout": "-1s"}]`}}},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("circuitbreaker.test")
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewMock()
+			breakerMiddleware := NewMiddleware(log).(*Middleware)
+			breakerMiddleware.clock = clk
+
+			handler, err := breakerMiddleware.GetHandler(test.meta)
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			app := &flakyApp{failures: test.failures}
+			h := handler(app)
+			for i, req := range test.requests {
+				clk.Add(req.advance)
+				method := req.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				r := httptest.NewRequest(method, "http://localhost:3500"+req.path, strings.NewReader(req.body))
+				for k, v := range req.headers {
+					r.Header.Set(k, v)
+				}
+				w := httptest.NewRecorder()
+				if req.panics {
+					assert.Panics(t, func() { h.ServeHTTP(w, r) }, "request %d", i)
+					continue
+				}
+				h.ServeHTTP(w, r)
+
+				assert.Equal(t, req.status, w.Code, "request %d", i)
+				if req.resBody != nil {
+					assert.Equal(t, *req.resBody, w.Body.String(), "request %d", i)
+				}
+				for k, v := range req.resHeaders {
+					assert.Equal(t, v, w.Header().Get(k), "request %d: %s", i, k)
+				}
+			}
+			assert.Equal(t, test.calls, app.calls.Load())
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	clk := clock.NewMock()
+	breakerMiddleware := NewMiddleware(logger.NewLogger("circuitbreaker.test")).(*Middleware)
+	breakerMiddleware.clock = clk
+	handler, err := breakerMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"retryMaxRetries": "1",
+			"retryDuration":   "1s",
+		},
+	}})
+	require.NoError(t, err)
+	app := &flakyApp{failures: 1}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		handler(app).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:3500/v1.0/state/a", nil))
+		done <- w
+	}()
+	assert.Eventually(t, func() bool { return app.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(1), app.calls.Load())
+
+	clk.Add(time.Second)
+	select {
+	case w := <-done:
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int32(2), app.calls.Load())
+	case <-time.After(5 * time.Second):
+		t.Fatal("request didn't complete")
+	}
+}
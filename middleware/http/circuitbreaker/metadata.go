@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/retry"
+)
+
+const (
+	tripConsecutiveFailures = "consecutiveFailures"
+	tripErrorRatio          = "errorRatio"
+
+	// Defaults.
+	defaultConsecutiveFailures  = 5
+	defaultErrorRatio           = 0.5
+	defaultMinRequests          = 10
+	defaultWindow               = 10 * time.Second
+	defaultOpenTimeout          = 30 * time.Second
+	defaultHalfOpenRequests     = 1
+	defaultFailureStatusCodes   = "500-599"
+	defaultFallbackStatus       = http.StatusServiceUnavailable
+	defaultRetryDuration        = 100 * time.Millisecond
+	defaultRetryMaxBodySize     = 1 << 20
+	defaultRetryMaxResponseSize = 1 << 20
+)
+
+type circuitBreakerMiddlewareMetadata struct {
+	breakerConfig `mapstructure:",squash"`
+
+	// Routes is a JSON array of per-route breakers, which override the settings above.
+	// Each route has its own breaker; the requests that don't match a route share the default breaker.
+	Routes string `json:"routes"`
+	// RetryMaxBodySize is the size of the largest request body that is buffered so the request can be retried.
+	RetryMaxBodySize int64 `json:"retryMaxBodySize"`
+	// RetryMaxResponseSize is the size of the largest response body that is buffered while the request can be
+	// retried. Larger responses, and the responses the app flushes, are sent as they're written and aren't retried.
+	RetryMaxResponseSize int64 `json:"retryMaxResponseSize"`
+
+	// Retry policy, configured with the properties prefixed with "retry", such as retryMaxRetries.
+	retry retry.Config
+
+	routes []*breakerConfig
+}
+
+// breakerConfig is the configuration of a breaker.
+type breakerConfig struct {
+	PathPrefix string `json:"pathPrefix"`
+	// Trip is the strategy that opens the breaker: consecutiveFailures or errorRatio.
+	Trip string `json:"trip"`
+	// ConsecutiveFailures is the number of consecutive failures that open the breaker.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// ErrorRatio is the ratio of failed requests in the window that opens the breaker.
+	ErrorRatio float64 `json:"errorRatio"`
+	// MinRequests is the number of requests in the window before the error ratio is evaluated.
+	MinRequests int `json:"minRequests"`
+	// Window is the interval over which the error ratio is computed.
+	Window time.Duration `json:"window"`
+	// OpenTimeout is how long the breaker stays open before letting probe requests through.
+	OpenTimeout time.Duration `json:"openTimeout"`
+	// HalfOpenRequests is the number of successful probe requests that close the breaker.
+	HalfOpenRequests int `json:"halfOpenRequests"`
+	// FailureStatusCodes is a comma-separated list of status codes and ranges, such as "500-599,429", that count as failures.
+	FailureStatusCodes string `json:"failureStatusCodes"`
+
+	// Fallback response sent while the breaker is open.
+	FallbackStatus      int    `json:"fallbackStatus"`
+	FallbackBody        string `json:"fallbackBody"`
+	FallbackContentType string `json:"fallbackContentType"`
+
+	failureCodes []statusRange
+}
+
+type statusRange struct {
+	from int
+	to   int
+}
+
+func getNativeMetadata(metadata middleware.Metadata) (*circuitBreakerMiddlewareMetadata, error) {
+	middlewareMetadata := circuitBreakerMiddlewareMetadata{
+		breakerConfig: breakerConfig{
+			Trip:                tripConsecutiveFailures,
+			ConsecutiveFailures: defaultConsecutiveFailures,
+			ErrorRatio:          defaultErrorRatio,
+			MinRequests:         defaultMinRequests,
+			Window:              defaultWindow,
+			OpenTimeout:         defaultOpenTimeout,
+			HalfOpenRequests:    defaultHalfOpenRequests,
+			FailureStatusCodes:  defaultFailureStatusCodes,
+			FallbackStatus:      defaultFallbackStatus,
+		},
+		RetryMaxBodySize:     defaultRetryMaxBodySize,
+		RetryMaxResponseSize: defaultRetryMaxResponseSize,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+	middlewareMetadata.PathPrefix = ""
+	err = middlewareMetadata.breakerConfig.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.RetryMaxResponseSize <= 0 {
+		return nil, errors.New("retryMaxResponseSize must be greater than 0")
+	}
+
+	middlewareMetadata.retry = retry.DefaultConfigWithNoRetry()
+	middlewareMetadata.retry.Duration = defaultRetryDuration
+	err = retry.DecodeConfigWithPrefix(&middlewareMetadata.retry, metadata.Properties, "retry")
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry configuration: %w", err)
+	}
+
+	if middlewareMetadata.Routes != "" {
+		var routes []map[string]any
+		err = json.Unmarshal([]byte(middlewareMetadata.Routes), &routes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routes: %w", err)
+		}
+		for _, r := range routes {
+			// Routes inherit the settings they don't override
+			cfg := middlewareMetadata.breakerConfig
+			cfg.failureCodes = nil
+			err = mdutils.DecodeMetadata(r, &cfg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid route: %w", err)
+			}
+			if cfg.PathPrefix == "" {
+				return nil, errors.New("pathPrefix is required for every route")
+			}
+			err = cfg.validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid route %s: %w", cfg.PathPrefix, err)
+			}
+			middlewareMetadata.routes = append(middlewareMetadata.routes, &cfg)
+		}
+	}
+
+	return &middlewareMetadata, nil
+}
+
+func (c *breakerConfig) validate() error {
+	switch c.Trip {
+	case tripConsecutiveFailures:
+		if c.ConsecutiveFailures <= 0 {
+			return errors.New("consecutiveFailures must be greater than 0")
+		}
+	case tripErrorRatio:
+		if c.ErrorRatio <= 0 || c.ErrorRatio > 1 {
+			return errors.New("errorRatio must be between 0 and 1")
+		}
+		if c.Window <= 0 {
+			return errors.New("window must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("invalid trip %q: must be %s or %s", c.Trip, tripConsecutiveFailures, tripErrorRatio)
+	}
+	if c.OpenTimeout <= 0 {
+		return errors.New("openTimeout must be greater than 0")
+	}
+	if c.HalfOpenRequests <= 0 {
+		return errors.New("halfOpenRequests must be greater than 0")
+	}
+	if http.StatusText(c.FallbackStatus) == "" {
+		return fmt.Errorf("invalid fallbackStatus %d", c.FallbackStatus)
+	}
+
+	for _, code := range strings.Split(c.FailureStatusCodes, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(code, "-")
+		r := statusRange{}
+		var err error
+		r.from, err = strconv.Atoi(strings.TrimSpace(from))
+		if err == nil {
+			r.to = r.from
+			if isRange {
+				r.to, err = strconv.Atoi(strings.TrimSpace(to))
+			}
+		}
+		if err != nil || r.from > r.to {
+			return fmt.Errorf("invalid failure status code %q", code)
+		}
+		c.failureCodes = append(c.failureCodes, r)
+	}
+	return nil
+}
+
+// isFailure returns true if the status code counts as a failure.
+func (c *breakerConfig) isFailure(status int) bool {
+	for _, r := range c.failureCodes {
+		if status >= r.from && status <= r.to {
+			return true
+		}
+	}
+	return false
+}
@@ -19,14 +19,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 
+	oauth2auth "github.com/dapr/components-contrib/internal/authentication/oauth2"
 	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
 	"github.com/dapr/kit/logger"
@@ -34,13 +33,9 @@ import (
 
 // Metadata is the oAuth clientcredentials middleware config.
 type oAuth2ClientCredentialsMiddlewareMetadata struct {
-	ClientID            string `json:"clientID"`
-	ClientSecret        string `json:"clientSecret"`
-	Scopes              string `json:"scopes"`
-	TokenURL            string `json:"tokenURL"`
-	HeaderName          string `json:"headerName"`
-	EndpointParamsQuery string `json:"endpointParamsQuery,omitempty"`
-	AuthStyle           int    `json:"authStyle"`
+	oauth2auth.ClientCredentialsMetadata `mapstructure:",squash"`
+
+	HeaderName string `json:"headerName"`
 }
 
 // TokenProviderInterface provides a common interface to Mock the Token retrieval in unit tests.
@@ -75,19 +70,9 @@ func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Ha
 		return nil, err
 	}
 
-	endpointParams, err := url.ParseQuery(meta.EndpointParamsQuery)
+	conf, err := meta.Config()
 	if err != nil {
-		m.log.Warnf("Error parsing endpoint parameters: %s", err)
-		endpointParams, _ = url.ParseQuery("")
-	}
-
-	conf := &clientcredentials.Config{
-		ClientID:       meta.ClientID,
-		ClientSecret:   meta.ClientSecret,
-		Scopes:         strings.Split(meta.Scopes, ","),
-		TokenURL:       meta.TokenURL,
-		EndpointParams: endpointParams,
-		AuthStyle:      oauth2.AuthStyle(meta.AuthStyle),
+		return nil, fmt.Errorf("metadata errors: %w", err)
 	}
 
 	cacheKey := m.getCacheKey(meta)
@@ -135,14 +120,10 @@ func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*oAuth2Cli
 
 	// Check if values are present
 	m.checkMetadataValueExists(&errorString, &middlewareMetadata.HeaderName, "headerName")
-	m.checkMetadataValueExists(&errorString, &middlewareMetadata.ClientID, "clientID")
-	m.checkMetadataValueExists(&errorString, &middlewareMetadata.ClientSecret, "clientSecret")
-	m.checkMetadataValueExists(&errorString, &middlewareMetadata.Scopes, "scopes")
-	m.checkMetadataValueExists(&errorString, &middlewareMetadata.TokenURL, "tokenURL")
-
-	// Value-check AuthStyle
-	if middlewareMetadata.AuthStyle < 0 || middlewareMetadata.AuthStyle > 2 {
-		errorString += fmt.Sprintf("Parameter 'authStyle' can only have the values 0,1,2. Received: '%d'. ", middlewareMetadata.AuthStyle)
+
+	// Check the client credentials
+	if err = middlewareMetadata.Validate(); err != nil {
+		errorString += err.Error() + " "
 	}
 
 	// Return errors if any found
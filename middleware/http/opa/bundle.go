@@ -0,0 +1,233 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+
+	"github.com/dapr/components-contrib/internal/utils"
+)
+
+const bundleDownloadTimeout = 30 * time.Second
+
+// policy holds the prepared query, which is replaced when the bundle changes.
+type policy struct {
+	query      atomic.Pointer[rego.PreparedEvalQuery]
+	revision   atomic.Pointer[string]
+	bundleHash string
+	bundleETag string
+	client     *http.Client
+
+	decisionLogs  decisionLogSink
+	maskedHeaders []string
+
+	// ctx is canceled when the policy is closed, stopping the background goroutines
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newPolicy loads the bundle, if any, and prepares the query. When a bundle is configured with a reload
+// interval, it's reloaded in the background until the policy is closed.
+func (m *Middleware) newPolicy(meta *middlewareMetadata) (*policy, error) {
+	p := &policy{
+		client: &http.Client{Timeout: bundleDownloadTimeout},
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	var b *bundle.Bundle
+	if meta.BundlePath != "" || meta.BundleURL != "" {
+		var err error
+		b, err = p.loadBundle(p.ctx, meta)
+		if err != nil {
+			p.cancel()
+			return nil, fmt.Errorf("failed to load OPA bundle: %w", err)
+		}
+	}
+	err := p.prepare(meta, b)
+	if err != nil {
+		p.cancel()
+		return nil, err
+	}
+
+	if utils.IsTruthy(meta.DecisionLogs) {
+		p.decisionLogs, err = m.newDecisionLogSink(p.ctx, &p.wg, meta)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.maskedHeaders = meta.decisionLogMaskedHeadersParsed
+	}
+	if b != nil && meta.bundleReloadIntervalParsed > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			m.reloadBundle(p, meta)
+		}()
+	}
+	return p, nil
+}
+
+// close stops the background goroutines and waits for them to return.
+func (p *policy) close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// prepare compiles the inline policy and the bundle, and swaps the prepared query.
+func (p *policy) prepare(meta *middlewareMetadata, b *bundle.Bundle) error {
+	opts := []func(*rego.Rego){
+		rego.Query("result = data.http.allow"),
+	}
+	if meta.Rego != "" {
+		opts = append(opts, rego.Module("inline.rego", meta.Rego))
+	}
+	revision := ""
+	if b != nil {
+		opts = append(opts, rego.ParsedBundle("bundle", b))
+		revision = b.Manifest.Revision
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+	query, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return err
+	}
+	p.query.Store(&query)
+	p.revision.Store(&revision)
+	return nil
+}
+
+func (m *Middleware) reloadBundle(p *policy, meta *middlewareMetadata) {
+	ticker := time.NewTicker(meta.bundleReloadIntervalParsed)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		b, err := p.loadBundle(p.ctx, meta)
+		if err != nil {
+			m.logger.Warnf("Failed to reload OPA bundle, keeping the current policy: %v", err)
+			continue
+		}
+		if b == nil {
+			// Unchanged
+			continue
+		}
+		err = p.prepare(meta, b)
+		if err != nil {
+			m.logger.Warnf("Failed to compile reloaded OPA bundle, keeping the current policy: %v", err)
+			continue
+		}
+		m.logger.Infof("Reloaded OPA bundle, revision %q", b.Manifest.Revision)
+	}
+}
+
+// loadBundle reads the bundle from the directory or the URL. It returns nil if the bundle didn't change
+// since the last time it was loaded.
+func (p *policy) loadBundle(ctx context.Context, meta *middlewareMetadata) (*bundle.Bundle, error) {
+	var (
+		b   bundle.Bundle
+		err error
+	)
+	if meta.BundlePath != "" {
+		b, err = bundle.NewCustomReader(bundle.NewDirectoryLoader(meta.BundlePath)).
+			WithSizeLimitBytes(meta.maxBundleSizeParsed).
+			Read()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.BundleURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if p.bundleETag != "" {
+			req.Header.Set("If-None-Match", p.bundleETag)
+		}
+		res, err := p.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNotModified {
+			return nil, nil
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d downloading bundle", res.StatusCode)
+		}
+		// The limit of the reader only applies to each file of the bundle, so the download is limited too
+		body := &io.LimitedReader{R: res.Body, N: meta.maxBundleSizeParsed + 1}
+		b, err = bundle.NewReader(body).WithSizeLimitBytes(meta.maxBundleSizeParsed).Read()
+		if body.N <= 0 {
+			return nil, fmt.Errorf("bundle is larger than %d bytes", meta.maxBundleSizeParsed)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.bundleETag = res.Header.Get("ETag")
+	}
+
+	if len(b.Modules) == 0 && len(b.Data) == 0 {
+		return nil, errors.New("bundle is empty")
+	}
+	hash, err := bundleHash(&b)
+	if err != nil {
+		return nil, err
+	}
+	if hash == p.bundleHash {
+		return nil, nil
+	}
+	p.bundleHash = hash
+	return &b, nil
+}
+
+// bundleHash returns a hash of the policies and data of the bundle, used to detect changes.
+func bundleHash(b *bundle.Bundle) (string, error) {
+	modules := make([]bundle.ModuleFile, len(b.Modules))
+	copy(modules, b.Modules)
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+
+	h := sha256.New()
+	for _, mf := range modules {
+		h.Write([]byte(mf.Path))
+		h.Write(mf.Raw)
+	}
+	data, err := json.Marshal(b.Data)
+	if err != nil {
+		return "", err
+	}
+	h.Write(data)
+	h.Write([]byte(b.Manifest.Revision))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,649 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/pubsub"
+	inmemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	allowGetPolicy = `package http
+default allow = false
+allow { input.request.method == "GET" }`
+	allowPostPolicy = `package http
+default allow = false
+allow { input.request.method == "POST" }`
+)
+
+func writeBundleDir(t *testing.T, dir, policy, revision string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte(policy), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".manifest"), []byte(`{"revision":"`+revision+`"}`), 0o600))
+}
+
+func bundleTarball(t *testing.T, policy string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "/policy.rego", Mode: 0o600, Size: int64(len(policy))}))
+	_, err := tw.Write([]byte(policy))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+// bundleServer serves the bundle of the policy, which is replaced by calling the returned function. Requests with
+// the ETag of the current policy are answered with 304, and counted.
+func bundleServer(t *testing.T, policy string) (srv *httptest.Server, update func(policy string), notModified func() int) {
+	t.Helper()
+
+	var (
+		lock     sync.Mutex
+		revision = 1
+		count    int
+	)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		etag := `"v` + strconv.Itoa(revision) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			count++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(bundleTarball(t, policy))
+	}))
+	t.Cleanup(srv.Close)
+
+	update = func(p string) {
+		lock.Lock()
+		defer lock.Unlock()
+		policy = p
+		revision++
+	}
+	notModified = func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return count
+	}
+	return srv, update, notModified
+}
+
+// fakeBinding is an output binding sending the data of the invocations to a channel.
+type fakeBinding struct {
+	invocations chan *bindings.InvokeRequest
+}
+
+func (b *fakeBinding) Init(metadata bindings.Metadata) error {
+	return nil
+}
+
+func (b *fakeBinding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	b.invocations <- req
+	return nil, nil
+}
+
+func (b *fakeBinding) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation}
+}
+
+// fakeComponents resolves the output bindings and the pubsubs of the maps.
+type fakeComponents struct {
+	bindings map[string]bindings.OutputBinding
+	pubsubs  map[string]pubsub.PubSub
+}
+
+func (c fakeComponents) StateStore(name string) (state.Store, error) {
+	return nil, errors.New("state store " + name + " not found")
+}
+
+func (c fakeComponents) SecretStore(name string) (secretstores.SecretStore, error) {
+	return nil, errors.New("secret store " + name + " not found")
+}
+
+func (c fakeComponents) OutputBinding(name string) (bindings.OutputBinding, error) {
+	if binding, ok := c.bindings[name]; ok {
+		return binding, nil
+	}
+	return nil, errors.New("output binding " + name + " not found")
+}
+
+func (c fakeComponents) PubSub(name string) (pubsub.PubSub, error) {
+	if ps, ok := c.pubsubs[name]; ok {
+		return ps, nil
+	}
+	return nil, errors.New("pubsub " + name + " not found")
+}
+
+func TestBundle(t *testing.T) {
+	tests := map[string]struct {
+		properties         func(t *testing.T) map[string]string
+		resolver           components.Resolver
+		allowed            []string
+		denied             []string
+		shouldHandlerError bool
+	}{
+		"directory": {
+			properties: func(t *testing.T) map[string]string {
+				dir := t.TempDir()
+				writeBundleDir(t, dir, allowGetPolicy, "v1")
+				return map[string]string{"bundlePath": dir}
+			},
+			allowed: []string{http.MethodGet},
+			denied:  []string{http.MethodPost},
+		},
+		"url": {
+			properties: func(t *testing.T) map[string]string {
+				srv, _, _ := bundleServer(t, allowPostPolicy)
+				return map[string]string{"bundleURL": srv.URL + "/bundle.tar.gz"}
+			},
+			allowed: []string{http.MethodPost},
+			denied:  []string{http.MethodGet},
+		},
+		"missing directory": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{"bundlePath": filepath.Join(t.TempDir(), "missing")}
+			},
+			shouldHandlerError: true,
+		},
+		"empty directory": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{"bundlePath": t.TempDir()}
+			},
+			shouldHandlerError: true,
+		},
+		"path and url": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{"bundlePath": t.TempDir(), "bundleURL": "http://localhost"}
+			},
+			shouldHandlerError: true,
+		},
+		"file larger than maxBundleSize": {
+			properties: func(t *testing.T) map[string]string {
+				dir := t.TempDir()
+				writeBundleDir(t, dir, allowGetPolicy, "v1")
+				return map[string]string{"bundlePath": dir, "maxBundleSize": "10"}
+			},
+			shouldHandlerError: true,
+		},
+		"download larger than maxBundleSize": {
+			properties: func(t *testing.T) map[string]string {
+				srv, _, _ := bundleServer(t, allowGetPolicy)
+				return map[string]string{"bundleURL": srv.URL + "/bundle.tar.gz", "maxBundleSize": "10"}
+			},
+			shouldHandlerError: true,
+		},
+		"download error": {
+			properties: func(t *testing.T) map[string]string {
+				srv := httptest.NewServer(http.NotFoundHandler())
+				t.Cleanup(srv.Close)
+				return map[string]string{"bundleURL": srv.URL + "/bundle.tar.gz"}
+			},
+			shouldHandlerError: true,
+		},
+		"invalid reload interval": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{"rego": allowGetPolicy, "bundleReloadInterval": "soon"}
+			},
+			shouldHandlerError: true,
+		},
+		"invalid max body size": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{"rego": allowGetPolicy, "maxResponseBodySize": "0"}
+			},
+			shouldHandlerError: true,
+		},
+		"invalid max bundle size": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{"rego": allowGetPolicy, "maxBundleSize": "-1"}
+			},
+			shouldHandlerError: true,
+		},
+		"decision logs to an output binding and a pubsub": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{
+					"rego":                     allowGetPolicy,
+					"decisionLogs":             "true",
+					"decisionLogOutputBinding": "decisions",
+					"decisionLogPubsubName":    "pubsub",
+					"decisionLogTopic":         "decisions",
+				}
+			},
+			resolver:           fakeComponents{},
+			shouldHandlerError: true,
+		},
+		"decision logs to a pubsub without topic": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{
+					"rego":                  allowGetPolicy,
+					"decisionLogs":          "true",
+					"decisionLogPubsubName": "pubsub",
+				}
+			},
+			resolver:           fakeComponents{pubsubs: map[string]pubsub.PubSub{"pubsub": inmemory.New(logger.NewLogger("test"))}},
+			shouldHandlerError: true,
+		},
+		"decision logs to an unknown output binding": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{
+					"rego":                     allowGetPolicy,
+					"decisionLogs":             "true",
+					"decisionLogOutputBinding": "decisions",
+				}
+			},
+			resolver:           fakeComponents{},
+			shouldHandlerError: true,
+		},
+		"decision logs to a component without resolver": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{
+					"rego":                     allowGetPolicy,
+					"decisionLogs":             "true",
+					"decisionLogOutputBinding": "decisions",
+				}
+			},
+			shouldHandlerError: true,
+		},
+		"decision logs to the logger": {
+			properties: func(t *testing.T) map[string]string {
+				return map[string]string{
+					"rego":         allowGetPolicy,
+					"decisionLogs": "true",
+				}
+			},
+			allowed: []string{http.MethodGet},
+			denied:  []string{http.MethodPost},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opaMiddleware := NewMiddleware(logger.NewLogger("opa.test")).(*Middleware)
+			if test.resolver != nil {
+				opaMiddleware.SetComponentResolver(test.resolver)
+			}
+			defer opaMiddleware.Close()
+
+			handler, err := opaMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: test.properties(t)}})
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			h := handler(http.HandlerFunc(mockedRequestHandler))
+
+			for _, method := range test.allowed {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(method, "https://my.site/v1.0/state", nil))
+				assert.Equal(t, http.StatusOK, w.Code, method)
+			}
+			for _, method := range test.denied {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(method, "https://my.site/v1.0/state", nil))
+				assert.Equal(t, http.StatusForbidden, w.Code, method)
+			}
+		})
+	}
+}
+
+func TestBundleReload(t *testing.T) {
+	tests := map[string]struct {
+		// properties returns the metadata of a bundle allowing GET, and a function replacing its policy
+		properties func(t *testing.T) (map[string]string, func(policy string))
+	}{
+		"directory": {
+			properties: func(t *testing.T) (map[string]string, func(policy string)) {
+				dir := t.TempDir()
+				writeBundleDir(t, dir, allowGetPolicy, "v1")
+				revision := 1
+				return map[string]string{"bundlePath": dir}, func(policy string) {
+					revision++
+					writeBundleDir(t, dir, policy, "v"+strconv.Itoa(revision))
+				}
+			},
+		},
+		"url": {
+			properties: func(t *testing.T) (map[string]string, func(policy string)) {
+				srv, update, notModified := bundleServer(t, allowGetPolicy)
+				return map[string]string{"bundleURL": srv.URL + "/bundle.tar.gz"}, func(policy string) {
+					// The unchanged bundle isn't downloaded again
+					assert.Eventually(t, func() bool {
+						return notModified() > 0
+					}, 5*time.Second, 10*time.Millisecond)
+					update(policy)
+				}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			props, update := test.properties(t)
+			props["bundleReloadInterval"] = "10ms"
+
+			opaMiddleware := NewMiddleware(logger.NewLogger("opa.test"))
+			defer opaMiddleware.(*Middleware).Close()
+			handler, err := opaMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: props}})
+			require.NoError(t, err)
+			h := handler(http.HandlerFunc(mockedRequestHandler))
+			status := func(method string) int {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(method, "https://my.site/v1.0/state", nil))
+				return w.Code
+			}
+
+			assert.Equal(t, http.StatusOK, status(http.MethodGet))
+			assert.Equal(t, http.StatusForbidden, status(http.MethodPost))
+
+			update(allowPostPolicy)
+			assert.Eventually(t, func() bool {
+				return status(http.MethodPost) == http.StatusOK
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, http.StatusForbidden, status(http.MethodGet))
+
+			// An invalid bundle keeps the current policy
+			update("package http\nallow {")
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, http.StatusOK, status(http.MethodPost))
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleDir(t, dir, allowGetPolicy, "v1")
+
+	opaMiddleware := NewMiddleware(logger.NewLogger("opa.test")).(*Middleware)
+	opaMiddleware.SetComponentResolver(fakeComponents{bindings: map[string]bindings.OutputBinding{
+		"decisions": &fakeBinding{invocations: make(chan *bindings.InvokeRequest, 10)},
+	}})
+	handler, err := opaMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"bundlePath":               dir,
+		"bundleReloadInterval":     "10ms",
+		"decisionLogs":             "true",
+		"decisionLogOutputBinding": "decisions",
+	}}})
+	require.NoError(t, err)
+	h := handler(http.HandlerFunc(mockedRequestHandler))
+	require.NoError(t, opaMiddleware.Close())
+
+	// The bundle isn't reloaded anymore
+	writeBundleDir(t, dir, allowPostPolicy, "v2")
+	time.Sleep(50 * time.Millisecond)
+	for method, status := range map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "https://my.site/v1.0/state", nil))
+		assert.Equal(t, status, w.Code, method)
+	}
+}
+
+func TestDecisionLogs(t *testing.T) {
+	// The policy sees the values of the headers which are masked in the logs
+	policy := `package http
+default allow = false
+allow {
+	input.request.method == "GET"
+	input.request.headers["Authorization"] == "Bearer s3cr3t"
+}`
+
+	tests := map[string]struct {
+		properties map[string]string
+		// resolver returns the components sending the data of the decisions to the channel
+		resolver func(t *testing.T, decisions chan []byte) components.Resolver
+		headers  map[string]string
+	}{
+		"output binding": {
+			properties: map[string]string{
+				"decisionLogOutputBinding": "decisions",
+			},
+			resolver: func(t *testing.T, decisions chan []byte) components.Resolver {
+				invocations := make(chan *bindings.InvokeRequest, 10)
+				go func() {
+					for req := range invocations {
+						assert.Equal(t, bindings.CreateOperation, req.Operation)
+						assert.Equal(t, "application/json", req.Metadata["contentType"])
+						decisions <- req.Data
+					}
+				}()
+				t.Cleanup(func() { close(invocations) })
+				return fakeComponents{bindings: map[string]bindings.OutputBinding{
+					"decisions": &fakeBinding{invocations: invocations},
+				}}
+			},
+			headers: map[string]string{
+				"Authorization": "[REDACTED]",
+				"X-Custom":      "visible",
+			},
+		},
+		"pubsub": {
+			properties: map[string]string{
+				"decisionLogPubsubName":    "pubsub",
+				"decisionLogTopic":         "decisions",
+				"decisionLogMaskedHeaders": "x-custom, authorization",
+			},
+			resolver: func(t *testing.T, decisions chan []byte) components.Resolver {
+				ps := inmemory.New(logger.NewLogger("test"))
+				require.NoError(t, ps.Init(pubsub.Metadata{}))
+				ctx, cancel := context.WithCancel(context.Background())
+				t.Cleanup(cancel)
+				require.NoError(t, ps.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "decisions"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+					decisions <- msg.Data
+					return nil
+				}))
+				return fakeComponents{pubsubs: map[string]pubsub.PubSub{"pubsub": ps}}
+			},
+			headers: map[string]string{
+				"Authorization": "[REDACTED]",
+				"X-Custom":      "[REDACTED]",
+			},
+		},
+		"no masked headers": {
+			properties: map[string]string{
+				"decisionLogOutputBinding": "decisions",
+				"decisionLogMaskedHeaders": " ",
+			},
+			resolver: func(t *testing.T, decisions chan []byte) components.Resolver {
+				invocations := make(chan *bindings.InvokeRequest, 10)
+				go func() {
+					for req := range invocations {
+						decisions <- req.Data
+					}
+				}()
+				t.Cleanup(func() { close(invocations) })
+				return fakeComponents{bindings: map[string]bindings.OutputBinding{
+					"decisions": &fakeBinding{invocations: invocations},
+				}}
+			},
+			headers: map[string]string{
+				"Authorization": "Bearer s3cr3t",
+				"X-Custom":      "visible",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeBundleDir(t, dir, policy, "rev-42")
+			props := map[string]string{
+				"bundlePath":      dir,
+				"decisionLogs":    "true",
+				"includedHeaders": "Authorization, X-Custom",
+			}
+			for k, v := range test.properties {
+				props[k] = v
+			}
+
+			decisions := make(chan []byte, 10)
+			opaMiddleware := NewMiddleware(logger.NewLogger("opa.test")).(*Middleware)
+			opaMiddleware.SetComponentResolver(test.resolver(t, decisions))
+			defer opaMiddleware.Close()
+			handler, err := opaMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: props}})
+			require.NoError(t, err)
+			h := handler(http.HandlerFunc(mockedRequestHandler))
+
+			for method, status := range map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusForbidden} {
+				r := httptest.NewRequest(method, "https://my.site/v1.0/state", nil)
+				r.Header.Set("Authorization", "Bearer s3cr3t")
+				r.Header.Set("X-Custom", "visible")
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				assert.Equal(t, status, w.Code, method)
+			}
+
+			for i := 0; i < 2; i++ {
+				select {
+				case data := <-decisions:
+					var entry DecisionLog
+					require.NoError(t, json.Unmarshal(data, &entry))
+					assert.NotEmpty(t, entry.DecisionID)
+					assert.Equal(t, "rev-42", entry.BundleRevision)
+					assert.Equal(t, entry.Allowed, entry.Result)
+					assert.Equal(t, entry.Input["request"].(map[string]any)["method"] == http.MethodGet, entry.Allowed)
+					assert.Empty(t, entry.Error)
+					headers := entry.Input["request"].(map[string]any)["headers"]
+					assert.Equal(t, len(test.headers), len(headers.(map[string]any)))
+					for k, v := range test.headers {
+						assert.Equal(t, v, headers.(map[string]any)[k], k)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("decision log not received")
+				}
+			}
+		})
+	}
+}
+
+func TestResponseFiltering(t *testing.T) {
+	policy := `package http
+allow = {
+	"allow": true,
+	"response_headers": {"x-policy": "applied"},
+	"remove_response_headers": ["x-internal"],
+	"redact_response_fields": ["password", "$.items[*].secret", "missing.field", "$..token"],
+}`
+	app := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Internal", "secret")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/text" {
+			w.Write([]byte("not json"))
+			return
+		}
+		if r.URL.Path == "/large" {
+			w.Write([]byte(`{"password":"` + strings.Repeat("x", 100) + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"user":"alice","password":"p4ss","items":[{"id":1,"secret":"a"},{"id":2,"session":{"token":"b"}}]}`))
+	}
+
+	tests := map[string]struct {
+		rego    string
+		path    string
+		status  int
+		headers map[string]string
+		body    string
+		// hidden is a value that must not be returned
+		hidden string
+	}{
+		"redacted fields": {
+			rego:   policy,
+			path:   "/json",
+			status: http.StatusCreated,
+			headers: map[string]string{
+				"X-Policy":   "applied",
+				"X-Internal": "",
+			},
+			body: `{"user":"alice","password":"[REDACTED]","items":[{"id":1,"secret":"[REDACTED]"},{"id":2,"session":{"token":"[REDACTED]"}}]}`,
+		},
+		"body that can't be redacted": {
+			rego:    policy,
+			path:    "/text",
+			status:  http.StatusInternalServerError,
+			headers: map[string]string{opaErrorHeaderKey: "true"},
+			hidden:  "not json",
+		},
+		"body larger than maxResponseBodySize": {
+			rego:    policy,
+			path:    "/large",
+			status:  http.StatusInternalServerError,
+			headers: map[string]string{opaErrorHeaderKey: "true"},
+			hidden:  "xxx",
+		},
+		"invalid path": {
+			rego: `package http
+allow = {"allow": true, "redact_response_fields": ["$.items["]}`,
+			path:   "/json",
+			status: http.StatusInternalServerError,
+			hidden: "p4ss",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opaMiddleware := NewMiddleware(logger.NewLogger("opa.test"))
+			handler, err := opaMiddleware.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"rego":                test.rego,
+				"maxResponseBodySize": "100",
+			}}})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler(http.HandlerFunc(app)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://my.site"+test.path, nil))
+
+			assert.Equal(t, test.status, w.Code)
+			for k, v := range test.headers {
+				assert.Equal(t, v, w.Header().Get(k), k)
+			}
+			if test.body != "" {
+				assert.JSONEq(t, test.body, w.Body.String())
+			}
+			if test.hidden != "" {
+				assert.NotContains(t, w.Body.String(), test.hidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	decisionLogBufferSize  = 1000
+	decisionLogTimeout     = 10 * time.Second
+	decisionLogContentType = "application/json"
+)
+
+// DecisionLog is the record of a policy evaluation.
+type DecisionLog struct {
+	DecisionID     string         `json:"decision_id"`
+	Timestamp      time.Time      `json:"timestamp"`
+	BundleRevision string         `json:"bundle_revision,omitempty"`
+	Input          map[string]any `json:"input"`
+	Result         any            `json:"result,omitempty"`
+	Allowed        bool           `json:"allowed"`
+	Error          string         `json:"error,omitempty"`
+	LatencyMs      float64        `json:"latency_ms"`
+}
+
+type decisionLogSink interface {
+	Log(entry *DecisionLog)
+}
+
+// newDecisionLogSink returns the sink for the decisions: the output binding or the pubsub named in the metadata,
+// or the logger. The decisions sent to a component are sent in a goroutine, added to wg, which returns when ctx
+// is canceled.
+func (m *Middleware) newDecisionLogSink(ctx context.Context, wg *sync.WaitGroup, meta *middlewareMetadata) (decisionLogSink, error) {
+	var send func(ctx context.Context, data []byte) error
+	switch {
+	case meta.DecisionLogOutputBinding == "" && meta.DecisionLogPubsubName == "":
+		return &loggerDecisionLogSink{logger: m.logger}, nil
+	case m.resolver == nil:
+		return nil, errors.New("the decision logs can't be sent: the host doesn't provide other components")
+	case meta.DecisionLogOutputBinding != "":
+		binding, err := m.resolver.OutputBinding(meta.DecisionLogOutputBinding)
+		if err != nil {
+			return nil, fmt.Errorf("error getting output binding %s: %w", meta.DecisionLogOutputBinding, err)
+		}
+		send = func(ctx context.Context, data []byte) error {
+			_, err := binding.Invoke(ctx, &bindings.InvokeRequest{
+				Data:      data,
+				Operation: bindings.OperationKind(meta.DecisionLogBindingOperation),
+				Metadata:  map[string]string{"contentType": decisionLogContentType},
+			})
+			return err
+		}
+	default:
+		ps, err := m.resolver.PubSub(meta.DecisionLogPubsubName)
+		if err != nil {
+			return nil, fmt.Errorf("error getting pubsub %s: %w", meta.DecisionLogPubsubName, err)
+		}
+		send = func(ctx context.Context, data []byte) error {
+			contentType := decisionLogContentType
+			return ps.Publish(ctx, &pubsub.PublishRequest{
+				Data:        data,
+				PubsubName:  meta.DecisionLogPubsubName,
+				Topic:       meta.DecisionLogTopic,
+				ContentType: &contentType,
+			})
+		}
+	}
+
+	s := &componentDecisionLogSink{
+		send:    send,
+		logger:  m.logger,
+		entries: make(chan *DecisionLog, decisionLogBufferSize),
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.run(ctx)
+	}()
+	return s, nil
+}
+
+// loggerDecisionLogSink writes the decisions to the component's logger.
+type loggerDecisionLogSink struct {
+	logger logger.Logger
+}
+
+func (s *loggerDecisionLogSink) Log(entry *DecisionLog) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		s.logger.Warnf("Failed to serialize OPA decision log: %v", err)
+		return
+	}
+	s.logger.Infof("OPA decision: %s", b)
+}
+
+// componentDecisionLogSink sends the decisions to an output binding or a pubsub in the background, so requests
+// aren't slowed down. Decisions are dropped when the buffer is full.
+type componentDecisionLogSink struct {
+	send    func(ctx context.Context, data []byte) error
+	logger  logger.Logger
+	entries chan *DecisionLog
+}
+
+func (s *componentDecisionLogSink) Log(entry *DecisionLog) {
+	select {
+	case s.entries <- entry:
+	default:
+		s.logger.Warnf("OPA decision log buffer is full, dropping decision %s", entry.DecisionID)
+	}
+}
+
+func (s *componentDecisionLogSink) run(ctx context.Context) {
+	for {
+		var entry *DecisionLog
+		select {
+		case <-ctx.Done():
+			return
+		case entry = <-s.entries:
+		}
+
+		b, err := json.Marshal(entry)
+		if err != nil {
+			s.logger.Warnf("Failed to serialize OPA decision log: %v", err)
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, decisionLogTimeout)
+		err = s.send(sendCtx, b)
+		cancel()
+		if err != nil {
+			s.logger.Warnf("Failed to send OPA decision log %s: %v", entry.DecisionID, err)
+		}
+	}
+}
+
+func (p *policy) logDecision(input map[string]any, result any, allowed bool, err error, latency time.Duration) {
+	if p.decisionLogs == nil {
+		return
+	}
+	entry := &DecisionLog{
+		DecisionID: uuid.NewString(),
+		Timestamp:  time.Now().UTC(),
+		Input:      maskHeaders(input, p.maskedHeaders),
+		Result:     result,
+		Allowed:    allowed,
+		LatencyMs:  float64(latency.Microseconds()) / 1000,
+	}
+	if revision := p.revision.Load(); revision != nil {
+		entry.BundleRevision = *revision
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	p.decisionLogs.Log(entry)
+}
+
+// maskHeaders returns a copy of the input in which the values of the masked request headers are redacted.
+// The input itself is left unchanged, as it's shared with the policy evaluation.
+func maskHeaders(input map[string]any, masked []string) map[string]any {
+	req, ok := input["request"].(map[string]any)
+	if !ok {
+		return input
+	}
+	headers, ok := req["headers"].(map[string]string)
+	if !ok {
+		return input
+	}
+
+	var maskedHeaders map[string]string
+	for _, name := range masked {
+		if _, ok := headers[name]; !ok {
+			continue
+		}
+		if maskedHeaders == nil {
+			maskedHeaders = make(map[string]string, len(headers))
+			for k, v := range headers {
+				maskedHeaders[k] = v
+			}
+		}
+		maskedHeaders[name] = redactedValue
+	}
+	if maskedHeaders == nil {
+		return input
+	}
+
+	maskedReq := make(map[string]any, len(req))
+	for k, v := range req {
+		maskedReq[k] = v
+	}
+	maskedReq["headers"] = maskedHeaders
+	res := make(map[string]any, len(input))
+	for k, v := range input {
+		res[k] = v
+	}
+	res["request"] = maskedReq
+	return res
+}
@@ -15,7 +15,6 @@ package opa
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,11 +24,13 @@ import (
 	"net/textproto"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/open-policy-agent/opa/rego"
 	"k8s.io/utils/strings/slices"
 
+	"github.com/dapr/components-contrib/components"
 	"github.com/dapr/components-contrib/internal/httputils"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/components-contrib/middleware"
@@ -44,6 +45,37 @@ type middlewareMetadata struct {
 	IncludedHeaders       string   `json:"includedHeaders,omitempty"`
 	ReadBody              string   `json:"readBody,omitempty"`
 	includedHeadersParsed []string `json:"-"`
+
+	// BundlePath is a directory holding an OPA bundle.
+	BundlePath string `json:"bundlePath,omitempty"`
+	// BundleURL is the URL of an OPA bundle tarball.
+	BundleURL string `json:"bundleURL,omitempty"`
+	// BundleReloadInterval is how often the bundle is reloaded. Set to 0 to disable reloading.
+	BundleReloadInterval       string        `json:"bundleReloadInterval,omitempty"`
+	bundleReloadIntervalParsed time.Duration `json:"-"`
+	// MaxBundleSize is the largest bundle, in bytes, that is downloaded from BundleURL. It also limits the size of
+	// each file of the bundle.
+	MaxBundleSize       string `json:"maxBundleSize,omitempty"`
+	maxBundleSizeParsed int64  `json:"-"`
+	// DecisionLogs enables logging of every decision. Decisions are written to the logger, unless
+	// DecisionLogOutputBinding or DecisionLogPubsubName is set.
+	DecisionLogs string `json:"decisionLogs,omitempty"`
+	// DecisionLogOutputBinding is the name of the output binding component the decisions are sent to.
+	DecisionLogOutputBinding string `json:"decisionLogOutputBinding,omitempty"`
+	// DecisionLogBindingOperation is the operation invoked on the output binding.
+	DecisionLogBindingOperation string `json:"decisionLogBindingOperation,omitempty"`
+	// DecisionLogPubsubName is the name of the pubsub component the decisions are published to.
+	DecisionLogPubsubName string `json:"decisionLogPubsubName,omitempty"`
+	// DecisionLogTopic is the topic the decisions are published to.
+	DecisionLogTopic string `json:"decisionLogTopic,omitempty"`
+	// DecisionLogMaskedHeaders is a comma-separated list of the request headers whose values are replaced in the
+	// logged decisions.
+	DecisionLogMaskedHeaders       string   `json:"decisionLogMaskedHeaders,omitempty"`
+	decisionLogMaskedHeadersParsed []string `json:"-"`
+	// MaxResponseBodySize is the largest response body, in bytes, that fields are redacted from. Larger
+	// responses are rejected.
+	MaxResponseBodySize       string `json:"maxResponseBodySize,omitempty"`
+	maxResponseBodySizeParsed int64  `json:"-"`
 }
 
 // NewMiddleware returns a new Open Policy Agent middleware.
@@ -51,9 +83,20 @@ func NewMiddleware(logger logger.Logger) middleware.Middleware {
 	return &Middleware{logger: logger}
 }
 
+var _ components.ResolverConsumer = (*Middleware)(nil)
+
 // Middleware is an OPA  middleware.
 type Middleware struct {
-	logger logger.Logger
+	logger   logger.Logger
+	resolver components.Resolver
+
+	lock     sync.Mutex
+	policies []*policy
+}
+
+// SetComponentResolver sets the resolver of the output binding or of the pubsub named in the metadata.
+func (m *Middleware) SetComponentResolver(resolver components.Resolver) {
+	m.resolver = resolver
 }
 
 // RegoResult is the expected result from rego policy.
@@ -61,9 +104,24 @@ type RegoResult struct {
 	Allow             bool              `json:"allow"`
 	AdditionalHeaders map[string]string `json:"additional_headers,omitempty"`
 	StatusCode        int               `json:"status_code,omitempty"`
+	// ResponseHeaders are set on the app's response, overriding the app's values.
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	// RemoveResponseHeaders are removed from the app's response.
+	RemoveResponseHeaders []string `json:"remove_response_headers,omitempty"`
+	// RedactResponseFields are JSONPath expressions, such as "$.password" or "$.items[*].secret", of the
+	// fields redacted from the app's JSON response.
+	RedactResponseFields []string `json:"redact_response_fields,omitempty"`
 }
 
-const opaErrorHeaderKey = "x-dapr-opa-error"
+const (
+	opaErrorHeaderKey = "x-dapr-opa-error"
+
+	defaultBundleReloadInterval        = time.Minute
+	defaultMaxBundleSize               = 64 << 20
+	defaultMaxResponseBodySize         = 4 << 20
+	defaultDecisionLogBindingOperation = "create"
+	defaultDecisionLogMaskedHeaders    = "Authorization,Proxy-Authorization,Cookie"
+)
 
 var (
 	errOpaNoResult          = errors.New("received no results back from rego policy. Are you setting data.http.allow?")
@@ -112,27 +170,49 @@ func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Ha
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
-	query, err := rego.New(
-		rego.Query("result = data.http.allow"),
-		rego.Module("inline.rego", meta.Rego),
-	).PrepareForEval(ctx)
-	cancel()
+	p, err := m.newPolicy(meta)
 	if err != nil {
 		return nil, err
 	}
+	m.lock.Lock()
+	m.policies = append(m.policies, p)
+	m.lock.Unlock()
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if allow := m.evalRequest(w, r, meta, &query); !allow {
+			result, allow := m.evalRequest(w, r, meta, p)
+			if !allow {
+				return
+			}
+			if !result.rewritesResponse() {
+				next.ServeHTTP(w, r)
 				return
 			}
-			next.ServeHTTP(w, r)
+
+			rw := newResponseRewriter(w, result, meta.maxResponseBodySizeParsed)
+			next.ServeHTTP(rw, r)
+			if err := rw.finish(); err != nil {
+				w.Header().Set(opaErrorHeaderKey, "true")
+				httputils.RespondWithError(w, http.StatusInternalServerError)
+				m.logger.Warnf("Error redacting response: %v", err)
+			}
 		})
 	}, nil
 }
 
-func (m *Middleware) evalRequest(w http.ResponseWriter, r *http.Request, meta *middlewareMetadata, query *rego.PreparedEvalQuery) bool {
+// Close stops reloading the bundles and sending the decision logs of the handlers.
+func (m *Middleware) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, p := range m.policies {
+		p.close()
+	}
+	m.policies = nil
+	return nil
+}
+
+func (m *Middleware) evalRequest(w http.ResponseWriter, r *http.Request, meta *middlewareMetadata, p *policy) (*RegoResult, bool) {
 	headers := map[string]string{}
 
 	for key, value := range r.Header {
@@ -164,41 +244,45 @@ func (m *Middleware) evalRequest(w http.ResponseWriter, r *http.Request, meta *m
 		},
 	}
 
-	results, err := query.Eval(r.Context(), rego.EvalInput(input))
+	start := time.Now()
+	results, err := p.query.Load().Eval(r.Context(), rego.EvalInput(input))
+	if err == nil && len(results) == 0 {
+		err = errOpaNoResult
+	}
 	if err != nil {
+		p.logDecision(input, nil, false, err, time.Since(start))
 		m.opaError(w, meta, err)
-		return false
+		return nil, false
 	}
 
-	if len(results) == 0 {
-		m.opaError(w, meta, errOpaNoResult)
-		return false
-	}
-
-	return m.handleRegoResult(w, meta, results[0].Bindings["result"])
+	result := results[0].Bindings["result"]
+	regoResult, allowed := m.handleRegoResult(w, meta, result)
+	p.logDecision(input, result, allowed, nil, time.Since(start))
+	return regoResult, allowed
 }
 
 // handleRegoResult takes the in process request and open policy agent evaluation result
 // and maps it the appropriate response or headers.
 // It returns true if the request should continue, or false if a response should be immediately returned.
-func (m *Middleware) handleRegoResult(w http.ResponseWriter, meta *middlewareMetadata, result any) bool {
+// The parsed result is returned when the policy returned an object.
+func (m *Middleware) handleRegoResult(w http.ResponseWriter, meta *middlewareMetadata, result any) (*RegoResult, bool) {
 	if allowed, ok := result.(bool); ok {
 		if !allowed {
 			httputils.RespondWithError(w, int(meta.DefaultStatus))
 		}
-		return allowed
+		return nil, allowed
 	}
 
 	if _, ok := result.(map[string]any); !ok {
 		m.opaError(w, meta, errOpaInvalidResultType)
-		return false
+		return nil, false
 	}
 
 	// Is it expensive to marshal back and forth? Should we just manually pull out properties?
 	marshaled, err := json.Marshal(result)
 	if err != nil {
 		m.opaError(w, meta, err)
-		return false
+		return nil, false
 	}
 
 	regoResult := RegoResult{
@@ -209,7 +293,7 @@ func (m *Middleware) handleRegoResult(w http.ResponseWriter, meta *middlewareMet
 
 	if err = json.Unmarshal(marshaled, &regoResult); err != nil {
 		m.opaError(w, meta, err)
-		return false
+		return nil, false
 	}
 
 	// Set the headers on the ongoing request (overriding as necessary)
@@ -222,7 +306,7 @@ func (m *Middleware) handleRegoResult(w http.ResponseWriter, meta *middlewareMet
 		httputils.RespondWithError(w, regoResult.StatusCode)
 	}
 
-	return regoResult.Allow
+	return &regoResult, regoResult.Allow
 }
 
 func (m *Middleware) opaError(w http.ResponseWriter, meta *middlewareMetadata, err error) {
@@ -238,23 +322,65 @@ func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*middlewar
 	}
 
 	meta := middlewareMetadata{
-		DefaultStatus: 403,
+		DefaultStatus:               403,
+		DecisionLogBindingOperation: defaultDecisionLogBindingOperation,
+		DecisionLogMaskedHeaders:    defaultDecisionLogMaskedHeaders,
 	}
 	err = json.Unmarshal(b, &meta)
 	if err != nil {
 		return nil, err
 	}
 
-	meta.includedHeadersParsed = strings.Split(meta.IncludedHeaders, ",")
+	meta.includedHeadersParsed = parseHeaderList(meta.IncludedHeaders)
+	meta.decisionLogMaskedHeadersParsed = parseHeaderList(meta.DecisionLogMaskedHeaders)
+
+	if meta.Rego == "" && meta.BundlePath == "" && meta.BundleURL == "" {
+		return nil, errors.New("one of rego, bundlePath or bundleURL is required")
+	}
+	if meta.BundlePath != "" && meta.BundleURL != "" {
+		return nil, errors.New("only one of bundlePath and bundleURL can be set")
+	}
+	meta.bundleReloadIntervalParsed = defaultBundleReloadInterval
+	if meta.BundleReloadInterval != "" {
+		meta.bundleReloadIntervalParsed, err = time.ParseDuration(meta.BundleReloadInterval)
+		if err != nil || meta.bundleReloadIntervalParsed < 0 {
+			return nil, fmt.Errorf("invalid bundleReloadInterval %q", meta.BundleReloadInterval)
+		}
+	}
+	meta.maxResponseBodySizeParsed = defaultMaxResponseBodySize
+	if meta.MaxResponseBodySize != "" {
+		meta.maxResponseBodySizeParsed, err = strconv.ParseInt(meta.MaxResponseBodySize, 10, 64)
+		if err != nil || meta.maxResponseBodySizeParsed <= 0 {
+			return nil, fmt.Errorf("invalid maxResponseBodySize %q", meta.MaxResponseBodySize)
+		}
+	}
+	meta.maxBundleSizeParsed = defaultMaxBundleSize
+	if meta.MaxBundleSize != "" {
+		meta.maxBundleSizeParsed, err = strconv.ParseInt(meta.MaxBundleSize, 10, 64)
+		if err != nil || meta.maxBundleSizeParsed <= 0 {
+			return nil, fmt.Errorf("invalid maxBundleSize %q", meta.MaxBundleSize)
+		}
+	}
+	switch {
+	case meta.DecisionLogOutputBinding != "" && meta.DecisionLogPubsubName != "":
+		return nil, errors.New("only one of decisionLogOutputBinding or decisionLogPubsubName can be set")
+	case meta.DecisionLogPubsubName != "" && meta.DecisionLogTopic == "":
+		return nil, errors.New("decisionLogTopic is required with decisionLogPubsubName")
+	}
+
+	return &meta, nil
+}
+
+// parseHeaderList returns the canonical names of a comma-separated list of headers.
+func parseHeaderList(list string) []string {
+	headers := strings.Split(list, ",")
 	n := 0
-	for i := range meta.includedHeadersParsed {
-		scrubbed := strings.ReplaceAll(meta.includedHeadersParsed[i], " ", "")
+	for i := range headers {
+		scrubbed := strings.ReplaceAll(headers[i], " ", "")
 		if scrubbed != "" {
-			meta.includedHeadersParsed[n] = textproto.CanonicalMIMEHeaderKey(scrubbed)
+			headers[n] = textproto.CanonicalMIMEHeaderKey(scrubbed)
 			n++
 		}
 	}
-	meta.includedHeadersParsed = meta.includedHeadersParsed[:n]
-
-	return &meta, nil
+	return headers[:n]
 }
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dapr/components-contrib/internal/jsonpath"
+)
+
+const redactedValue = "[REDACTED]"
+
+var (
+	errResponseNotJSON  = errors.New("the response body is not JSON")
+	errResponseTooLarge = errors.New("the response body is larger than maxResponseBodySize")
+)
+
+// rewritesResponse returns true if the policy changes the app's response.
+func (r *RegoResult) rewritesResponse() bool {
+	return r != nil && (len(r.ResponseHeaders) > 0 || len(r.RemoveResponseHeaders) > 0 || len(r.RedactResponseFields) > 0)
+}
+
+// responseRewriter applies the response headers of the policy result when the app writes the response,
+// and buffers the body, up to maxBodySize bytes, when fields have to be redacted.
+type responseRewriter struct {
+	http.ResponseWriter
+	result      *RegoResult
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	maxBodySize int64
+	tooLarge    bool
+}
+
+func newResponseRewriter(w http.ResponseWriter, result *RegoResult, maxBodySize int64) *responseRewriter {
+	return &responseRewriter{
+		ResponseWriter: w,
+		result:         result,
+		status:         http.StatusOK,
+		maxBodySize:    maxBodySize,
+	}
+}
+
+func (rw *responseRewriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = status
+
+	h := rw.Header()
+	for _, key := range rw.result.RemoveResponseHeaders {
+		h.Del(key)
+	}
+	for key, value := range rw.result.ResponseHeaders {
+		h.Set(key, value)
+	}
+	if len(rw.result.RedactResponseFields) == 0 {
+		rw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rw *responseRewriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if len(rw.result.RedactResponseFields) == 0 {
+		return rw.ResponseWriter.Write(b)
+	}
+	if rw.tooLarge || int64(rw.buf.Len()+len(b)) > rw.maxBodySize {
+		// The rest of the body is discarded, the response is rejected in finish
+		rw.tooLarge = true
+		rw.buf.Reset()
+		return len(b), nil
+	}
+	return rw.buf.Write(b)
+}
+
+// finish sends the redacted response. It returns an error if the body couldn't be redacted, in which case
+// nothing was sent.
+func (rw *responseRewriter) finish() error {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if len(rw.result.RedactResponseFields) == 0 {
+		return nil
+	}
+	if rw.tooLarge {
+		return errResponseTooLarge
+	}
+
+	body := rw.buf.Bytes()
+	if len(bytes.TrimSpace(body)) > 0 {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			// Fail closed: the fields can't be redacted from a non-JSON body
+			return errResponseNotJSON
+		}
+		for _, field := range rw.result.RedactResponseFields {
+			p, err := jsonpath.Parse(field)
+			if err != nil {
+				return err
+			}
+			if len(p) == 0 {
+				return fmt.Errorf("invalid path %q: the whole document can't be redacted", field)
+			}
+			doc, _ = jsonpath.Update(doc, p, func(any) (any, error) {
+				return redactedValue, nil
+			})
+		}
+		var err error
+		body, err = json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	rw.ResponseWriter.WriteHeader(rw.status)
+	rw.ResponseWriter.Write(body)
+	return nil
+}
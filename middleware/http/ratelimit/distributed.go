@@ -0,0 +1,320 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
+
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+)
+
+// nowScript sets now to the time of the Redis server in milliseconds, so that the limits don't depend on the
+// clocks of the replicas. ARGV[3] overrides it in tests.
+// replicate_commands allows writes after TIME on Redis versions before 5.
+const nowScript = `
+redis.replicate_commands()
+local now = tonumber(ARGV[3])
+if now == nil then
+	local t = redis.call('TIME')
+	now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+end
+`
+
+// tokenBucketScript refills the bucket based on the time elapsed since the last request and takes a token.
+// It returns {allowed, remaining, retryAfterMs, resetMs}.
+const tokenBucketScript = nowScript + `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, math.floor(tokens), retry, math.ceil((burst - tokens) * 1000 / rate)}
+`
+
+// slidingWindowScript keeps the timestamps of the requests in the window in a sorted set.
+// ARGV[4] makes the member of the request unique.
+// It returns {allowed, remaining, retryAfterMs, resetMs}.
+const slidingWindowScript = nowScript + `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, now .. '-' .. ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local reset = 0
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if #oldest > 0 then
+	reset = math.max(0, tonumber(oldest[2]) + window - now)
+end
+local retry = 0
+if allowed == 0 then
+	retry = reset
+end
+return {allowed, limit - count, retry, reset}
+`
+
+// rateLimitRule is a limit applied to the requests matching a path prefix and methods.
+// Unset limits are inherited from the middleware metadata.
+type rateLimitRule struct {
+	Name                 string   `json:"name"`
+	PathPrefix           string   `json:"pathPrefix"`
+	Methods              []string `json:"methods"`
+	MaxRequestsPerSecond float64  `json:"maxRequestsPerSecond"`
+	Burst                int      `json:"burst"`
+	Window               string   `json:"window"`
+	KeyBy                string   `json:"keyBy"`
+
+	window time.Duration
+	keyBy  []string
+}
+
+type limitResult struct {
+	allowed    bool
+	limit      int
+	remaining  int64
+	retryAfter time.Duration
+	reset      time.Duration
+}
+
+// distributedLimiter keeps the counters in Redis so the limits are shared by all replicas.
+type distributedLimiter struct {
+	client rediscomponent.RedisClient
+	// clock replaces the time of the Redis server when set, in tests.
+	clock     clock.Clock
+	logger    logger.Logger
+	algorithm string
+	keyPrefix string
+	failOpen  bool
+	rules     []rateLimitRule
+	fallback  rateLimitRule
+}
+
+func newDistributedLimiter(meta *rateLimitMiddlewareMetadata, client rediscomponent.RedisClient, clk clock.Clock, logger logger.Logger) (*distributedLimiter, error) {
+	l := &distributedLimiter{
+		client:    client,
+		clock:     clk,
+		logger:    logger,
+		algorithm: meta.Algorithm,
+		keyPrefix: meta.KeyPrefix,
+		failOpen:  meta.FailOpen,
+		fallback: rateLimitRule{
+			Name:                 "default",
+			MaxRequestsPerSecond: meta.MaxRequestsPerSecond,
+			Burst:                meta.Burst,
+			window:               meta.Window,
+			KeyBy:                meta.KeyBy,
+		},
+	}
+	if l.fallback.Burst == 0 {
+		l.fallback.Burst = int(math.Ceil(meta.MaxRequestsPerSecond))
+	}
+	var err error
+	l.fallback.keyBy, err = parseKeyBy(meta.KeyBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.Rules != "" {
+		err = json.Unmarshal([]byte(meta.Rules), &l.rules)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ratelimit middleware rules: %w", err)
+		}
+	}
+	for i := range l.rules {
+		err = l.rules[i].inherit(l.fallback, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// inherit validates the rule and fills the unset limits from the default rule.
+func (r *rateLimitRule) inherit(fallback rateLimitRule, i int) (err error) {
+	if r.Name == "" {
+		r.Name = "rule" + strconv.Itoa(i)
+	}
+	if r.MaxRequestsPerSecond < 0 || r.Burst < 0 {
+		return fmt.Errorf("ratelimit middleware rule %s: limits must not be negative", r.Name)
+	}
+	if r.MaxRequestsPerSecond == 0 {
+		r.MaxRequestsPerSecond = fallback.MaxRequestsPerSecond
+	}
+	if r.Burst == 0 {
+		r.Burst = int(math.Ceil(r.MaxRequestsPerSecond))
+	}
+	r.window = fallback.window
+	if r.Window != "" {
+		r.window, err = time.ParseDuration(r.Window)
+		if err != nil || r.window <= 0 {
+			return fmt.Errorf("ratelimit middleware rule %s: invalid window %q", r.Name, r.Window)
+		}
+	}
+	if r.KeyBy == "" {
+		r.KeyBy = fallback.KeyBy
+	}
+	r.keyBy, err = parseKeyBy(r.KeyBy)
+	if err != nil {
+		return fmt.Errorf("ratelimit middleware rule %s: %w", r.Name, err)
+	}
+	return nil
+}
+
+func (r *rateLimitRule) matches(req *http.Request) bool {
+	if !strings.HasPrefix(req.URL.Path, r.PathPrefix) {
+		return false
+	}
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if strings.EqualFold(m, req.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+// limit returns the number of requests allowed in a burst or a window.
+func (r *rateLimitRule) limit(algorithm string) int {
+	if algorithm == algorithmSlidingWindow {
+		return int(math.Ceil(r.MaxRequestsPerSecond * r.window.Seconds()))
+	}
+	return r.Burst
+}
+
+func (l *distributedLimiter) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule := l.ruleFor(r)
+		res, err := l.take(r, rule)
+		if err != nil {
+			l.logger.Errorf("error checking rate limit for rule %s: %v", rule.Name, err)
+			if l.failOpen {
+				next.ServeHTTP(w, r)
+			} else {
+				httputils.RespondWithError(w, http.StatusServiceUnavailable)
+			}
+			return
+		}
+
+		h := w.Header()
+		h.Set(headerRateLimitLimit, strconv.Itoa(res.limit))
+		h.Set(headerRateLimitRemaining, strconv.FormatInt(res.remaining, 10))
+		h.Set(headerRateLimitReset, strconv.FormatInt(ceilSeconds(res.reset), 10))
+		if !res.allowed {
+			h.Set(headerRetryAfter, strconv.FormatInt(ceilSeconds(res.retryAfter), 10))
+			httputils.RespondWithError(w, http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *distributedLimiter) ruleFor(r *http.Request) *rateLimitRule {
+	for i := range l.rules {
+		if l.rules[i].matches(r) {
+			return &l.rules[i]
+		}
+	}
+	return &l.fallback
+}
+
+// take consumes one request from the limit of the rule for the request's key.
+func (l *distributedLimiter) take(r *http.Request, rule *rateLimitRule) (*limitResult, error) {
+	key := l.keyPrefix + ":" + rule.Name + ":" + requestKey(r, rule.keyBy)
+	limit := rule.limit(l.algorithm)
+	now := ""
+	if l.clock != nil {
+		now = strconv.FormatInt(l.clock.Now().UnixMilli(), 10)
+	}
+
+	var (
+		res interface{}
+		err error
+	)
+	if l.algorithm == algorithmSlidingWindow {
+		res, err = l.client.DoWriteResult(r.Context(), "EVAL", slidingWindowScript, 1, key,
+			limit, rule.window.Milliseconds(), now, uuid.NewString())
+	} else {
+		res, err = l.client.DoWriteResult(r.Context(), "EVAL", tokenBucketScript, 1, key,
+			strconv.FormatFloat(rule.MaxRequestsPerSecond, 'f', -1, 64), rule.Burst, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script reply %v", res)
+	}
+	ints := make([]int64, len(vals))
+	for i, v := range vals {
+		ints[i], ok = v.(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected rate limit script reply %v", res)
+		}
+	}
+
+	return &limitResult{
+		allowed:    ints[0] == 1,
+		limit:      limit,
+		remaining:  ints[1],
+		retryAfter: time.Duration(ints[2]) * time.Millisecond,
+		reset:      time.Duration(ints[3]) * time.Millisecond,
+	}, nil
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	libstring "github.com/didip/tollbooth/v7/libstring"
+)
+
+const (
+	keyByIP     = "ip"
+	keyByMethod = "method"
+	keyByPath   = "path"
+
+	keyByHeaderPrefix = "header:"
+	keyByClaimPrefix  = "claim:"
+)
+
+var ipLookups = []string{"X-Forwarded-For", "X-Real-IP", "RemoteAddr"}
+
+// parseKeyBy parses a comma-separated list of the request attributes a limit is keyed by.
+func parseKeyBy(keyBy string) ([]string, error) {
+	var parts []string
+	for _, p := range strings.Split(keyBy, ",") {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "":
+			continue
+		case p == keyByIP, p == keyByMethod, p == keyByPath:
+		case strings.HasPrefix(p, keyByHeaderPrefix) && len(p) > len(keyByHeaderPrefix),
+			strings.HasPrefix(p, keyByClaimPrefix) && len(p) > len(keyByClaimPrefix):
+		default:
+			return nil, fmt.Errorf("invalid ratelimit middleware keyBy %q", p)
+		}
+		parts = append(parts, p)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("ratelimit middleware keyBy must not be empty")
+	}
+	return parts, nil
+}
+
+// requestKey returns the values of the request attributes joined together.
+// Requests missing an attribute share the limit of the empty value.
+func requestKey(r *http.Request, keyBy []string) string {
+	values := make([]string, len(keyBy))
+	for i, k := range keyBy {
+		switch {
+		case k == keyByIP:
+			values[i] = libstring.CanonicalizeIP(libstring.RemoteIP(ipLookups, 0, r))
+		case k == keyByMethod:
+			values[i] = r.Method
+		case k == keyByPath:
+			values[i] = r.URL.Path
+		case strings.HasPrefix(k, keyByHeaderPrefix):
+			values[i] = r.Header.Get(k[len(keyByHeaderPrefix):])
+		case strings.HasPrefix(k, keyByClaimPrefix):
+			values[i] = tokenClaim(r, k[len(keyByClaimPrefix):])
+		}
+	}
+	return strings.Join(values, "|")
+}
+
+// tokenClaim returns a claim of the bearer token in the Authorization header.
+// The token isn't verified: the middleware is meant to run after one that authenticates the request.
+func tokenClaim(r *http.Request, claim string) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return ""
+	}
+	parts := strings.Split(auth[7:], ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	var claims map[string]interface{}
+	if json.Unmarshal(payload, &claims) != nil {
+		return ""
+	}
+	switch v := claims[claim].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
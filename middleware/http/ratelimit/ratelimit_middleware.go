@@ -14,36 +14,79 @@ limitations under the License.
 package ratelimit
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	tollbooth "github.com/didip/tollbooth/v7"
 	libstring "github.com/didip/tollbooth/v7/libstring"
 
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
 	"github.com/dapr/kit/logger"
 )
 
 // Metadata is the ratelimit middleware config.
 type rateLimitMiddlewareMetadata struct {
-	MaxRequestsPerSecond float64 `json:"maxRequestsPerSecond"`
+	// Parsed separately to validate the value.
+	MaxRequestsPerSecond float64 `json:"maxRequestsPerSecond" mapstructure:"-"`
+
+	// Mode is either "local" for an in-process limiter or "redis" for counters shared by all replicas.
+	Mode string `json:"mode"`
+	// Algorithm used in redis mode: "tokenBucket" or "slidingWindow".
+	Algorithm string `json:"algorithm"`
+	// Burst is the token bucket capacity. Defaults to maxRequestsPerSecond rounded up.
+	Burst int `json:"burst"`
+	// Window is the sliding window length; the window allows maxRequestsPerSecond*window requests.
+	Window time.Duration `json:"window"`
+	// KeyBy is a comma-separated list of request attributes the limit is keyed by:
+	// "ip", "method", "path", "header:<name>" or "claim:<name>".
+	KeyBy string `json:"keyBy"`
+	// Rules is a JSON array of per-route rules that override the limits above.
+	Rules string `json:"rules"`
+	// KeyPrefix is prepended to all keys stored in Redis.
+	KeyPrefix string `json:"keyPrefix"`
+	// FailOpen lets requests through when Redis can't be reached.
+	FailOpen bool `json:"failOpen"`
 }
 
 const (
 	maxRequestsPerSecondKey = "maxRequestsPerSecond"
 
+	modeLocal = "local"
+	modeRedis = "redis"
+
+	algorithmTokenBucket   = "tokenBucket"
+	algorithmSlidingWindow = "slidingWindow"
+
 	// Defaults.
 	defaultMaxRequestsPerSecond = 100
+	defaultWindow               = time.Second
+	defaultKeyBy                = "ip"
+	defaultKeyPrefix            = "dapr-ratelimit"
 )
 
 // NewRateLimitMiddleware returns a new ratelimit middleware.
-func NewRateLimitMiddleware(_ logger.Logger) middleware.Middleware {
-	return &Middleware{}
+func NewRateLimitMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: logger,
+	}
 }
 
 // Middleware is an ratelimit middleware.
-type Middleware struct{}
+type Middleware struct {
+	logger logger.Logger
+	// clock replaces the time of the Redis server in redis mode, in tests.
+	clock clock.Clock
+
+	lock    sync.Mutex
+	clients []rediscomponent.RedisClient
+}
 
 // GetHandler returns the HTTP handler provided by the middleware.
 func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
@@ -52,6 +95,22 @@ func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Ha
 		return nil, err
 	}
 
+	if meta.Mode == modeRedis {
+		client, _, err := rediscomponent.ParseClientFromProperties(metadata.Properties, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating redis client for ratelimit middleware: %w", err)
+		}
+		limiter, err := newDistributedLimiter(meta, client, m.clock, m.logger)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		m.lock.Lock()
+		m.clients = append(m.clients, client)
+		m.lock.Unlock()
+		return limiter.handler, nil
+	}
+
 	limiter := tollbooth.NewLimiter(meta.MaxRequestsPerSecond, nil)
 
 	return func(next http.Handler) http.Handler {
@@ -83,8 +142,34 @@ func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Ha
 	}, nil
 }
 
+// Close implements io.Closer: it closes the Redis clients created by GetHandler.
+func (m *Middleware) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var err error
+	for _, client := range m.clients {
+		if closeErr := client.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	m.clients = nil
+	return err
+}
+
 func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*rateLimitMiddlewareMetadata, error) {
-	var middlewareMetadata rateLimitMiddlewareMetadata
+	middlewareMetadata := rateLimitMiddlewareMetadata{
+		Mode:      modeLocal,
+		Algorithm: algorithmTokenBucket,
+		Window:    defaultWindow,
+		KeyBy:     defaultKeyBy,
+		KeyPrefix: defaultKeyPrefix,
+		FailOpen:  true,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ratelimit middleware metadata: %w", err)
+	}
 
 	middlewareMetadata.MaxRequestsPerSecond = defaultMaxRequestsPerSecond
 	if val, ok := metadata.Properties[maxRequestsPerSecondKey]; ok {
@@ -98,5 +183,22 @@ func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*rateLimit
 		middlewareMetadata.MaxRequestsPerSecond = f
 	}
 
+	switch middlewareMetadata.Mode {
+	case modeLocal, modeRedis:
+	default:
+		return nil, fmt.Errorf("ratelimit middleware property mode must be %q or %q", modeLocal, modeRedis)
+	}
+	switch middlewareMetadata.Algorithm {
+	case algorithmTokenBucket, algorithmSlidingWindow:
+	default:
+		return nil, fmt.Errorf("ratelimit middleware property algorithm must be %q or %q", algorithmTokenBucket, algorithmSlidingWindow)
+	}
+	if middlewareMetadata.Burst < 0 {
+		return nil, errors.New("ratelimit middleware property burst must not be negative")
+	}
+	if middlewareMetadata.Window <= 0 {
+		return nil, errors.New("ratelimit middleware property window must be a positive duration")
+	}
+
 	return &middlewareMetadata, nil
 }
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+)
+
+var (
+	defaultAllowedMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	}
+	// defaultAllowedHeaders are the CORS-safelisted request headers.
+	defaultAllowedHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type"}
+)
+
+type securityMiddlewareMetadata struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests. "*" allows any origin and
+	// a "*" in an origin matches any subdomain, as in "https://*.example.com".
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// AllowedMethods are the methods allowed in cross-origin requests.
+	AllowedMethods []string `json:"allowedMethods"`
+	// AllowedHeaders are the request headers allowed in cross-origin requests. "*" allows any header.
+	// Defaults to the CORS-safelisted request headers.
+	AllowedHeaders []string `json:"allowedHeaders"`
+	// ExposedHeaders are the response headers exposed to the browser.
+	ExposedHeaders []string `json:"exposedHeaders"`
+	// AllowCredentials allows cookies and credentials in cross-origin requests. It can't be used when any origin is
+	// allowed.
+	AllowCredentials bool `json:"allowCredentials"`
+	// MaxAge is how long browsers can cache the preflight response.
+	MaxAge time.Duration `json:"maxAge"`
+
+	// HSTSMaxAge enables the Strict-Transport-Security header when set.
+	HSTSMaxAge            time.Duration `json:"hstsMaxAge"`
+	HSTSIncludeSubdomains bool          `json:"hstsIncludeSubdomains"`
+	HSTSPreload           bool          `json:"hstsPreload"`
+	// ContentSecurityPolicy is the value of the Content-Security-Policy header.
+	ContentSecurityPolicy string `json:"contentSecurityPolicy"`
+	// FrameOptions is the value of the X-Frame-Options header. Defaults to DENY.
+	FrameOptions string `json:"frameOptions"`
+	// ReferrerPolicy is the value of the Referrer-Policy header. Defaults to strict-origin-when-cross-origin.
+	ReferrerPolicy string `json:"referrerPolicy"`
+	// ContentTypeOptions is the value of the X-Content-Type-Options header. Defaults to nosniff.
+	ContentTypeOptions string `json:"contentTypeOptions"`
+
+	// MaxBodySize is the largest request body accepted, in bytes. 0 means unlimited.
+	MaxBodySize int64 `json:"maxBodySize"`
+}
+
+func getNativeMetadata(metadata middleware.Metadata) (*securityMiddlewareMetadata, error) {
+	middlewareMetadata := securityMiddlewareMetadata{
+		// Copies, as decoding overwrites the elements of the slices
+		AllowedMethods:     append([]string(nil), defaultAllowedMethods...),
+		AllowedHeaders:     append([]string(nil), defaultAllowedHeaders...),
+		FrameOptions:       "DENY",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+		ContentTypeOptions: "nosniff",
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.MaxBodySize < 0 {
+		return nil, errors.New("maxBodySize must not be negative")
+	}
+	if middlewareMetadata.MaxAge < 0 || middlewareMetadata.HSTSMaxAge < 0 {
+		return nil, errors.New("maxAge and hstsMaxAge must not be negative")
+	}
+	for _, o := range middlewareMetadata.AllowedOrigins {
+		if strings.Count(o, "*") > 1 {
+			return nil, errors.New("allowed origins can contain at most one wildcard: " + o)
+		}
+	}
+	if middlewareMetadata.AllowCredentials && contains(middlewareMetadata.AllowedOrigins, "*") {
+		return nil, errors.New("allowCredentials can't be used when all origins are allowed")
+	}
+	methods := make([]string, len(middlewareMetadata.AllowedMethods))
+	for i, m := range middlewareMetadata.AllowedMethods {
+		methods[i] = strings.ToUpper(m)
+	}
+	middlewareMetadata.AllowedMethods = methods
+
+	return &middlewareMetadata, nil
+}
+
+// hstsHeader returns the value of the Strict-Transport-Security header.
+func (md *securityMiddlewareMetadata) hstsHeader() string {
+	if md.HSTSMaxAge == 0 {
+		return ""
+	}
+	v := "max-age=" + strconv.FormatInt(int64(md.HSTSMaxAge.Seconds()), 10)
+	if md.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if md.HSTSPreload {
+		v += "; preload"
+	}
+	return v
+}
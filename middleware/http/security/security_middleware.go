@@ -0,0 +1,195 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// NewMiddleware returns a new CORS and security headers middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{logger: logger}
+}
+
+// Middleware handles CORS, adds security headers to the responses and limits the size of request bodies.
+type Middleware struct {
+	logger logger.Logger
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	securityHeaders := map[string]string{
+		"Strict-Transport-Security": meta.hstsHeader(),
+		"Content-Security-Policy":   meta.ContentSecurityPolicy,
+		"X-Frame-Options":           meta.FrameOptions,
+		"Referrer-Policy":           meta.ReferrerPolicy,
+		"X-Content-Type-Options":    meta.ContentTypeOptions,
+	}
+	for k, v := range securityHeaders {
+		if v == "" {
+			delete(securityHeaders, k)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			for k, v := range securityHeaders {
+				h.Set(k, v)
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin != "" && r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				m.preflight(w, r, meta, origin)
+				return
+			}
+			if origin != "" && meta.originAllowed(origin) {
+				meta.setAllowOrigin(h, origin)
+				if len(meta.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(meta.ExposedHeaders, ", "))
+				}
+			}
+
+			if meta.MaxBodySize > 0 && !limitBody(r, meta.MaxBodySize) {
+				httputils.RespondWithError(w, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// preflight answers a CORS preflight request without calling the app.
+func (m *Middleware) preflight(w http.ResponseWriter, r *http.Request, meta *securityMiddlewareMetadata, origin string) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	requestedHeaders := splitList(r.Header.Get("Access-Control-Request-Headers"))
+	if !meta.originAllowed(origin) || !contains(meta.AllowedMethods, method) || !meta.headersAllowed(requestedHeaders) {
+		m.logger.Debugf("rejected CORS preflight from origin %s for %s %v", origin, method, requestedHeaders)
+		httputils.RespondWithError(w, http.StatusForbidden)
+		return
+	}
+
+	meta.setAllowOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(meta.AllowedMethods, ", "))
+	if len(requestedHeaders) > 0 {
+		if contains(meta.AllowedHeaders, "*") {
+			h.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+		} else {
+			h.Set("Access-Control-Allow-Headers", strings.Join(meta.AllowedHeaders, ", "))
+		}
+	}
+	if meta.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.FormatInt(int64(meta.MaxAge.Seconds()), 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// originAllowed returns true if the origin matches one of the allowed origins.
+func (md *securityMiddlewareMetadata) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range md.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (md *securityMiddlewareMetadata) headersAllowed(headers []string) bool {
+	if contains(md.AllowedHeaders, "*") {
+		return true
+	}
+	for _, header := range headers {
+		if !contains(md.AllowedHeaders, header) {
+			return false
+		}
+	}
+	return true
+}
+
+func (md *securityMiddlewareMetadata) setAllowOrigin(h http.Header, origin string) {
+	if contains(md.AllowedOrigins, "*") {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Add("Vary", "Origin")
+	if md.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// limitBody returns false if the request body is larger than max. Bodies of unknown length are buffered
+// up to max, so oversized requests are rejected before reaching the app.
+func limitBody(r *http.Request, max int64) bool {
+	if r.ContentLength > max {
+		return false
+	}
+	if r.ContentLength >= 0 || r.Body == nil || r.Body == http.NoBody {
+		r.Body = http.MaxBytesReader(nil, r.Body, max)
+		return true
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	r.Body.Close()
+	if err != nil || int64(len(buf)) > max {
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+	r.ContentLength = int64(len(buf))
+	return true
+}
+
+func splitList(v string) []string {
+	var res []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// mockedRequestHandler acts like an upstream service: it reads the request body and returns success status
+// code 200 and a fixed response body.
+func mockedRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("from mock"))
+}
+
+func preflight(origin, method, headers string) func() *http.Request {
+	return func() *http.Request {
+		r := httptest.NewRequest(http.MethodOptions, "http://localhost:3500/v1.0/state", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		return r
+	}
+}
+
+func TestSecurityMiddleware(t *testing.T) {
+	corsMetadata := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"allowedOrigins":   "https://app.example.com,https://*.example.org",
+			"allowedMethods":   "get,post",
+			"allowedHeaders":   "Content-Type,Authorization",
+			"allowCredentials": "true",
+			"maxAge":           "10m",
+		},
+	}}
+
+	tests := map[string]struct {
+		meta               middleware.Metadata
+		req                func() *http.Request
+		status             int
+		headers            *[][]string
+		shouldHandlerError bool
+	}{
+		"preflight": {
+			meta:   corsMetadata,
+			req:    preflight("https://app.example.com", "POST", "content-type, authorization"),
+			status: http.StatusNoContent,
+			headers: &[][]string{
+				{"Access-Control-Allow-Origin", "https://app.example.com"},
+				{"Access-Control-Allow-Methods", "GET, POST"},
+				{"Access-Control-Allow-Headers", "Content-Type, Authorization"},
+				{"Access-Control-Allow-Credentials", "true"},
+				{"Access-Control-Max-Age", "600"},
+			},
+		},
+		"preflight from subdomain": {
+			meta:   corsMetadata,
+			req:    preflight("https://api.eu.example.org", "GET", ""),
+			status: http.StatusNoContent,
+			headers: &[][]string{
+				{"Access-Control-Allow-Origin", "https://api.eu.example.org"},
+			},
+		},
+		"preflight from parent domain": {
+			meta:   corsMetadata,
+			req:    preflight("https://example.org", "GET", ""),
+			status: http.StatusForbidden,
+		},
+		"preflight from disallowed origin": {
+			meta:   corsMetadata,
+			req:    preflight("https://evil.com", "GET", ""),
+			status: http.StatusForbidden,
+		},
+		"preflight with disallowed method": {
+			meta:   corsMetadata,
+			req:    preflight("https://app.example.com", "DELETE", ""),
+			status: http.StatusForbidden,
+		},
+		"preflight with disallowed header": {
+			meta:   corsMetadata,
+			req:    preflight("https://app.example.com", "GET", "X-Custom"),
+			status: http.StatusForbidden,
+		},
+		"preflight with default allowed headers": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"allowedOrigins": "*"},
+			}},
+			req:    preflight("https://app.example.com", "GET", "Content-Type"),
+			status: http.StatusNoContent,
+			headers: &[][]string{
+				{"Access-Control-Allow-Origin", "*"},
+				{"Access-Control-Allow-Headers", "Accept, Accept-Language, Content-Language, Content-Type"},
+			},
+		},
+		"preflight with header not allowed by default": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"allowedOrigins": "*"},
+			}},
+			req:    preflight("https://app.example.com", "GET", "Authorization"),
+			status: http.StatusForbidden,
+		},
+		"preflight with any header allowed": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"allowedOrigins": "*", "allowedHeaders": "*"},
+			}},
+			req:    preflight("https://app.example.com", "GET", "X-Custom"),
+			status: http.StatusNoContent,
+			headers: &[][]string{
+				{"Access-Control-Allow-Headers", "X-Custom"},
+			},
+		},
+		"request with credentials": {
+			meta: corsMetadata,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/", nil)
+				r.Header.Set("Origin", "https://app.example.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"Access-Control-Allow-Origin", "https://app.example.com"},
+				{"Access-Control-Allow-Credentials", "true"},
+				{"Vary", "Origin"},
+			},
+		},
+		"request from any origin": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"allowedOrigins": "*", "exposedHeaders": "X-Custom"},
+			}},
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/", nil)
+				r.Header.Set("Origin", "https://any.example.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"Access-Control-Allow-Origin", "*"},
+				{"Access-Control-Allow-Credentials", ""},
+				{"Access-Control-Expose-Headers", "X-Custom"},
+			},
+		},
+		"request from disallowed origin": {
+			meta: corsMetadata,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/", nil)
+				r.Header.Set("Origin", "https://evil.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"Access-Control-Allow-Origin", ""},
+			},
+		},
+		"any origin with credentials": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"allowedOrigins": "*", "allowCredentials": "true"},
+			}},
+			shouldHandlerError: true,
+		},
+		"default security headers": {
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"X-Frame-Options", "DENY"},
+				{"Referrer-Policy", "strict-origin-when-cross-origin"},
+				{"X-Content-Type-Options", "nosniff"},
+				{"Strict-Transport-Security", ""},
+				{"Content-Security-Policy", ""},
+			},
+		},
+		"security headers": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"hstsMaxAge":            "8760h",
+					"hstsIncludeSubdomains": "true",
+					"contentSecurityPolicy": "default-src 'self'",
+					"frameOptions":          "SAMEORIGIN",
+					"referrerPolicy":        "",
+				},
+			}},
+			status: http.StatusOK,
+			headers: &[][]string{
+				{"Strict-Transport-Security", "max-age=31536000; includeSubDomains"},
+				{"Content-Security-Policy", "default-src 'self'"},
+				{"X-Frame-Options", "SAMEORIGIN"},
+				{"Referrer-Policy", ""},
+			},
+		},
+		"body within maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"maxBodySize": "10"},
+			}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/", strings.NewReader("0123456789"))
+			},
+			status: http.StatusOK,
+		},
+		"body larger than maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"maxBodySize": "10"},
+			}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/", strings.NewReader("0123456789a"))
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		"body of unknown length larger than maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"maxBodySize": "10"},
+			}},
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/", io.NopCloser(strings.NewReader("0123456789a")))
+				r.ContentLength = -1
+				return r
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		"body of unknown length within maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"maxBodySize": "10"},
+			}},
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/", io.NopCloser(strings.NewReader("short")))
+				r.ContentLength = -1
+				return r
+			},
+			status: http.StatusOK,
+		},
+		"negative maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"maxBodySize": "-1"},
+			}},
+			shouldHandlerError: true,
+		},
+		"negative maxAge": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"maxAge": "-1s"},
+			}},
+			shouldHandlerError: true,
+		},
+		"origin with several wildcards": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"allowedOrigins": "https://*.*.example.com"},
+			}},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("security.test")
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler, err := NewMiddleware(log).GetHandler(test.meta)
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var r *http.Request
+			if test.req != nil {
+				r = test.req()
+			} else {
+				r = httptest.NewRequest(http.MethodGet, "http://localhost:3500/", nil)
+			}
+			w := httptest.NewRecorder()
+
+			handler(http.HandlerFunc(mockedRequestHandler)).ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, "from mock", w.Body.String())
+			} else {
+				assert.NotEqual(t, "from mock", w.Body.String())
+			}
+
+			if test.headers != nil {
+				for _, header := range *test.headers {
+					assert.Equal(t, header[1], w.Header().Get(header[0]), header[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/dapr/components-contrib/internal/jsonpath"
+)
+
+var errNotJSON = errors.New("body is not JSON")
+
+// notFound marks a source path that doesn't exist.
+type notFound struct{}
+
+// escapeFunc is the function added to the actions of the templates to escape the values they print.
+const escapeFunc = "_escapeJSON"
+
+var templateFuncs = template.FuncMap{
+	escapeFunc: escapeJSON,
+	// json serializes a value as JSON
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// path returns the value at a path of a document, or nil
+	"path": func(doc any, expr string) (any, error) {
+		p, err := parseValuePath(expr)
+		if err != nil {
+			return nil, err
+		}
+		v, _ := jsonpath.Lookup(doc, p)
+		return v, nil
+	},
+	// default returns def if v is nil or empty
+	"default": func(def, v any) any {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+// escapeJSON formats a value as the content of a JSON string, so the values printed in string literals can't
+// change the structure of the document.
+func escapeJSON(v any) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(fmt.Sprint(v))
+	if err != nil {
+		return "", err
+	}
+	return string(b[1 : len(b)-1]), nil
+}
+
+// addEscapes appends escapeFunc to the pipelines of the actions printing values, except those ending with
+// the json function, whose output is already JSON.
+func addEscapes(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addEscapes(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 {
+			// Variable assignments don't print anything
+			return
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "json" || ident.Ident == escapeFunc) {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      last.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapeFunc).SetTree(nil).SetPos(last.Pos)},
+		})
+	case *parse.IfNode:
+		addEscapes(n.List)
+		addEscapes(n.ElseList)
+	case *parse.RangeNode:
+		addEscapes(n.List)
+		addEscapes(n.ElseList)
+	case *parse.WithNode:
+		addEscapes(n.List)
+		addEscapes(n.ElseList)
+	}
+}
+
+// templateData is the data available to the templates.
+type templateData struct {
+	Body    any
+	Headers map[string]string
+	Query   map[string]string
+	Method  string
+	Path    string
+	// Status is the status code of the response; it's 0 for requests.
+	Status int
+}
+
+func newTemplateData(r *http.Request, header http.Header, status int) *templateData {
+	return &templateData{
+		Headers: firstValues(header),
+		Query:   firstValues(r.URL.Query()),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Status:  status,
+	}
+}
+
+func firstValues(values map[string][]string) map[string]string {
+	res := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			res[k] = v[0]
+		}
+	}
+	return res
+}
+
+// apply transforms the body. It returns errNotJSON if the body can't be parsed; empty bodies are
+// transformed as a null document, unless they're left unchanged because nothing would be added to them.
+func (b *bodyTransformation) apply(body []byte, data *templateData) ([]byte, error) {
+	var doc any
+	if len(bytes.TrimSpace(body)) > 0 {
+		err := json.Unmarshal(body, &doc)
+		if err != nil {
+			return nil, errNotJSON
+		}
+	} else if len(b.sets) == 0 && b.tmpl == nil {
+		return body, nil
+	}
+
+	var err error
+	for _, r := range b.renames {
+		doc, err = jsonpath.Update(doc, r.scope, func(node any) (any, error) {
+			v, ok := jsonpath.Lookup(node, r.from)
+			if !ok {
+				return node, nil
+			}
+			jsonpath.Delete(node, r.from)
+			return jsonpath.Set(node, r.to, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Source paths are resolved against the document before any value is set, once per element of the scope
+	values := make([][]any, len(b.sets))
+	for i, pv := range b.sets {
+		if pv.source == nil {
+			continue
+		}
+		_, err = jsonpath.Update(doc, pv.scope, func(node any) (any, error) {
+			v, ok := jsonpath.Lookup(node, pv.source)
+			if ok {
+				v = jsonpath.Clone(v)
+			} else {
+				v = notFound{}
+			}
+			values[i] = append(values[i], v)
+			return node, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, pv := range b.sets {
+		n := 0
+		doc, err = jsonpath.Update(doc, pv.scope, func(node any) (any, error) {
+			if pv.source == nil {
+				return jsonpath.Set(node, pv.path, jsonpath.Clone(pv.value))
+			}
+			if n >= len(values[i]) {
+				// The array was extended by a previous set
+				return node, nil
+			}
+			v := values[i][n]
+			n++
+			if _, ok := v.(notFound); ok {
+				return node, nil
+			}
+			return jsonpath.Set(node, pv.path, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range b.removes {
+		jsonpath.Delete(doc, p)
+	}
+
+	if b.tmpl == nil {
+		return json.Marshal(doc)
+	}
+	data.Body = doc
+	var buf bytes.Buffer
+	err = b.tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("the template didn't render valid JSON")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/internal/jsonpath"
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+)
+
+const defaultMaxBodySize = 1 << 20
+
+type transformMiddlewareMetadata struct {
+	// Rules is a JSON array of transformations; each request uses the rule with the longest matching pathPrefix.
+	Rules string `json:"rules"`
+	// MaxBodySize is the size of the largest request or response body that is transformed. Larger requests are
+	// rejected, and larger responses are sent unchanged.
+	MaxBodySize int64 `json:"maxBodySize"`
+
+	rules []*rule
+}
+
+// rule is the transformation of the requests to the paths starting with PathPrefix, and of their responses.
+type rule struct {
+	PathPrefix string          `json:"pathPrefix"`
+	Methods    []string        `json:"methods"`
+	Request    *transformation `json:"request"`
+	Response   *transformation `json:"response"`
+}
+
+// transformation changes the headers and the body of a request or a response.
+// Headers are renamed first, then removed, then set.
+type transformation struct {
+	RenameHeaders map[string]string `json:"renameHeaders"`
+	RemoveHeaders []string          `json:"removeHeaders"`
+	SetHeaders    map[string]string `json:"setHeaders"`
+	// QueryToHeader copies query parameters to headers. Only used for requests.
+	QueryToHeader map[string]string   `json:"queryToHeader"`
+	Body          *bodyTransformation `json:"body"`
+}
+
+// bodyTransformation rewrites a JSON body. Fields are renamed first, then set, then removed. If Template
+// is set, it's rendered with the resulting document to produce the new body.
+type bodyTransformation struct {
+	// Rename moves the values from a path to another.
+	Rename map[string]string `json:"rename"`
+	// Set sets paths to a JSON value, or to the value at another path when the value is a string starting with "$".
+	Set map[string]json.RawMessage `json:"set"`
+	// Remove deletes the fields at the paths; unlike the other paths, they can use the ".." recursive descent.
+	Remove []string `json:"remove"`
+	// Template is a Go template that renders the new body, which must be valid JSON. The values it prints are
+	// escaped as the content of JSON strings, except the output of the json function.
+	Template string `json:"template"`
+	// ContentType is the content type of the new body; the original content type is kept when empty.
+	ContentType string `json:"contentType"`
+
+	renames []pathRename
+	sets    []pathValue
+	removes []jsonpath.Path
+	tmpl    *template.Template
+}
+
+// pathRename moves the value from a path to another in every element matched by scope, so "items[*].a" can be
+// renamed to "items[*].b". from and to are relative to the elements.
+type pathRename struct {
+	scope jsonpath.Path
+	from  jsonpath.Path
+	to    jsonpath.Path
+}
+
+// pathValue is either a constant value or a source path. With a source path, the value is copied in every
+// element matched by scope, and path and source are relative to the elements.
+type pathValue struct {
+	scope  jsonpath.Path
+	path   jsonpath.Path
+	value  any
+	source jsonpath.Path
+}
+
+func getNativeMetadata(metadata middleware.Metadata) (*transformMiddlewareMetadata, error) {
+	middlewareMetadata := transformMiddlewareMetadata{
+		MaxBodySize: defaultMaxBodySize,
+	}
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.MaxBodySize <= 0 {
+		return nil, errors.New("maxBodySize must be greater than 0")
+	}
+	if middlewareMetadata.Rules == "" {
+		return nil, errors.New("rules are required")
+	}
+	err = json.Unmarshal([]byte(middlewareMetadata.Rules), &middlewareMetadata.rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	for _, r := range middlewareMetadata.rules {
+		for i, method := range r.Methods {
+			r.Methods[i] = strings.ToUpper(method)
+		}
+		for _, t := range []*transformation{r.Request, r.Response} {
+			if t == nil || t.Body == nil {
+				continue
+			}
+			err = t.Body.compile()
+			if err != nil {
+				return nil, fmt.Errorf("invalid body transformation for route %s: %w", r.PathPrefix, err)
+			}
+		}
+	}
+
+	return &middlewareMetadata, nil
+}
+
+// parseValuePath parses a path that a value is read from or written to. Recursive descents are only
+// supported by the paths that are removed.
+func parseValuePath(expr string) (jsonpath.Path, error) {
+	p, err := jsonpath.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsRecursive() {
+		return nil, fmt.Errorf("invalid path %q: recursive descents can only be removed", expr)
+	}
+	return p, nil
+}
+
+// compile parses the paths and the template.
+func (b *bodyTransformation) compile() error {
+	for _, from := range sortedKeys(b.Rename) {
+		fromPath, err := parseValuePath(from)
+		if err != nil {
+			return err
+		}
+		toPath, err := parseValuePath(b.Rename[from])
+		if err != nil {
+			return err
+		}
+		var r pathRename
+		r.scope, r.from, r.to, err = jsonpath.SplitScope(fromPath, toPath)
+		if err != nil {
+			return fmt.Errorf("invalid rename of %s to %s: %w", from, b.Rename[from], err)
+		}
+		b.renames = append(b.renames, r)
+	}
+
+	// Sorted so that parents are set before their children
+	for _, path := range sortedKeys(b.Set) {
+		p, err := parseValuePath(path)
+		if err != nil {
+			return err
+		}
+		pv := pathValue{path: p}
+		err = json.Unmarshal(b.Set[path], &pv.value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", path, err)
+		}
+		if s, ok := pv.value.(string); ok && strings.HasPrefix(s, "$") {
+			pv.source, err = parseValuePath(s)
+			if err != nil {
+				return err
+			}
+			pv.scope, pv.path, pv.source, err = jsonpath.SplitScope(pv.path, pv.source)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", path, err)
+			}
+		}
+		b.sets = append(b.sets, pv)
+	}
+
+	for _, path := range b.Remove {
+		p, err := jsonpath.Parse(path)
+		if err != nil {
+			return err
+		}
+		b.removes = append(b.removes, p)
+	}
+
+	if b.Template != "" {
+		var err error
+		b.tmpl, err = template.New("body").Funcs(templateFuncs).Option("missingkey=zero").Parse(b.Template)
+		if err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+		// The printed values are escaped, as html/template does for HTML
+		for _, t := range b.tmpl.Templates() {
+			addEscapes(t.Root)
+		}
+	}
+	return nil
+}
+
+// ruleFor returns the rule with the longest prefix matching the request, or nil.
+func (md *transformMiddlewareMetadata) ruleFor(r *http.Request) *rule {
+	var match *rule
+	for _, rule := range md.rules {
+		if !httputils.HasPathPrefix(r.URL.Path, rule.PathPrefix) || (match != nil && len(rule.PathPrefix) <= len(match.PathPrefix)) {
+			continue
+		}
+		if len(rule.Methods) > 0 && !contains(rule.Methods, r.Method) {
+			continue
+		}
+		match = rule
+	}
+	return match
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
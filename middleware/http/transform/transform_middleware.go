@@ -0,0 +1,243 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// NewMiddleware returns a new request and response transformation middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{logger: logger}
+}
+
+// Middleware rewrites the headers and the JSON bodies of requests and responses.
+type Middleware struct {
+	logger logger.Logger
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rule := meta.ruleFor(r)
+			if rule == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if rule.Request != nil {
+				status := m.transformRequest(r, rule.Request, meta.MaxBodySize)
+				if status != 0 {
+					httputils.RespondWithError(w, status)
+					return
+				}
+			}
+			if rule.Response == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := &responseWriter{
+				ResponseWriter: w,
+				t:              rule.Response,
+				maxSize:        meta.MaxBodySize,
+				status:         http.StatusOK,
+			}
+			next.ServeHTTP(rw, r)
+			m.finishResponse(rw, r)
+		})
+	}, nil
+}
+
+// transformRequest transforms the request in place. It returns the status code of the error response
+// to send, or 0 if the request can continue.
+func (m *Middleware) transformRequest(r *http.Request, t *transformation, maxBodySize int64) int {
+	query := r.URL.Query()
+	for param, header := range t.QueryToHeader {
+		if v := query.Get(param); v != "" {
+			r.Header.Set(header, v)
+		}
+	}
+	t.applyHeaders(r.Header)
+	if t.Body == nil {
+		return 0
+	}
+
+	if r.ContentLength > maxBodySize {
+		return http.StatusRequestEntityTooLarge
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		r.Body.Close()
+		if err != nil {
+			m.logger.Debugf("failed to read request body: %v", err)
+			return http.StatusBadRequest
+		}
+		if int64(len(body)) > maxBodySize {
+			return http.StatusRequestEntityTooLarge
+		}
+	}
+
+	out, err := t.Body.apply(body, newTemplateData(r, r.Header, 0))
+	switch {
+	case errors.Is(err, errNotJSON):
+		m.logger.Debugf("request body for %s is not JSON, leaving it unchanged", r.URL.Path)
+		out = body
+	case err != nil:
+		m.logger.Warnf("failed to transform request body for %s: %v", r.URL.Path, err)
+		return http.StatusInternalServerError
+	case len(out) == 0:
+		r.Body = http.NoBody
+		r.ContentLength = 0
+		return 0
+	case t.Body.ContentType != "":
+		r.Header.Set("Content-Type", t.Body.ContentType)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(out))
+	r.ContentLength = int64(len(out))
+	r.Header.Set("Content-Length", strconv.Itoa(len(out)))
+	return 0
+}
+
+// finishResponse transforms the buffered response body and sends it.
+func (m *Middleware) finishResponse(rw *responseWriter, r *http.Request) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.t.Body == nil {
+		return
+	}
+
+	if rw.passthrough {
+		m.logger.Debugf("response body for %s is larger than %d bytes or was flushed, sent unchanged", r.URL.Path, rw.maxSize)
+		return
+	}
+
+	h := rw.ResponseWriter.Header()
+	body := rw.buf.Bytes()
+	out, err := rw.t.Body.apply(body, newTemplateData(r, h, rw.status))
+	switch {
+	case errors.Is(err, errNotJSON):
+		m.logger.Debugf("response body for %s is not JSON, leaving it unchanged", r.URL.Path)
+		out = body
+	case err != nil:
+		m.logger.Warnf("failed to transform response body for %s: %v", r.URL.Path, err)
+		h.Del("Content-Length")
+		httputils.RespondWithError(rw.ResponseWriter, http.StatusInternalServerError)
+		return
+	case rw.t.Body.ContentType != "":
+		h.Set("Content-Type", rw.t.Body.ContentType)
+	}
+
+	h.Set("Content-Length", strconv.Itoa(len(out)))
+	rw.ResponseWriter.WriteHeader(rw.status)
+	rw.ResponseWriter.Write(out)
+}
+
+func (t *transformation) applyHeaders(h http.Header) {
+	for from, to := range t.RenameHeaders {
+		if values := h.Values(from); len(values) > 0 {
+			h.Del(from)
+			h[http.CanonicalHeaderKey(to)] = values
+		}
+	}
+	for _, key := range t.RemoveHeaders {
+		h.Del(key)
+	}
+	for key, value := range t.SetHeaders {
+		h.Set(key, value)
+	}
+}
+
+// responseWriter applies the header transformations when the app writes the response, and buffers the
+// body up to maxSize when it has to be transformed. Larger or flushed bodies are sent unchanged.
+type responseWriter struct {
+	http.ResponseWriter
+	t           *transformation
+	maxSize     int64
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         bytes.Buffer
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = status
+	rw.t.applyHeaders(rw.Header())
+	if rw.t.Body == nil {
+		rw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.t.Body == nil {
+		return rw.ResponseWriter.Write(b)
+	}
+	if !rw.passthrough && int64(rw.buf.Len()+len(b)) > rw.maxSize {
+		rw.startPassthrough()
+	}
+	if rw.passthrough {
+		return rw.ResponseWriter.Write(b)
+	}
+	return rw.buf.Write(b)
+}
+
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.t.Body != nil {
+		rw.startPassthrough()
+	}
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// startPassthrough sends the status and the buffered body, and stops buffering.
+func (rw *responseWriter) startPassthrough() {
+	if rw.passthrough {
+		return
+	}
+	rw.passthrough = true
+	rw.ResponseWriter.WriteHeader(rw.status)
+	if rw.buf.Len() > 0 {
+		rw.ResponseWriter.Write(rw.buf.Bytes())
+		rw.buf.Reset()
+	}
+}
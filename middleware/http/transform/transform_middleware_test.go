@@ -0,0 +1,432 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// mockedRequestHandler acts like an upstream service: it returns the request body with the status code 202,
+// and copies the request headers to the response with an "Echo-" prefix.
+func mockedRequestHandler(w http.ResponseWriter, r *http.Request) {
+	for k, v := range r.Header {
+		w.Header()["Echo-"+k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Internal", "true")
+	body, _ := io.ReadAll(r.Body)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(body)
+}
+
+func TestTransform(t *testing.T) {
+	headerRules := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"rules": `[{
+				"pathPrefix": "/v1.0/invoke/legacy",
+				"request": {
+					"renameHeaders": {"X-Old-Token": "X-Token"},
+					"removeHeaders": ["X-Debug"],
+					"setHeaders": {"X-Api-Version": "2"},
+					"queryToHeader": {"tenant": "X-Tenant-Id"}
+				},
+				"response": {
+					"removeHeaders": ["X-Internal"],
+					"setHeaders": {"X-Transformed": "true"}
+				}
+			}]`,
+		},
+	}}
+	bodyRules := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"rules": `[{
+				"pathPrefix": "/orders",
+				"methods": ["post"],
+				"request": {
+					"body": {
+						"rename": {"$.customer_name": "$.customer.name"},
+						"set": {"$.version": 2, "$.customer.id": "$.cid", "$.firstItem": "$.items[0]"},
+						"remove": ["$.cid", "$.items[*].internal"]
+					}
+				},
+				"response": {
+					"body": {
+						"template": "{\"order\": {{ json .Body }}, \"status\": {{ .Status }}, \"tenant\": {{ json (default \"none\" (index .Query \"tenant\")) }}}",
+						"contentType": "application/vnd.legacy+json"
+					}
+				}
+			}]`,
+		},
+	}}
+	sizeRules := middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"maxBodySize": "64",
+			"rules": `[
+				{"pathPrefix": "/in", "request": {"body": {"set": {"a": 1}}}},
+				{"pathPrefix": "/out", "response": {"body": {"set": {"a": 1}}}}
+			]`,
+		},
+	}}
+	large := `{"data":"` + strings.Repeat("x", 100) + `"}`
+
+	tests := map[string]struct {
+		meta               middleware.Metadata
+		req                func() *http.Request
+		handler            http.HandlerFunc
+		status             int
+		headers            *[][]string
+		body               string
+		jsonBody           string
+		shouldHandlerError bool
+	}{
+		"headers": {
+			meta: headerRules,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/v1.0/invoke/legacy/method/a?tenant=acme", nil)
+				r.Header.Set("X-Old-Token", "abc")
+				r.Header.Set("X-Debug", "1")
+				return r
+			},
+			status: http.StatusAccepted,
+			headers: &[][]string{
+				{"Echo-X-Token", "abc"},
+				{"Echo-X-Old-Token", ""},
+				{"Echo-X-Debug", ""},
+				{"Echo-X-Api-Version", "2"},
+				{"Echo-X-Tenant-Id", "acme"},
+				{"X-Internal", ""},
+				{"X-Transformed", "true"},
+			},
+		},
+		"headers of other routes": {
+			meta: headerRules,
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/v1.0/state/a?tenant=acme", nil)
+				r.Header.Set("X-Debug", "1")
+				return r
+			},
+			status: http.StatusAccepted,
+			headers: &[][]string{
+				{"Echo-X-Debug", "1"},
+				{"X-Internal", "true"},
+				{"X-Transformed", ""},
+			},
+		},
+		"body": {
+			meta: bodyRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/orders?tenant=acme",
+					strings.NewReader(`{"customer_name":"alice","cid":7,"items":[{"sku":"a","internal":true},{"sku":"b"}]}`))
+			},
+			status: http.StatusAccepted,
+			headers: &[][]string{
+				{"Content-Type", "application/vnd.legacy+json"},
+			},
+			jsonBody: `{
+				"order": {
+					"customer": {"name": "alice", "id": 7},
+					"version": 2,
+					"firstItem": {"sku": "a", "internal": true},
+					"items": [{"sku": "a"}, {"sku": "b"}]
+				},
+				"status": 202,
+				"tenant": "acme"
+			}`,
+		},
+		"body that is not JSON": {
+			meta: bodyRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/orders", strings.NewReader("plain text"))
+			},
+			status: http.StatusAccepted,
+			body:   "plain text",
+		},
+		"body of other methods": {
+			meta: bodyRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPut, "http://localhost:3500/orders", strings.NewReader(`{"cid":7}`))
+			},
+			status:   http.StatusAccepted,
+			jsonBody: `{"cid":7}`,
+		},
+		"wildcard rename and set": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{
+						"pathPrefix": "/",
+						"request": {"body": {
+							"rename": {"items[*].a": "items[*].b"},
+							"set": {"items[*].c": "$.items[*].b", "items[*].d": true, "first": "$.items[0].b"}
+						}}
+					}]`,
+				},
+			}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/", strings.NewReader(`{"items":[{"a":1},{"a":2},{"x":3}]}`))
+			},
+			status:   http.StatusAccepted,
+			jsonBody: `{"items":[{"b":1,"c":1,"d":true},{"b":2,"c":2,"d":true},{"x":3,"d":true}],"first":1}`,
+		},
+		"wildcard rename to another array": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"rename": {"items[*].a": "other[*].a"}}}}]`,
+				},
+			}},
+			shouldHandlerError: true,
+		},
+		"wildcard rename from an array": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"rename": {"items[*].a": "all"}}}}]`,
+				},
+			}},
+			shouldHandlerError: true,
+		},
+		"wildcard set from another array": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"set": {"items[*].a": "$.other[*].a"}}}}]`,
+				},
+			}},
+			shouldHandlerError: true,
+		},
+		"recursive remove": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"remove": ["$..secret"]}}}]`,
+				},
+			}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/", strings.NewReader(`{"secret":1,"items":[{"a":1,"secret":2}]}`))
+			},
+			status:   http.StatusAccepted,
+			jsonBody: `{"items":[{"a":1}]}`,
+		},
+		"recursive rename": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"rename": {"$..a": "b"}}}}]`,
+				},
+			}},
+			shouldHandlerError: true,
+		},
+		"empty body": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"rename": {"a": "b"}, "remove": ["c"]}}}]`,
+				},
+			}},
+			status: http.StatusAccepted,
+			body:   "",
+			headers: &[][]string{
+				{"Echo-Content-Length", ""},
+			},
+		},
+		"empty body with set": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"set": {"a": 1}}}}]`,
+				},
+			}},
+			status:   http.StatusAccepted,
+			jsonBody: `{"a":1}`,
+		},
+		"request larger than maxBodySize": {
+			meta: sizeRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/in", strings.NewReader(large))
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		"request within maxBodySize": {
+			meta: sizeRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/in", strings.NewReader(`{"b":2}`))
+			},
+			status:   http.StatusAccepted,
+			jsonBody: `{"a":1,"b":2}`,
+		},
+		"response larger than maxBodySize": {
+			meta: sizeRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "http://localhost:3500/out", nil)
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(large[:50]))
+				w.Write([]byte(large[50:]))
+			},
+			status:   http.StatusOK,
+			jsonBody: large,
+		},
+		"flushed response": {
+			meta: sizeRules,
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "http://localhost:3500/out", nil)
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"b":`))
+				w.(http.Flusher).Flush()
+				w.Write([]byte(`2}`))
+			},
+			status:   http.StatusCreated,
+			jsonBody: `{"b":2}`,
+		},
+		"escaped template values": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {
+						"template": "{\"name\": \"{{ .Body.name }}\", \"note\": \"{{ .Body.note | printf \"%s!\" }}\", \"tags\": {{ json .Body.tags }}{{ $n := .Body.n }}, \"n\": {{ $n }}}"
+					}}}]`,
+				},
+			}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/",
+					strings.NewReader(`{"name":"al\"ice\", \"admin\": true, \"x\": \"","note":"<b>","tags":["a"],"n":3}`))
+			},
+			status:   http.StatusAccepted,
+			jsonBody: `{"name":"al\"ice\", \"admin\": true, \"x\": \"","note":"<b>!","tags":["a"],"n":3}`,
+		},
+		"template rendering invalid JSON": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{
+					"rules": `[{"pathPrefix": "/", "request": {"body": {"template": "{\"name\": {{ .Body.name }}}"}}}]`,
+				},
+			}},
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "http://localhost:3500/", strings.NewReader(`{"name":"alice"}`))
+			},
+			status: http.StatusInternalServerError,
+		},
+		"missing rules": {
+			meta:               middleware.Metadata{Base: metadata.Base{Properties: map[string]string{}}},
+			shouldHandlerError: true,
+		},
+		"invalid rules": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"rules": "not json"},
+			}},
+			shouldHandlerError: true,
+		},
+		"invalid maxBodySize": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"rules": `[]`, "maxBodySize": "0"},
+			}},
+			shouldHandlerError: true,
+		},
+		"invalid path": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"rules": `[{"pathPrefix": "/", "request": {"body": {"remove": ["items[x]"]}}}]`},
+			}},
+			shouldHandlerError: true,
+		},
+		"invalid template": {
+			meta: middleware.Metadata{Base: metadata.Base{
+				Properties: map[string]string{"rules": `[{"pathPrefix": "/", "response": {"body": {"template": "{{ .Body "}}}]`},
+			}},
+			shouldHandlerError: true,
+		},
+	}
+
+	log := logger.NewLogger("transform.test")
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler, err := NewMiddleware(log).GetHandler(test.meta)
+			if test.shouldHandlerError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var r *http.Request
+			if test.req != nil {
+				r = test.req()
+			} else {
+				r = httptest.NewRequest(http.MethodGet, "http://localhost:3500/", nil)
+			}
+			w := httptest.NewRecorder()
+			next := test.handler
+			if next == nil {
+				next = mockedRequestHandler
+			}
+
+			handler(next).ServeHTTP(w, r)
+
+			assert.Equal(t, test.status, w.Code)
+			if test.jsonBody != "" {
+				assert.JSONEq(t, test.jsonBody, w.Body.String())
+			} else if test.status == http.StatusAccepted {
+				assert.Equal(t, test.body, w.Body.String())
+			} else {
+				assert.NotContains(t, w.Body.String(), "xxx")
+			}
+			if cl := w.Header().Get("Content-Length"); cl != "" {
+				assert.Equal(t, strconv.Itoa(w.Body.Len()), cl)
+			}
+
+			if test.headers != nil {
+				for _, header := range *test.headers {
+					assert.Equal(t, header[1], w.Header().Get(header[0]), header[0])
+				}
+			}
+		})
+	}
+}
+
+func TestRuleMatching(t *testing.T) {
+	meta, err := getNativeMetadata(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"rules": `[
+			{"pathPrefix": "/v1.0"},
+			{"pathPrefix": "/v1.0/invoke"},
+			{"pathPrefix": "/v1.0/invoke/app", "methods": ["DELETE"]}
+		]`,
+	}}})
+	require.NoError(t, err)
+
+	for path, expected := range map[string]string{
+		"/v1.0/state":           "/v1.0",
+		"/v1.0/invoke/app/a":    "/v1.0/invoke",
+		"/v1.0/invoke/other/a":  "/v1.0/invoke",
+		"/healthz":              "",
+		"/v1.0/invoke/app/a?x=": "/v1.0/invoke",
+		"/v1.0/invokes/a":       "/v1.0",
+		"/v1.0-beta/state":      "",
+	} {
+		rule := meta.ruleFor(httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil))
+		if expected == "" {
+			assert.Nil(t, rule, path)
+			continue
+		}
+		require.NotNil(t, rule, path)
+		assert.Equal(t, expected, rule.PathPrefix, path)
+	}
+
+	rule := meta.ruleFor(httptest.NewRequest(http.MethodDelete, "http://localhost/v1.0/invoke/app/a", nil))
+	assert.Equal(t, "/v1.0/invoke/app", rule.PathPrefix)
+}
@@ -62,8 +62,30 @@ func (m *middleware) getHandler(metadata dapr.Metadata) (*requestHandler, error)
 		return nil, fmt.Errorf("wasm basic: failed to parse metadata: %w", err)
 	}
 
+	// Create the runtime here, so that the key/value host module is compiled
+	// once and instantiated in the namespace of each pooled guest.
+	rt := wazero.NewRuntime(ctx)
+	kv := newKVStore()
+	kvModule, err := kv.compile(ctx, rt)
+	if err != nil {
+		_ = rt.Close(ctx)
+		return nil, err
+	}
+
 	var stdout, stderr bytes.Buffer
 	mw, err := wasmnethttp.NewMiddleware(ctx, meta.guest,
+		handler.Runtime(func(context.Context) (wazero.Runtime, error) { return rt, nil }),
+		handler.Namespace(func(ctx context.Context, r wazero.Runtime) (wazero.Namespace, error) {
+			ns, err := handler.DefaultNamespace(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = ns.InstantiateModule(ctx, kvModule, wazero.NewModuleConfig()); err != nil {
+				_ = ns.Close(ctx)
+				return nil, err
+			}
+			return ns, nil
+		}),
 		handler.Logger(m),
 		handler.ModuleConfig(wazero.NewModuleConfig().
 			WithStdout(&stdout). // reset per request
@@ -77,7 +99,7 @@ func (m *middleware) getHandler(metadata dapr.Metadata) (*requestHandler, error)
 		return nil, err
 	}
 
-	return &requestHandler{mw: mw, logger: m.logger, stdout: &stdout, stderr: &stderr}, nil
+	return &requestHandler{mw: mw, kv: kv, logger: m.logger, stdout: &stdout, stderr: &stderr}, nil
 }
 
 // IsEnabled implements the same method as documented on api.Logger.
@@ -140,6 +162,7 @@ func (m *middleware) getMetadata(metadata dapr.Metadata) (*middlewareMetadata, e
 
 type requestHandler struct {
 	mw             wasmnethttp.Middleware
+	kv             *kvStore
 	logger         logger.Logger
 	stdout, stderr *bytes.Buffer
 }
@@ -118,6 +118,7 @@ func Test_middleware_getHandler(t *testing.T) {
 			if tc.expectedErr == "" {
 				require.NoError(t, err)
 				require.NotNil(t, h.mw)
+				require.NotNil(t, h.kv)
 			} else {
 				require.EqualError(t, err, tc.expectedErr)
 			}
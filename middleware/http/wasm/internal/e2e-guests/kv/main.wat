@@ -0,0 +1,210 @@
+;; kv shows how guests share state across requests with the key/value host
+;; functions in the "dapr" module: the store is shared by all modules in the
+;; pool, so the count and allow-list below are consistent across requests.
+;;
+;; This is written in WebAssembly text format, as the TinyGo SDK doesn't define
+;; imports for the "dapr" host module.
+(module $kv
+  ;; get_uri writes the request URI value to memory, if it isn't larger than
+  ;; the buffer size limit. The result is the actual URI length in bytes.
+  (import "http_handler" "get_uri" (func $get_uri
+    (param $buf i32) (param $buf_limit i32)
+    (result (; uri_len ;) i32)))
+
+  ;; set_header_value overwrites a header of the given $kind and $name with a
+  ;; single value.
+  (import "http_handler" "set_header_value" (func $set_header_value
+    (param $kind i32)
+    (param $name i32) (param $name_len i32)
+    (param $value i32) (param $value_len i32)))
+
+  ;; get_header_values writes all header values of the given $kind and $name,
+  ;; NUL-terminated, to memory if the encoded length isn't larger than
+  ;; $buf_limit. The result is the count of values in the upper 32 bits and the
+  ;; encoded length in the lower 32 bits.
+  (import "http_handler" "get_header_values" (func $get_header_values
+    (param $kind i32)
+    (param $name i32) (param $name_len i32)
+    (param $buf i32) (param $buf_limit i32)
+    (result (; count << 32| len ;) i64)))
+
+  ;; set_status_code overrides the status code. The default is 200.
+  (import "http_handler" "set_status_code" (func $set_status_code
+    (param $status_code i32)))
+
+  ;; kv_get writes the value of $key to memory, if it isn't larger than
+  ;; $buf_limit. The result is 1 in the upper 32 bits when the key exists, and
+  ;; the value length in the lower 32 bits.
+  (import "dapr" "kv_get" (func $kv_get
+    (param $key i32) (param $key_len i32)
+    (param $buf i32) (param $buf_limit i32)
+    (result (; found << 32| len ;) i64)))
+
+  ;; kv_set overwrites the value of $key.
+  (import "dapr" "kv_set" (func $kv_set
+    (param $key i32) (param $key_len i32)
+    (param $value i32) (param $value_len i32)))
+
+  ;; kv_delete removes $key, if it exists.
+  (import "dapr" "kv_delete" (func $kv_delete
+    (param $key i32) (param $key_len i32)))
+
+  ;; kv_incr adds $delta to the decimal integer stored at $key, and returns
+  ;; the result.
+  (import "dapr" "kv_incr" (func $kv_incr
+    (param $key i32) (param $key_len i32) (param $delta i64)
+    (result (; value ;) i64)))
+
+  ;; http-wasm guests are required to export "memory", so that imported
+  ;; functions like "log" can read memory.
+  (memory (export "memory") 1 (; 1 page==64KB ;))
+
+  ;; define the key of the request counter
+  (global $requests i32 (i32.const 0))
+  (data (i32.const 0) "requests")
+  (global $requests_len i32 (i32.const 8))
+
+  ;; define the header the request count is written to, for the app.
+  (global $count_header i32 (i32.const 16))
+  (data (i32.const 16) "X-Request-Count")
+  (global $count_header_len i32 (i32.const 15))
+
+  ;; define the header identifying the client.
+  (global $client_header i32 (i32.const 32))
+  (data (i32.const 32) "X-Client")
+  (global $client_header_len i32 (i32.const 8))
+
+  ;; define the URIs that change the allow-list.
+  (global $allow_uri i32 (i32.const 48))
+  (data (i32.const 48) "/admin/allow")
+  (global $allow_uri_len i32 (i32.const 12))
+
+  (global $deny_uri i32 (i32.const 64))
+  (data (i32.const 64) "/admin/deny")
+  (global $deny_uri_len i32 (i32.const 11))
+
+  ;; define the value of allowed clients.
+  (global $allowed i32 (i32.const 80))
+  (data (i32.const 80) "1")
+  (global $allowed_len i32 (i32.const 1))
+
+  ;; define the key of an allowed client: "allow:" followed by the client,
+  ;; which is read directly after the prefix.
+  (global $allow_key i32 (i32.const 128))
+  (data (i32.const 128) "allow:")
+  (global $allow_key_prefix_len i32 (i32.const 6))
+  (global $client_buf i32 (i32.const 134))
+  (global $client_limit i32 (i32.const 64))
+
+  ;; count_buf is where the request count is read to.
+  (global $count_buf i32 (i32.const 256))
+  (global $count_limit i32 (i32.const 20))
+
+  ;; uri_buf is where the request URI is read to.
+  (global $uri_buf i32 (i32.const 512))
+  (global $uri_limit i32 (i32.const 64))
+
+  ;; handle_request counts requests, and only allows clients in the allow-list.
+  (func (export "handle_request") (result (; ctx_next ;) i64)
+    (local $count_len i32)
+    (local $client i64)
+    (local $client_len i32)
+    (local $key_len i32)
+    (local $uri_len i32)
+
+    ;; Count all requests, including rejected ones.
+    (drop (call $kv_incr
+      (global.get $requests) (global.get $requests_len) (i64.const 1)))
+
+    ;; Read the count back, as the value is stored as decimal text, and pass it
+    ;; to the app in a request header.
+    (local.set $count_len (i32.wrap_i64 (call $kv_get
+      (global.get $requests) (global.get $requests_len)
+      (global.get $count_buf) (global.get $count_limit))))
+    (call $set_header_value (i32.const 0 (; request ;))
+      (global.get $count_header) (global.get $count_header_len)
+      (global.get $count_buf) (local.get $count_len))
+
+    ;; Read the client after the allow-list key prefix.
+    (local.set $client (call $get_header_values (i32.const 0 (; request ;))
+      (global.get $client_header) (global.get $client_header_len)
+      (global.get $client_buf) (global.get $client_limit)))
+    (local.set $client_len (i32.wrap_i64 (local.get $client)))
+
+    ;; if count != 1 || client_len > client_limit { reject }
+    (if (i32.or
+          (i64.ne (i64.shr_u (local.get $client) (i64.const 32)) (i64.const 1))
+          (i32.gt_u (local.get $client_len) (global.get $client_limit)))
+      (then (return (call $reject))))
+
+    ;; key_len = allow_key_prefix_len + client_len - 1 (; NUL ;)
+    (local.set $key_len (i32.add
+      (global.get $allow_key_prefix_len)
+      (i32.sub (local.get $client_len) (i32.const 1))))
+
+    ;; Next, handle changes to the allow-list.
+    (local.set $uri_len
+      (call $get_uri (global.get $uri_buf) (global.get $uri_limit)))
+
+    ;; if uri == allow_uri { allow client }
+    (if (i32.eq (local.get $uri_len) (global.get $allow_uri_len))
+      (then (if (call $memeq
+                  (global.get $uri_buf)
+                  (global.get $allow_uri)
+                  (global.get $allow_uri_len)) (then
+        (call $kv_set
+          (global.get $allow_key) (local.get $key_len)
+          (global.get $allowed) (global.get $allowed_len))
+        (call $set_status_code (i32.const 204))
+        (return (i64.const 0))))))
+
+    ;; if uri == deny_uri { deny client }
+    (if (i32.eq (local.get $uri_len) (global.get $deny_uri_len))
+      (then (if (call $memeq
+                  (global.get $uri_buf)
+                  (global.get $deny_uri)
+                  (global.get $deny_uri_len)) (then
+        (call $kv_delete (global.get $allow_key) (local.get $key_len))
+        (call $set_status_code (i32.const 204))
+        (return (i64.const 0))))))
+
+    ;; Finally, only dispatch clients in the allow-list. A zero buf_limit
+    ;; checks if the key exists, without reading the value.
+    (if (i64.eqz (i64.shr_u
+          (call $kv_get
+            (global.get $allow_key) (local.get $key_len)
+            (i32.const 0) (i32.const 0))
+          (i64.const 32)))
+      (then (return (call $reject))))
+
+    (return (i64.const 1)))
+
+  ;; reject responds with 403 instead of dispatching to the next handler.
+  (func $reject (result (; ctx_next ;) i64)
+    (call $set_status_code (i32.const 403))
+    (i64.const 0))
+
+  ;; handle_response is no-op as this is a request-only handler.
+  (func (export "handle_response") (param $reqCtx i32) (param $is_error i32))
+
+  ;; memeq is like memcmp except it returns 0 (ne) or 1 (eq)
+  (func $memeq (param $ptr1 i32) (param $ptr2 i32) (param $len i32) (result i32)
+    (local $i1 i32)
+    (local $i2 i32)
+    (local.set $i1 (local.get $ptr1)) ;; i1 := ptr1
+    (local.set $i2 (local.get $ptr2)) ;; i2 := ptr1
+
+    (loop $len_gt_zero
+      ;; if mem[i1] != mem[i2]
+      (if (i32.ne (i32.load8_u (local.get $i1)) (i32.load8_u (local.get $i2)))
+        (then (return (i32.const 0)))) ;; return 0
+
+      (local.set $i1  (i32.add (local.get $i1)  (i32.const 1))) ;; i1++
+      (local.set $i2  (i32.add (local.get $i2)  (i32.const 1))) ;; i2++
+      (local.set $len (i32.sub (local.get $len) (i32.const 1))) ;; $len--
+
+      ;; if $len > 0 { continue } else { break }
+      (br_if $len_gt_zero (i32.gt_s (local.get $len) (i32.const 0))))
+
+    (i32.const 1)) ;; return 1
+)
@@ -0,0 +1,134 @@
+;; response shows how guests handle the response of the next handler: it hides
+;; internal response headers, and wraps successful response bodies in a JSON
+;; envelope: {"data":<body>}.
+(module $response
+  ;; enable_features tries to enable the given features and returns the
+  ;; features the host enabled.
+  (import "http_handler" "enable_features" (func $enable_features
+    (param $enable_features i32)
+    (result (; enabled_features ;) i32)))
+
+  ;; read_body reads up to $buf_limit bytes of the body of the given $kind to
+  ;; memory. The result is 1 in the upper 32 bits on EOF, and the length read in
+  ;; the lower 32 bits.
+  (import "http_handler" "read_body" (func $read_body
+    (param $kind i32)
+    (param $buf i32) (param $buf_limit i32)
+    (result (; eof << 32| len ;) i64)))
+
+  ;; write_body writes to the body of the given $kind. The first call replaces
+  ;; the body, and the next calls append to it.
+  (import "http_handler" "write_body" (func $write_body
+    (param $kind i32)
+    (param $body i32) (param $body_len i32)))
+
+  ;; set_header_value overwrites a header of the given $kind and $name with a
+  ;; single value.
+  (import "http_handler" "set_header_value" (func $set_header_value
+    (param $kind i32)
+    (param $name i32) (param $name_len i32)
+    (param $value i32) (param $value_len i32)))
+
+  ;; remove_header removes any values for the given header $kind and $name.
+  (import "http_handler" "remove_header" (func $remove_header
+    (param $kind i32)
+    (param $name i32) (param $name_len i32)))
+
+  ;; get_status_code returns the status code produced by the next handler.
+  (import "http_handler" "get_status_code" (func $get_status_code
+    (result (; status_code ;) i32)))
+
+  ;; http-wasm guests are required to export "memory", so that imported
+  ;; functions like "log" can read memory.
+  (memory (export "memory") 1 (; 1 page==64KB ;))
+
+  ;; buffer_response is the feature flag which allows handle_response to
+  ;; change the response of the next handler.
+  (global $buffer_response i32 (i32.const 2))
+
+  ;; define the header that must not leave the sidecar.
+  (global $internal_header i32 (i32.const 0))
+  (data (i32.const 0) "X-Internal-Token")
+  (global $internal_header_len i32 (i32.const 16))
+
+  ;; define the header set when the body is wrapped.
+  (global $wasm_header i32 (i32.const 32))
+  (data (i32.const 32) "X-Wasm-Response")
+  (global $wasm_header_len i32 (i32.const 15))
+
+  (global $wasm_value i32 (i32.const 48))
+  (data (i32.const 48) "enveloped")
+  (global $wasm_value_len i32 (i32.const 9))
+
+  (global $content_type i32 (i32.const 64))
+  (data (i32.const 64) "Content-Type")
+  (global $content_type_len i32 (i32.const 12))
+
+  (global $json i32 (i32.const 80))
+  (data (i32.const 80) "application/json")
+  (global $json_len i32 (i32.const 16))
+
+  ;; the content length of the next handler is wrong after wrapping the body.
+  (global $content_length i32 (i32.const 96))
+  (data (i32.const 96) "Content-Length")
+  (global $content_length_len i32 (i32.const 14))
+
+  ;; define the envelope of the body.
+  (global $prefix i32 (i32.const 112))
+  (data (i32.const 112) "{\"data\":")
+  (global $prefix_len i32 (i32.const 8))
+
+  (global $suffix i32 (i32.const 120))
+  (data (i32.const 120) "}")
+  (global $suffix_len i32 (i32.const 1))
+
+  ;; body_buf is where the response body is read to, until the end of memory.
+  (global $body_buf i32 (i32.const 1024))
+  (global $body_limit i32 (i32.const 64512))
+
+  ;; handle_request enables buffering the response, so that handle_response
+  ;; can change it, and dispatches to the next handler.
+  (func (export "handle_request") (result (; ctx_next ;) i64)
+    (drop (call $enable_features (global.get $buffer_response)))
+    (return (i64.const 1)))
+
+  ;; handle_response hides internal headers, and wraps successful responses.
+  (func (export "handle_response") (param $reqCtx i32) (param $is_error i32)
+    (local $body i64)
+    (local $body_len i32)
+
+    ;; if is_error { don't change the response }
+    (if (local.get $is_error) (then (return)))
+
+    (call $remove_header (i32.const 1 (; response ;))
+      (global.get $internal_header) (global.get $internal_header_len))
+
+    ;; if status_code != 200 { don't wrap the body }
+    (if (i32.ne (call $get_status_code) (i32.const 200)) (then (return)))
+
+    (local.set $body (call $read_body (i32.const 1 (; response ;))
+      (global.get $body_buf) (global.get $body_limit)))
+    (local.set $body_len (i32.wrap_i64 (local.get $body)))
+
+    ;; if !eof || body_len == 0 { don't wrap the body }
+    (if (i32.or
+          (i64.eqz (i64.shr_u (local.get $body) (i64.const 32)))
+          (i32.eqz (local.get $body_len)))
+      (then (return)))
+
+    (call $write_body (i32.const 1 (; response ;))
+      (global.get $prefix) (global.get $prefix_len))
+    (call $write_body (i32.const 1 (; response ;))
+      (global.get $body_buf) (local.get $body_len))
+    (call $write_body (i32.const 1 (; response ;))
+      (global.get $suffix) (global.get $suffix_len))
+
+    (call $remove_header (i32.const 1 (; response ;))
+      (global.get $content_length) (global.get $content_length_len))
+    (call $set_header_value (i32.const 1 (; response ;))
+      (global.get $content_type) (global.get $content_type_len)
+      (global.get $json) (global.get $json_len))
+    (call $set_header_value (i32.const 1 (; response ;))
+      (global.get $wasm_header) (global.get $wasm_header_len)
+      (global.get $wasm_value) (global.get $wasm_value_len)))
+)
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"testing"
 
 	"github.com/dapr/components-contrib/middleware/http/wasm"
@@ -24,13 +25,15 @@ import (
 var guestWasm map[string][]byte
 
 const (
-	guestWasmOutput  = "output"
-	guestWasmRewrite = "rewrite"
+	guestWasmOutput   = "output"
+	guestWasmRewrite  = "rewrite"
+	guestWasmKV       = "kv"
+	guestWasmResponse = "response"
 )
 
 // TestMain ensures we can read the test wasm prior to running e2e tests.
 func TestMain(m *testing.M) {
-	wasms := []string{guestWasmOutput, guestWasmRewrite}
+	wasms := []string{guestWasmOutput, guestWasmRewrite, guestWasmKV, guestWasmResponse}
 	guestWasm = make(map[string][]byte, len(wasms))
 	for _, name := range wasms {
 		if wasm, err := os.ReadFile(path.Join("e2e-guests", name, "main.wasm")); err != nil {
@@ -51,6 +54,7 @@ func Test_EndToEnd(t *testing.T) {
 	type testCase struct {
 		name  string
 		guest []byte
+		next  http.HandlerFunc
 		test  func(t *testing.T, handler http.Handler, log *bytes.Buffer)
 	}
 
@@ -128,6 +132,73 @@ func Test_EndToEnd(t *testing.T) {
 				require.Equal(t, "/v1.0/hello?name=teddy", r.URL.RequestURI())
 			},
 		},
+		{
+			name:  "kv counter and allow-list",
+			guest: guestWasm[guestWasmKV],
+			next: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Seen-Count", r.Header.Get("X-Request-Count"))
+			},
+			test: func(t *testing.T, handler http.Handler, log *bytes.Buffer) {
+				serve := func(uri, client string) *httptest.ResponseRecorder {
+					r := httptest.NewRequest(http.MethodGet, uri, nil)
+					if client != "" {
+						r.Header.Set("X-Client", client)
+					}
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, r)
+					return w
+				}
+
+				require.Equal(t, http.StatusForbidden, serve("/v1.0/hi", "teddy").Code)
+				require.Equal(t, http.StatusNoContent, serve("/admin/allow", "teddy").Code)
+
+				// The count is shared by all requests, including rejected ones.
+				w := serve("/v1.0/hi", "teddy")
+				require.Equal(t, http.StatusOK, w.Code)
+				require.Equal(t, "3", w.Header().Get("X-Seen-Count"))
+				require.Equal(t, http.StatusForbidden, serve("/v1.0/hi", "panda").Code)
+				require.Equal(t, http.StatusForbidden, serve("/v1.0/hi", "").Code)
+
+				for i := 0; i < 10; i++ {
+					require.Equal(t, http.StatusOK, serve("/v1.0/hi", "teddy").Code)
+				}
+				require.Equal(t, "16", serve("/v1.0/hi", "teddy").Header().Get("X-Seen-Count"))
+
+				require.Equal(t, http.StatusNoContent, serve("/admin/deny", "teddy").Code)
+				require.Equal(t, http.StatusForbidden, serve("/v1.0/hi", "teddy").Code)
+			},
+		},
+		{
+			name:  "response rewrite",
+			guest: guestWasm[guestWasmResponse],
+			next: func(w http.ResponseWriter, r *http.Request) {
+				body := `{"name":"panda"}`
+				w.Header().Set("X-Internal-Token", "secret")
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				if r.URL.Path == "/missing" {
+					w.WriteHeader(http.StatusNotFound)
+				}
+				w.Write([]byte(body))
+			},
+			test: func(t *testing.T, handler http.Handler, log *bytes.Buffer) {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1.0/hi", nil))
+				require.Equal(t, http.StatusOK, w.Code)
+				require.Equal(t, `{"data":{"name":"panda"}}`, w.Body.String())
+				require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				require.Equal(t, "enveloped", w.Header().Get("X-Wasm-Response"))
+				require.Empty(t, w.Header().Get("X-Internal-Token"))
+				require.Empty(t, w.Header().Get("Content-Length"))
+
+				// Only successful responses are wrapped.
+				w = httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+				require.Equal(t, http.StatusNotFound, w.Code)
+				require.Equal(t, `{"name":"panda"}`, w.Body.String())
+				require.Empty(t, w.Header().Get("X-Wasm-Response"))
+				require.Empty(t, w.Header().Get("X-Internal-Token"))
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -141,7 +212,11 @@ func Test_EndToEnd(t *testing.T) {
 			meta := metadata.Base{Properties: map[string]string{"path": wasmPath}}
 			handlerFn, err := wasm.NewMiddleware(l).GetHandler(middleware.Metadata{Base: meta})
 			require.NoError(t, err)
-			handler := handlerFn(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			next := tc.next
+			if next == nil {
+				next = func(w http.ResponseWriter, r *http.Request) {}
+			}
+			handler := handlerFn(next)
 			tc.test(t, handler, &buf)
 		})
 	}
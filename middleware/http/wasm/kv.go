@@ -0,0 +1,201 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// The below are the names of the key/value host functions, imported by guests
+// from kvModule. See README.md for the signature of each function.
+const (
+	kvModule    = "dapr"
+	funcKVGet   = "kv_get"
+	funcKVSet   = "kv_set"
+	funcKVDel   = "kv_delete"
+	funcKVIncr  = "kv_incr"
+	kvFoundFlag = uint64(1) << 32
+)
+
+// The below are the limits of the key/value store, which guests can't exceed.
+const (
+	kvMaxKeySize   = 256
+	kvMaxValueSize = 64 * 1024
+	kvMaxEntries   = 10000
+)
+
+// kvStore is the in-process key/value store shared by all guests of a
+// middleware instance. This allows guests to keep small pieces of state, such
+// as counters or allow-lists, across requests and pooled modules.
+//
+// Keys, values and the number of entries are limited, so guests can't grow
+// the host memory without bound.
+//
+// Note: Values are not persisted, nor shared between Dapr sidecars.
+type kvStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+
+	maxKeySize, maxValueSize, maxEntries int
+}
+
+func newKVStore() *kvStore {
+	return &kvStore{
+		values:       map[string][]byte{},
+		maxKeySize:   kvMaxKeySize,
+		maxValueSize: kvMaxValueSize,
+		maxEntries:   kvMaxEntries,
+	}
+}
+
+func (s *kvStore) get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *kvStore) set(key string, value []byte) error {
+	if len(value) > s.maxValueSize {
+		return fmt.Errorf("value of %s is larger than %d bytes", key, s.maxValueSize)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkKey(key); err != nil {
+		return err
+	}
+	s.values[key] = value
+	return nil
+}
+
+// checkKey returns an error if the key is too large, or if it's a new key and
+// the store is full. It must be called with the lock held.
+func (s *kvStore) checkKey(key string) error {
+	if len(key) > s.maxKeySize {
+		return fmt.Errorf("key is larger than %d bytes", s.maxKeySize)
+	}
+	if _, ok := s.values[key]; !ok && len(s.values) >= s.maxEntries {
+		return fmt.Errorf("can't add %s: the store is full with %d keys", key, s.maxEntries)
+	}
+	return nil
+}
+
+func (s *kvStore) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// incr adds delta to the decimal integer stored at key, which is zero when
+// missing, and returns the result.
+func (s *kvStore) incr(key string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkKey(key); err != nil {
+		return 0, err
+	}
+	var n int64
+	if v, ok := s.values[key]; ok {
+		var err error
+		if n, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			return 0, fmt.Errorf("value of %s is not an integer", key)
+		}
+	}
+	n += delta
+	s.values[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+const i32, i64 = api.ValueTypeI32, api.ValueTypeI64
+
+// compile compiles the host module guests import the key/value functions
+// from.
+func (s *kvStore) compile(ctx context.Context, r wazero.Runtime) (wazero.CompiledModule, error) {
+	compiled, err := r.NewHostModuleBuilder(kvModule).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(s.kvGet), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i64}).
+		WithParameterNames("key", "key_len", "buf", "buf_limit").Export(funcKVGet).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(s.kvSet), []api.ValueType{i32, i32, i32, i32}, []api.ValueType{}).
+		WithParameterNames("key", "key_len", "value", "value_len").Export(funcKVSet).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(s.kvDelete), []api.ValueType{i32, i32}, []api.ValueType{}).
+		WithParameterNames("key", "key_len").Export(funcKVDel).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(s.kvIncr), []api.ValueType{i32, i32, i64}, []api.ValueType{i64}).
+		WithParameterNames("key", "key_len", "delta").Export(funcKVIncr).
+		Compile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: error compiling %s host module: %w", kvModule, err)
+	}
+	return compiled, nil
+}
+
+// kvGet implements the WebAssembly host function funcKVGet. The value is only
+// written to memory if it isn't larger than buf_limit. The result has
+// kvFoundFlag set when the key exists, and the value length in the lower 32
+// bits, so that the guest can retry with a larger buffer.
+func (s *kvStore) kvGet(_ context.Context, mod api.Module, stack []uint64) {
+	key := mustReadString(mod.Memory(), "key", uint32(stack[0]), uint32(stack[1]))
+	buf, bufLimit := uint32(stack[2]), uint32(stack[3])
+
+	v, ok := s.get(key)
+	if !ok {
+		stack[0] = 0
+		return
+	}
+	vLen := uint32(len(v))
+	if vLen > 0 && vLen <= bufLimit {
+		mod.Memory().Write(buf, v)
+	}
+	stack[0] = kvFoundFlag | uint64(vLen)
+}
+
+// kvSet implements the WebAssembly host function funcKVSet.
+func (s *kvStore) kvSet(_ context.Context, mod api.Module, stack []uint64) {
+	key := mustReadString(mod.Memory(), "key", uint32(stack[0]), uint32(stack[1]))
+	value := mustRead(mod.Memory(), "value", uint32(stack[2]), uint32(stack[3]))
+
+	// Copy, as the guest memory can change after this call.
+	if err := s.set(key, append([]byte(nil), value...)); err != nil {
+		panic(err) // traps the guest, like other misuse of host functions.
+	}
+}
+
+// kvDelete implements the WebAssembly host function funcKVDel.
+func (s *kvStore) kvDelete(_ context.Context, mod api.Module, stack []uint64) {
+	key := mustReadString(mod.Memory(), "key", uint32(stack[0]), uint32(stack[1]))
+	s.delete(key)
+}
+
+// kvIncr implements the WebAssembly host function funcKVIncr.
+func (s *kvStore) kvIncr(_ context.Context, mod api.Module, stack []uint64) {
+	key := mustReadString(mod.Memory(), "key", uint32(stack[0]), uint32(stack[1]))
+	n, err := s.incr(key, int64(stack[2]))
+	if err != nil {
+		panic(err) // traps the guest, like other misuse of host functions.
+	}
+	stack[0] = uint64(n)
+}
+
+// mustReadString is like mustRead, except it returns a string.
+func mustReadString(mem api.Memory, fieldName string, offset, byteCount uint32) string {
+	return string(mustRead(mem, fieldName, offset, byteCount))
+}
+
+// mustRead is like api.Memory.Read, except it panics if the offset and
+// byteCount are out of range.
+func mustRead(mem api.Memory, fieldName string, offset, byteCount uint32) []byte {
+	if byteCount == 0 {
+		return nil
+	}
+	buf, ok := mem.Read(offset, byteCount)
+	if !ok {
+		panic(fmt.Errorf("out of memory reading %s", fieldName))
+	}
+	return buf
+}
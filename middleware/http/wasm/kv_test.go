@@ -0,0 +1,61 @@
+package wasm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_kvStore(t *testing.T) {
+	s := newKVStore()
+
+	_, ok := s.get("allow:teddy")
+	require.False(t, ok)
+
+	require.NoError(t, s.set("allow:teddy", []byte("1")))
+	v, ok := s.get("allow:teddy")
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), v)
+
+	s.delete("allow:teddy")
+	_, ok = s.get("allow:teddy")
+	require.False(t, ok)
+
+	n, err := s.incr("requests", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	n, err = s.incr("requests", -3)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2), n)
+	v, _ = s.get("requests")
+	require.Equal(t, []byte("-2"), v)
+
+	require.NoError(t, s.set("name", []byte("panda")))
+	_, err = s.incr("name", 1)
+	require.EqualError(t, err, "value of name is not an integer")
+}
+
+func Test_kvStoreLimits(t *testing.T) {
+	s := newKVStore()
+	s.maxKeySize, s.maxValueSize, s.maxEntries = 4, 8, 2
+
+	require.EqualError(t, s.set("large", nil), "key is larger than 4 bytes")
+	_, err := s.incr("large", 1)
+	require.EqualError(t, err, "key is larger than 4 bytes")
+	require.EqualError(t, s.set("a", []byte(strings.Repeat("x", 9))), "value of a is larger than 8 bytes")
+
+	require.NoError(t, s.set("a", []byte(strings.Repeat("x", 8))))
+	_, err = s.incr("b", 1)
+	require.NoError(t, err)
+	require.EqualError(t, s.set("c", nil), "can't add c: the store is full with 2 keys")
+	_, err = s.incr("c", 1)
+	require.EqualError(t, err, "can't add c: the store is full with 2 keys")
+
+	// Existing keys can still be updated, and deleting makes room
+	require.NoError(t, s.set("a", nil))
+	_, err = s.incr("b", 1)
+	require.NoError(t, err)
+	s.delete("a")
+	require.NoError(t, s.set("c", nil))
+}
@@ -0,0 +1,353 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package claimcheck provides a pubsub wrapper that compresses message payloads and
+// offloads the ones that are too large for the broker to an external store,
+// publishing a reference instead (the claim-check pattern).
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/components"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+const envelopeVersion = "1.0"
+
+// envelopePrefix is the beginning of every serialized envelope, used to tell
+// wrapped payloads apart from the ones published without the wrapper.
+var envelopePrefix = []byte(`{"payloadEnvelope":`)
+
+// envelope is published in place of a compressed or claim-checked payload.
+// It is JSON so that brokers that only accept text can carry it.
+type envelope struct {
+	Version     string      `json:"payloadEnvelope"`
+	Compression Compression `json:"compression,omitempty"`
+	ClaimCheck  string      `json:"claimCheck,omitempty"`
+	Data        []byte      `json:"data,omitempty"`
+}
+
+type claimCheckPubSub struct {
+	pubsub.PubSub
+
+	resolver components.Resolver
+	metadata metadata
+	logger   logger.Logger
+
+	storeLock sync.Mutex
+	store     PayloadStore
+}
+
+var (
+	_ components.ResolverConsumer = (*claimCheckPubSub)(nil)
+	_ pubsub.BulkPublisher        = (*claimCheckPubSub)(nil)
+	_ pubsub.BulkSubscriber       = (*claimCheckPubSub)(nil)
+)
+
+// New wraps a pubsub component.
+// The wrapper is configured with the following properties of the component metadata,
+// which are also passed to the wrapped component:
+//   - compression: none (default), gzip, zstd or snappy
+//   - compressionMinSize: payloads smaller than this size in bytes are not compressed
+//   - claimCheckThreshold: payloads larger than this size in bytes, as they would be published
+//     (compressed and serialized in the envelope), are stored in the payload store; 0 (default)
+//     disables the claim-check
+//   - claimCheckStateStore: name of the state store the payloads are stored in
+//   - claimCheckBinding: name of the output binding the payloads are stored in, instead of a state store;
+//     claimCheckBindingKeyMetadata, claimCheckBindingCreateOperation and claimCheckBindingGetOperation
+//     configure how it's invoked
+//   - claimCheckKeyPrefix: prefix of the keys in the store, "claimcheck/" by default
+//   - claimCheckTTL: TTL of the stored payloads, if supported by the store
+//   - maxDecompressedSize: messages whose payload decompresses to more than this size in bytes
+//     are rejected, 64MiB by default
+//
+// The payload store is looked up with the resolver set by SetComponentResolver, the first time it's used.
+// Subscribers must be wrapped too, and have access to the same store.
+func New(inner pubsub.PubSub, logger logger.Logger) pubsub.PubSub {
+	return &claimCheckPubSub{
+		PubSub: inner,
+		logger: logger,
+	}
+}
+
+// SetComponentResolver sets the resolver of the payload store named in the metadata.
+func (c *claimCheckPubSub) SetComponentResolver(resolver components.Resolver) {
+	c.resolver = resolver
+}
+
+func (c *claimCheckPubSub) Init(metadata pubsub.Metadata) error {
+	m, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	if m.hasStore() && c.resolver == nil {
+		return errors.New("claimcheck error: the payload store can't be used: the host doesn't provide other components")
+	}
+	c.metadata = m
+
+	return c.PubSub.Init(metadata)
+}
+
+// payloadStore returns the payload store named in the metadata. It's resolved on first use, as the store may be
+// initialized after the pubsub.
+func (c *claimCheckPubSub) payloadStore() (PayloadStore, error) {
+	c.storeLock.Lock()
+	defer c.storeLock.Unlock()
+
+	if c.store != nil {
+		return c.store, nil
+	}
+	switch {
+	case c.metadata.ClaimCheckStateStore != "":
+		store, err := c.resolver.StateStore(c.metadata.ClaimCheckStateStore)
+		if err != nil {
+			return nil, fmt.Errorf("error getting state store %s: %w", c.metadata.ClaimCheckStateStore, err)
+		}
+		c.store = NewStateStore(store)
+	case c.metadata.ClaimCheckBinding != "":
+		binding, err := c.resolver.OutputBinding(c.metadata.ClaimCheckBinding)
+		if err != nil {
+			return nil, fmt.Errorf("error getting output binding %s: %w", c.metadata.ClaimCheckBinding, err)
+		}
+		c.store = NewBindingStore(binding, BindingStoreOptions{
+			KeyMetadata:     c.metadata.ClaimCheckBindingKeyMetadata,
+			CreateOperation: bindings.OperationKind(c.metadata.ClaimCheckBindingCreateOperation),
+			GetOperation:    bindings.OperationKind(c.metadata.ClaimCheckBindingGetOperation),
+		})
+	default:
+		return nil, errors.New("no payload store is configured")
+	}
+	return c.store, nil
+}
+
+func (c *claimCheckPubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+	data, err := c.wrap(ctx, req.Topic, req.Data)
+	if err != nil {
+		return err
+	}
+
+	wrapped := *req
+	wrapped.Data = data
+	return c.PubSub.Publish(ctx, &wrapped)
+}
+
+func (c *claimCheckPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	return c.PubSub.Subscribe(ctx, req, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		data, err := c.unwrap(ctx, msg.Data)
+		if err != nil {
+			c.logger.Errorf("claimcheck error: failed to restore payload of message on topic %s: %v", msg.Topic, err)
+			return err
+		}
+
+		unwrapped := *msg
+		unwrapped.Data = data
+		return handler(ctx, &unwrapped)
+	})
+}
+
+// BulkPublish wraps the payloads of the entries. The entries that can't be wrapped are reported as failed, and
+// the others are published with the wrapped component, one at a time if it doesn't support bulk publishing.
+func (c *claimCheckPubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest) (pubsub.BulkPublishResponse, error) {
+	var res pubsub.BulkPublishResponse
+	wrapped := *req
+	wrapped.Entries = make([]pubsub.BulkMessageEntry, 0, len(req.Entries))
+	for _, entry := range req.Entries {
+		data, err := c.wrap(ctx, req.Topic, entry.Event)
+		if err != nil {
+			res.FailedEntries = append(res.FailedEntries, pubsub.BulkPublishResponseFailedEntry{EntryId: entry.EntryId, Error: err})
+			continue
+		}
+		entry.Event = data
+		wrapped.Entries = append(wrapped.Entries, entry)
+	}
+
+	if publisher, ok := c.PubSub.(pubsub.BulkPublisher); ok {
+		if len(wrapped.Entries) > 0 {
+			innerRes, err := publisher.BulkPublish(ctx, &wrapped)
+			if err != nil && len(innerRes.FailedEntries) == 0 {
+				for _, entry := range wrapped.Entries {
+					innerRes.FailedEntries = append(innerRes.FailedEntries, pubsub.BulkPublishResponseFailedEntry{EntryId: entry.EntryId, Error: err})
+				}
+			}
+			res.FailedEntries = append(res.FailedEntries, innerRes.FailedEntries...)
+		}
+	} else {
+		for _, entry := range wrapped.Entries {
+			md := make(map[string]string, len(req.Metadata)+len(entry.Metadata))
+			for k, v := range req.Metadata {
+				md[k] = v
+			}
+			for k, v := range entry.Metadata {
+				md[k] = v
+			}
+			contentType := entry.ContentType
+			err := c.PubSub.Publish(ctx, &pubsub.PublishRequest{
+				Data:        entry.Event,
+				PubsubName:  req.PubsubName,
+				Topic:       req.Topic,
+				Metadata:    md,
+				ContentType: &contentType,
+			})
+			if err != nil {
+				res.FailedEntries = append(res.FailedEntries, pubsub.BulkPublishResponseFailedEntry{EntryId: entry.EntryId, Error: err})
+			}
+		}
+	}
+
+	if len(res.FailedEntries) > 0 {
+		return res, fmt.Errorf("claimcheck error: failed to publish %d of %d entries: %w", len(res.FailedEntries), len(req.Entries), res.FailedEntries[0].Error)
+	}
+	return res, nil
+}
+
+// BulkSubscribe restores the payloads of the entries. The entries that can't be restored are reported as failed,
+// and the others are passed to the handler. If the wrapped component doesn't support bulk subscribing, the handler
+// receives the messages one at a time.
+func (c *claimCheckPubSub) BulkSubscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.BulkHandler) error {
+	subscriber, ok := c.PubSub.(pubsub.BulkSubscriber)
+	if !ok {
+		return c.Subscribe(ctx, req, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			entry := pubsub.BulkMessageEntry{
+				EntryId:  uuid.New().String(),
+				Event:    msg.Data,
+				Metadata: msg.Metadata,
+			}
+			if msg.ContentType != nil {
+				entry.ContentType = *msg.ContentType
+			}
+			_, err := handler(ctx, &pubsub.BulkMessage{
+				Entries:  []pubsub.BulkMessageEntry{entry},
+				Topic:    msg.Topic,
+				Metadata: msg.Metadata,
+			})
+			return err
+		})
+	}
+
+	return subscriber.BulkSubscribe(ctx, req, func(ctx context.Context, msg *pubsub.BulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+		responses := make([]pubsub.BulkSubscribeResponseEntry, len(msg.Entries))
+		unwrapped := *msg
+		unwrapped.Entries = make([]pubsub.BulkMessageEntry, 0, len(msg.Entries))
+		indexes := make([]int, 0, len(msg.Entries))
+		var failed error
+		for i, entry := range msg.Entries {
+			responses[i].EntryId = entry.EntryId
+			data, err := c.unwrap(ctx, entry.Event)
+			if err != nil {
+				c.logger.Errorf("claimcheck error: failed to restore payload of entry %s on topic %s: %v", entry.EntryId, msg.Topic, err)
+				responses[i].Error = err
+				failed = err
+				continue
+			}
+			entry.Event = data
+			unwrapped.Entries = append(unwrapped.Entries, entry)
+			indexes = append(indexes, i)
+		}
+
+		if len(unwrapped.Entries) > 0 {
+			handlerResponses, err := handler(ctx, &unwrapped)
+			if err != nil {
+				failed = err
+				for j, i := range indexes {
+					if handlerResponses == nil {
+						responses[i].Error = err
+					} else if j < len(handlerResponses) {
+						responses[i].Error = handlerResponses[j].Error
+					}
+				}
+			}
+		}
+		return responses, failed
+	})
+}
+
+// wrap compresses the payload and stores it in the claim-check store if what would be published is above
+// the threshold. Payloads that need neither are published unchanged.
+func (c *claimCheckPubSub) wrap(ctx context.Context, topic string, data []byte) ([]byte, error) {
+	env := envelope{
+		Version: envelopeVersion,
+		Data:    data,
+	}
+
+	published := data
+	if c.metadata.compression != CompressionNone && len(data) >= c.metadata.CompressionMinSize {
+		compressed, err := c.metadata.compression.Compress(data)
+		if err != nil {
+			return nil, fmt.Errorf("claimcheck error: failed to compress payload: %w", err)
+		}
+		env.Compression = c.metadata.compression
+		env.Data = compressed
+		// The compressed payload is base64-encoded in the envelope, so it's larger than the compressed bytes
+		published, err = json.Marshal(env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.metadata.ClaimCheckThreshold <= 0 || len(published) <= c.metadata.ClaimCheckThreshold {
+		return published, nil
+	}
+
+	store, err := c.payloadStore()
+	if err != nil {
+		return nil, fmt.Errorf("claimcheck error: %w", err)
+	}
+	env.ClaimCheck = c.metadata.ClaimCheckKeyPrefix + topic + "/" + uuid.New().String()
+	if err := store.Put(ctx, env.ClaimCheck, env.Data, c.metadata.ClaimCheckTTL); err != nil {
+		return nil, fmt.Errorf("claimcheck error: failed to store payload %s: %w", env.ClaimCheck, err)
+	}
+	env.Data = nil
+	return json.Marshal(env)
+}
+
+// unwrap restores a payload published by wrap.
+func (c *claimCheckPubSub) unwrap(ctx context.Context, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, envelopePrefix) {
+		return data, nil
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid payload envelope: %w", err)
+	}
+	if env.Version != envelopeVersion {
+		return nil, fmt.Errorf("unsupported payload envelope version: %s", env.Version)
+	}
+
+	if env.ClaimCheck != "" {
+		store, err := c.payloadStore()
+		if err != nil {
+			return nil, fmt.Errorf("payload %s is in the claim-check store: %w", env.ClaimCheck, err)
+		}
+		env.Data, err = store.Get(ctx, env.ClaimCheck)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve payload %s: %w", env.ClaimCheck, err)
+		}
+	}
+
+	res, err := env.Compression.Decompress(env.Data, c.metadata.MaxDecompressedSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,457 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/components"
+	mdata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	inmemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/state"
+	inmemoryState "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+// recordingPubSub records the payloads published to the wrapped component.
+type recordingPubSub struct {
+	pubsub.PubSub
+	published [][]byte
+}
+
+func (r *recordingPubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+	r.published = append(r.published, req.Data)
+	return r.PubSub.Publish(ctx, req)
+}
+
+type fakeBinding struct {
+	objects map[string][]byte
+	ttls    map[string]string
+}
+
+func (f *fakeBinding) Init(metadata bindings.Metadata) error { return nil }
+
+func (f *fakeBinding) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation, bindings.GetOperation}
+}
+
+func (f *fakeBinding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		f.objects[req.Metadata["blobName"]] = req.Data
+		f.ttls[req.Metadata["blobName"]] = req.Metadata["ttlInSeconds"]
+		return nil, nil
+	default:
+		return &bindings.InvokeResponse{Data: f.objects[req.Metadata["blobName"]]}, nil
+	}
+}
+
+// bulkPubSub adds bulk publishing and subscribing to the recorded component, one message at a time.
+type bulkPubSub struct {
+	*recordingPubSub
+	bulkPublished int
+}
+
+func (b *bulkPubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest) (pubsub.BulkPublishResponse, error) {
+	b.bulkPublished++
+	for _, entry := range req.Entries {
+		err := b.Publish(ctx, &pubsub.PublishRequest{Topic: req.Topic, Data: entry.Event})
+		if err != nil {
+			return pubsub.BulkPublishResponse{}, err
+		}
+	}
+	return pubsub.BulkPublishResponse{}, nil
+}
+
+func (b *bulkPubSub) BulkSubscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.BulkHandler) error {
+	return b.Subscribe(ctx, req, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		_, err := handler(ctx, &pubsub.BulkMessage{
+			Topic:   msg.Topic,
+			Entries: []pubsub.BulkMessageEntry{{EntryId: "1", Event: msg.Data}},
+		})
+		return err
+	})
+}
+
+type fakeComponents struct {
+	stateStores map[string]state.Store
+	bindings    map[string]bindings.OutputBinding
+}
+
+func (f *fakeComponents) StateStore(name string) (state.Store, error) {
+	if s, ok := f.stateStores[name]; ok {
+		return s, nil
+	}
+	return nil, errors.New("state store not found")
+}
+
+func (f *fakeComponents) SecretStore(name string) (secretstores.SecretStore, error) {
+	return nil, errors.New("secret store not found")
+}
+
+func (f *fakeComponents) OutputBinding(name string) (bindings.OutputBinding, error) {
+	if b, ok := f.bindings[name]; ok {
+		return b, nil
+	}
+	return nil, errors.New("output binding not found")
+}
+
+func (f *fakeComponents) PubSub(name string) (pubsub.PubSub, error) {
+	return nil, errors.New("pubsub not found")
+}
+
+func newTestPubSub(t *testing.T, inner pubsub.PubSub, resolver components.Resolver, properties map[string]string) pubsub.PubSub {
+	t.Helper()
+
+	ps := New(inner, logger.NewLogger("test"))
+	if resolver != nil {
+		ps.(components.ResolverConsumer).SetComponentResolver(resolver)
+	}
+	err := ps.Init(pubsub.Metadata{Base: mdata.Base{Properties: properties}})
+	require.NoError(t, err)
+	return ps
+}
+
+func newRecordingPubSub() *recordingPubSub {
+	return &recordingPubSub{PubSub: inmemory.New(logger.NewLogger("test"))}
+}
+
+func publishAndReceive(t *testing.T, ps pubsub.PubSub, data []byte) []byte {
+	t.Helper()
+
+	received := make(chan []byte, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := ps.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "orders"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		received <- msg.Data
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = ps.Publish(context.Background(), &pubsub.PublishRequest{PubsubName: "test", Topic: "orders", Data: data})
+	require.NoError(t, err)
+
+	select {
+	case res := <-received:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+		return nil
+	}
+}
+
+func TestCompression(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"item":"book","price":9.5}`, 100))
+
+	for _, compression := range []string{"gzip", "zstd", "snappy"} {
+		t.Run(compression, func(t *testing.T) {
+			inner := newRecordingPubSub()
+			ps := newTestPubSub(t, inner, nil, map[string]string{"compression": compression})
+
+			res := publishAndReceive(t, ps, payload)
+			assert.Equal(t, payload, res)
+
+			require.Len(t, inner.published, 1)
+			var env envelope
+			require.NoError(t, json.Unmarshal(inner.published[0], &env))
+			assert.Equal(t, Compression(compression), env.Compression)
+			assert.Less(t, len(inner.published[0]), len(payload))
+		})
+	}
+
+	t.Run("decompressed size limit", func(t *testing.T) {
+		for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionSnappy} {
+			b, err := compression.Compress(payload)
+			require.NoError(t, err)
+
+			_, err = compression.Decompress(b, len(payload)-1)
+			require.ErrorIs(t, err, ErrDecompressedTooLarge, compression)
+			res, err := compression.Decompress(b, len(payload))
+			require.NoError(t, err)
+			assert.Equal(t, payload, res)
+		}
+
+		inner := newRecordingPubSub()
+		ps := newTestPubSub(t, inner, nil, map[string]string{"compression": "gzip"})
+		require.NoError(t, ps.Publish(context.Background(), &pubsub.PublishRequest{Topic: "orders", Data: payload}))
+		c := ps.(*claimCheckPubSub)
+		c.metadata.MaxDecompressedSize = 1024
+		_, err := c.unwrap(context.Background(), inner.published[0])
+		require.ErrorIs(t, err, ErrDecompressedTooLarge)
+	})
+
+	t.Run("below minimum size", func(t *testing.T) {
+		inner := newRecordingPubSub()
+		ps := newTestPubSub(t, inner, nil, map[string]string{"compression": "gzip", "compressionMinSize": "1024"})
+
+		res := publishAndReceive(t, ps, []byte("hello"))
+		assert.Equal(t, "hello", string(res))
+		assert.Equal(t, "hello", string(inner.published[0]))
+	})
+}
+
+func TestClaimCheck(t *testing.T) {
+	payload := bytes.Repeat([]byte{1, 2, 3, 4}, 256)
+
+	t.Run("state store", func(t *testing.T) {
+		store := inmemoryState.NewInMemoryStateStore(logger.NewLogger("test"))
+		require.NoError(t, store.Init(state.Metadata{}))
+		inner := newRecordingPubSub()
+		resolver := &fakeComponents{stateStores: map[string]state.Store{"payloads": store}}
+		ps := newTestPubSub(t, inner, resolver, map[string]string{
+			"claimCheckThreshold":  "512",
+			"claimCheckStateStore": "payloads",
+		})
+
+		res := publishAndReceive(t, ps, payload)
+		assert.Equal(t, payload, res)
+
+		var env envelope
+		require.NoError(t, json.Unmarshal(inner.published[0], &env))
+		assert.True(t, strings.HasPrefix(env.ClaimCheck, "claimcheck/orders/"))
+		assert.Empty(t, env.Data)
+
+		stored, err := store.Get(context.Background(), &state.GetRequest{Key: env.ClaimCheck})
+		require.NoError(t, err)
+		assert.Equal(t, payload, stored.Data)
+	})
+
+	t.Run("binding with compression", func(t *testing.T) {
+		binding := &fakeBinding{objects: map[string][]byte{}, ttls: map[string]string{}}
+		inner := newRecordingPubSub()
+		resolver := &fakeComponents{bindings: map[string]bindings.OutputBinding{"blobs": binding}}
+		ps := newTestPubSub(t, inner, resolver, map[string]string{
+			"compression":                  "zstd",
+			"claimCheckThreshold":          "16",
+			"claimCheckKeyPrefix":          "large/",
+			"claimCheckTTL":                "1h",
+			"claimCheckBinding":            "blobs",
+			"claimCheckBindingKeyMetadata": "blobName",
+		})
+
+		res := publishAndReceive(t, ps, payload)
+		assert.Equal(t, payload, res)
+
+		var env envelope
+		require.NoError(t, json.Unmarshal(inner.published[0], &env))
+		assert.Equal(t, CompressionZstd, env.Compression)
+		require.Contains(t, binding.objects, env.ClaimCheck)
+		assert.True(t, strings.HasPrefix(env.ClaimCheck, "large/orders/"))
+		assert.Equal(t, "3600", binding.ttls[env.ClaimCheck])
+	})
+
+	t.Run("threshold applies to the envelope", func(t *testing.T) {
+		// Random bytes don't compress, and grow by a third when base64-encoded in the envelope
+		random := make([]byte, 600)
+		_, err := rand.Read(random)
+		require.NoError(t, err)
+		compressed, err := CompressionGzip.Compress(random)
+		require.NoError(t, err)
+		require.Less(t, len(compressed), 700)
+
+		binding := &fakeBinding{objects: map[string][]byte{}, ttls: map[string]string{}}
+		inner := newRecordingPubSub()
+		resolver := &fakeComponents{bindings: map[string]bindings.OutputBinding{"blobs": binding}}
+		ps := newTestPubSub(t, inner, resolver, map[string]string{
+			"compression":                  "gzip",
+			"claimCheckThreshold":          "700",
+			"claimCheckBinding":            "blobs",
+			"claimCheckBindingKeyMetadata": "blobName",
+		})
+
+		res := publishAndReceive(t, ps, random)
+		assert.Equal(t, random, res)
+		assert.LessOrEqual(t, len(inner.published[0]), 700)
+		var env envelope
+		require.NoError(t, json.Unmarshal(inner.published[0], &env))
+		assert.Contains(t, binding.objects, env.ClaimCheck)
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		binding := &fakeBinding{objects: map[string][]byte{}, ttls: map[string]string{}}
+		inner := newRecordingPubSub()
+		resolver := &fakeComponents{bindings: map[string]bindings.OutputBinding{"blobs": binding}}
+		ps := newTestPubSub(t, inner, resolver, map[string]string{
+			"claimCheckThreshold": "4096",
+			"claimCheckBinding":   "blobs",
+		})
+
+		res := publishAndReceive(t, ps, payload)
+		assert.Equal(t, payload, res)
+		assert.Equal(t, payload, inner.published[0])
+		assert.Empty(t, binding.objects)
+	})
+
+	t.Run("store not found", func(t *testing.T) {
+		ps := newTestPubSub(t, newRecordingPubSub(), &fakeComponents{}, map[string]string{
+			"claimCheckThreshold":  "16",
+			"claimCheckStateStore": "missing",
+		})
+
+		err := ps.Publish(context.Background(), &pubsub.PublishRequest{Topic: "orders", Data: payload})
+		require.ErrorContains(t, err, "error getting state store missing")
+	})
+}
+
+func TestBulk(t *testing.T) {
+	payload := bytes.Repeat([]byte{1, 2, 3, 4}, 256)
+
+	bulkPublishAndReceive := func(t *testing.T, ps pubsub.PubSub) [][]byte {
+		received := make(chan []byte, 2)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := ps.(pubsub.BulkSubscriber).BulkSubscribe(ctx, pubsub.SubscribeRequest{Topic: "orders"}, func(ctx context.Context, msg *pubsub.BulkMessage) ([]pubsub.BulkSubscribeResponseEntry, error) {
+			res := make([]pubsub.BulkSubscribeResponseEntry, len(msg.Entries))
+			for i, entry := range msg.Entries {
+				received <- entry.Event
+				res[i].EntryId = entry.EntryId
+			}
+			return res, nil
+		})
+		require.NoError(t, err)
+
+		_, err = ps.(pubsub.BulkPublisher).BulkPublish(context.Background(), &pubsub.BulkPublishRequest{
+			Topic: "orders",
+			Entries: []pubsub.BulkMessageEntry{
+				{EntryId: "1", Event: payload, ContentType: "application/octet-stream"},
+				{EntryId: "2", Event: []byte("small"), ContentType: "text/plain"},
+			},
+		})
+		require.NoError(t, err)
+
+		var res [][]byte
+		for len(res) < 2 {
+			select {
+			case data := <-received:
+				res = append(res, data)
+			case <-time.After(5 * time.Second):
+				t.Fatal("messages not received")
+			}
+		}
+		return res
+	}
+
+	newStore := func(t *testing.T) (state.Store, components.Resolver) {
+		store := inmemoryState.NewInMemoryStateStore(logger.NewLogger("test"))
+		require.NoError(t, store.Init(state.Metadata{}))
+		return store, &fakeComponents{stateStores: map[string]state.Store{"payloads": store}}
+	}
+	properties := map[string]string{
+		"claimCheckThreshold":  "512",
+		"claimCheckStateStore": "payloads",
+	}
+
+	t.Run("bulk component", func(t *testing.T) {
+		_, resolver := newStore(t)
+		inner := &bulkPubSub{recordingPubSub: newRecordingPubSub()}
+		ps := newTestPubSub(t, inner, resolver, properties)
+
+		res := bulkPublishAndReceive(t, ps)
+		assert.ElementsMatch(t, [][]byte{payload, []byte("small")}, res)
+		assert.Equal(t, 1, inner.bulkPublished)
+		require.Len(t, inner.published, 2)
+		assert.Less(t, len(inner.published[0]), 512)
+	})
+
+	t.Run("component without bulk support", func(t *testing.T) {
+		_, resolver := newStore(t)
+		inner := newRecordingPubSub()
+		ps := newTestPubSub(t, inner, resolver, properties)
+
+		res := bulkPublishAndReceive(t, ps)
+		assert.ElementsMatch(t, [][]byte{payload, []byte("small")}, res)
+		require.Len(t, inner.published, 2)
+		assert.Less(t, len(inner.published[0]), 512)
+	})
+
+	t.Run("entries that can't be stored fail", func(t *testing.T) {
+		inner := newRecordingPubSub()
+		ps := newTestPubSub(t, inner, &fakeComponents{}, properties)
+
+		res, err := ps.(pubsub.BulkPublisher).BulkPublish(context.Background(), &pubsub.BulkPublishRequest{
+			Topic: "orders",
+			Entries: []pubsub.BulkMessageEntry{
+				{EntryId: "1", Event: payload},
+				{EntryId: "2", Event: []byte("small")},
+			},
+		})
+		require.Error(t, err)
+		require.Len(t, res.FailedEntries, 1)
+		assert.Equal(t, "1", res.FailedEntries[0].EntryId)
+		assert.Equal(t, [][]byte{[]byte("small")}, inner.published)
+	})
+}
+
+func TestInit(t *testing.T) {
+	l := logger.NewLogger("test")
+	resolver := &fakeComponents{}
+
+	tests := map[string]struct {
+		properties map[string]string
+		resolver   components.Resolver
+	}{
+		"claim-check without store": {
+			properties: map[string]string{"claimCheckThreshold": "1024"},
+			resolver:   resolver,
+		},
+		"state store and binding": {
+			properties: map[string]string{"claimCheckThreshold": "1024", "claimCheckStateStore": "a", "claimCheckBinding": "b"},
+			resolver:   resolver,
+		},
+		"store without resolver": {
+			properties: map[string]string{"claimCheckThreshold": "1024", "claimCheckStateStore": "a"},
+		},
+		"invalid compression": {
+			properties: map[string]string{"compression": "lz4"},
+		},
+		"invalid decompressed size limit": {
+			properties: map[string]string{"maxDecompressedSize": "0"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ps := New(inmemory.New(l), l)
+			if tc.resolver != nil {
+				ps.(components.ResolverConsumer).SetComponentResolver(tc.resolver)
+			}
+			err := ps.Init(pubsub.Metadata{Base: mdata.Base{Properties: tc.properties}})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestUnwrapPassthrough(t *testing.T) {
+	c := &claimCheckPubSub{}
+
+	res, err := c.unwrap(context.Background(), []byte(`{"specversion":"1.0"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"specversion":"1.0"}`, string(res))
+
+	_, err = c.unwrap(context.Background(), []byte(`{"payloadEnvelope":"1.0","claimCheck":"a"}`))
+	require.Error(t, err)
+}
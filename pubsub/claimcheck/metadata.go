@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	defaultClaimCheckKeyPrefix = "claimcheck/"
+	defaultMaxDecompressedSize = 64 << 20
+)
+
+type metadata struct {
+	// Compression algorithm applied to the payloads: none, gzip, zstd or snappy.
+	Compression string `mapstructure:"compression"`
+	// Payloads smaller than this size, in bytes, are not compressed.
+	CompressionMinSize int `mapstructure:"compressionMinSize"`
+	// Payloads larger than this size, in bytes, after compression, are stored in the
+	// claim-check store and replaced by a reference. 0 disables the claim-check.
+	ClaimCheckThreshold int `mapstructure:"claimCheckThreshold"`
+	// Prefix of the keys of the payloads stored in the claim-check store.
+	ClaimCheckKeyPrefix string `mapstructure:"claimCheckKeyPrefix"`
+	// TTL of the payloads stored in the claim-check store, if supported by the store.
+	ClaimCheckTTL time.Duration `mapstructure:"claimCheckTTL"`
+	// Messages whose payload decompresses to more than this size, in bytes, are rejected.
+	MaxDecompressedSize int `mapstructure:"maxDecompressedSize"`
+	// Name of the state store component the claim-checked payloads are kept in.
+	ClaimCheckStateStore string `mapstructure:"claimCheckStateStore"`
+	// Name of the output binding component the claim-checked payloads are kept in, such as an object storage.
+	ClaimCheckBinding string `mapstructure:"claimCheckBinding"`
+	// Name of the request metadata property carrying the key with the binding, for example "key" for AWS S3
+	// or "blobName" for Azure Blob Storage. Defaults to "key".
+	ClaimCheckBindingKeyMetadata string `mapstructure:"claimCheckBindingKeyMetadata"`
+	// Operation used to store a payload with the binding. Defaults to "create".
+	ClaimCheckBindingCreateOperation string `mapstructure:"claimCheckBindingCreateOperation"`
+	// Operation used to retrieve a payload with the binding. Defaults to "get".
+	ClaimCheckBindingGetOperation string `mapstructure:"claimCheckBindingGetOperation"`
+
+	compression Compression
+}
+
+func parseMetadata(meta pubsub.Metadata) (metadata, error) {
+	m := metadata{
+		ClaimCheckKeyPrefix: defaultClaimCheckKeyPrefix,
+		MaxDecompressedSize: defaultMaxDecompressedSize,
+	}
+	if err := contribMetadata.DecodeMetadata(meta.Properties, &m); err != nil {
+		return m, fmt.Errorf("claimcheck error: failed to parse metadata: %w", err)
+	}
+
+	var err error
+	m.compression, err = ParseCompression(m.Compression)
+	if err != nil {
+		return m, fmt.Errorf("claimcheck error: %w", err)
+	}
+	if m.CompressionMinSize < 0 {
+		return m, errors.New("claimcheck error: compressionMinSize must not be negative")
+	}
+	if m.ClaimCheckThreshold < 0 {
+		return m, errors.New("claimcheck error: claimCheckThreshold must not be negative")
+	}
+	if m.MaxDecompressedSize <= 0 {
+		return m, errors.New("claimcheck error: maxDecompressedSize must be positive")
+	}
+	if m.ClaimCheckTTL < 0 {
+		return m, errors.New("claimcheck error: claimCheckTTL must not be negative")
+	}
+	if m.ClaimCheckStateStore != "" && m.ClaimCheckBinding != "" {
+		return m, errors.New("claimcheck error: only one of claimCheckStateStore or claimCheckBinding can be set")
+	}
+	if m.ClaimCheckThreshold > 0 && !m.hasStore() {
+		return m, errors.New("claimcheck error: claimCheckThreshold is set but no payload store is configured")
+	}
+
+	return m, nil
+}
+
+// hasStore returns true if a payload store is configured.
+func (m metadata) hasStore() bool {
+	return m.ClaimCheckStateStore != "" || m.ClaimCheckBinding != ""
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/state"
+)
+
+const ttlInSecondsMetadata = "ttlInSeconds"
+
+// PayloadStore stores the payloads that are too large to be published.
+type PayloadStore interface {
+	Put(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// BindingStoreOptions configures how payloads are stored with an output binding.
+type BindingStoreOptions struct {
+	// KeyMetadata is the name of the request metadata property carrying the key,
+	// for example "key" for AWS S3 or "blobName" for Azure Blob Storage.
+	KeyMetadata string
+	// CreateOperation is the operation used to store a payload. Defaults to "create".
+	CreateOperation bindings.OperationKind
+	// GetOperation is the operation used to retrieve a payload. Defaults to "get".
+	GetOperation bindings.OperationKind
+}
+
+type bindingStore struct {
+	binding bindings.OutputBinding
+	opts    BindingStoreOptions
+}
+
+// NewBindingStore returns a PayloadStore that keeps the payloads in an output binding,
+// such as an object storage.
+func NewBindingStore(binding bindings.OutputBinding, opts BindingStoreOptions) PayloadStore {
+	if opts.KeyMetadata == "" {
+		opts.KeyMetadata = "key"
+	}
+	if opts.CreateOperation == "" {
+		opts.CreateOperation = bindings.CreateOperation
+	}
+	if opts.GetOperation == "" {
+		opts.GetOperation = bindings.GetOperation
+	}
+	return &bindingStore{
+		binding: binding,
+		opts:    opts,
+	}
+}
+
+func (s *bindingStore) Put(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	md := map[string]string{s.opts.KeyMetadata: key}
+	if ttl > 0 {
+		md[ttlInSecondsMetadata] = strconv.FormatInt(int64(ttl.Seconds()), 10)
+	}
+	_, err := s.binding.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: s.opts.CreateOperation,
+		Data:      data,
+		Metadata:  md,
+	})
+	return err
+}
+
+func (s *bindingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.binding.Invoke(ctx, &bindings.InvokeRequest{
+		Operation: s.opts.GetOperation,
+		Metadata:  map[string]string{s.opts.KeyMetadata: key},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, fmt.Errorf("payload %s not found", key)
+	}
+	return res.Data, nil
+}
+
+type stateStore struct {
+	store state.Store
+}
+
+// NewStateStore returns a PayloadStore that keeps the payloads in a state store.
+func NewStateStore(store state.Store) PayloadStore {
+	return &stateStore{
+		store: store,
+	}
+}
+
+func (s *stateStore) Put(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	req := &state.SetRequest{
+		Key:   key,
+		Value: data,
+	}
+	if ttl > 0 {
+		req.Metadata = map[string]string{
+			ttlInSecondsMetadata: strconv.FormatInt(int64(ttl.Seconds()), 10),
+		}
+	}
+	return s.store.Set(ctx, req)
+}
+
+func (s *stateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.store.Get(ctx, &state.GetRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || res.Data == nil {
+		return nil, fmt.Errorf("payload %s not found", key)
+	}
+	return res.Data, nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	contribContenttype "github.com/dapr/components-contrib/contenttype"
+)
+
+const (
+	// CloudEventsHeaderPrefix is the prefix of the headers carrying the attributes
+	// of binary-mode CloudEvents in Kafka and most other protocol bindings.
+	CloudEventsHeaderPrefix = "ce_"
+	// CloudEventsAMQPHeaderPrefix is the prefix of the application properties carrying
+	// the attributes of binary-mode CloudEvents in AMQP.
+	CloudEventsAMQPHeaderPrefix = "cloudEvents:"
+	// ContentTypeHeader is the header carrying the datacontenttype of binary-mode CloudEvents.
+	ContentTypeHeader = "content-type"
+)
+
+// IsBinaryCloudEvent checks if the headers of a message carry the attributes of a binary-mode CloudEvent.
+func IsBinaryCloudEvent(headers map[string]string, prefix string) bool {
+	_, ok := headers[prefix+SpecVersionField]
+	return ok
+}
+
+// FromBinaryCloudEvent returns a map representation of a binary-mode CloudEvent,
+// whose attributes are in the headers with the given prefix and whose data is the message payload.
+// The datacontenttype is read from the content-type header.
+func FromBinaryCloudEvent(data []byte, headers map[string]string, prefix string) (map[string]interface{}, error) {
+	if !IsBinaryCloudEvent(headers, prefix) {
+		return nil, fmt.Errorf("missing %s%s header", prefix, SpecVersionField)
+	}
+
+	ce := make(map[string]interface{}, len(headers)+1)
+	for name, value := range headers {
+		if attr, ok := cutPrefixFold(name, prefix); ok && attr != "" {
+			ce[strings.ToLower(attr)] = value
+		}
+	}
+
+	dataContentType := headers[ContentTypeHeader]
+	if dataContentType != "" {
+		ce[DataContentTypeField] = dataContentType
+	}
+
+	if len(data) == 0 {
+		return ce, nil
+	}
+
+	switch {
+	case contribContenttype.IsJSONContentType(dataContentType):
+		var ceData interface{}
+		if err := unmarshalPrecise(data, &ceData); err != nil {
+			return nil, fmt.Errorf("cannot parse data of CloudEvent as JSON: %w", err)
+		}
+		ce[DataField] = ceData
+	case contribContenttype.IsStringContentType(dataContentType):
+		ce[DataField] = string(data)
+	default:
+		ce[DataBase64Field] = base64.StdEncoding.EncodeToString(data)
+	}
+
+	return ce, nil
+}
+
+// ToBinaryCloudEvent splits the map representation of a CloudEvent into the payload and the headers
+// of a binary-mode message, prefixing the attribute names with the given prefix.
+// The datacontenttype is written to the content-type header.
+func ToBinaryCloudEvent(cloudEvent map[string]interface{}, prefix string) ([]byte, map[string]string, error) {
+	headers := make(map[string]string, len(cloudEvent))
+	for attr, value := range cloudEvent {
+		switch attr {
+		case DataField, DataBase64Field:
+			continue
+		case DataContentTypeField:
+			headers[ContentTypeHeader] = fmt.Sprint(value)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		headerValue, err := attributeString(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot convert CloudEvent attribute %s: %w", attr, err)
+		}
+		headers[prefix+attr] = headerValue
+	}
+
+	if encoded, ok := cloudEvent[DataBase64Field]; ok {
+		str, ok := encoded.(string)
+		if !ok {
+			return nil, nil, errors.New("data_base64 of CloudEvent is not a string")
+		}
+		data, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot decode data_base64 of CloudEvent: %w", err)
+		}
+		return data, headers, nil
+	}
+
+	ceData, ok := cloudEvent[DataField]
+	if !ok || ceData == nil {
+		return nil, headers, nil
+	}
+	if str, ok := ceData.(string); ok && !contribContenttype.IsJSONContentType(headers[ContentTypeHeader]) {
+		return []byte(str), headers, nil
+	}
+	data, err := json.Marshal(ceData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot serialize data of CloudEvent: %w", err)
+	}
+	return data, headers, nil
+}
+
+// StructuredCloudEventFromBinary converts a binary-mode CloudEvent to a structured-mode JSON CloudEvent.
+func StructuredCloudEventFromBinary(data []byte, headers map[string]string, prefix string) ([]byte, error) {
+	ce, err := FromBinaryCloudEvent(data, headers, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ce)
+}
+
+// BinaryCloudEventFromStructured converts a structured-mode JSON CloudEvent to the payload
+// and the headers of a binary-mode message.
+func BinaryCloudEventFromStructured(cloudEvent []byte, prefix string) ([]byte, map[string]string, error) {
+	var ce map[string]interface{}
+	if err := unmarshalPrecise(cloudEvent, &ce); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse CloudEvent: %w", err)
+	}
+	return ToBinaryCloudEvent(ce, prefix)
+}
+
+func attributeString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number, bool, int, int32, int64, float64:
+		return fmt.Sprint(v), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
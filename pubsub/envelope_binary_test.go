@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromBinaryCloudEvent(t *testing.T) {
+	t.Run("json data", func(t *testing.T) {
+		headers := map[string]string{
+			"ce_specversion": "1.0",
+			"ce_id":          "a",
+			"ce_source":      "/orders",
+			"ce_type":        "order.created",
+			"ce_myextension": "ext",
+			"content-type":   "application/json",
+			"partitionKey":   "key",
+		}
+		assert.True(t, IsBinaryCloudEvent(headers, CloudEventsHeaderPrefix))
+
+		ce, err := FromBinaryCloudEvent([]byte(`{"id":7}`), headers, CloudEventsHeaderPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0", ce[SpecVersionField])
+		assert.Equal(t, "a", ce[IDField])
+		assert.Equal(t, "/orders", ce[SourceField])
+		assert.Equal(t, "order.created", ce[TypeField])
+		assert.Equal(t, "ext", ce["myextension"])
+		assert.Equal(t, "application/json", ce[DataContentTypeField])
+		assert.Equal(t, map[string]interface{}{"id": json.Number("7")}, ce[DataField])
+		assert.NotContains(t, ce, "partitionKey")
+	})
+
+	t.Run("text data", func(t *testing.T) {
+		headers := map[string]string{"cloudEvents:specversion": "1.0", "cloudEvents:id": "a", "content-type": "text/plain"}
+		ce, err := FromBinaryCloudEvent([]byte("hello"), headers, CloudEventsAMQPHeaderPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", ce[DataField])
+	})
+
+	t.Run("binary data", func(t *testing.T) {
+		headers := map[string]string{"ce_specversion": "1.0", "ce_id": "a"}
+		ce, err := FromBinaryCloudEvent([]byte{1, 2, 3}, headers, CloudEventsHeaderPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, "AQID", ce[DataBase64Field])
+		assert.NotContains(t, ce, DataField)
+	})
+
+	t.Run("not a cloud event", func(t *testing.T) {
+		headers := map[string]string{"ce_id": "a"}
+		assert.False(t, IsBinaryCloudEvent(headers, CloudEventsHeaderPrefix))
+		_, err := FromBinaryCloudEvent([]byte("hello"), headers, CloudEventsHeaderPrefix)
+		require.Error(t, err)
+	})
+}
+
+func TestToBinaryCloudEvent(t *testing.T) {
+	t.Run("json data", func(t *testing.T) {
+		ce := NewCloudEventsEnvelope("a", "/orders", "order.created", "", "orders", "mypubsub", "application/json", []byte(`{"id":7}`), "", "")
+		data, headers, err := ToBinaryCloudEvent(ce, CloudEventsHeaderPrefix)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":7}`, string(data))
+		assert.Equal(t, "a", headers["ce_id"])
+		assert.Equal(t, "1.0", headers["ce_specversion"])
+		assert.Equal(t, "/orders", headers["ce_source"])
+		assert.Equal(t, "orders", headers["ce_topic"])
+		assert.Equal(t, "application/json", headers[ContentTypeHeader])
+		assert.NotContains(t, headers, "ce_data")
+	})
+
+	t.Run("binary data", func(t *testing.T) {
+		ce := NewCloudEventsEnvelope("a", "", "", "", "", "", "application/octet-stream", []byte{1, 2, 3}, "", "")
+		data, headers, err := ToBinaryCloudEvent(ce, CloudEventsHeaderPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 2, 3}, data)
+		assert.NotContains(t, headers, "ce_data_base64")
+	})
+
+	t.Run("round trip through structured mode", func(t *testing.T) {
+		structured := []byte(`{"specversion":"1.0","id":"a","source":"s","type":"t","datacontenttype":"text/plain","data":"hello","count":3}`)
+		data, headers, err := BinaryCloudEventFromStructured(structured, CloudEventsHeaderPrefix)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+		assert.Equal(t, "3", headers["ce_count"])
+
+		res, err := StructuredCloudEventFromBinary(data, headers, CloudEventsHeaderPrefix)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"specversion":"1.0","id":"a","source":"s","type":"t","datacontenttype":"text/plain","data":"hello","count":"3"}`, string(res))
+	})
+}
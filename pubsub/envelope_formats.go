@@ -0,0 +1,622 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	contribContenttype "github.com/dapr/components-contrib/contenttype"
+)
+
+const (
+	// CloudEventProtobufContentType is the content type of CloudEvents in the protobuf format.
+	CloudEventProtobufContentType = "application/cloudevents+protobuf"
+	// CloudEventAvroContentType is the content type of CloudEvents in the Avro format.
+	CloudEventAvroContentType = "application/cloudevents+avro"
+)
+
+// Field numbers of the CloudEvent and CloudEventAttributeValue messages
+// of the CloudEvents protobuf format.
+const (
+	protoIDField          protowire.Number = 1
+	protoSourceField      protowire.Number = 2
+	protoSpecVersionField protowire.Number = 3
+	protoTypeField        protowire.Number = 4
+	protoAttributesField  protowire.Number = 5
+	protoBinaryDataField  protowire.Number = 6
+	protoTextDataField    protowire.Number = 7
+
+	protoMapKeyField   protowire.Number = 1
+	protoMapValueField protowire.Number = 2
+
+	protoAttrBooleanField   protowire.Number = 1
+	protoAttrIntegerField   protowire.Number = 2
+	protoAttrStringField    protowire.Number = 3
+	protoAttrBytesField     protowire.Number = 4
+	protoAttrURIField       protowire.Number = 5
+	protoAttrURIRefField    protowire.Number = 6
+	protoAttrTimestampField protowire.Number = 7
+)
+
+// Well-known attributes with a dedicated type in the protobuf format.
+const dataSchemaField = "dataschema"
+
+// MarshalCloudEventProtobuf serializes the map representation of a CloudEvent in the CloudEvents protobuf format.
+func MarshalCloudEventProtobuf(cloudEvent map[string]interface{}) ([]byte, error) {
+	var b []byte
+	for attr, value := range cloudEvent {
+		if value == nil {
+			continue
+		}
+
+		var err error
+		switch attr {
+		case IDField:
+			b = appendProtoString(b, protoIDField, fmt.Sprint(value))
+		case SourceField:
+			b = appendProtoString(b, protoSourceField, fmt.Sprint(value))
+		case SpecVersionField:
+			b = appendProtoString(b, protoSpecVersionField, fmt.Sprint(value))
+		case TypeField:
+			b = appendProtoString(b, protoTypeField, fmt.Sprint(value))
+		case DataBase64Field:
+			str, ok := value.(string)
+			if !ok {
+				return nil, errors.New("data_base64 of CloudEvent is not a string")
+			}
+			var data []byte
+			data, err = base64.StdEncoding.DecodeString(str)
+			b = protowire.AppendTag(b, protoBinaryDataField, protowire.BytesType)
+			b = protowire.AppendBytes(b, data)
+		case DataField:
+			text, ok := value.(string)
+			if !ok || contribContenttype.IsJSONContentType(fmt.Sprint(cloudEvent[DataContentTypeField])) {
+				var data []byte
+				data, err = json.Marshal(value)
+				text = string(data)
+			}
+			b = appendProtoString(b, protoTextDataField, text)
+		default:
+			var attrValue []byte
+			attrValue, err = marshalProtoAttribute(attr, value)
+			entry := appendProtoString(nil, protoMapKeyField, attr)
+			entry = protowire.AppendTag(entry, protoMapValueField, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, attrValue)
+			b = protowire.AppendTag(b, protoAttributesField, protowire.BytesType)
+			b = protowire.AppendBytes(b, entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot serialize CloudEvent attribute %s: %w", attr, err)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalCloudEventProtobuf parses a CloudEvent in the CloudEvents protobuf format into its map representation.
+func UnmarshalCloudEventProtobuf(data []byte) (map[string]interface{}, error) {
+	ce := make(map[string]interface{})
+	var textData *string
+	err := rangeProtoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case protoIDField:
+			ce[IDField] = string(value)
+		case protoSourceField:
+			ce[SourceField] = string(value)
+		case protoSpecVersionField:
+			ce[SpecVersionField] = string(value)
+		case protoTypeField:
+			ce[TypeField] = string(value)
+		case protoBinaryDataField:
+			ce[DataBase64Field] = base64.StdEncoding.EncodeToString(value)
+		case protoTextDataField:
+			text := string(value)
+			textData = &text
+		case protoAttributesField:
+			return unmarshalProtoAttributeEntry(value, ce)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if textData != nil {
+		ce[DataField] = *textData
+		if contribContenttype.IsJSONContentType(fmt.Sprint(ce[DataContentTypeField])) {
+			var ceData interface{}
+			if err = unmarshalPrecise([]byte(*textData), &ceData); err == nil {
+				ce[DataField] = ceData
+			}
+		}
+	}
+
+	return ce, nil
+}
+
+func appendProtoString(b []byte, num protowire.Number, value string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func marshalProtoAttribute(attr string, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case bool:
+		b := protowire.AppendTag(nil, protoAttrBooleanField, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v)), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil || i < math.MinInt32 || i > math.MaxInt32 {
+			return appendProtoString(nil, protoAttrStringField, v.String()), nil
+		}
+		b := protowire.AppendTag(nil, protoAttrIntegerField, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(int32(i))), nil
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return appendProtoString(nil, protoAttrStringField, fmt.Sprint(v)), nil
+		}
+		b := protowire.AppendTag(nil, protoAttrIntegerField, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(int32(v))), nil
+	case []byte:
+		b := protowire.AppendTag(nil, protoAttrBytesField, protowire.BytesType)
+		return protowire.AppendBytes(b, v), nil
+	}
+
+	str, err := attributeString(value)
+	if err != nil {
+		return nil, err
+	}
+	switch attr {
+	case TimeField:
+		if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(t.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(t.Nanosecond()))
+			b := protowire.AppendTag(nil, protoAttrTimestampField, protowire.BytesType)
+			return protowire.AppendBytes(b, ts), nil
+		}
+	case dataSchemaField:
+		return appendProtoString(nil, protoAttrURIField, str), nil
+	}
+	return appendProtoString(nil, protoAttrStringField, str), nil
+}
+
+func unmarshalProtoAttributeEntry(entry []byte, ce map[string]interface{}) error {
+	var (
+		key   string
+		value interface{}
+	)
+	err := rangeProtoFields(entry, func(num protowire.Number, typ protowire.Type, field []byte) error {
+		switch num {
+		case protoMapKeyField:
+			key = string(field)
+		case protoMapValueField:
+			var err error
+			value, err = unmarshalProtoAttribute(field)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		ce[key] = value
+	}
+	return nil
+}
+
+func unmarshalProtoAttribute(data []byte) (interface{}, error) {
+	var value interface{}
+	err := rangeProtoFields(data, func(num protowire.Number, typ protowire.Type, field []byte) error {
+		switch num {
+		case protoAttrBooleanField, protoAttrIntegerField:
+			v, n := protowire.ConsumeVarint(field)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if num == protoAttrBooleanField {
+				value = protowire.DecodeBool(v)
+			} else {
+				value = json.Number(fmt.Sprint(int32(v)))
+			}
+		case protoAttrStringField, protoAttrURIField, protoAttrURIRefField:
+			value = string(field)
+		case protoAttrBytesField:
+			value = base64.StdEncoding.EncodeToString(field)
+		case protoAttrTimestampField:
+			var seconds, nanos uint64
+			err := rangeProtoFields(field, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				n, l := protowire.ConsumeVarint(v)
+				if l < 0 {
+					return protowire.ParseError(l)
+				}
+				if num == 1 {
+					seconds = n
+				} else if num == 2 {
+					nanos = n
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			value = time.Unix(int64(seconds), int64(nanos)).UTC().Format(time.RFC3339Nano)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// rangeProtoFields calls fn for each field of a protobuf message.
+// For varint fields, the value passed to fn is the encoded varint.
+func rangeProtoFields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf message: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return fmt.Errorf("invalid protobuf message: %w", protowire.ParseError(m))
+			}
+			value, n = v, m
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, data)
+			if m < 0 {
+				return fmt.Errorf("invalid protobuf message: %w", protowire.ParseError(m))
+			}
+			value, n = data[:m], m
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloudEventAvroSchema is the schema of the CloudEvents Avro format.
+const cloudEventAvroSchema = `{
+	"namespace": "io.cloudevents",
+	"type": "record",
+	"name": "CloudEvent",
+	"fields": [
+		{"name": "attribute", "type": {"type": "map", "values": ["null", "boolean", "int", "string", "bytes"]}},
+		{"name": "data", "type": [
+			"bytes", "null", "boolean",
+			{"type": "map", "values": ["null", "boolean", {
+				"type": "record", "name": "CloudEventData", "fields": [
+					{"name": "value", "type": ["null", "boolean", {"type": "map", "values": "CloudEventData"}, {"type": "array", "items": "CloudEventData"}, "double", "string"]}
+				]
+			}, "double", "string"]},
+			{"type": "array", "items": "CloudEventData"},
+			"double", "string"
+		]}
+	]
+}`
+
+var cloudEventAvro = avro.MustParse(cloudEventAvroSchema)
+
+const avroCloudEventDataName = "io.cloudevents.CloudEventData"
+
+// MarshalCloudEventAvro serializes the map representation of a CloudEvent in the CloudEvents Avro format.
+func MarshalCloudEventAvro(cloudEvent map[string]interface{}) ([]byte, error) {
+	record, err := cloudEventToAvro(cloudEvent)
+	if err != nil {
+		return nil, err
+	}
+	return avro.Marshal(cloudEventAvro, record)
+}
+
+// UnmarshalCloudEventAvro parses a CloudEvent in the CloudEvents Avro format into its map representation.
+func UnmarshalCloudEventAvro(data []byte) (map[string]interface{}, error) {
+	var record map[string]interface{}
+	if err := avro.Unmarshal(cloudEventAvro, data, &record); err != nil {
+		return nil, err
+	}
+	return cloudEventFromAvro(record), nil
+}
+
+// IsCloudEventFormatContentType checks if the content type is one of the CloudEvents formats supported by MarshalCloudEventFormat and UnmarshalCloudEventFormat.
+func IsCloudEventFormatContentType(contentType string) bool {
+	switch normalizeContentType(contentType) {
+	case contribContenttype.CloudEventContentType, CloudEventProtobufContentType, CloudEventAvroContentType:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarshalCloudEventFormat serializes the map representation of a CloudEvent in the
+// CloudEvents format identified by the content type.
+func MarshalCloudEventFormat(cloudEvent map[string]interface{}, contentType string) ([]byte, error) {
+	switch normalizeContentType(contentType) {
+	case contribContenttype.CloudEventContentType:
+		return json.Marshal(cloudEvent)
+	case CloudEventProtobufContentType:
+		return MarshalCloudEventProtobuf(cloudEvent)
+	case CloudEventAvroContentType:
+		return MarshalCloudEventAvro(cloudEvent)
+	default:
+		return nil, fmt.Errorf("unsupported CloudEvents format: %s", contentType)
+	}
+}
+
+// UnmarshalCloudEventFormat parses a CloudEvent in the CloudEvents format identified
+// by the content type into its map representation.
+func UnmarshalCloudEventFormat(data []byte, contentType string) (map[string]interface{}, error) {
+	switch normalizeContentType(contentType) {
+	case contribContenttype.CloudEventContentType:
+		var ce map[string]interface{}
+		if err := unmarshalPrecise(data, &ce); err != nil {
+			return nil, err
+		}
+		return ce, nil
+	case CloudEventProtobufContentType:
+		return UnmarshalCloudEventProtobuf(data)
+	case CloudEventAvroContentType:
+		return UnmarshalCloudEventAvro(data)
+	default:
+		return nil, fmt.Errorf("unsupported CloudEvents format: %s", contentType)
+	}
+}
+
+// CloudEventFormatFromStructured converts a structured-mode JSON CloudEvent to the CloudEvents format
+// identified by the content type.
+func CloudEventFormatFromStructured(cloudEvent []byte, contentType string) ([]byte, error) {
+	var ce map[string]interface{}
+	if err := unmarshalPrecise(cloudEvent, &ce); err != nil {
+		return nil, fmt.Errorf("cannot parse CloudEvent: %w", err)
+	}
+	return MarshalCloudEventFormat(ce, contentType)
+}
+
+// StructuredCloudEventFromFormat converts a CloudEvent in the CloudEvents format identified by the
+// content type to a structured-mode JSON CloudEvent.
+func StructuredCloudEventFromFormat(data []byte, contentType string) ([]byte, error) {
+	ce, err := UnmarshalCloudEventFormat(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ce)
+}
+
+func normalizeContentType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Values of Avro unions are represented as single-entry maps keyed by the type name,
+// with an empty map for null.
+func cloudEventToAvro(cloudEvent map[string]interface{}) (map[string]interface{}, error) {
+	attributes := make(map[string]interface{}, len(cloudEvent))
+	data := map[string]interface{}{}
+	for attr, value := range cloudEvent {
+		switch attr {
+		case DataBase64Field:
+			str, ok := value.(string)
+			if !ok {
+				return nil, errors.New("data_base64 of CloudEvent is not a string")
+			}
+			b, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode data_base64 of CloudEvent: %w", err)
+			}
+			data = map[string]interface{}{"bytes": b}
+		case DataField:
+			data = avroData(value)
+		default:
+			switch v := value.(type) {
+			case nil:
+				attributes[attr] = map[string]interface{}{}
+			case bool:
+				attributes[attr] = map[string]interface{}{"boolean": v}
+			case []byte:
+				attributes[attr] = map[string]interface{}{"bytes": v}
+			default:
+				if n, ok := v.(json.Number); ok {
+					if i, err := n.Int64(); err == nil && i >= math.MinInt32 && i <= math.MaxInt32 {
+						attributes[attr] = map[string]interface{}{"int": int(i)}
+						continue
+					}
+				}
+				str, err := attributeString(v)
+				if err != nil {
+					return nil, fmt.Errorf("cannot convert CloudEvent attribute %s: %w", attr, err)
+				}
+				attributes[attr] = map[string]interface{}{"string": str}
+			}
+		}
+	}
+	return map[string]interface{}{"attribute": attributes, "data": data}, nil
+}
+
+// avroScalar wraps a JSON scalar in the Avro union representation.
+func avroScalar(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case nil:
+		return map[string]interface{}{}
+	case bool:
+		return map[string]interface{}{"boolean": v}
+	case string:
+		return map[string]interface{}{"string": v}
+	case json.Number:
+		f, _ := v.Float64()
+		return map[string]interface{}{"double": f}
+	case float64:
+		return map[string]interface{}{"double": v}
+	case int:
+		return map[string]interface{}{"double": float64(v)}
+	default:
+		b, _ := json.Marshal(v)
+		return map[string]interface{}{"string": string(b)}
+	}
+}
+
+// avroData converts a JSON value to the data field of the Avro format.
+func avroData(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				obj[k] = map[string]interface{}{avroCloudEventDataName: avroDataRecord(item)}
+			default:
+				obj[k] = avroScalar(item)
+			}
+		}
+		return map[string]interface{}{"map": obj}
+	case []interface{}:
+		return map[string]interface{}{"array": avroDataRecords(v)}
+	default:
+		return avroScalar(v)
+	}
+}
+
+// avroDataRecord converts a JSON value to a CloudEventData record.
+func avroDataRecord(value interface{}) map[string]interface{} {
+	var union map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			obj[k] = avroDataRecord(item)
+		}
+		union = map[string]interface{}{"map": obj}
+	case []interface{}:
+		union = map[string]interface{}{"array": avroDataRecords(v)}
+	default:
+		union = avroScalar(v)
+	}
+	return map[string]interface{}{"value": union}
+}
+
+func avroDataRecords(arr []interface{}) []interface{} {
+	records := make([]interface{}, len(arr))
+	for i, item := range arr {
+		records[i] = avroDataRecord(item)
+	}
+	return records
+}
+
+func cloudEventFromAvro(record map[string]interface{}) map[string]interface{} {
+	ce := make(map[string]interface{})
+	if attributes, ok := record["attribute"].(map[string]interface{}); ok {
+		for attr, value := range attributes {
+			_, v := avroUnionEntry(value)
+			if b, ok := v.([]byte); ok {
+				v = base64.StdEncoding.EncodeToString(b)
+			}
+			if i, ok := v.(int); ok {
+				v = json.Number(fmt.Sprint(i))
+			}
+			ce[attr] = v
+		}
+	}
+
+	data := jsonFromAvroData(record["data"])
+	if b, ok := data.([]byte); ok {
+		ce[DataBase64Field] = base64.StdEncoding.EncodeToString(b)
+	} else if data != nil {
+		ce[DataField] = data
+	}
+	return ce
+}
+
+// jsonFromAvroData converts the decoded data field of the Avro format back to a JSON value.
+func jsonFromAvroData(union interface{}) interface{} {
+	name, value := avroUnionEntry(union)
+	switch name {
+	case "map":
+		obj, _ := value.(map[string]interface{})
+		res := make(map[string]interface{}, len(obj))
+		for k, item := range obj {
+			itemName, itemValue := avroUnionEntry(item)
+			if itemName == avroCloudEventDataName {
+				res[k] = jsonFromAvroDataRecord(itemValue)
+			} else {
+				res[k] = itemValue
+			}
+		}
+		return res
+	case "array":
+		arr, _ := value.([]interface{})
+		res := make([]interface{}, len(arr))
+		for i, item := range arr {
+			res[i] = jsonFromAvroDataRecord(item)
+		}
+		return res
+	default:
+		return value
+	}
+}
+
+// jsonFromAvroDataRecord converts a decoded CloudEventData record back to a JSON value.
+func jsonFromAvroDataRecord(record interface{}) interface{} {
+	rec, _ := record.(map[string]interface{})
+	name, value := avroUnionEntry(rec["value"])
+	switch name {
+	case "map":
+		obj, _ := value.(map[string]interface{})
+		res := make(map[string]interface{}, len(obj))
+		for k, item := range obj {
+			res[k] = jsonFromAvroDataRecord(item)
+		}
+		return res
+	case "array":
+		arr, _ := value.([]interface{})
+		res := make([]interface{}, len(arr))
+		for i, item := range arr {
+			res[i] = jsonFromAvroDataRecord(item)
+		}
+		return res
+	default:
+		return value
+	}
+}
+
+// avroUnionEntry returns the type name and the value of a decoded Avro union.
+func avroUnionEntry(union interface{}) (string, interface{}) {
+	m, ok := union.(map[string]interface{})
+	if !ok {
+		return "", union
+	}
+	for name, value := range m {
+		return name, value
+	}
+	return "null", nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCloudEvents() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			SpecVersionField:     "1.0",
+			IDField:              "a",
+			SourceField:          "/orders",
+			TypeField:            "order.created",
+			TimeField:            "2023-01-31T10:00:00Z",
+			DataContentTypeField: "application/json",
+			"retries":            json.Number("3"),
+			"urgent":             true,
+			DataField: map[string]interface{}{
+				"item":  "book",
+				"price": json.Number("9.5"),
+				"tags":  []interface{}{"new", json.Number("1")},
+				"buyer": map[string]interface{}{"name": "x", "vip": false, "note": nil},
+			},
+		},
+		{
+			SpecVersionField:     "1.0",
+			IDField:              "b",
+			SourceField:          "/orders",
+			TypeField:            "order.attachment",
+			DataContentTypeField: "application/octet-stream",
+			DataBase64Field:      "AQID",
+		},
+		{
+			SpecVersionField:     "1.0",
+			IDField:              "c",
+			SourceField:          "/orders",
+			TypeField:            "order.note",
+			DataContentTypeField: "text/plain",
+			DataField:            "hello",
+		},
+	}
+}
+
+func assertCloudEventJSONEq(t *testing.T, expected, actual map[string]interface{}) {
+	t.Helper()
+
+	e, err := json.Marshal(expected)
+	require.NoError(t, err)
+	a, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(e), string(a))
+}
+
+func TestCloudEventProtobuf(t *testing.T) {
+	t.Run("single event", func(t *testing.T) {
+		for _, ce := range testCloudEvents() {
+			b, err := MarshalCloudEventProtobuf(ce)
+			require.NoError(t, err)
+
+			res, err := UnmarshalCloudEventProtobuf(b)
+			require.NoError(t, err)
+			assertCloudEventJSONEq(t, ce, res)
+		}
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		_, err := UnmarshalCloudEventProtobuf([]byte{0x0a, 0x05, 'a'})
+		require.Error(t, err)
+	})
+}
+
+func TestCloudEventAvro(t *testing.T) {
+	t.Run("single event", func(t *testing.T) {
+		for _, ce := range testCloudEvents() {
+			b, err := MarshalCloudEventAvro(ce)
+			require.NoError(t, err)
+
+			res, err := UnmarshalCloudEventAvro(b)
+			require.NoError(t, err)
+			assertCloudEventJSONEq(t, ce, res)
+		}
+	})
+
+}
+
+func TestCloudEventFormatFromStructured(t *testing.T) {
+	structured := []byte(`{"specversion":"1.0","id":"a","source":"s","type":"t","datacontenttype":"application/json","data":{"count":9007199254740993}}`)
+
+	for _, contentType := range []string{CloudEventProtobufContentType, CloudEventAvroContentType + "; charset=utf-8"} {
+		b, err := CloudEventFormatFromStructured(structured, contentType)
+		require.NoError(t, err, contentType)
+
+		res, err := StructuredCloudEventFromFormat(b, contentType)
+		require.NoError(t, err, contentType)
+		assert.JSONEq(t, string(structured), string(res), contentType)
+	}
+
+	_, err := CloudEventFormatFromStructured([]byte("not json"), CloudEventProtobufContentType)
+	require.Error(t, err)
+	_, err = CloudEventFormatFromStructured(structured, "application/xml")
+	require.Error(t, err)
+}
@@ -61,6 +61,16 @@ func (p *PubSub) BulkSubscribe(ctx context.Context, req pubsub.SubscribeRequest,
 }
 
 func (p *PubSub) subscribeUtil(ctx context.Context, req pubsub.SubscribeRequest, handlerConfig kafka.SubscriptionHandlerConfig) error {
+	valueSchemaType, err := kafka.GetValueSchemaType(req.Metadata)
+	if err != nil {
+		return err
+	}
+	handlerConfig.ValueSchemaType = valueSchemaType
+	handlerConfig.StartPosition, err = kafka.GetStartPosition(req.Metadata)
+	if err != nil {
+		return err
+	}
+
 	p.kafka.AddTopicHandler(req.Topic, handlerConfig)
 
 	go func() {
@@ -108,6 +118,14 @@ func (p *PubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest
 	return p.kafka.BulkPublish(ctx, req.Topic, req.Entries, req.Metadata)
 }
 
+// ResetOffsets moves the consumer group to new offsets on a topic.
+func (p *PubSub) ResetOffsets(_ context.Context, req pubsub.ResetOffsetsRequest) error {
+	return p.kafka.ResetOffsets(req.Topic, kafka.StartPosition{
+		Offsets:   req.Offsets,
+		Timestamp: req.Timestamp,
+	})
+}
+
 func (p *PubSub) Close() (err error) {
 	p.subscribeCancel()
 	return p.kafka.Close()
@@ -46,6 +46,12 @@ type BulkSubscriber interface {
 	BulkSubscribe(ctx context.Context, req SubscribeRequest, bulkHandler BulkHandler) error
 }
 
+// OffsetResetter is the interface for message buses that can move the position
+// of a consumer group on a topic, for example to replay messages after an incident.
+type OffsetResetter interface {
+	ResetOffsets(ctx context.Context, req ResetOffsetsRequest) error
+}
+
 // Handler is the handler used to invoke the app handler.
 type Handler func(ctx context.Context, msg *NewMessage) error
 
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rabbitmq
+
+import (
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	reqMetadataCloudEventsMode = "cloudEventsMode"
+	cloudEventsModeStructured  = "structured"
+	cloudEventsModeBinary      = "binary"
+	cloudEventsModeProtobuf    = "protobuf"
+	cloudEventsModeAvro        = "avro"
+
+	defaultContentType = "text/plain"
+)
+
+// newPublishing creates the AMQP message for a publish request.
+// With the `cloudEventsMode` request metadata set to `binary`, the structured CloudEvent
+// in the request is sent as a binary-mode CloudEvent, with its attributes in the
+// `cloudEvents:` application properties and its datacontenttype as the content type.
+// In protobuf and avro mode, the event is re-encoded in the corresponding CloudEvents format.
+func newPublishing(req *pubsub.PublishRequest) (amqp.Publishing, error) {
+	msg := amqp.Publishing{
+		ContentType: defaultContentType,
+		Body:        req.Data,
+	}
+
+	switch mode := strings.ToLower(req.Metadata[reqMetadataCloudEventsMode]); mode {
+	case "", cloudEventsModeStructured:
+		return msg, nil
+
+	case cloudEventsModeBinary:
+		data, headers, err := pubsub.BinaryCloudEventFromStructured(req.Data, pubsub.CloudEventsAMQPHeaderPrefix)
+		if err != nil {
+			return msg, fmt.Errorf("cannot convert message to a binary CloudEvent: %w", err)
+		}
+		msg.Body = data
+		msg.ContentType = ""
+		msg.Headers = make(amqp.Table, len(headers))
+		for name, value := range headers {
+			if name == pubsub.ContentTypeHeader {
+				msg.ContentType = value
+				continue
+			}
+			msg.Headers[name] = value
+		}
+		return msg, nil
+
+	case cloudEventsModeProtobuf, cloudEventsModeAvro:
+		contentType := pubsub.CloudEventProtobufContentType
+		if mode == cloudEventsModeAvro {
+			contentType = pubsub.CloudEventAvroContentType
+		}
+		data, err := pubsub.CloudEventFormatFromStructured(req.Data, contentType)
+		if err != nil {
+			return msg, fmt.Errorf("cannot convert message to %s: %w", contentType, err)
+		}
+		msg.Body = data
+		msg.ContentType = contentType
+		return msg, nil
+
+	default:
+		return msg, fmt.Errorf("invalid value for '%s' metadata: %s", reqMetadataCloudEventsMode, req.Metadata[reqMetadataCloudEventsMode])
+	}
+}
+
+// deliveryData returns the payload of a delivery, converting binary-mode CloudEvents
+// and CloudEvents in the protobuf or Avro format to structured JSON CloudEvents.
+func deliveryData(d amqp.Delivery) ([]byte, error) {
+	headers := make(map[string]string, len(d.Headers)+1)
+	for name, value := range d.Headers {
+		switch v := value.(type) {
+		case string:
+			headers[name] = v
+		case []byte:
+			headers[name] = string(v)
+		default:
+			headers[name] = fmt.Sprint(v)
+		}
+	}
+	if d.ContentType != "" {
+		headers[pubsub.ContentTypeHeader] = d.ContentType
+	}
+
+	if pubsub.IsBinaryCloudEvent(headers, pubsub.CloudEventsAMQPHeaderPrefix) {
+		return pubsub.StructuredCloudEventFromBinary(d.Body, headers, pubsub.CloudEventsAMQPHeaderPrefix)
+	}
+
+	if contenttype.IsCloudEventContentType(d.ContentType) || !pubsub.IsCloudEventFormatContentType(d.ContentType) {
+		return d.Body, nil
+	}
+	return pubsub.StructuredCloudEventFromFormat(d.Body, d.ContentType)
+}
@@ -191,7 +191,7 @@ func (r *rabbitMQ) reconnect(connectionCount int) error {
 	return nil
 }
 
-func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest) (rabbitMQChannelBroker, int, error) {
+func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest, msg amqp.Publishing) (rabbitMQChannelBroker, int, error) {
 	r.channelMutex.Lock()
 	defer r.channelMutex.Unlock()
 
@@ -221,12 +221,10 @@ func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest)
 		expiration = strconv.FormatInt(r.metadata.defaultQueueTTL.Milliseconds(), 10)
 	}
 
-	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(ctx, req.Topic, routingKey, false, false, amqp.Publishing{
-		ContentType:  "text/plain",
-		Body:         req.Data,
-		DeliveryMode: r.metadata.deliveryMode,
-		Expiration:   expiration,
-	})
+	msg.DeliveryMode = r.metadata.deliveryMode
+	msg.Expiration = expiration
+
+	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(ctx, req.Topic, routingKey, false, false, msg)
 	if err != nil {
 		r.logger.Errorf("%s publishing to %s failed in channel.Publish: %v", logMessagePrefix, req.Topic, err)
 
@@ -249,10 +247,16 @@ func (r *rabbitMQ) publishSync(ctx context.Context, req *pubsub.PublishRequest)
 func (r *rabbitMQ) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
 	r.logger.Debugf("%s publishing message to %s", logMessagePrefix, req.Topic)
 
+	msg, err := newPublishing(req)
+	if err != nil {
+		r.logger.Errorf("%s publishing to %s failed: %v", logMessagePrefix, req.Topic, err)
+		return err
+	}
+
 	attempt := 0
 	for {
 		attempt++
-		channel, connectionCount, err := r.publishSync(ctx, req)
+		channel, connectionCount, err := r.publishSync(ctx, req, msg)
 		if err == nil {
 			return nil
 		}
@@ -480,12 +484,17 @@ func (r *rabbitMQ) listenMessages(ctx context.Context, channel rabbitMQChannelBr
 }
 
 func (r *rabbitMQ) handleMessage(ctx context.Context, d amqp.Delivery, topic string, handler pubsub.Handler) error {
-	pubsubMsg := &pubsub.NewMessage{
-		Data:  d.Body,
-		Topic: topic,
-	}
+	data, err := deliveryData(d)
+	if err != nil {
+		err = fmt.Errorf("cannot convert CloudEvent: %w", err)
+	} else {
+		pubsubMsg := &pubsub.NewMessage{
+			Data:  data,
+			Topic: topic,
+		}
 
-	err := handler(ctx, pubsubMsg)
+		err = handler(ctx, pubsubMsg)
+	}
 
 	if err != nil {
 		r.logger.Errorf("%s handling message from topic '%s', %s", errorMessagePrefix, topic, err)
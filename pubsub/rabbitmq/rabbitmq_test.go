@@ -149,6 +149,79 @@ func TestPublishAndSubscribe(t *testing.T) {
 	assert.Equal(t, "foo bar", lastMessage)
 }
 
+func TestPublishAndSubscribeBinaryCloudEvent(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:   "anyhost",
+			metadataConsumerIDKey: "consumer",
+		},
+	}}
+	err := pubsubRabbitMQ.Init(metadata)
+	assert.Nil(t, err)
+
+	topic := "mytopic"
+	received := make(chan []byte, 1)
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		received <- msg.Data
+		return nil
+	}
+	err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler)
+	assert.Nil(t, err)
+
+	ce := `{"specversion":"1.0","id":"a","source":"/orders","type":"order.created","datacontenttype":"application/json","data":{"id":7}}`
+	err = pubsubRabbitMQ.Publish(context.Background(), &pubsub.PublishRequest{
+		Topic:    topic,
+		Data:     []byte(ce),
+		Metadata: map[string]string{reqMetadataCloudEventsMode: "binary"},
+	})
+	assert.Nil(t, err)
+	assert.JSONEq(t, ce, string(<-received))
+
+	err = pubsubRabbitMQ.Publish(context.Background(), &pubsub.PublishRequest{
+		Topic:    topic,
+		Data:     []byte(ce),
+		Metadata: map[string]string{reqMetadataCloudEventsMode: "unknown"},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewPublishingBinaryCloudEvent(t *testing.T) {
+	msg, err := newPublishing(&pubsub.PublishRequest{
+		Data:     []byte(`{"specversion":"1.0","id":"a","datacontenttype":"text/plain","data":"hello"}`),
+		Metadata: map[string]string{reqMetadataCloudEventsMode: "binary"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(msg.Body))
+	assert.Equal(t, "text/plain", msg.ContentType)
+	assert.Equal(t, amqp.Table{"cloudEvents:specversion": "1.0", "cloudEvents:id": "a"}, msg.Headers)
+}
+
+func TestNewPublishingAvroCloudEvent(t *testing.T) {
+	structured := `{"specversion":"1.0","id":"a","datacontenttype":"text/plain","data":"hello"}`
+	msg, err := newPublishing(&pubsub.PublishRequest{
+		Data:     []byte(structured),
+		Metadata: map[string]string{reqMetadataCloudEventsMode: "avro"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, pubsub.CloudEventAvroContentType, msg.ContentType)
+	assert.Nil(t, msg.Headers)
+
+	data, err := deliveryData(amqp.Delivery{Body: msg.Body, ContentType: msg.ContentType})
+	assert.Nil(t, err)
+	assert.JSONEq(t, structured, string(data))
+}
+
+func TestDeliveryDataProtobufCloudEvent(t *testing.T) {
+	body, err := pubsub.MarshalCloudEventProtobuf(map[string]interface{}{"specversion": "1.0", "id": "a", "data": "hello"})
+	assert.Nil(t, err)
+
+	data, err := deliveryData(amqp.Delivery{Body: body, ContentType: pubsub.CloudEventProtobufContentType})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"specversion":"1.0","id":"a","data":"hello"}`, string(data))
+}
+
 func TestPublishReconnect(t *testing.T) {
 	broker := newBroker()
 	pubsubRabbitMQ := newRabbitMQTest(broker)
@@ -327,8 +400,8 @@ func TestSubscribeReconnect(t *testing.T) {
 	assert.Equal(t, 4, broker.closeCount)   // two counts for each connection closure - one for connection, one for channel
 }
 
-func createAMQPMessage(body []byte) amqp.Delivery {
-	return amqp.Delivery{Body: body}
+func createAMQPMessage(msg amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{Body: msg.Body, ContentType: msg.ContentType, Headers: msg.Headers}
 }
 
 type rabbitMQInMemoryBroker struct {
@@ -353,7 +426,7 @@ func (r *rabbitMQInMemoryBroker) PublishWithDeferredConfirmWithContext(ctx conte
 		return nil, errors.New(errorChannelConnection)
 	}
 
-	r.buffer <- createAMQPMessage(msg.Body)
+	r.buffer <- createAMQPMessage(msg)
 
 	return nil, nil
 }
@@ -31,4 +31,10 @@ type metadata struct {
 
 	// the max len of stream
 	maxLenApprox int64
+	// The age after which messages are trimmed from the stream (0 disables time-based trimming)
+	retentionPeriod time.Duration
+	// The interval between retention runs
+	trimInterval time.Duration
+	// The amount of time a consumer without pending messages must be idle before it is removed (0 disables removal)
+	consumerIdleTimeout time.Duration
 }
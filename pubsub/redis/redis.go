@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
@@ -26,13 +27,16 @@ import (
 )
 
 const (
-	consumerID        = "consumerID"
-	enableTLS         = "enableTLS"
-	processingTimeout = "processingTimeout"
-	redeliverInterval = "redeliverInterval"
-	queueDepth        = "queueDepth"
-	concurrency       = "concurrency"
-	maxLenApprox      = "maxLenApprox"
+	consumerID          = "consumerID"
+	enableTLS           = "enableTLS"
+	processingTimeout   = "processingTimeout"
+	redeliverInterval   = "redeliverInterval"
+	queueDepth          = "queueDepth"
+	concurrency         = "concurrency"
+	maxLenApprox        = "maxLenApprox"
+	retentionPeriod     = "retentionPeriod"
+	trimInterval        = "trimInterval"
+	consumerIdleTimeout = "consumerIdleTimeout"
 )
 
 // redisStreams handles consuming from a Redis stream using
@@ -49,6 +53,9 @@ type redisStreams struct {
 
 	queue chan redisMessageWrapper
 
+	streams     map[string]bool
+	streamsLock sync.Mutex
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -74,6 +81,7 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		redeliverInterval: 15 * time.Second,
 		queueDepth:        100,
 		concurrency:       10,
+		trimInterval:      time.Minute,
 	}
 
 	if val, ok := meta.Properties[consumerID]; ok && val != "" {
@@ -126,6 +134,30 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		m.maxLenApprox = maxLenApprox
 	}
 
+	if val, ok := meta.Properties[retentionPeriod]; ok && val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return m, fmt.Errorf("redis streams error: can't parse retentionPeriod field: %s", err)
+		}
+		m.retentionPeriod = d
+	}
+
+	if val, ok := meta.Properties[trimInterval]; ok && val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return m, fmt.Errorf("redis streams error: invalid trimInterval %s", val)
+		}
+		m.trimInterval = d
+	}
+
+	if val, ok := meta.Properties[consumerIdleTimeout]; ok && val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return m, fmt.Errorf("redis streams error: can't parse consumerIdleTimeout field: %s", err)
+		}
+		m.consumerIdleTimeout = d
+	}
+
 	return m, nil
 }
 
@@ -151,10 +183,16 @@ func (r *redisStreams) Init(metadata pubsub.Metadata) error {
 		go r.worker()
 	}
 
+	if r.metadata.retentionPeriod > 0 || r.metadata.consumerIdleTimeout > 0 {
+		go r.retentionLoop(r.ctx)
+	}
+
 	return nil
 }
 
 func (r *redisStreams) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+	r.trackStream(req.Topic, false)
+
 	_, err := r.client.XAdd(ctx, req.Topic, r.metadata.maxLenApprox, map[string]interface{}{"data": req.Data})
 	if err != nil {
 		return fmt.Errorf("redis streams: error from publish: %s", err)
@@ -171,6 +209,8 @@ func (r *redisStreams) Subscribe(ctx context.Context, req pubsub.SubscribeReques
 		return err
 	}
 
+	r.trackStream(req.Topic, true)
+
 	go r.pollNewMessagesLoop(ctx, req.Topic, handler)
 	go r.reclaimPendingMessagesLoop(ctx, req.Topic, handler)
 
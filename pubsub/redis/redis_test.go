@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -126,3 +127,111 @@ func generateRedisStreamTestData(topicCount, messageCount int, data string) []in
 
 	return xmessageArray
 }
+
+func TestParseRetentionMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: getFakeProperties()}})
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), m.retentionPeriod)
+		assert.Equal(t, time.Minute, m.trimInterval)
+		assert.Equal(t, time.Duration(0), m.consumerIdleTimeout)
+	})
+
+	t.Run("retention settings", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[retentionPeriod] = "24h"
+		fakeProperties[trimInterval] = "5m"
+		fakeProperties[consumerIdleTimeout] = "1h"
+
+		m, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}})
+		assert.NoError(t, err)
+		assert.Equal(t, 24*time.Hour, m.retentionPeriod)
+		assert.Equal(t, 5*time.Minute, m.trimInterval)
+		assert.Equal(t, time.Hour, m.consumerIdleTimeout)
+	})
+
+	t.Run("invalid trimInterval", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[trimInterval] = "0s"
+
+		_, err := parseRedisMetadata(pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}})
+		assert.Error(t, err)
+	})
+}
+
+type fakeRetentionClient struct {
+	internalredis.RedisClient
+
+	minIDs           []string
+	consumers        []internalredis.RedisXInfoConsumer
+	deletedConsumers []string
+}
+
+func (c *fakeRetentionClient) XTrimMinIDApprox(ctx context.Context, stream string, minID string) (int64, error) {
+	c.minIDs = append(c.minIDs, stream+"/"+minID)
+	return 1, nil
+}
+
+func (c *fakeRetentionClient) XInfoConsumersResult(ctx context.Context, stream string, group string) ([]internalredis.RedisXInfoConsumer, error) {
+	return c.consumers, nil
+}
+
+func (c *fakeRetentionClient) XGroupDelConsumer(ctx context.Context, stream string, group string, consumer string) (int64, error) {
+	c.deletedConsumers = append(c.deletedConsumers, consumer)
+	return 0, nil
+}
+
+func TestRetention(t *testing.T) {
+	t.Run("trim stream by age", func(t *testing.T) {
+		client := &fakeRetentionClient{}
+		r := &redisStreams{
+			client:   client,
+			logger:   logger.NewLogger("test"),
+			metadata: metadata{retentionPeriod: time.Hour},
+		}
+
+		r.trimStream(context.Background(), "orders", time.UnixMilli(7200000))
+		assert.Equal(t, []string{"orders/3600000-0"}, client.minIDs)
+	})
+
+	t.Run("remove idle consumers", func(t *testing.T) {
+		client := &fakeRetentionClient{
+			consumers: []internalredis.RedisXInfoConsumer{
+				{Name: "fakeConsumer", Idle: 2 * time.Hour},
+				{Name: "old-pod", Idle: 2 * time.Hour},
+				{Name: "old-pod-with-pending", Pending: 3, Idle: 2 * time.Hour},
+				{Name: "active-pod", Idle: time.Second},
+			},
+		}
+		r := &redisStreams{
+			client:   client,
+			logger:   logger.NewLogger("test"),
+			metadata: metadata{consumerID: "fakeConsumer", consumerIdleTimeout: time.Hour},
+		}
+
+		r.removeIdleConsumers(context.Background(), "orders")
+		assert.Equal(t, []string{"old-pod"}, client.deletedConsumers)
+	})
+
+	t.Run("only subscribed streams are cleaned up", func(t *testing.T) {
+		client := &fakeRetentionClient{
+			consumers: []internalredis.RedisXInfoConsumer{{Name: "old-pod", Idle: 2 * time.Hour}},
+		}
+		r := &redisStreams{
+			client:   client,
+			logger:   logger.NewLogger("test"),
+			metadata: metadata{consumerID: "fakeConsumer", retentionPeriod: time.Hour, consumerIdleTimeout: time.Hour},
+		}
+		r.trackStream("published", false)
+
+		r.applyRetention(context.Background())
+		assert.Len(t, client.minIDs, 1)
+		assert.Empty(t, client.deletedConsumers)
+
+		r.trackStream("subscribed", true)
+		r.trackStream("subscribed", false)
+		r.applyRetention(context.Background())
+		assert.Len(t, client.minIDs, 3)
+		assert.Equal(t, []string{"old-pod"}, client.deletedConsumers)
+	})
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// trackStream records a stream this component publishes to or consumes from,
+// so that the retention loop can trim it.
+func (r *redisStreams) trackStream(stream string, subscribed bool) {
+	r.streamsLock.Lock()
+	defer r.streamsLock.Unlock()
+
+	if r.streams == nil {
+		r.streams = make(map[string]bool)
+	}
+	r.streams[stream] = r.streams[stream] || subscribed
+}
+
+// retentionLoop periodically trims the tracked streams and removes idle consumers
+// based on the `retentionPeriod` and `consumerIdleTimeout` settings.
+func (r *redisStreams) retentionLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.metadata.trimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			r.applyRetention(ctx)
+		}
+	}
+}
+
+func (r *redisStreams) applyRetention(ctx context.Context) {
+	r.streamsLock.Lock()
+	streams := make(map[string]bool, len(r.streams))
+	for stream, subscribed := range r.streams {
+		streams[stream] = subscribed
+	}
+	r.streamsLock.Unlock()
+
+	for stream, subscribed := range streams {
+		if r.metadata.retentionPeriod > 0 {
+			r.trimStream(ctx, stream, time.Now())
+		}
+		if subscribed && r.metadata.consumerIdleTimeout > 0 {
+			r.removeIdleConsumers(ctx, stream)
+		}
+	}
+}
+
+// trimStream removes the messages older than the retention period from the stream,
+// using `XTRIM MINID` with the message ID derived from the cutoff time.
+func (r *redisStreams) trimStream(ctx context.Context, stream string, now time.Time) {
+	minID := strconv.FormatInt(now.Add(-r.metadata.retentionPeriod).UnixMilli(), 10) + "-0"
+	trimmed, err := r.client.XTrimMinIDApprox(ctx, stream, minID)
+	if err != nil {
+		r.logger.Errorf("redis streams: error trimming stream %s: %v", stream, err)
+		return
+	}
+	if trimmed > 0 {
+		r.logger.Debugf("redis streams: trimmed %d messages older than %s from stream %s", trimmed, r.metadata.retentionPeriod, stream)
+	}
+}
+
+// removeIdleConsumers deletes the consumers of the group that have been idle for
+// longer than `consumerIdleTimeout` and have no pending messages.
+// Consumers with pending messages are left alone so that `reclaimPendingMessages`
+// can still claim their messages.
+func (r *redisStreams) removeIdleConsumers(ctx context.Context, stream string) {
+	consumers, err := r.client.XInfoConsumersResult(ctx, stream, r.metadata.consumerID)
+	if err != nil {
+		r.logger.Errorf("redis streams: error retrieving consumers of stream %s: %v", stream, err)
+		return
+	}
+
+	for _, consumer := range consumers {
+		if consumer.Name == r.metadata.consumerID || consumer.Pending > 0 || consumer.Idle < r.metadata.consumerIdleTimeout {
+			continue
+		}
+		if _, err = r.client.XGroupDelConsumer(ctx, stream, r.metadata.consumerID, consumer.Name); err != nil {
+			r.logger.Errorf("redis streams: error removing idle consumer %s from stream %s: %v", consumer.Name, stream, err)
+			continue
+		}
+		r.logger.Infof("redis streams: removed consumer %s from stream %s after being idle for %s", consumer.Name, stream, consumer.Idle)
+	}
+}
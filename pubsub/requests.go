@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // PublishRequest is the request to publish a message.
@@ -43,6 +44,14 @@ type SubscribeRequest struct {
 	BulkSubscribeConfig BulkSubscribeConfig `json:"bulkSubscribe,omitempty"`
 }
 
+// ResetOffsetsRequest is the request to move the position of a consumer group on a topic.
+// Offsets are keyed by partition and take precedence over Timestamp.
+type ResetOffsetsRequest struct {
+	Topic     string          `json:"topic"`
+	Offsets   map[int32]int64 `json:"offsets,omitempty"`
+	Timestamp time.Time       `json:"timestamp,omitempty"`
+}
+
 // NewMessage is an event arriving from a message bus instance.
 type NewMessage struct {
 	Data        []byte            `json:"data"`
@@ -15,7 +15,9 @@ package parameterstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
 	"time"
@@ -96,6 +98,10 @@ func (o *oosSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 		ParameterVersion: parameterVersion,
 	}, runtime)
 	if err != nil {
+		var sdkErr *tea.SDKError
+		if errors.As(err, &sdkErr) && tea.IntValue(sdkErr.StatusCode) == http.StatusNotFound {
+			return secretstores.GetSecretResponse{Data: nil}, secretstores.NewSecretNotFoundError(fmt.Errorf("couldn't get secret: %w", err))
+		}
 		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %w", err)
 	}
 
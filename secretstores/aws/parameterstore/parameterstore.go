@@ -15,10 +15,12 @@ package parameterstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 
@@ -86,6 +88,10 @@ func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && (awsErr.Code() == ssm.ErrCodeParameterNotFound || awsErr.Code() == ssm.ErrCodeParameterVersionNotFound) {
+			return secretstores.GetSecretResponse{Data: nil}, secretstores.NewSecretNotFoundError(fmt.Errorf("couldn't get secret: %s", err))
+		}
 		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %s", err)
 	}
 
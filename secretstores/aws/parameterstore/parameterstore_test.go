@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
@@ -162,6 +163,23 @@ func TestGetSecret(t *testing.T) {
 		}
 		_, err := s.GetSecret(context.Background(), req)
 		assert.NotNil(t, err)
+		assert.NotErrorIs(t, err, secretstores.ErrSecretNotFound)
+	})
+
+	t.Run("secret not found", func(t *testing.T) {
+		s := ssmSecretStore{
+			client: &mockedSSM{
+				GetParameterFn: func(ctx context.Context, input *ssm.GetParameterInput, option ...request.Option) (*ssm.GetParameterOutput, error) {
+					return nil, awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil)
+				},
+			},
+		}
+		req := secretstores.GetSecretRequest{
+			Name:     "/aws/dev/secret",
+			Metadata: map[string]string{},
+		}
+		_, err := s.GetSecret(context.Background(), req)
+		assert.ErrorIs(t, err, secretstores.ErrSecretNotFound)
 	})
 }
 
@@ -16,9 +16,11 @@ package secretmanager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 
@@ -85,6 +87,10 @@ func (s *smSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecre
 		VersionStage: versionStage,
 	})
 	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+			return secretstores.GetSecretResponse{Data: nil}, secretstores.NewSecretNotFoundError(fmt.Errorf("couldn't get secret: %s", err))
+		}
 		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %s", err)
 	}
 
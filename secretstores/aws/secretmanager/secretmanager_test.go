@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
@@ -149,6 +150,23 @@ func TestGetSecret(t *testing.T) {
 		}
 		_, err := s.GetSecret(context.Background(), req)
 		assert.NotNil(t, err)
+		assert.NotErrorIs(t, err, secretstores.ErrSecretNotFound)
+	})
+
+	t.Run("secret not found", func(t *testing.T) {
+		s := smSecretStore{
+			client: &mockedSM{
+				GetSecretValueFn: func(ctx context.Context, input *secretsmanager.GetSecretValueInput, option ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+					return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+				},
+			},
+		}
+		req := secretstores.GetSecretRequest{
+			Name:     "/aws/secret/testing",
+			Metadata: map[string]string{},
+		}
+		_, err := s.GetSecret(context.Background(), req)
+		assert.ErrorIs(t, err, secretstores.ErrSecretNotFound)
 	})
 }
 
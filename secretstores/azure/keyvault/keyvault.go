@@ -15,7 +15,9 @@ package keyvault
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
@@ -122,6 +124,10 @@ func (k *keyvaultSecretStore) GetSecret(ctx context.Context, req secretstores.Ge
 
 	secretResp, err := k.vaultClient.GetSecret(ctx, req.Name, version, nil)
 	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return secretstores.GetSecretResponse{}, secretstores.NewSecretNotFoundError(err)
+		}
 		return secretstores.GetSecretResponse{}, err
 	}
 
@@ -23,6 +23,8 @@ import (
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/secretstores"
@@ -117,7 +119,9 @@ func (s *Store) GetSecret(ctx context.Context, req secretstores.GetSecretRequest
 	}
 
 	secret, err := s.getSecret(ctx, secretName, versionID)
-	if err != nil {
+	if status.Code(err) == codes.NotFound {
+		return res, secretstores.NewSecretNotFoundError(fmt.Errorf("failed to access secret version: %v", err))
+	} else if err != nil {
 		return res, fmt.Errorf("failed to access secret version: %v", err)
 	}
 
@@ -15,9 +15,12 @@ package csms
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"reflect"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
 	csms "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/csms/v1"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/csms/v1/model"
 	csmsRegion "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/csms/v1/region"
@@ -84,6 +87,10 @@ func (c *csmsSecretStore) GetSecret(ctx context.Context, req secretstores.GetSec
 
 	response, err := c.client.ShowSecretVersion(request)
 	if err != nil {
+		var resErr *sdkerr.ServiceResponseError
+		if errors.As(err, &resErr) && resErr.StatusCode == http.StatusNotFound {
+			return secretstores.GetSecretResponse{}, secretstores.NewSecretNotFoundError(err)
+		}
 		return secretstores.GetSecretResponse{}, err
 	}
 
@@ -20,6 +20,7 @@ import (
 	"os"
 	"reflect"
 
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
@@ -63,7 +64,9 @@ func (k *kubernetesSecretStore) GetSecret(ctx context.Context, req secretstores.
 	}
 
 	secret, err := k.kubeClient.CoreV1().Secrets(namespace).Get(ctx, req.Name, meta_v1.GetOptions{}) //nolint:nosnakecase
-	if err != nil {
+	if k8serrors.IsNotFound(err) {
+		return resp, secretstores.NewSecretNotFoundError(err)
+	} else if err != nil {
 		return resp, err
 	}
 
@@ -15,11 +15,37 @@ package secretstores
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dapr/components-contrib/health"
 )
 
+// ErrSecretNotFound is wrapped by the errors GetSecret returns when the secret doesn't exist, so callers can tell
+// missing secrets apart from failures of the store.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// NewSecretNotFoundError returns an error that wraps both err and ErrSecretNotFound, with the message of err.
+func NewSecretNotFoundError(err error) error {
+	return secretNotFoundError{err: err}
+}
+
+type secretNotFoundError struct {
+	err error
+}
+
+func (e secretNotFoundError) Error() string {
+	return e.err.Error()
+}
+
+func (e secretNotFoundError) Unwrap() error {
+	return e.err
+}
+
+func (e secretNotFoundError) Is(target error) bool {
+	return target == ErrSecretNotFound
+}
+
 // SecretStore is the interface for a component that handles secrets management.
 type SecretStore interface {
 	// Init authenticates with the actual secret store and performs other init operation
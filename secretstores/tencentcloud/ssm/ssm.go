@@ -18,8 +18,10 @@ import (
 	"errors"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	sdkerrors "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	ssm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ssm/v20190923"
 
@@ -104,6 +106,10 @@ func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 	ssmReq.VersionId = &versionID
 	ssResp, err := s.client.GetSecretValueWithContext(ctx, ssmReq)
 	if err != nil {
+		var sdkErr *sdkerrors.TencentCloudSDKError
+		if errors.As(err, &sdkErr) && strings.HasPrefix(sdkErr.GetCode(), ssm.RESOURCENOTFOUND) {
+			return response, secretstores.NewSecretNotFoundError(err)
+		}
 		return response, err
 	}
 	var val string
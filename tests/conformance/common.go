@@ -57,6 +57,7 @@ import (
 	b_postgres "github.com/dapr/components-contrib/bindings/postgres"
 	b_rabbitmq "github.com/dapr/components-contrib/bindings/rabbitmq"
 	b_redis "github.com/dapr/components-contrib/bindings/redis"
+	b_sqlite "github.com/dapr/components-contrib/bindings/sqlite"
 	c_redis "github.com/dapr/components-contrib/configuration/redis"
 	p_snssqs "github.com/dapr/components-contrib/pubsub/aws/snssqs"
 	p_eventhubs "github.com/dapr/components-contrib/pubsub/azure/eventhubs"
@@ -609,6 +610,8 @@ func loadOutputBindings(tc TestComponent) bindings.OutputBinding {
 		binding = b_kubemq.NewKubeMQ(testLogger)
 	case "postgres":
 		binding = b_postgres.NewPostgres(testLogger)
+	case "sqlite":
+		binding = b_sqlite.NewSQLite(testLogger)
 	default:
 		return nil
 	}